@@ -46,6 +46,7 @@ type Cluster struct {
 	configFile        string
 	kubeClientset     *kubernetes.Clientset
 	kubeAppsClient    *k8sappsv1.AppsV1Client
+	client            *request.Client
 }
 
 // Worker represents a cruiser or patrol worker
@@ -71,7 +72,6 @@ type AppPart struct {
 }
 
 var (
-	api request.Data
 	//orgID        string
 	//spaceID      string
 	totalWorkers int
@@ -83,8 +83,8 @@ func isInitialized() bool {
 	return true
 }
 
-// CreateCluster creates a new cluster
-func CreateCluster(clusterName string, inTotalWorkers int) (Cluster, error) {
+// CreateCluster creates a new cluster using the specified API client
+func CreateCluster(client *request.Client, clusterName string, inTotalWorkers int) (Cluster, error) {
 	if Debug {
 		fmt.Printf("CreateCluster(): %s\n", clusterName)
 	}
@@ -96,8 +96,8 @@ func CreateCluster(clusterName string, inTotalWorkers int) (Cluster, error) {
 
 	isInitialized()
 
-	api = request.Data{Action: config.ActionCreateCluster, ClusterName: clusterName, TotalWorkers: totalWorkers}
-	result := request.PerformRequest(api, true)
+	api := request.Data{Action: config.ActionCreateCluster, ClusterName: clusterName, TotalWorkers: totalWorkers}
+	result := client.PerformRequest(api, true)
 
 	if result.StatusCode == http.StatusCreated {
 		var dat map[string]interface{}
@@ -106,16 +106,16 @@ func CreateCluster(clusterName string, inTotalWorkers int) (Cluster, error) {
 			return clust, err
 		}
 		clusterID := dat["id"].(string)
-		result := request.PerformRequest(request.Data{Action: config.ActionGetCluster, ClusterName: clusterID, TotalWorkers: totalWorkers}, false)
+		result := client.PerformRequest(request.Data{Action: config.ActionGetCluster, ClusterName: clusterID, TotalWorkers: totalWorkers}, false)
 
-		clust = initCluster(result.Body)
+		clust = initCluster(client, result.Body)
 	} else {
 		fmt.Println(result.Status)
 		err = fmt.Errorf("CreateCluster action returned: %s", result.Status)
 		if result.StatusCode == http.StatusConflict {
 			fmt.Println("Assume its a conflict with existing cluster and delete it")
 			api = request.Data{Action: config.ActionDeleteCluster, ClusterName: clusterName}
-			result := request.PerformRequest(api, true)
+			result := client.PerformRequest(api, true)
 
 			if result.StatusCode == http.StatusOK {
 				fmt.Println("... Got OK on delete")
@@ -126,8 +126,8 @@ func CreateCluster(clusterName string, inTotalWorkers int) (Cluster, error) {
 	return clust, err
 }
 
-// GetClusters returns a list of clusters active within the org/space
-func GetClusters() ([]Cluster, error) {
+// GetClusters returns a list of clusters active within the org/space of the specified API client
+func GetClusters(client *request.Client) ([]Cluster, error) {
 	if Debug {
 		fmt.Println("GetClusters()")
 	}
@@ -135,7 +135,7 @@ func GetClusters() ([]Cluster, error) {
 	var dat []Cluster
 	var err error
 	api := request.Data{Action: config.ActionGetClusters}
-	result := request.PerformRequest(api, true)
+	result := client.PerformRequest(api, true)
 
 	if result.StatusCode == http.StatusOK {
 		if err = json.Unmarshal(result.Body, &dat); err != nil {
@@ -143,6 +143,9 @@ func GetClusters() ([]Cluster, error) {
 				fmt.Println(err)
 			}
 		}
+		for i := range dat {
+			dat[i].client = client
+		}
 	} else if result.StatusCode == http.StatusUnauthorized {
 		panic("ERROR: Unauthorized to make API requests")
 	} else {
@@ -154,12 +157,13 @@ func GetClusters() ([]Cluster, error) {
 	return dat, err
 }
 
-func initCluster(body []byte) Cluster {
+func initCluster(client *request.Client, body []byte) Cluster {
 	var cl Cluster
 	if err := json.Unmarshal(body, &cl); err != nil {
 		fmt.Println("ERROR: initCluster()")
 		panic(err)
 	}
+	cl.client = client
 
 	return cl
 }
@@ -171,7 +175,7 @@ func (cl *Cluster) getClusterConfig() bool {
 	var success = false
 	var err error
 
-	result := request.PerformRequest(request.Data{Action: config.ActionGetClusterConfig, ClusterName: cl.Name}, false)
+	result := cl.client.PerformRequest(request.Data{Action: config.ActionGetClusterConfig, ClusterName: cl.Name}, false)
 	if result.StatusCode == http.StatusOK && result.ContentType == "application/zip" {
 		var zipFile = "./" + cl.ID + ".zip"
 		var zipData *zip.ReadCloser
@@ -559,8 +563,8 @@ func (cl *Cluster) Delete() error {
 	}
 
 	var err error
-	api = request.Data{Action: config.ActionDeleteCluster, ClusterName: cl.Name}
-	result := request.PerformRequest(api, true)
+	api := request.Data{Action: config.ActionDeleteCluster, ClusterName: cl.Name}
+	result := cl.client.PerformRequest(api, true)
 
 	if result.StatusCode == http.StatusOK {
 		if Debug {
@@ -587,14 +591,14 @@ func (cl *Cluster) WaitForDeleted() bool {
 	}
 	var success = false
 
-	api = request.Data{Action: config.ActionGetCluster, ClusterName: cl.Name}
+	api := request.Data{Action: config.ActionGetCluster, ClusterName: cl.Name}
 
 	// Keep checking for 60 minutes
 	for i := 0; i < 180; i++ {
 		if Debug {
 			fmt.Printf("Testing for deleted: %s\n", cl.Name)
 		}
-		r := request.PerformRequest(api, false)
+		r := cl.client.PerformRequest(api, false)
 
 		if r.StatusCode == http.StatusNotFound {
 			break
@@ -619,7 +623,7 @@ func (cl *Cluster) WaitForDeployed() bool {
 	}
 	var success = false
 
-	api = request.Data{Action: config.ActionGetCluster, ClusterName: cl.Name}
+	api := request.Data{Action: config.ActionGetCluster, ClusterName: cl.Name}
 
 	// Keep checking for 60 minutes: It used to be 20 minutes, but now cluster seems to go active after workers active.
 outerLoop:
@@ -627,7 +631,7 @@ outerLoop:
 		if Debug {
 			fmt.Printf("Testing for deployed: %s\n", cl.Name)
 		}
-		r := request.PerformRequest(api, false)
+		r := cl.client.PerformRequest(api, false)
 
 		if r.StatusCode == http.StatusOK {
 			var dat map[string]interface{}
@@ -666,12 +670,12 @@ func (cl *Cluster) WaitForWorkers(waitMinutes int) bool {
 	}
 	var success = false
 
-	api = request.Data{Action: config.ActionGetClusterWorkers, ClusterName: cl.Name}
+	api := request.Data{Action: config.ActionGetClusterWorkers, ClusterName: cl.Name}
 
 	var waited float32
 	for {
 		fmt.Printf("Testing for deployed workers: %s\n", cl.Name)
-		r := request.PerformRequest(api, false)
+		r := cl.client.PerformRequest(api, false)
 
 		if r.StatusCode == http.StatusOK {
 			//fmt.Println(r.Body)
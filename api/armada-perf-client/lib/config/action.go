@@ -276,3 +276,8 @@ func (act *ActionType) Set(value string) error {
 
 	return errors.New("Invalid action")
 }
+
+// UnmarshalText allows an action to be specified by name in a configuration file
+func (act *ActionType) UnmarshalText(text []byte) error {
+	return act.Set(string(text))
+}
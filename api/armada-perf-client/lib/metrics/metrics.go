@@ -3,16 +3,20 @@ package metrics
 
 import (
 	"fmt"
-	"math"
-	"sort"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/config"
 	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
 )
 
+// HistogramDir is the directory the raw latency histograms are dumped to, for offline merging across runs.
+// Histograms aren't dumped if not set.
+var HistogramDir string
+
 // WorkerMetrics tracks worker creation times
 type WorkerMetrics struct {
 	MetricTime     int64
@@ -45,31 +49,28 @@ type RequestMetric struct {
 	ClusterWorkerStates ClusterWorkerStateMetrics
 	ActionFailed        bool
 	BackendFailed       bool
+
+	// Populated for requests issued by an open-loop scheduler.
+	// The difference is the delay in sending the request, which is included in the response time.
+	IntendedSendTime time.Time
+	ActualSendTime   time.Time
 }
 
 // ArmadaMetrics defines the metrics details
 type ArmadaMetrics []RequestMetric
 
-func percentile(values []float64, pcnt int) (float64, error) {
-	if pcnt < 0 || pcnt > 100 {
-		return 0.0, fmt.Errorf("Invalid percentile %d requested", pcnt)
-	}
+// WriteArmadaMetrics sends the results to the Bluemix metrics service
+func WriteArmadaMetrics(action config.ActionType, workerCount int, metrics *ArmadaMetrics, testName string, dbKey string) {
+	writeArmadaMetrics("", action, workerCount, metrics, testName, dbKey)
+}
 
-	var pcntVal float64
-	numValues := len(values)
-	if (numValues > 1) && (math.Remainder(float64(pcnt)*float64(numValues), 100.0) == 0) {
-		pos := pcnt * numValues / 100
-		lower := values[pos-1]
-		upper := values[pos]
-		pcntVal = (lower + upper) / 2.0
-	} else {
-		pcntVal = values[int(math.Ceil((float64(pcnt)/100.0)*float64(numValues)))-1]
-	}
-	return pcntVal, nil
+// WriteArmadaPhaseMetrics sends the results for a single action in a phase of a mixed workload scenario
+// to the Bluemix metrics service
+func WriteArmadaPhaseMetrics(phase string, action config.ActionType, workerCount int, metrics *ArmadaMetrics, testName string, dbKey string) {
+	writeArmadaMetrics(phase, action, workerCount, metrics, testName, dbKey)
 }
 
-// WriteArmadaMetrics sends the results to the Bluemix metrics service
-func WriteArmadaMetrics(action config.ActionType, workerCount int, metrics *ArmadaMetrics, testName string, dbKey string) {
+func writeArmadaMetrics(phase string, action config.ActionType, workerCount int, metrics *ArmadaMetrics, testName string, dbKey string) {
 
 	apiRequestCount := len(*metrics)
 
@@ -88,6 +89,9 @@ func WriteArmadaMetrics(action config.ActionType, workerCount int, metrics *Arma
 	}
 
 	metricsPrefix := strings.Join([]string{"armada_api", resourceCountStr, actionStr}, ".")
+	if len(phase) > 0 {
+		metricsPrefix = strings.Join([]string{"armada_api", phase, resourceCountStr, actionStr}, ".")
+	}
 
 	// We're supplied a slice of response and action times for each Armada API request.
 	// The request time is how long it takes armada-api to respond to our request
@@ -97,38 +101,36 @@ func WriteArmadaMetrics(action config.ActionType, workerCount int, metrics *Arma
 	// So, let's start by generating these one or two metrics:
 	//  the name is the specified prefix from the configuration file combined with the number and type of API request(s).
 	//  the value is the mean response time across all these requests/actions
-	var totalActionTime time.Duration
 	var totalResponseTime time.Duration
 
 	var bm []metricsservice.BluemixMetric
 
-	responseTimes := make([]float64, apiRequestCount)
-	actionTimes := make([]float64, apiRequestCount)
+	responseTimes := histogram.New()
+	actionTimes := histogram.New()
+	sendDelays := histogram.New()
 	var actionFailures int
 	var backendFailures int
-	var firstAction int
 	var clusterNames []string
 
-	for i, mval := range *metrics {
+	for _, mval := range *metrics {
 		//Gather a list of cluster names - these will be removed from the influx db metric name so it is displayed correctly
 		if len(mval.ClusterName) > 0 {
 			if !contains(clusterNames, mval.ClusterName) {
 				clusterNames = append(clusterNames, mval.ClusterName)
 			}
 		}
-		responseTimes[i] = mval.ResponseTime.Seconds()
+		responseTimes.Record(mval.ResponseTime)
+		if !mval.IntendedSendTime.IsZero() {
+			sendDelays.Record(mval.ActualSendTime.Sub(mval.IntendedSendTime))
+		}
 		if mval.ActionFailed {
 			actionFailures++
-			firstAction++
 			if mval.BackendFailed {
 				backendFailures++
 			}
 		} else if mval.ActionTime.Seconds() > 0 {
-			actionTimes[i] = mval.ActionTime.Seconds()
-			totalActionTime += mval.ActionTime
+			actionTimes.Record(mval.ActionTime)
 			totalResponseTime += mval.ResponseTime
-		} else {
-			firstAction++
 		}
 
 		for _, wm := range mval.Workers {
@@ -139,132 +141,106 @@ func WriteArmadaMetrics(action config.ActionType, workerCount int, metrics *Arma
 			})
 		}
 
-		type stateTimes struct {
-			minTime   time.Duration
-			maxTime   time.Duration
-			totalTime time.Duration
-		}
-
-		sm := make(map[string]*stateTimes)
+		sm := make(map[string]*histogram.Histogram)
 		for _, w := range mval.ClusterWorkerStates {
 			for s, d := range w.Metrics {
-				if val, ok := sm[s]; ok {
-					if d < val.minTime {
-						val.minTime = d
-					}
-					if d > val.maxTime {
-						val.maxTime = d
-					}
-					val.totalTime += d
-				} else {
-					sm[s] = new(stateTimes)
-					sm[s].minTime = d
-					sm[s].maxTime = d
-					sm[s].totalTime = d
+				if _, ok := sm[s]; !ok {
+					sm[s] = histogram.New()
 				}
+				sm[s].Record(d)
 			}
 		}
 
 		totalWorkers := len(mval.WorkerCreationTimes)
 		if totalWorkers > 0 {
-			var totalWorkerCreationTime float64
-
-			var minWorkerCreationTime = math.MaxFloat64
-			var maxWorkerCreationTime float64
+			workerCreationTimes := histogram.New()
 			for _, wm := range mval.WorkerCreationTimes {
-				if wm < minWorkerCreationTime {
-					minWorkerCreationTime = wm
-				}
-				if wm > maxWorkerCreationTime {
-					maxWorkerCreationTime = wm
-				}
-				totalWorkerCreationTime += wm
+				workerCreationTimes.Record(time.Duration(wm * float64(time.Second)))
 			}
 
-			meanWorkerCreationTime := totalWorkerCreationTime / float64(totalWorkers)
 			bm = append(bm, metricsservice.BluemixMetric{
 				Name:  strings.Join([]string{metricsPrefix, mval.ClusterName, "Min_Worker_Creation_Time", "min"}, "."),
-				Value: minWorkerCreationTime,
+				Value: workerCreationTimes.Min().Seconds(),
 			})
 			bm = append(bm, metricsservice.BluemixMetric{
 				Name:  strings.Join([]string{metricsPrefix, mval.ClusterName, "Max_Worker_Creation_Time", "max"}, "."),
-				Value: maxWorkerCreationTime,
+				Value: workerCreationTimes.Max().Seconds(),
 			})
 			bm = append(bm, metricsservice.BluemixMetric{
 				Name:  strings.Join([]string{metricsPrefix, mval.ClusterName, "Mean_Worker_Creation_Time", "sparse-avg"}, "."),
-				Value: meanWorkerCreationTime,
+				Value: workerCreationTimes.Mean().Seconds(),
 			})
+			bm = appendPercentiles(bm, strings.Join([]string{metricsPrefix, mval.ClusterName, "Worker_Creation_Time"}, "."), workerCreationTimes)
+			dumpHistogram(strings.Join([]string{metricsPrefix, mval.ClusterName, "Worker_Creation_Time"}, "."), workerCreationTimes)
 
-			// Generate min, mean and max state transition times across all workers.
+			// Generate min, mean, max and percentile state transition times across all workers.
 			for state, d := range sm {
 				bm = append(bm, metricsservice.BluemixMetric{
 					Name:  strings.Join([]string{metricsPrefix, mval.ClusterName, state, "duration", "min"}, "."),
-					Value: d.minTime.Seconds(),
+					Value: d.Min().Seconds(),
 				})
 				bm = append(bm, metricsservice.BluemixMetric{
 					Name:  strings.Join([]string{metricsPrefix, mval.ClusterName, state, "duration", "max"}, "."),
-					Value: d.maxTime.Seconds(),
+					Value: d.Max().Seconds(),
 				})
 				bm = append(bm, metricsservice.BluemixMetric{
 					Name:  strings.Join([]string{metricsPrefix, mval.ClusterName, state, "duration", "sparse-avg"}, "."),
-					Value: d.totalTime.Seconds() / float64(totalWorkers),
+					Value: d.Total().Seconds() / float64(totalWorkers),
 				})
+				bm = appendPercentiles(bm, strings.Join([]string{metricsPrefix, mval.ClusterName, state, "duration"}, "."), d)
+				dumpHistogram(strings.Join([]string{metricsPrefix, mval.ClusterName, state, "duration"}, "."), d)
 			}
 		}
 	}
 
 	// Generate response time metrics
-	sort.Float64s(responseTimes)
-	minResponseTime := responseTimes[0]
-	maxResponseTime := responseTimes[apiRequestCount-1]
 	meanResponseTime := totalResponseTime.Seconds() / float64(apiRequestCount)
 	bm = append(bm, metricsservice.BluemixMetric{
 		Name:  metricsPrefix + ".Min_Response_Time.min",
-		Value: minResponseTime,
+		Value: responseTimes.Min().Seconds(),
 	})
 	bm = append(bm, metricsservice.BluemixMetric{
 		Name:  metricsPrefix + ".Max_Response_Time.max",
-		Value: maxResponseTime,
+		Value: responseTimes.Max().Seconds(),
 	})
 	bm = append(bm, metricsservice.BluemixMetric{
 		Name:  metricsPrefix + ".Mean_Response_Time.sparse-avg",
 		Value: meanResponseTime,
 	})
+	bm = appendPercentiles(bm, metricsPrefix+".Response_Time", responseTimes)
+	dumpHistogram(metricsPrefix+".Response_Time", responseTimes)
 
-	p90ResponseTime, err := percentile(responseTimes, 90)
-	if err == nil {
+	// Requests were scheduled at a target rate, so report how far behind schedule they were sent.
+	// Response times already include this delay.
+	if sendDelays.TotalCount() > 0 {
 		bm = append(bm, metricsservice.BluemixMetric{
-			Name:  metricsPrefix + ".P90_Response_Time.sparse-avg",
-			Value: p90ResponseTime,
+			Name:  metricsPrefix + ".Max_Send_Delay.max",
+			Value: sendDelays.Max().Seconds(),
 		})
+		bm = append(bm, metricsservice.BluemixMetric{
+			Name:  metricsPrefix + ".Mean_Send_Delay.sparse-avg",
+			Value: sendDelays.Mean().Seconds(),
+		})
+		bm = appendPercentiles(bm, metricsPrefix+".Send_Delay", sendDelays)
+		dumpHistogram(metricsPrefix+".Send_Delay", sendDelays)
 	}
 
 	// Add the action time metrics if it makes sense to do so
-	if totalActionTime > 0 {
-		sort.Float64s(actionTimes)
-		minActionTime := actionTimes[firstAction]
-		maxActionTime := actionTimes[apiRequestCount-1]
-		meanActionTime := totalActionTime.Seconds() / float64(apiRequestCount-firstAction)
+	if actionTimes.TotalCount() > 0 {
 		bm = append(bm, metricsservice.BluemixMetric{
 			Name:  metricsPrefix + ".Min_Action_Time.min",
-			Value: minActionTime,
+			Value: actionTimes.Min().Seconds(),
 		})
 		bm = append(bm, metricsservice.BluemixMetric{
 			Name:  metricsPrefix + ".Max_Action_Time.max",
-			Value: maxActionTime,
+			Value: actionTimes.Max().Seconds(),
 		})
 		bm = append(bm, metricsservice.BluemixMetric{
 			Name:  metricsPrefix + ".Mean_Action_Time.sparse-avg",
-			Value: meanActionTime,
+			Value: actionTimes.Mean().Seconds(),
 		})
-
-		p90ActionTime, errat := percentile(actionTimes, 90)
-		if errat == nil {
-			bm = append(bm, metricsservice.BluemixMetric{
-				Name:  metricsPrefix + ".P90_Action_Time.sparse-avg",
-				Value: p90ActionTime,
-			})
-		}
+		bm = appendPercentiles(bm, metricsPrefix+".Action_Time", actionTimes)
+		dumpHistogram(metricsPrefix+".Action_Time", actionTimes)
 	}
 
 	if actionFailures > 0 {
@@ -285,6 +261,29 @@ func WriteArmadaMetrics(action config.ActionType, workerCount int, metrics *Arma
 	metricsservice.WriteClusterCreateBluemixMetrics(bm, true, testName, dbKey, clusterNames)
 }
 
+// appendPercentiles adds the reported percentiles of a histogram, e.g. <prefix>.Response_Time.p99. The names match
+// those from armada-perf-client2.
+func appendPercentiles(bm []metricsservice.BluemixMetric, mp string, h *histogram.Histogram) []metricsservice.BluemixMetric {
+	for _, p := range histogram.Percentiles {
+		bm = append(bm, metricsservice.BluemixMetric{
+			Name:  strings.Join([]string{mp, strings.ToLower(histogram.PercentileName(p))}, "."),
+			Value: h.Percentile(p).Seconds(),
+		})
+	}
+	return bm
+}
+
+// dumpHistogram writes the raw histogram to the histogram directory, if requested
+func dumpHistogram(name string, h *histogram.Histogram) {
+	if len(HistogramDir) == 0 {
+		return
+	}
+	path := filepath.Join(HistogramDir, histogram.DumpFileName(name))
+	if err := h.WriteFile(path, name); err != nil {
+		fmt.Println("Failed to write histogram", path, ":", err)
+	}
+}
+
 func contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	softlayer_datatypes "github.com/softlayer/softlayer-go/datatypes"
@@ -46,6 +47,10 @@ type Data struct {
 	ClusterID         string
 	KubeUpdateVersion string
 	Failure           FailureType
+
+	// ScheduledTime is when an open-loop scheduler intended the request to be sent.
+	// If set, the response time is measured from this time rather than the actual send time.
+	ScheduledTime time.Time
 }
 
 // FailureType defines an failure 'enum' for supported Aramda API requests
@@ -58,75 +63,152 @@ const (
 	FailureBackendIssue
 )
 
-var armadaURL url.URL
-var armadaClustersURL url.URL
-
-var debug bool
-var verbose bool
-var monitor bool
-var mockDeploy *deploy.MockDeploy
-var mockBootstrap *bootstrap.MockBootstrap
-var blockingRequest bool
-var postDataSized string
-var machineType string
-var kubeVersion string
-var workerPoolName string
-var (
+// TokenSource supplies the IAM and refresh tokens used to authorize Armada API requests
+type TokenSource interface {
+	Tokens() (iamToken string, refreshToken string)
+}
+
+// BluemixTokenSource obtains tokens from IBM Cloud IAM for the configured account
+type BluemixTokenSource struct {
+	Config *config.BluemixConfig
+	Debug  bool
+}
+
+// Tokens returns a new IAM and refresh token pair from IBM Cloud IAM
+func (ts BluemixTokenSource) Tokens() (string, string) {
+	iamToken, refreshToken, _ := token.GetTokens(ts.Config, ts.Debug)
+	return iamToken, refreshToken
+}
+
+// FileTokenSource reads a dummy IAM token from a file. Used when running against a dummy Bluemix
+type FileTokenSource struct {
+	Path  string
+	Debug bool
+}
+
+// Tokens returns the IAM token held in the file. There is no refresh token.
+func (ts FileTokenSource) Tokens() (string, string) {
+	if ts.Debug {
+		fmt.Println("dummy IAM token")
+	}
+
+	// #nosec G304
+	contents, err := ioutil.ReadFile(ts.Path)
+	if err != nil {
+		panic(err)
+	}
+	return string(contents), ""
+}
+
+// Options defines the optional settings used when creating a Client
+type Options struct {
+	MachineType    string
+	KubeVersion    string
+	WorkerPoolName string
+	Verbose        bool
+	Debug          bool
+	Monitor        bool
+	MockDeploy     *deploy.MockDeploy
+	MockBootstrap  *bootstrap.MockBootstrap
+
+	// TokenSource defaults to IBM Cloud IAM, or the dummy token file if Bluemix is dummied out
+	TokenSource TokenSource
+
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// Client generates and performs Armada API requests for a single Armada endpoint and identity.
+// Each Client holds its own configuration, tokens and request templates, so multiple clients
+// can be used concurrently to drive several users or regions from one process.
+type Client struct {
+	conf *config.Config
+
+	armadaURL         url.URL
+	armadaClustersURL url.URL
+
+	debug          bool
+	verbose        bool
+	monitor        bool
+	mockDeploy     *deploy.MockDeploy
+	mockBootstrap  *bootstrap.MockBootstrap
+	machineType    string
+	workerPoolName string
+
+	tokenSource TokenSource
+	httpClient  *http.Client
+
 	addClusterWorkersTemplate,
 	workerPoolConfigTemplate,
 	workerPoolSizeTemplate,
 	workerZoneConfigTemplate []byte
-)
-var iamToken, refreshToken string
-var requestCompleted *chan Data
-var clusterCreateTemplateLines []string
-
-var reqConf *config.Config
-
-// InitRequests returns a request instance
-func InitRequests(inmachineType string, inKubeVersion string, inWorkerPoolName string, conf *config.Config, inVerbose bool, inDebug bool, inMonitor bool, inMockDeploy *deploy.MockDeploy, inMockBootstrap *bootstrap.MockBootstrap, inNumThreads int, inRequestJobs *chan Data, inClusterComplete *chan Data) {
-	debug = inDebug
-	verbose = inVerbose
-	monitor = inMonitor
-	machineType = inmachineType
-	kubeVersion = inKubeVersion
-	workerPoolName = inWorkerPoolName
-	mockDeploy = inMockDeploy
-	mockBootstrap = inMockBootstrap
-	requestCompleted = inClusterComplete
-
-	blockingRequest = inMockBootstrap != nil
+	clusterCreateTemplateLines []string
+
+	// mu guards the fields below, which can change while requests are in flight
+	mu              sync.RWMutex
+	iamToken        string
+	refreshToken    string
+	kubeVersion     string
+	postData        string
+	blockingRequest bool
+}
 
-	reqConf = conf
+// NewClient returns a Client for the Armada API defined in conf
+func NewClient(conf *config.Config, opts Options) *Client {
+	c := &Client{
+		conf:            conf,
+		debug:           opts.Debug,
+		verbose:         opts.Verbose,
+		monitor:         opts.Monitor,
+		mockDeploy:      opts.MockDeploy,
+		mockBootstrap:   opts.MockBootstrap,
+		machineType:     opts.MachineType,
+		workerPoolName:  opts.WorkerPoolName,
+		kubeVersion:     opts.KubeVersion,
+		tokenSource:     opts.TokenSource,
+		httpClient:      opts.HTTPClient,
+		blockingRequest: opts.MockBootstrap != nil,
+	}
 
 	configPath := config.GetConfigPath()
 
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.tokenSource == nil {
+		if !conf.Bluemix.BluemixDummy {
+			c.tokenSource = BluemixTokenSource{Config: conf.Bluemix, Debug: c.debug}
+		} else {
+			c.tokenSource = FileTokenSource{Path: filepath.Join(configPath, config.GetConfigString("armada_iam_token", conf.Bluemix.IAMToken)), Debug: c.debug}
+		}
+	}
+
 	var err error
 
-	Authenticate()
+	c.Authenticate()
 
 	// Read request body template for add worker requests
 	// #nosec G304
-	addClusterWorkersTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_add_workers_template", conf.Request.AddWorkers)))
+	c.addClusterWorkersTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_add_workers_template", conf.Request.AddWorkers)))
 	if err != nil {
 		panic(err)
 	}
 
 	// Read request body template for worker pool creation requests
 	// #nosec G304
-	workerPoolConfigTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_worker_pool_config_template", conf.Request.CreateWorkerPool)))
+	c.workerPoolConfigTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_worker_pool_config_template", conf.Request.CreateWorkerPool)))
 	if err != nil {
 		panic(err)
 	}
 
 	// #nosec G304
-	workerPoolSizeTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_worker_pool_size_template", conf.Request.ResizeWorkerPool)))
+	c.workerPoolSizeTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_worker_pool_size_template", conf.Request.ResizeWorkerPool)))
 	if err != nil {
 		panic(err)
 	}
 
 	// #nosec G304
-	workerZoneConfigTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_worker_pool_config_template", conf.Request.AddWorkerPoolZone)))
+	c.workerZoneConfigTemplate, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_worker_pool_config_template", conf.Request.AddWorkerPoolZone)))
 	if err != nil {
 		panic(err)
 	}
@@ -148,13 +230,13 @@ func InitRequests(inmachineType string, inKubeVersion string, inWorkerPoolName s
 		templateFileLine := scanner.Text()
 
 		if strings.Contains(templateFileLine, "KUBEVERSION%") {
-			if len(kubeVersion) > 0 {
+			if len(c.kubeVersion) > 0 {
 				templateLines = append(templateLines, templateFileLine)
 			}
 			continue
 		}
 
-		if !(machineType == config.FreeAccountStr && strings.Contains(templateFileLine, "VLAN%")) {
+		if !(c.machineType == config.FreeAccountStr && strings.Contains(templateFileLine, "VLAN%")) {
 			templateLines = append(templateLines, templateFileLine)
 		}
 	}
@@ -162,9 +244,9 @@ func InitRequests(inmachineType string, inKubeVersion string, inWorkerPoolName s
 		panic(err)
 	}
 
-	clusterCreateTemplateLines = templateLines
+	c.clusterCreateTemplateLines = templateLines
 
-	setPostDataSized()
+	c.setPostDataSized()
 
 	if conf.Softlayer != nil {
 		// If we're running Armada Cluster with a dummy SL provider, we need to provide it with VLAN data
@@ -215,99 +297,128 @@ func InitRequests(inmachineType string, inKubeVersion string, inWorkerPoolName s
 		}
 	}
 
-	armadaURL = url.URL{Scheme: conf.API.APIServerScheme, Host: config.GetConfigString("armada_api_server_ip", conf.API.APIServerIP) + ":" +
+	c.armadaURL = url.URL{Scheme: conf.API.APIServerScheme, Host: config.GetConfigString("armada_api_server_ip", conf.API.APIServerIP) + ":" +
 		config.GetConfigString("armada_api_server_port", conf.API.APIServerPort), Path: config.GetConfigString("armada_api_version", conf.API.APIVersion)}
-	armadaClustersURL = url.URL{Scheme: conf.API.APIServerScheme, Host: config.GetConfigString("armada_api_server_ip", conf.API.APIServerIP) + ":" +
+	c.armadaClustersURL = url.URL{Scheme: conf.API.APIServerScheme, Host: config.GetConfigString("armada_api_server_ip", conf.API.APIServerIP) + ":" +
 		config.GetConfigString("armada_api_server_port", conf.API.APIServerPort), Path: config.GetConfigString("armada_api_version", conf.API.APIVersion) +
 		"/" + "clusters"}
 
-	for j := 1; j <= inNumThreads; j++ {
-		go worker(j, *inRequestJobs)
+	return c
+}
+
+// Start launches a pool of numThreads workers that perform each request received on jobs,
+// sending the results to completed
+func (c *Client) Start(numThreads int, jobs <-chan Data, completed chan<- Data) {
+	for j := 1; j <= numThreads; j++ {
+		go c.worker(j, jobs, completed)
 	}
 }
 
+// Config returns the configuration used by the client
+func (c *Client) Config() *config.Config {
+	return c.conf
+}
+
 // IsBlocking returns whether the request waits for the action to complete
-func IsBlocking() bool {
-	return blockingRequest
+func (c *Client) IsBlocking() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blockingRequest
+}
+
+func (c *Client) setBlocking(blocking bool) {
+	c.mu.Lock()
+	c.blockingRequest = blocking
+	c.mu.Unlock()
 }
 
-func setPostDataSized() {
+// KubeVersion returns the default kube version for new clusters
+func (c *Client) KubeVersion() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.kubeVersion
+}
+
+// SetKubeVersion sets the default kube version for new clusters
+func (c *Client) SetKubeVersion(kubeVersion string) {
+	c.mu.Lock()
+	c.kubeVersion = kubeVersion
+	c.mu.Unlock()
+	c.setPostDataSized()
+}
+
+func (c *Client) postDataSized() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.postData
+}
+
+func (c *Client) setPostDataSized() {
+	kubeVersion := c.KubeVersion()
+
 	// Update template for create requests with machine size
-	postDataSized = strings.Replace(strings.Join(clusterCreateTemplateLines, "\n"), "%MACHINETYPE%", machineType, 1)
+	postDataSized := strings.Replace(strings.Join(c.clusterCreateTemplateLines, "\n"), "%MACHINETYPE%", c.machineType, 1)
 
 	if len(kubeVersion) > 0 {
 		postDataSized = strings.Replace(postDataSized, "%KUBEVERSION%", kubeVersion, 1)
 	}
-	postDataSized = strings.Replace(postDataSized, "%DATACENTER%", reqConf.Location.Environment+"-"+reqConf.Location.Datacenter, 1)
+	postDataSized = strings.Replace(postDataSized, "%DATACENTER%", c.conf.Location.Environment+"-"+c.conf.Location.Datacenter, 1)
 
-	if reqConf.Softlayer != nil {
-		postDataSized = strings.Replace(postDataSized, "%PRIVATEVLAN%", reqConf.Softlayer.SoftlayerPrivateVLAN, 1)
-		postDataSized = strings.Replace(postDataSized, "%PUBLICVLAN%", reqConf.Softlayer.SoftlayerPublicVLAN, 1)
-		postDataSized = strings.Replace(postDataSized, "%BILLING%", reqConf.Softlayer.SoftlayerBilling, 1)
-		postDataSized = strings.Replace(postDataSized, "%ISOLATION%", reqConf.Softlayer.SoftlayerIsolation, 1)
-		postDataSized = strings.Replace(postDataSized, "%NOSUBNET%", strconv.FormatBool(!reqConf.Softlayer.SoftlayerPortableSubnet), 1)
-		postDataSized = strings.Replace(postDataSized, "%DISKENCRYPTION%", strconv.FormatBool(reqConf.Softlayer.SoftlayerDiskEncryption), 1)
+	if c.conf.Softlayer != nil {
+		postDataSized = strings.Replace(postDataSized, "%PRIVATEVLAN%", c.conf.Softlayer.SoftlayerPrivateVLAN, 1)
+		postDataSized = strings.Replace(postDataSized, "%PUBLICVLAN%", c.conf.Softlayer.SoftlayerPublicVLAN, 1)
+		postDataSized = strings.Replace(postDataSized, "%BILLING%", c.conf.Softlayer.SoftlayerBilling, 1)
+		postDataSized = strings.Replace(postDataSized, "%ISOLATION%", c.conf.Softlayer.SoftlayerIsolation, 1)
+		postDataSized = strings.Replace(postDataSized, "%NOSUBNET%", strconv.FormatBool(!c.conf.Softlayer.SoftlayerPortableSubnet), 1)
+		postDataSized = strings.Replace(postDataSized, "%DISKENCRYPTION%", strconv.FormatBool(c.conf.Softlayer.SoftlayerDiskEncryption), 1)
 	}
+
+	c.mu.Lock()
+	c.postData = postDataSized
+	c.mu.Unlock()
 }
 
-func setCommonRequestHeaders(req *http.Request) {
+func (c *Client) setCommonRequestHeaders(req *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	// Set headers that are mandatory for all requests
 	// (Strictly speaking, not all requests require the iamToken, but does no harm to set it)
-	req.Header.Set("Authorization", iamToken)
-	req.Header.Set("X-Auth-Refresh-Token", refreshToken)
+	req.Header.Set("Authorization", c.iamToken)
+	req.Header.Set("X-Auth-Refresh-Token", c.refreshToken)
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 }
 
 // Authenticate initializes the tokens necessary to talk to armada
-func Authenticate() {
-	if debug {
+func (c *Client) Authenticate() {
+	if c.debug {
 		fmt.Printf("%s\tAuthenticating via ", time.Now().Format(time.StampMilli))
 	}
-	configPath := config.GetConfigPath()
 
-	// Get the authentication tokens for our user from Bluemix
-	if !reqConf.Bluemix.BluemixDummy {
-		// Get UAA and IAM tokens from Bluemix
-		iamToken, refreshToken, _ = token.GetTokens(reqConf.Bluemix, debug)
-	} else {
-		if debug {
-			fmt.Println("dummy IAM token")
-		}
-		// Get Dummy tokens from file
-		var contents []byte
-		var err error
+	iamToken, refreshToken := c.tokenSource.Tokens()
 
-		// #nosec G304
-		contents, err = ioutil.ReadFile(filepath.Join(configPath, config.GetConfigString("armada_iam_token", reqConf.Bluemix.IAMToken)))
-		iamToken = string(contents)
-		if err != nil {
-			panic(err)
-		}
-	}
+	c.mu.Lock()
+	c.iamToken = iamToken
+	c.refreshToken = refreshToken
+	c.mu.Unlock()
 }
 
 // worker provides a pool of workers to submit API requests
-func worker(id int, jobs <-chan Data) {
+func (c *Client) worker(id int, jobs <-chan Data, completed chan<- Data) {
 	for r := range jobs {
-		if debug {
+		if c.debug {
 			fmt.Printf("%s\tRW: %d - %s for cluster %s\n", time.Now().Format(time.StampMilli), id, r.Action, r.ClusterName)
 		}
-		*requestCompleted <- PerformRequest(r, true)
+		completed <- c.PerformRequest(r, true)
 	}
 }
 
-// SetKubeVersion sets the default kube version for new clusters
-func SetKubeVersion(inKubeVersion string) {
-	kubeVersion = inKubeVersion
-	setPostDataSized()
-}
-
 // CreateClusterRequest creates a request that triggers a cluster create
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/CreateCluster
-func CreateClusterRequest(body string) *http.Request {
-	if req, err := http.NewRequest("POST", armadaClustersURL.String(), bytes.NewBufferString(body)); err != nil {
+func (c *Client) CreateClusterRequest(body string) *http.Request {
+	if req, err := http.NewRequest("POST", c.armadaClustersURL.String(), bytes.NewBufferString(body)); err != nil {
 		panic(err)
 	} else {
 		return req
@@ -316,8 +427,8 @@ func CreateClusterRequest(body string) *http.Request {
 
 // GetClustersRequest creates a request that retrieves the list of clusters a user has access to
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetClusters
-func GetClustersRequest() *http.Request {
-	if req, err := http.NewRequest("GET", armadaClustersURL.String(), nil); err != nil {
+func (c *Client) GetClustersRequest() *http.Request {
+	if req, err := http.NewRequest("GET", c.armadaClustersURL.String(), nil); err != nil {
 		panic(err)
 	} else {
 		return req
@@ -326,8 +437,8 @@ func GetClustersRequest() *http.Request {
 
 // GetClusterRequest creates a request that retrieves the specified cluster's details
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetClusters
-func GetClusterRequest(clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) GetClusterRequest(clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 
@@ -335,7 +446,7 @@ func GetClusterRequest(clusterName string) *http.Request {
 		panic(err)
 	} else {
 		values := req.URL.Query()
-		values.Add("showResources", strconv.FormatBool(reqConf.Request.ShowResources))
+		values.Add("showResources", strconv.FormatBool(c.conf.Request.ShowResources))
 		req.URL.RawQuery = values.Encode()
 		return req
 	}
@@ -343,12 +454,12 @@ func GetClusterRequest(clusterName string) *http.Request {
 
 // GetClusterConfigRequest creates a request that retrieves the specified cluster's kube config
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetClusterConfig
-func GetClusterConfigRequest(clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) GetClusterConfigRequest(clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/config")
-	if reqConf.Request.AdminConfig {
+	if c.conf.Request.AdminConfig {
 		buffer.WriteString("/admin")
 	}
 
@@ -361,8 +472,8 @@ func GetClusterConfigRequest(clusterName string) *http.Request {
 
 // DeleteClusterRequest creates a request that deletes the specified cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/RemoveCluster
-func DeleteClusterRequest(clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) DeleteClusterRequest(clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	if req, err := http.NewRequest("DELETE", buffer.String(), nil); err != nil {
@@ -370,7 +481,7 @@ func DeleteClusterRequest(clusterName string) *http.Request {
 	} else {
 		// Always delete additional resources linked to the cluster
 		values := req.URL.Query()
-		values.Add("deleteResources", strconv.FormatBool(reqConf.Request.DeleteResources))
+		values.Add("deleteResources", strconv.FormatBool(c.conf.Request.DeleteResources))
 		req.URL.RawQuery = values.Encode()
 
 		return req
@@ -379,8 +490,8 @@ func DeleteClusterRequest(clusterName string) *http.Request {
 
 // GetWorkerPoolsRequest creates a request that lists the worker pools in a cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetWorkerPools
-func GetWorkerPoolsRequest(clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) GetWorkerPoolsRequest(clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools")
@@ -394,8 +505,8 @@ func GetWorkerPoolsRequest(clusterName string) *http.Request {
 
 // CreateWorkerPoolRequest creates a request that creates a worker pool for a cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/CreateWorkerPool
-func CreateWorkerPoolRequest(body string, clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) CreateWorkerPoolRequest(body string, clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools")
@@ -409,11 +520,11 @@ func CreateWorkerPoolRequest(body string, clusterName string) *http.Request {
 
 // RemoveWorkerPoolRequest creates a request that removes aworker pool from a cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/RemoveWorkerPool
-func RemoveWorkerPoolRequest(clusterName string, workerPoolName string) *http.Request {
+func (c *Client) RemoveWorkerPoolRequest(clusterName string, workerPoolName string) *http.Request {
 	if len(workerPoolName) == 0 {
 		log.Fatalln("Please specify worker pool using -workerPoolName option")
 	}
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools/")
@@ -427,11 +538,11 @@ func RemoveWorkerPoolRequest(clusterName string, workerPoolName string) *http.Re
 
 // GetWorkerPoolRequest creates a request that views the details of a cluster's worker pool
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetWorkerPool
-func GetWorkerPoolRequest(clusterName string, workerPoolID string) *http.Request {
+func (c *Client) GetWorkerPoolRequest(clusterName string, workerPoolID string) *http.Request {
 	if len(workerPoolID) == 0 {
 		log.Fatalln("Please specify worker pool using -workerPoolId option")
 	}
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools/")
@@ -447,8 +558,8 @@ func GetWorkerPoolRequest(clusterName string, workerPoolID string) *http.Request
 // It will change the number of worker nodes that an existing worker pool deploys in each zone (datacenter
 // by resizing the worker pool.
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/PatchWorkerPool
-func ResizeWorkerPoolRequest(body string, clusterName string, workerPoolName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) ResizeWorkerPoolRequest(body string, clusterName string, workerPoolName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools/")
@@ -462,8 +573,8 @@ func ResizeWorkerPoolRequest(body string, clusterName string, workerPoolName str
 
 // AddWorkerPoolZoneRequest creates a request that adds a zone to the specified worker pool for a cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/AddWorkerPoolZone
-func AddWorkerPoolZoneRequest(body string, clusterName string, workerPoolName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) AddWorkerPoolZoneRequest(body string, clusterName string, workerPoolName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools/")
@@ -478,12 +589,12 @@ func AddWorkerPoolZoneRequest(body string, clusterName string, workerPoolName st
 
 // RemoveWorkerPoolZoneRequest creates a request that removes a zone from a worker pool
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/RemoveWorkerPoolZone
-func RemoveWorkerPoolZoneRequest(clusterName string, workerPoolName string, zoneID string) *http.Request {
+func (c *Client) RemoveWorkerPoolZoneRequest(clusterName string, workerPoolName string, zoneID string) *http.Request {
 	if len(workerPoolName) == 0 {
 		log.Fatalln("Please specify worker pool using -workerPoolName option")
 	}
 
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workerpools/")
@@ -500,8 +611,8 @@ func RemoveWorkerPoolZoneRequest(clusterName string, workerPoolName string, zone
 
 // GetClusterWorkersRequest creates a request that retrieves the list of workers for the specified cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetClusterWorkers
-func GetClusterWorkersRequest(clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) GetClusterWorkersRequest(clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workers")
@@ -515,8 +626,8 @@ func GetClusterWorkersRequest(clusterName string) *http.Request {
 
 // AddClusterWorkersRequest creates a request that adds additional workers to the specified cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/AddClusterWorkers
-func AddClusterWorkersRequest(body string, clusterName string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) AddClusterWorkersRequest(body string, clusterName string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workers")
@@ -530,11 +641,11 @@ func AddClusterWorkersRequest(body string, clusterName string) *http.Request {
 
 // GetWorkerRequest creates a request that retrieves a worker's details for the specified cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/GetWorkers
-func GetWorkerRequest(workerID string) *http.Request {
+func (c *Client) GetWorkerRequest(workerID string) *http.Request {
 	if len(workerID) == 0 {
 		log.Fatalln("Please specify worker using -workerId option")
 	}
-	buffer := bytes.NewBufferString(armadaURL.String())
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/workers/")
 	buffer.WriteString(workerID)
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -546,11 +657,11 @@ func GetWorkerRequest(workerID string) *http.Request {
 
 // DeleteWorkerRequest creates a request that deletes a worker for the specified cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/RemoveClusterWorker
-func DeleteWorkerRequest(workerID string) *http.Request {
+func (c *Client) DeleteWorkerRequest(workerID string) *http.Request {
 	if len(workerID) == 0 {
 		log.Fatalln("Please specify worker using -workerId option")
 	}
-	buffer := bytes.NewBufferString(armadaURL.String())
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/workers/")
 	buffer.WriteString(workerID)
 	if req, err := http.NewRequest("DELETE", buffer.String(), nil); err != nil {
@@ -562,11 +673,11 @@ func DeleteWorkerRequest(workerID string) *http.Request {
 
 // UpdateWorkerRequest creates a request that reboots or reloads a worker for the specified cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/UpdateClusterWorker
-func UpdateWorkerRequest(clusterName, workerID, command string) *http.Request {
+func (c *Client) UpdateWorkerRequest(clusterName, workerID, command string) *http.Request {
 	if len(workerID) == 0 {
 		log.Fatalln("Please specify worker using -workerId option")
 	}
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/workers/")
@@ -583,8 +694,8 @@ func UpdateWorkerRequest(clusterName, workerID, command string) *http.Request {
 // GetDatacentersRequest creates a request that retrieves the list of datacenters
 // See https://containers.cloud.ibm.com/swagger-api/#/properties/getDataCenters
 // Historical request, superseded by GetZones
-func GetDatacentersRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetDatacentersRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/datacenters")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -596,8 +707,8 @@ func GetDatacentersRequest() *http.Request {
 
 // GetRegionsRequest creates a request that retrieves the list of available zones(datacenters) in a region (Deprecated)
 // See https://containers.cloud.ibm.com/swagger-api/#/util/GetRegions
-func GetRegionsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetRegionsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/regions")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -609,8 +720,8 @@ func GetRegionsRequest() *http.Request {
 
 // GetZonesRequest creates a request that retrieves the list of available zones(datacenters) in a region
 // See https://containers.cloud.ibm.com/swagger-api/#/util/GetZones
-func GetZonesRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetZonesRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/zones")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -622,10 +733,10 @@ func GetZonesRequest() *http.Request {
 
 // GetMachineTypesRequest creates a request that retrieves the list of datacenter machine types
 // See https://containers.cloud.ibm.com/swagger-api/#/properties/getMachineTypes
-func GetMachineTypesRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetMachineTypesRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/datacenters/")
-	buffer.WriteString(reqConf.Location.Datacenter)
+	buffer.WriteString(c.conf.Location.Datacenter)
 	buffer.WriteString("/machine-types")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -637,10 +748,10 @@ func GetMachineTypesRequest() *http.Request {
 
 // GetVLANsRequest creates a request that retrieves the list of valid datacenter VLANs
 // See https://containers.cloud.ibm.com/swagger-api/#/properties/getDatacenterVLANs
-func GetVLANsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetVLANsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/datacenters/")
-	buffer.WriteString(reqConf.Location.Datacenter)
+	buffer.WriteString(c.conf.Location.Datacenter)
 	buffer.WriteString("/vlans")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -652,8 +763,8 @@ func GetVLANsRequest() *http.Request {
 
 // GetKubeVersionsRequest creates a request that retrieves the list of supported Kubernetes versions
 // See https://containers.cloud.ibm.com/swagger-api/#/util/GetKubeVersions (Deprecated)
-func GetKubeVersionsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetKubeVersionsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/kube-versions")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -665,8 +776,8 @@ func GetKubeVersionsRequest() *http.Request {
 
 // GetVersionsRequest creates a request that retrieves the list of supported Kubernetes versions
 // See https://containers.cloud.ibm.com/swagger-api/#/util/GetVersions
-func GetVersionsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetVersionsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/versions")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -679,8 +790,8 @@ func GetVersionsRequest() *http.Request {
 // GetSubnetsRequest creates a request that retrieves the list of availbale portable subnets
 // in the user's Bluemix Infrastructure (Softlayer) account
 // See https://containers.cloud.ibm.com/swagger-api/#/properties/ListSubnets
-func GetSubnetsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetSubnetsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/subnets")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -693,8 +804,8 @@ func GetSubnetsRequest() *http.Request {
 // CreateSubnetRequest creates a request that creates a portable subnet under your public or private vlan
 // in the user's Bluemix Infrastructure (Softlayer) account. and make it available to a cluster
 // See https://containers.cloud.ibm.com/swagger-api/#/properties/ListSubnets
-func CreateSubnetRequest(clusterName, vlanID string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) CreateSubnetRequest(clusterName, vlanID string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 	buffer.WriteString("/vlans/")
@@ -703,7 +814,7 @@ func CreateSubnetRequest(clusterName, vlanID string) *http.Request {
 	if req, err := http.NewRequest("POST", buffer.String(), nil); err != nil {
 		panic(err)
 	} else {
-		subnetSize := reqConf.Softlayer.SoftlayerPortableSubnetSize
+		subnetSize := c.conf.Softlayer.SoftlayerPortableSubnetSize
 		if subnetSize == 0 {
 			subnetSize = 16
 		}
@@ -717,8 +828,8 @@ func CreateSubnetRequest(clusterName, vlanID string) *http.Request {
 
 // GetCredentialsRequest creates a request that sets Bluemix Infrastructure (Softlayer) account credentials.
 // See https://containers.cloud.ibm.com/swagger-api/#/accounts/GetUserCredentials
-func GetCredentialsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetCredentialsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/credentials")
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
 		panic(err)
@@ -729,14 +840,14 @@ func GetCredentialsRequest() *http.Request {
 
 // SetCredentialsRequest creates a request that sets Bluemix Infrastructure (Softlayer) account credentials.
 // See https://containers.cloud.ibm.com/swagger-api/#/accounts/storeUserCredentials
-func SetCredentialsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) SetCredentialsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/credentials")
 	if req, err := http.NewRequest("POST", buffer.String(), nil); err != nil {
 		panic(err)
 	} else {
-		req.Header.Set("X-Auth-Softlayer-Username", config.GetConfigString("armada_softlayer_username", reqConf.Softlayer.SoftlayerUsername))
-		req.Header.Set("X-Auth-Softlayer-APIKey", config.GetConfigString("armada_softlayer_api_key", reqConf.Softlayer.SoftlayerAPIKey))
+		req.Header.Set("X-Auth-Softlayer-Username", config.GetConfigString("armada_softlayer_username", c.conf.Softlayer.SoftlayerUsername))
+		req.Header.Set("X-Auth-Softlayer-APIKey", config.GetConfigString("armada_softlayer_api_key", c.conf.Softlayer.SoftlayerAPIKey))
 
 		return req
 	}
@@ -744,8 +855,8 @@ func SetCredentialsRequest() *http.Request {
 
 // DeleteCredentialsRequest creates a request that removes Bluemix Infrastructure (Softlayer) account credentials.
 // See https://containers.cloud.ibm.com/swagger-api/#/accounts/removeUserCredentials
-func DeleteCredentialsRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) DeleteCredentialsRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/credentials")
 	if req, err := http.NewRequest("DELETE", buffer.String(), nil); err != nil {
 		panic(err)
@@ -756,8 +867,8 @@ func DeleteCredentialsRequest() *http.Request {
 
 // GetVLANSpanningRequest creates a request that retrieves the vlan spanning status for an infrastructure account
 // See https://containers.cloud.ibm.com/swagger-api/#/accounts/GetVlanSpanning
-func GetVLANSpanningRequest() *http.Request {
-	buffer := bytes.NewBufferString(armadaURL.String())
+func (c *Client) GetVLANSpanningRequest() *http.Request {
+	buffer := bytes.NewBufferString(c.armadaURL.String())
 	buffer.WriteString("/subnets/vlan-spanning")
 
 	if req, err := http.NewRequest("GET", buffer.String(), nil); err != nil {
@@ -769,8 +880,8 @@ func GetVLANSpanningRequest() *http.Request {
 
 // UpdateClusterRequest updates the version of the Kubernetes cluster master node
 // See https://containers.cloud.ibm.com/swagger-api/#/clusters/UpdateCluster
-func UpdateClusterRequest(clusterName string, kubeVersion string, action string) *http.Request {
-	buffer := bytes.NewBufferString(armadaClustersURL.String())
+func (c *Client) UpdateClusterRequest(clusterName string, kubeVersion string, action string) *http.Request {
+	buffer := bytes.NewBufferString(c.armadaClustersURL.String())
 	buffer.WriteString("/")
 	buffer.WriteString(clusterName)
 
@@ -788,170 +899,168 @@ func UpdateClusterRequest(clusterName string, kubeVersion string, action string)
 }
 
 // PerformRequest makes the specified request to armada
-func PerformRequest(request Data, singleAttempt bool) Data {
+func (c *Client) PerformRequest(request Data, singleAttempt bool) Data {
 	var req *http.Request
 	var localKubeUpdateVersion string
 
 	// We're not a blocking request unless the action is associated with worker creation
-	blockingRequest = blockingRequest && request.Action.WorkerCreation()
+	c.setBlocking(c.IsBlocking() && request.Action.WorkerCreation())
 
 	totalWorkersStr := strconv.Itoa(request.TotalWorkers)
 	switch request.Action {
 	case config.ActionCreateCluster:
 		// Generate http request from template, substituting the cluster name and worker count
-		postData := strings.Replace(postDataSized, "%CLUSTERNAME%", request.ClusterName, 1)
+		postData := strings.Replace(c.postDataSized(), "%CLUSTERNAME%", request.ClusterName, 1)
 		postData = strings.Replace(postData, "\"%WORKERNUM%\"", totalWorkersStr, 1)
 
-		req = CreateClusterRequest(postData)
+		req = c.CreateClusterRequest(postData)
 
 	case config.ActionGetClusters:
-		req = GetClustersRequest()
+		req = c.GetClustersRequest()
 
 	case config.ActionGetCluster:
-		req = GetClusterRequest(request.ClusterName)
+		req = c.GetClusterRequest(request.ClusterName)
 
 	case config.ActionGetClusterConfig:
-		req = GetClusterConfigRequest(request.ClusterName)
+		req = c.GetClusterConfigRequest(request.ClusterName)
 
 	case config.ActionDeleteCluster:
-		req = DeleteClusterRequest(request.ClusterName)
+		req = c.DeleteClusterRequest(request.ClusterName)
 
 	case config.ActionGetWorkerPools:
-		req = GetWorkerPoolsRequest(request.ClusterName)
+		req = c.GetWorkerPoolsRequest(request.ClusterName)
 
 	case config.ActionCreateWorkerPool:
-		postData := strings.Replace(string(workerPoolConfigTemplate), "%DISKENCRYPTION%", strconv.FormatBool(reqConf.Softlayer.SoftlayerDiskEncryption), 1)
-		postData = strings.Replace(postData, "%ISOLATION%", reqConf.Softlayer.SoftlayerIsolation, 1)
-		postData = strings.Replace(postData, "%MACHINETYPE%", machineType, 1)
-		postData = strings.Replace(postData, "%WORKERPOOLNAME%", workerPoolName, 1)
+		postData := strings.Replace(string(c.workerPoolConfigTemplate), "%DISKENCRYPTION%", strconv.FormatBool(c.conf.Softlayer.SoftlayerDiskEncryption), 1)
+		postData = strings.Replace(postData, "%ISOLATION%", c.conf.Softlayer.SoftlayerIsolation, 1)
+		postData = strings.Replace(postData, "%MACHINETYPE%", c.machineType, 1)
+		postData = strings.Replace(postData, "%WORKERPOOLNAME%", c.workerPoolName, 1)
 		postData = strings.Replace(postData, "%LABELNAME%", "poolLabel", 1)
-		postData = strings.Replace(postData, "%LABEL%", workerPoolName, 1) // use workerpool name as the label
+		postData = strings.Replace(postData, "%LABEL%", c.workerPoolName, 1) // use workerpool name as the label
 		postData = strings.Replace(postData, "\"%SIZEPERZONE%\"", strconv.FormatInt(int64(request.PoolSize), 10), 1)
 
-		req = CreateWorkerPoolRequest(postData, request.ClusterName)
+		req = c.CreateWorkerPoolRequest(postData, request.ClusterName)
 
 	case config.ActionRemoveWorkerPool:
-		req = RemoveWorkerPoolRequest(request.ClusterName, request.WorkerPoolName)
+		req = c.RemoveWorkerPoolRequest(request.ClusterName, request.WorkerPoolName)
 
 	case config.ActionGetWorkerPool:
-		req = GetWorkerPoolRequest(request.ClusterName, request.WorkerPoolName)
+		req = c.GetWorkerPoolRequest(request.ClusterName, request.WorkerPoolName)
 
 	case config.ActionResizeWorkerPool:
-		postData := strings.Replace(string(workerPoolSizeTemplate), "\"%SIZEPERZONE%\"", strconv.FormatInt(int64(request.PoolSize), 10), 1)
-		req = ResizeWorkerPoolRequest(postData, request.ClusterName, request.WorkerPoolName)
+		postData := strings.Replace(string(c.workerPoolSizeTemplate), "\"%SIZEPERZONE%\"", strconv.FormatInt(int64(request.PoolSize), 10), 1)
+		req = c.ResizeWorkerPoolRequest(postData, request.ClusterName, request.WorkerPoolName)
 
 	case config.ActionAddWorkerPoolZone:
-		postData := strings.Replace(string(workerZoneConfigTemplate), "%ZONEID%", request.ZoneID, 1)
-		postData = strings.Replace(postData, "%PRIVATEVLAN%", reqConf.Softlayer.SoftlayerPrivateVLAN, 1)
-		postData = strings.Replace(postData, "%PUBLICVLAN%", reqConf.Softlayer.SoftlayerPublicVLAN, 1)
-		req = AddWorkerPoolZoneRequest(postData, request.ClusterName, request.WorkerPoolName)
+		postData := strings.Replace(string(c.workerZoneConfigTemplate), "%ZONEID%", request.ZoneID, 1)
+		postData = strings.Replace(postData, "%PRIVATEVLAN%", c.conf.Softlayer.SoftlayerPrivateVLAN, 1)
+		postData = strings.Replace(postData, "%PUBLICVLAN%", c.conf.Softlayer.SoftlayerPublicVLAN, 1)
+		req = c.AddWorkerPoolZoneRequest(postData, request.ClusterName, request.WorkerPoolName)
 
 	case config.ActionRemoveWorkerPoolZone:
-		req = RemoveWorkerPoolZoneRequest(request.ClusterName, request.WorkerPoolName, request.ZoneID)
+		req = c.RemoveWorkerPoolZoneRequest(request.ClusterName, request.WorkerPoolName, request.ZoneID)
 
 	case config.ActionGetClusterWorkers:
-		req = GetClusterWorkersRequest(request.ClusterName)
+		req = c.GetClusterWorkersRequest(request.ClusterName)
 
 	case config.ActionAddClusterWorkers:
-		postData := strings.Replace(string(addClusterWorkersTemplate), "\"%WORKERNUM%\"", totalWorkersStr, 1)
-		postData = strings.Replace(postData, "%MACHINETYPE%", machineType, 1)
-		postData = strings.Replace(postData, "%DISKENCRYPTION%", strconv.FormatBool(reqConf.Softlayer.SoftlayerDiskEncryption), 1)
-		postData = strings.Replace(postData, "%PRIVATEVLAN%", reqConf.Softlayer.SoftlayerPrivateVLAN, 1)
-		postData = strings.Replace(postData, "%PUBLICVLAN%", reqConf.Softlayer.SoftlayerPublicVLAN, 1)
+		postData := strings.Replace(string(c.addClusterWorkersTemplate), "\"%WORKERNUM%\"", totalWorkersStr, 1)
+		postData = strings.Replace(postData, "%MACHINETYPE%", c.machineType, 1)
+		postData = strings.Replace(postData, "%DISKENCRYPTION%", strconv.FormatBool(c.conf.Softlayer.SoftlayerDiskEncryption), 1)
+		postData = strings.Replace(postData, "%PRIVATEVLAN%", c.conf.Softlayer.SoftlayerPrivateVLAN, 1)
+		postData = strings.Replace(postData, "%PUBLICVLAN%", c.conf.Softlayer.SoftlayerPublicVLAN, 1)
 
-		req = AddClusterWorkersRequest(postData, request.ClusterName)
+		req = c.AddClusterWorkersRequest(postData, request.ClusterName)
 
 	case config.ActionGetWorker:
-		req = GetWorkerRequest(request.WorkerID)
+		req = c.GetWorkerRequest(request.WorkerID)
 
 	case config.ActionDeleteWorker:
-		req = DeleteWorkerRequest(request.WorkerID)
+		req = c.DeleteWorkerRequest(request.WorkerID)
 
 	case config.ActionRebootWorker:
 		// For now we'll support a soft reboot only.
 		// If we want to add support for hard reboot in the future, action would be "power_cycle"
-		req = UpdateWorkerRequest(request.ClusterName, request.WorkerID, "os_reboot")
+		req = c.UpdateWorkerRequest(request.ClusterName, request.WorkerID, "os_reboot")
 
 	case config.ActionReloadWorker:
-		req = UpdateWorkerRequest(request.ClusterName, request.WorkerID, "reload")
+		req = c.UpdateWorkerRequest(request.ClusterName, request.WorkerID, "reload")
 
 	case config.ActionGetDatacenters:
-		req = GetDatacentersRequest()
+		req = c.GetDatacentersRequest()
 
 	case config.ActionGetRegions:
-		req = GetRegionsRequest()
+		req = c.GetRegionsRequest()
 
 	case config.ActionGetZones:
-		req = GetZonesRequest()
+		req = c.GetZonesRequest()
 
 	case config.ActionGetMachineTypes:
-		req = GetMachineTypesRequest()
+		req = c.GetMachineTypesRequest()
 
 	case config.ActionGetVLANs:
-		req = GetVLANsRequest()
+		req = c.GetVLANsRequest()
 
 	case config.ActionGetKubeVersions:
-		req = GetKubeVersionsRequest()
+		req = c.GetKubeVersionsRequest()
 
 	case config.ActionGetSubnets:
-		req = GetSubnetsRequest()
+		req = c.GetSubnetsRequest()
 
 	case config.ActionCreateSubnet:
-		if reqConf.Request.PrivateVLAN {
-			req = CreateSubnetRequest(request.ClusterName, reqConf.Softlayer.SoftlayerPrivateVLAN)
+		if c.conf.Request.PrivateVLAN {
+			req = c.CreateSubnetRequest(request.ClusterName, c.conf.Softlayer.SoftlayerPrivateVLAN)
 		}
-		if reqConf.Request.PublicVLAN {
-			req = CreateSubnetRequest(request.ClusterName, reqConf.Softlayer.SoftlayerPublicVLAN)
+		if c.conf.Request.PublicVLAN {
+			req = c.CreateSubnetRequest(request.ClusterName, c.conf.Softlayer.SoftlayerPublicVLAN)
 		}
 
 	case config.ActionGetCredentials:
-		req = GetCredentialsRequest()
+		req = c.GetCredentialsRequest()
 
 	case config.ActionSetCredentials:
-		req = SetCredentialsRequest()
+		req = c.SetCredentialsRequest()
 
 	case config.ActionDeleteCredentials:
-		req = DeleteCredentialsRequest()
+		req = c.DeleteCredentialsRequest()
 
 	case config.ActionGetVLANSpanning:
-		req = GetVLANSpanningRequest()
+		req = c.GetVLANSpanningRequest()
 
 	case config.ActionUpdateCluster:
-		localKubeUpdateVersion = kubeVersion
+		localKubeUpdateVersion = c.KubeVersion()
 		if len(request.KubeUpdateVersion) > 0 {
 			localKubeUpdateVersion = request.KubeUpdateVersion
 		}
-		req = UpdateClusterRequest(request.ClusterName, localKubeUpdateVersion, "update")
+		req = c.UpdateClusterRequest(request.ClusterName, localKubeUpdateVersion, "update")
 
 	case config.ActionApplyPullSecret:
-		req = UpdateClusterRequest(request.ClusterName, "", "enablePullSecrets")
+		req = c.UpdateClusterRequest(request.ClusterName, "", "enablePullSecrets")
 
 	case config.ActionGetVersions:
-		req = GetVersionsRequest()
+		req = c.GetVersionsRequest()
 
 	default:
 		fmt.Println("Action not defined: ", request.Action)
 	}
 
 	// Set headers that are mandatory for all requests
-	setCommonRequestHeaders(req)
-
-	client := &http.Client{}
+	c.setCommonRequestHeaders(req)
 
 	var actionStr = request.Action.String()
 	if request.Action.HasCluster() {
 		actionStr = strings.Join([]string{actionStr, request.ClusterName}, " - ")
 	}
 	apiRequestTime := time.Now()
-	if verbose {
+	if c.verbose {
 		fmt.Printf("%s\tAPI request: %s : \"%s\"\n", apiRequestTime.Format(time.StampMilli), req.URL.String(), actionStr)
 	}
-	if debug {
+	if c.debug {
 		if req.Body != nil {
 			fmt.Println(req.Body)
 		}
 	}
-	if monitor {
+	if c.monitor {
 		fmt.Printf("%s: monitor %s %+v requested\n", time.Now().Format(time.StampMilli), request.ClusterName, request.Action)
 	}
 
@@ -959,7 +1068,7 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 	var err error
 	if singleAttempt {
 		// Only retry if there is an authentication error, and only once
-		resp, err = client.Do(req)
+		resp, err = c.httpClient.Do(req)
 		if err != nil {
 			fmt.Println(err)
 			request.ActionFailed = true
@@ -974,13 +1083,13 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 		if resp.StatusCode == http.StatusUnauthorized || (resp.StatusCode == http.StatusInternalServerError && request.Action == config.ActionCreateCluster) {
 			fmt.Printf("Request failed with %v error code. Retrying request. Cluster: %s\n", resp.StatusCode, request.ClusterName)
 			resp.Body.Close()
-			Authenticate()
-			setCommonRequestHeaders(req)
+			c.Authenticate()
+			c.setCommonRequestHeaders(req)
 
 			// Sleep before retry
 			time.Sleep(time.Second * 10)
 
-			resp, err = client.Do(req)
+			resp, err = c.httpClient.Do(req)
 
 			if err != nil {
 				if resp != nil {
@@ -1004,7 +1113,7 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 
 		var sleep = time.Millisecond
 		for {
-			resp, err = client.Do(req)
+			resp, err = c.httpClient.Do(req)
 			if err != nil {
 				fmt.Printf("%s\tAPI request: %s : \"%s\"\n", apiRequestTime.Format(time.StampMilli), req.URL.String(), request.Action)
 				fmt.Println(err)
@@ -1025,8 +1134,8 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 			}
 			if err != nil || resp.StatusCode == http.StatusUnauthorized {
 				// During long tests token may become stale, give 1 try at resolving issue
-				Authenticate()
-				setCommonRequestHeaders(req)
+				c.Authenticate()
+				c.setCommonRequestHeaders(req)
 			}
 			time.Sleep(sleep)
 			if sleep < time.Second*10 {
@@ -1047,12 +1156,19 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 	apiResponseTime := time.Now()
 	request.ResponseTime = apiResponseTime.Sub(apiRequestTime)
 
+	// Avoid coordinated omission by including any delay in sending a scheduled request
+	if !request.ScheduledTime.IsZero() {
+		request.Metrics.IntendedSendTime = request.ScheduledTime
+		request.Metrics.ActualSendTime = apiRequestTime
+		request.ResponseTime = apiResponseTime.Sub(request.ScheduledTime)
+	}
+
 	successResp := resp.StatusCode/100 == 2
 
-	if verbose {
+	if c.verbose {
 		fmt.Printf("%s\tAPI response: %s\n", apiResponseTime.Format(time.StampMilli), resp.Status)
 	}
-	if debug {
+	if c.debug {
 		fmt.Println(resp.Header)
 	}
 	if resp.StatusCode != http.StatusNoContent {
@@ -1062,7 +1178,7 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 			if err = json.Indent(&prettyJSON, body, "", "\t"); err != nil {
 				panic(err)
 			}
-			if verbose || (!successResp && !(request.Action == config.ActionGetCluster && resp.StatusCode == http.StatusNotFound)) {
+			if c.verbose || (!successResp && !(request.Action == config.ActionGetCluster && resp.StatusCode == http.StatusNotFound)) {
 				fmt.Println(string(prettyJSON.Bytes()))
 			}
 		} else if !strings.Contains(contentType, "application/zip") {
@@ -1107,12 +1223,12 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 
 			// When creating a Cruiser (cluster) we need to inform Armada cluster that we're ready to provision workers.
 			// This is normally done by Armada deploy once the cruiser master is ready.
-			if mockDeploy != nil || mockBootstrap != nil {
-				if mockDeploy != nil {
-					mockDeploy.DeployCruiserMaster(request.ClusterID)
+			if c.mockDeploy != nil || c.mockBootstrap != nil {
+				if c.mockDeploy != nil {
+					c.mockDeploy.DeployCruiserMaster(request.ClusterID)
 				}
-				if mockBootstrap != nil {
-					mockBootstrap.PerformBootstrap(request.Action, reqConf.Bluemix.AccountID, request.ClusterID, machineType == config.FreeAccountStr)
+				if c.mockBootstrap != nil {
+					c.mockBootstrap.PerformBootstrap(request.Action, c.conf.Bluemix.AccountID, request.ClusterID, c.machineType == config.FreeAccountStr)
 				}
 			}
 
@@ -1120,15 +1236,15 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 			pollForMastersEligible = true
 		case config.ActionAddClusterWorkers:
 			pollForWorkersEligible = true
-			if mockBootstrap != nil {
-				mockBootstrap.PerformBootstrap(request.Action, reqConf.Bluemix.AccountID, request.ClusterName, machineType == config.FreeAccountStr)
+			if c.mockBootstrap != nil {
+				c.mockBootstrap.PerformBootstrap(request.Action, c.conf.Bluemix.AccountID, request.ClusterName, c.machineType == config.FreeAccountStr)
 			}
 		case config.ActionGetClusterWorkers:
 			pollForWorkersEligible = true
 		case config.ActionGetClusterConfig:
 			// Create zip file containing cluster config
 			clusterConfigPrefix := "kubeConfig"
-			if reqConf.Request.AdminConfig {
+			if c.conf.Request.AdminConfig {
 				clusterConfigPrefix = clusterConfigPrefix + "Admin"
 			}
 			clusterConfigFilename := clusterConfigPrefix + "-" + request.ClusterName + ".zip"
@@ -1145,25 +1261,25 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 		}
 
 		// Synchronous request? If so, we'll block until the master is ready to rock 'n' roll
-		if pollForMastersEligible && reqConf.Request.MasterPollInterval.Duration > 0 {
+		if pollForMastersEligible && c.conf.Request.MasterPollInterval.Duration > 0 {
 			var clusterComplete bool
 
 			// Mark this request as blocking. Used for generating sensible metrics.
-			blockingRequest = true
+			c.setBlocking(true)
 
 			request.Metrics.ClusterName = request.ClusterName
 
 			for !clusterComplete {
 				clusterComplete = true
 
-				statusReq := GetClusterRequest(request.ClusterName)
+				statusReq := c.GetClusterRequest(request.ClusterName)
 
-				setCommonRequestHeaders(statusReq)
+				c.setCommonRequestHeaders(statusReq)
 
-				statusResp, err := client.Do(statusReq)
+				statusResp, err := c.httpClient.Do(statusReq)
 				if err != nil {
 					fmt.Println(err)
-					time.Sleep(reqConf.Request.MasterPollInterval.Duration)
+					time.Sleep(c.conf.Request.MasterPollInterval.Duration)
 					clusterComplete = false
 					continue
 				}
@@ -1174,9 +1290,9 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 				if statusResp.StatusCode == http.StatusUnauthorized {
 					// Token has probably expired. Let's try once more with new tokens.
 					statusResp.Body.Close()
-					Authenticate()
-					setCommonRequestHeaders(statusReq)
-					statusResp, err = client.Do(statusReq)
+					c.Authenticate()
+					c.setCommonRequestHeaders(statusReq)
+					statusResp, err = c.httpClient.Do(statusReq)
 					if err != nil {
 						panic(err)
 					}
@@ -1202,11 +1318,11 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 					err = json.Unmarshal(body, &cgResp)
 					if err != nil {
 						// Some hokey response that we weren't expecting, let's ignore and carry on polling
-						if debug && err != nil {
+						if c.debug && err != nil {
 							fmt.Println(err.Error())
 							fmt.Println(string(body))
 						}
-						time.Sleep(reqConf.Request.WorkerPollInterval.Duration)
+						time.Sleep(c.conf.Request.WorkerPollInterval.Duration)
 						clusterComplete = false
 						continue
 					}
@@ -1266,7 +1382,7 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 					}
 				}
 				if !clusterComplete {
-					time.Sleep(reqConf.Request.MasterPollInterval.Duration)
+					time.Sleep(c.conf.Request.MasterPollInterval.Duration)
 				}
 			}
 
@@ -1279,12 +1395,12 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 	}
 
 	// Synchronous request? If so, we'll block until the cluster is ready to rock 'n' roll
-	if pollForWorkersEligible && reqConf.Request.WorkerPollInterval.Duration > 0 && !request.ActionFailed {
+	if pollForWorkersEligible && c.conf.Request.WorkerPollInterval.Duration > 0 && !request.ActionFailed {
 		var clusterComplete bool
 		var prevTime time.Time
 
 		// Mark this request as blocking. Used for generating sensible metrics.
-		blockingRequest = true
+		c.setBlocking(true)
 
 		request.Metrics.ClusterName = request.ClusterName
 		request.Metrics.WorkerCreationTimes = make(map[string]float64)
@@ -1293,16 +1409,16 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 		for !clusterComplete {
 			clusterComplete = true
 
-			statusReq := GetClusterWorkersRequest(request.ClusterName)
-			setCommonRequestHeaders(statusReq)
+			statusReq := c.GetClusterWorkersRequest(request.ClusterName)
+			c.setCommonRequestHeaders(statusReq)
 
 			pollRequestTime := time.Now()
-			statusResp, err := client.Do(statusReq)
+			statusResp, err := c.httpClient.Do(statusReq)
 			if err != nil {
 				// Error occurred, let's ignore and carry on polling
 				fmt.Printf("Error occurred getting workers, will continue polling: %s", err.Error())
 
-				time.Sleep(reqConf.Request.WorkerPollInterval.Duration)
+				time.Sleep(c.conf.Request.WorkerPollInterval.Duration)
 				clusterComplete = false
 				continue
 			}
@@ -1313,15 +1429,15 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 			if statusResp.StatusCode == http.StatusUnauthorized {
 				// Token has probably expired. Let's try once more with new tokens.
 				statusResp.Body.Close()
-				Authenticate()
-				setCommonRequestHeaders(statusReq)
+				c.Authenticate()
+				c.setCommonRequestHeaders(statusReq)
 				pollRequestTime = time.Now()
-				statusResp, err = client.Do(statusReq)
+				statusResp, err = c.httpClient.Do(statusReq)
 				if err != nil {
 					// Error occurred, let's ignore and carry on polling
 					fmt.Printf("Error occurred getting workers, will continue polling: %s", err.Error())
 
-					time.Sleep(reqConf.Request.WorkerPollInterval.Duration)
+					time.Sleep(c.conf.Request.WorkerPollInterval.Duration)
 					clusterComplete = false
 					continue
 				}
@@ -1336,11 +1452,11 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 			err = json.Unmarshal(body, &cgResp)
 			if err != nil || len(cgResp) == 0 {
 				// Some hokey response that we weren't expecting, let's ignore and carry on polling
-				if debug && err != nil {
+				if c.debug && err != nil {
 					fmt.Println(err.Error())
 					fmt.Println(string(body))
 				}
-				time.Sleep(reqConf.Request.WorkerPollInterval.Duration)
+				time.Sleep(c.conf.Request.WorkerPollInterval.Duration)
 				clusterComplete = false
 				continue
 			}
@@ -1446,11 +1562,11 @@ func PerformRequest(request Data, singleAttempt bool) Data {
 					WorkersCreated: workersCompletedCount,
 				})
 			if !clusterComplete {
-				time.Sleep(reqConf.Request.WorkerPollInterval.Duration)
+				time.Sleep(c.conf.Request.WorkerPollInterval.Duration)
 			}
 		}
 
-		if debug {
+		if c.debug {
 			fmt.Println()
 			prevWorkersCreated := 0
 			for _, m := range request.Metrics.Workers {
@@ -0,0 +1,381 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	config "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/config"
+	"github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/metrics"
+	request "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/request"
+	schedule "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/schedule"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
+	"gopkg.in/yaml.v2"
+)
+
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// Scenario defines a mixed workload of weighted Armada API actions, run over one or more phases
+type Scenario struct {
+	Name    string   `toml:"name" yaml:"name"`
+	Phases  []Phase  `toml:"phase" yaml:"phase"`
+	Actions []Action `toml:"action" yaml:"action"`
+}
+
+// Phase defines a period of the scenario (e.g. warm-up, steady state, cool-down) with its own load.
+// An open-loop phase issues requests at Rate requests/sec, shared between the actions by weight.
+// A closed-loop phase (Rate of 0) runs Users concurrent users, each issuing one request at a time.
+type Phase struct {
+	Name     string               `toml:"name" yaml:"name"`
+	Duration duration             `toml:"duration" yaml:"duration"`
+	Rate     float64              `toml:"rate" yaml:"rate"`
+	Profile  schedule.ProfileType `toml:"profile" yaml:"profile"`
+	Users    int                  `toml:"users" yaml:"users"`
+}
+
+// Action defines an Armada API action and its share of the scenario's requests
+type Action struct {
+	Action config.ActionType `toml:"action" yaml:"action"`
+	Weight float64           `toml:"weight" yaml:"weight"`
+
+	// Rate fixes the rate (requests/sec) of this action in open-loop phases, rather than sharing the phase rate by weight
+	Rate float64 `toml:"rate" yaml:"rate"`
+
+	// ThinkTime is how long a user pauses after this action in closed-loop phases
+	ThinkTime duration `toml:"think_time" yaml:"think_time"`
+
+	// Optional request details. Defaults are taken from the command line.
+	ClusterName    string `toml:"cluster_name" yaml:"cluster_name"`
+	WorkerPoolName string `toml:"worker_pool_name" yaml:"worker_pool_name"`
+	WorkerID       string `toml:"worker_id" yaml:"worker_id"`
+	ZoneID         string `toml:"zone_id" yaml:"zone_id"`
+	PoolSize       int    `toml:"pool_size" yaml:"pool_size"`
+	Workers        int    `toml:"workers" yaml:"workers"`
+}
+
+// Load reads a scenario from a YAML file (.yaml or .yml), or otherwise a TOML file
+func Load(path string) (*Scenario, error) {
+	var sc Scenario
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// #nosec G304
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, &sc); err != nil {
+			return nil, err
+		}
+	default:
+		if _, err := toml.DecodeFile(path, &sc); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// Validate checks that the scenario can be run
+func (sc *Scenario) Validate() error {
+	if len(sc.Phases) == 0 {
+		return errors.New("Scenario must define at least one phase")
+	}
+	if len(sc.Actions) == 0 {
+		return errors.New("Scenario must define at least one action")
+	}
+
+	for _, a := range sc.Actions {
+		switch a.Action {
+		case config.ActionUnspecified, config.ActionAlignClusters, config.ActionChurnClusters:
+			return fmt.Errorf("Action %s not supported in a scenario", a.Action)
+		}
+		if a.Weight < 0 || a.Rate < 0 {
+			return fmt.Errorf("Action %s weight and rate must be >= 0", a.Action)
+		}
+	}
+
+	for i, p := range sc.Phases {
+		if len(p.Name) == 0 {
+			sc.Phases[i].Name = fmt.Sprintf("phase%d", i+1)
+		}
+		if p.Duration.Duration <= 0 {
+			return fmt.Errorf("Phase %s requires a duration > 0", sc.Phases[i].Name)
+		}
+		if p.Rate == 0 && p.Users == 0 && !sc.hasFixedRates() {
+			return fmt.Errorf("Phase %s requires a rate or a number of users", sc.Phases[i].Name)
+		}
+		switch p.Profile {
+		case schedule.ProfileUnspecified:
+			sc.Phases[i].Profile = schedule.ProfileConstant
+		case schedule.ProfileConstant, schedule.ProfilePoisson:
+		default:
+			return fmt.Errorf("Phase %s profile must be Constant or Poisson", sc.Phases[i].Name)
+		}
+	}
+	return nil
+}
+
+func (sc *Scenario) hasFixedRates() bool {
+	for _, a := range sc.Actions {
+		if a.Rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Results holds the metrics for each action, for each phase of a scenario
+type Results map[string]map[config.ActionType]metrics.ArmadaMetrics
+
+func (r Results) add(phase string, d request.Data) {
+	if r[phase] == nil {
+		r[phase] = make(map[config.ActionType]metrics.ArmadaMetrics)
+	}
+
+	m := d.Metrics
+	m.ResponseTime = d.ResponseTime
+	m.ActionTime = d.ActionTime
+	m.ActionFailed = d.ActionFailed
+	m.BackendFailed = d.ActionFailed && d.Failure != request.FailureUnspecified
+	r[phase][d.Action] = append(r[phase][d.Action], m)
+}
+
+// Runner runs a scenario against the Armada API
+type Runner struct {
+	Client *request.Client
+
+	// NumThreads is the number of concurrent requests in open-loop phases. 0 means unlimited.
+	NumThreads int
+
+	// Defaults supplies request details not specified by a scenario action
+	Defaults request.Data
+
+	// ClusterName returns the name of the cluster to be used for the n'th request of an action
+	// that doesn't specify a cluster
+	ClusterName func(n int) string
+}
+
+// Run runs each phase of the scenario in turn, returning the metrics for every request
+func (rn *Runner) Run(sc *Scenario) Results {
+	results := make(Results)
+	for _, p := range sc.Phases {
+		start := time.Now()
+		fmt.Printf("%s\tScenario %s: starting phase %s for %s\n", start.Format(time.StampMilli), sc.Name, p.Name, p.Duration.Duration)
+
+		var completed []request.Data
+		if p.Rate > 0 || (p.Users == 0 && sc.hasFixedRates()) {
+			completed = rn.runOpenLoop(sc, p)
+		} else {
+			completed = rn.runClosedLoop(sc, p)
+		}
+		for _, d := range completed {
+			results.add(p.Name, d)
+		}
+
+		fmt.Printf("%s\tScenario %s: completed phase %s, %d requests in %.3fs\n", time.Now().Format(time.StampMilli), sc.Name, p.Name, len(completed), time.Since(start).Seconds())
+	}
+	return results
+}
+
+// request generates the n'th request for the specified action
+func (rn *Runner) request(a Action, n int) request.Data {
+	d := rn.Defaults
+	d.Action = a.Action
+	d.RequestNum = n + 1
+
+	if len(a.ClusterName) > 0 {
+		d.ClusterName = a.ClusterName
+	} else if a.Action.HasCluster() && rn.ClusterName != nil {
+		d.ClusterName = rn.ClusterName(n)
+	} else if !a.Action.HasCluster() {
+		d.ClusterName = ""
+	}
+	if len(a.WorkerPoolName) > 0 {
+		d.WorkerPoolName = a.WorkerPoolName
+	}
+	if len(a.WorkerID) > 0 {
+		d.WorkerID = a.WorkerID
+	}
+	if len(a.ZoneID) > 0 {
+		d.ZoneID = a.ZoneID
+	}
+	if a.PoolSize > 0 {
+		d.PoolSize = a.PoolSize
+	}
+	if a.Workers > 0 {
+		d.TotalWorkers = a.Workers
+	}
+	return d
+}
+
+// arrival identifies the action to be requested at a scheduled time
+type arrival struct {
+	offset time.Duration
+	action int
+}
+
+// runOpenLoop issues requests for each action at its share of the phase rate
+func (rn *Runner) runOpenLoop(sc *Scenario, p Phase) []request.Data {
+	var totalWeight float64
+	for _, a := range sc.Actions {
+		if a.Rate == 0 {
+			totalWeight += a.Weight
+		}
+	}
+
+	// Merge the arrivals for every action into a single schedule
+	var arrivals []arrival
+	for i, a := range sc.Actions {
+		rate := a.Rate
+		if rate == 0 && totalWeight > 0 {
+			rate = p.Rate * a.Weight / totalWeight
+		}
+		if rate <= 0 {
+			continue
+		}
+
+		profile := schedule.Profile{Type: p.Profile, Rate: rate, Duration: p.Duration.Duration, Seed: time.Now().UnixNano() + int64(i)}
+		for _, offset := range profile.Arrivals() {
+			arrivals = append(arrivals, arrival{offset: offset, action: i})
+		}
+	}
+	sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].offset < arrivals[j].offset })
+
+	offsets := make([]time.Duration, len(arrivals))
+	for i, a := range arrivals {
+		offsets[i] = a.offset
+	}
+
+	numThreads := rn.NumThreads
+	if numThreads <= 0 || numThreads > len(arrivals) {
+		numThreads = len(arrivals)
+	}
+
+	jobs := make(chan request.Data, len(arrivals))
+	completed := make(chan request.Data, len(arrivals))
+	rn.Client.Start(numThreads, jobs, completed)
+
+	// Keep a count of requests per action, so that requests of each action are spread across the clusters
+	counts := make([]int, len(sc.Actions))
+	go func() {
+		schedule.Dispatch(offsets, jobs, func(n int) request.Data {
+			i := arrivals[n].action
+			d := rn.request(sc.Actions[i], counts[i])
+			counts[i]++
+			return d
+		})
+		close(jobs)
+	}()
+
+	results := make([]request.Data, 0, len(arrivals))
+	for range arrivals {
+		results = append(results, <-completed)
+	}
+	return results
+}
+
+// runClosedLoop runs the phase's users, each repeatedly choosing an action by weight
+// and pausing for the action's think time, until the phase ends
+func (rn *Runner) runClosedLoop(sc *Scenario, p Phase) []request.Data {
+	var totalWeight float64
+	for _, a := range sc.Actions {
+		totalWeight += a.Weight
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []request.Data
+	counts := make([]int, len(sc.Actions))
+
+	end := time.Now().Add(p.Duration.Duration)
+	for u := 0; u < p.Users; u++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed)) // #nosec G404
+			for time.Now().Before(end) {
+				i := pick(sc.Actions, totalWeight, rnd)
+
+				mu.Lock()
+				d := rn.request(sc.Actions[i], counts[i])
+				counts[i]++
+				mu.Unlock()
+
+				d = rn.Client.PerformRequest(d, true)
+
+				mu.Lock()
+				results = append(results, d)
+				mu.Unlock()
+
+				if think := sc.Actions[i].ThinkTime.Duration; think > 0 {
+					time.Sleep(think)
+				}
+			}
+		}(time.Now().UnixNano() + int64(u))
+	}
+	wg.Wait()
+	return results
+}
+
+// pick chooses an action at random, in proportion to its weight
+func pick(actions []Action, totalWeight float64, rnd *rand.Rand) int {
+	if totalWeight <= 0 {
+		return rnd.Intn(len(actions))
+	}
+	r := rnd.Float64() * totalWeight
+	for i, a := range actions {
+		r -= a.Weight
+		if r < 0 {
+			return i
+		}
+	}
+	return len(actions) - 1
+}
+
+// Print writes a latency summary for each action in each phase of the scenario
+func (r Results) Print(sc *Scenario, w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Phase\tAction\tRequests\tFailed\tMean(s)\tP50(s)\tP90(s)\tP99(s)\tMax(s)")
+	for _, p := range sc.Phases {
+		// An action can be listed more than once (e.g. for different clusters), but is only reported once
+		reported := make(map[config.ActionType]bool)
+		for _, a := range sc.Actions {
+			m := r[p.Name][a.Action]
+			if len(m) == 0 || reported[a.Action] {
+				continue
+			}
+			reported[a.Action] = true
+
+			var failed int
+			times := histogram.New()
+			for _, rm := range m {
+				times.Record(rm.ResponseTime)
+				if rm.ActionFailed {
+					failed++
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\n", p.Name, a.Action, len(m), failed,
+				times.Mean().Seconds(), times.Percentile(50).Seconds(), times.Percentile(90).Seconds(), times.Percentile(99).Seconds(), times.Max().Seconds())
+		}
+	}
+	tw.Flush()
+}
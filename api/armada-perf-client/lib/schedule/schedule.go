@@ -0,0 +1,180 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	request "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/request"
+)
+
+// ProfileType defines a load profile 'enum' for open-loop request scheduling
+type ProfileType int
+
+// Load profile enumerations
+const (
+	ProfileUnspecified ProfileType = iota
+	ProfileConstant
+	ProfileRamp
+	ProfileStep
+	ProfilePoisson
+)
+
+// Profiles provides an enumeration to String mapping
+var Profiles = []string{
+	"Unspecified",
+	"Constant",
+	"Ramp",
+	"Step",
+	"Poisson",
+}
+
+func (pt ProfileType) String() string {
+	return Profiles[pt]
+}
+
+// Set is used by the flag package to convert a command line option to a ProfileType
+func (pt *ProfileType) Set(value string) error {
+	for i, p := range Profiles {
+		if strings.EqualFold(p, value) {
+			*pt = ProfileType(i)
+			return nil
+		}
+	}
+	return errors.New("Invalid load profile. Valid options: " + strings.Join(Profiles[1:], ", "))
+}
+
+// UnmarshalText allows a load profile to be specified by name in a configuration file
+func (pt *ProfileType) UnmarshalText(text []byte) error {
+	return pt.Set(string(text))
+}
+
+// Profile defines the target arrival rate of requests over the duration of a test.
+// Requests are scheduled independently of how quickly earlier requests complete (i.e. open-loop),
+// so a slow API doesn't reduce the offered load.
+type Profile struct {
+	Type         ProfileType
+	Rate         float64       // Target rate (requests/sec). For Ramp and Step profiles, the final rate
+	StartRate    float64       // Initial rate (requests/sec) for Ramp and Step profiles
+	StepRate     float64       // Rate increase (requests/sec) at each step of a Step profile
+	StepInterval time.Duration // Duration of each step of a Step profile
+	Duration     time.Duration // Total duration of the test
+	Seed         int64         // Random number seed for Poisson profiles
+}
+
+// Validate checks that the profile is fully specified
+func (p Profile) Validate() error {
+	if p.Duration <= 0 {
+		return fmt.Errorf("%s load profile requires a duration > 0", p.Type)
+	}
+	if p.Rate <= 0 {
+		return fmt.Errorf("%s load profile requires a rate > 0", p.Type)
+	}
+
+	switch p.Type {
+	case ProfileConstant, ProfilePoisson:
+	case ProfileRamp:
+		if p.StartRate < 0 {
+			return errors.New("Ramp load profile requires a start rate >= 0")
+		}
+	case ProfileStep:
+		if p.StartRate <= 0 || p.StepRate <= 0 || p.StepInterval <= 0 {
+			return errors.New("Step load profile requires a start rate, step rate and step interval > 0")
+		}
+	default:
+		return errors.New("Load profile not specified")
+	}
+	return nil
+}
+
+// RateAt returns the target arrival rate (requests/sec) at the specified offset from the start of the test
+func (p Profile) RateAt(offset time.Duration) float64 {
+	switch p.Type {
+	case ProfileRamp:
+		return p.StartRate + (p.Rate-p.StartRate)*offset.Seconds()/p.Duration.Seconds()
+	case ProfileStep:
+		return math.Min(p.StartRate+float64(offset/p.StepInterval)*p.StepRate, p.Rate)
+	default:
+		return p.Rate
+	}
+}
+
+// Arrivals returns the scheduled start time, as an offset from the start of the test, of every request
+func (p Profile) Arrivals() []time.Duration {
+	var arrivals []time.Duration
+	duration := p.Duration.Seconds()
+
+	switch p.Type {
+	case ProfileConstant:
+		for k := 0; float64(k)/p.Rate < duration; k++ {
+			arrivals = append(arrivals, seconds(float64(k)/p.Rate))
+		}
+
+	case ProfileRamp:
+		// The number of requests issued by time t is the integral of the rate:
+		//   N(t) = StartRate*t + (Rate-StartRate)*t^2/(2*Duration)
+		// so the k'th request is scheduled at the root of N(t) = k
+		a := (p.Rate - p.StartRate) / (2 * duration)
+		b := p.StartRate
+		for k := 0; ; k++ {
+			var t float64
+			if a == 0 {
+				t = float64(k) / b
+			} else {
+				t = (-b + math.Sqrt(b*b+4*a*float64(k))) / (2 * a)
+			}
+			if t >= duration || math.IsNaN(t) {
+				break
+			}
+			arrivals = append(arrivals, seconds(t))
+		}
+
+	case ProfileStep:
+		for stepStart := time.Duration(0); stepStart < p.Duration; stepStart += p.StepInterval {
+			stepEnd := stepStart + p.StepInterval
+			if stepEnd > p.Duration {
+				stepEnd = p.Duration
+			}
+			rate := p.RateAt(stepStart)
+			for k := 0; ; k++ {
+				t := stepStart + seconds(float64(k)/rate)
+				if t >= stepEnd {
+					break
+				}
+				arrivals = append(arrivals, t)
+			}
+		}
+
+	case ProfilePoisson:
+		// Exponentially distributed inter-arrival times give a Poisson arrival process
+		rnd := rand.New(rand.NewSource(p.Seed)) // #nosec G404
+		for t := 0.0; t < duration; t += rnd.ExpFloat64() / p.Rate {
+			arrivals = append(arrivals, seconds(t))
+		}
+	}
+	return arrivals
+}
+
+// Dispatch sends a request onto jobs at each of the scheduled arrival times, stamping each request
+// with its scheduled start time so that latency can be measured from when the request should have been sent.
+// The next function is called to generate the n'th request.
+func Dispatch(arrivals []time.Duration, jobs chan<- request.Data, next func(n int) request.Data) {
+	start := time.Now()
+	for n, offset := range arrivals {
+		scheduled := start.Add(offset)
+		if wait := time.Until(scheduled); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		r := next(n)
+		r.ScheduledTime = scheduled
+		jobs <- r
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -18,6 +18,8 @@ import (
 	deploy "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/deploy"
 	"github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/metrics"
 	request "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/request"
+	scenario "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/scenario"
+	schedule "github.ibm.com/alchemy-containers/armada-performance/api/armada-perf-client/lib/schedule"
 	"github.ibm.com/alchemy-containers/armada-performance/tools/crypto/utils"
 )
 
@@ -54,6 +56,8 @@ func main() {
 	var useChurnVLAN bool                 // Use to determine whether to create clusters on the normal subnet or the churn subnet
 	var action = config.ActionUnspecified // Mandatory flag to indicate API request to generate
 	var monitor bool                      // Output monitoring data to stdout?
+	var loadProfile schedule.Profile      // Open-loop arrival rate profile. Replaces -numRequests if specified
+	var scenarioFile string               // Mixed workload scenario file. Replaces -action if specified
 
 	flag.StringVar(&singleClusterName, "clusterName", "", "Name of cluster. Use to specify name of a single patrol/cruiser")
 	flag.StringVar(&clusterPrefix, "clusterNamePrefix", "perfCluster", "Name prefix of clusters. Use to specify name prefix for multiple patrols/cruisers")
@@ -73,6 +77,7 @@ func main() {
 	flag.BoolVar(&debug, "debug", false, "Detailed logging output")
 	flag.BoolVar(&verbose, "verbose", true, "Request and response summary logging output")
 	flag.BoolVar(&sendMetrics, "metrics", false, "send metrics data to Bluemix metrics service")
+	flag.StringVar(&metrics.HistogramDir, "histogramDir", "", "directory to dump raw latency histograms to with -metrics, for offline merging across runs")
 	flag.BoolVar(&adminKubeConfig, "admin", false, "Retrieve Cluster Admin Configuration")
 	flag.BoolVar(&deleteResources, "deleteResources", false, "Delete additional resources linked to the cluster")
 	flag.BoolVar(&showResources, "showResources", false, "Show additional cluster resources")
@@ -83,14 +88,39 @@ func main() {
 	flag.DurationVar(&masterPollInterval, "masterPollInterval", (-1 * time.Second), "polling interval for checking master ready status; 0 means do not poll")
 	flag.BoolVar(&monitor, "monitor", false, "Output monitoring data on each request")
 	flag.Var(&action, "action", "Armada API action. Valid options: "+strings.Join(config.Actions[1:].Strings(), ", "))
+	flag.StringVar(&scenarioFile, "scenario", "", "Mixed workload scenario file (TOML, or YAML with a .yaml/.yml extension). Runs the scenario's weighted actions and phases instead of -action")
+	flag.Var(&loadProfile.Type, "loadProfile", "Issue requests at a target rate rather than -numRequests. Valid options: "+strings.Join(schedule.Profiles[1:], ", "))
+	flag.Float64Var(&loadProfile.Rate, "rate", 0, "load profile target rate (requests/sec). Final rate for Ramp and Step profiles")
+	flag.Float64Var(&loadProfile.StartRate, "startRate", 0, "load profile initial rate (requests/sec) for Ramp and Step profiles")
+	flag.Float64Var(&loadProfile.StepRate, "stepRate", 0, "load profile rate increase (requests/sec) at each step of a Step profile")
+	flag.DurationVar(&loadProfile.StepInterval, "stepInterval", time.Minute, "load profile duration of each step of a Step profile")
+	flag.DurationVar(&loadProfile.Duration, "duration", 0, "load profile test duration")
+	flag.Int64Var(&loadProfile.Seed, "seed", time.Now().UnixNano(), "load profile random number seed for Poisson profile")
 
 	flag.Parse()
 
 	// Enforce mandatory action flag
-	if action == config.ActionUnspecified {
+	var sc *scenario.Scenario
+	if len(scenarioFile) > 0 {
+		if action != config.ActionUnspecified || loadProfile.Type != schedule.ProfileUnspecified {
+			fmt.Fprintf(os.Stderr, "-scenario can't be used with -action or -loadProfile.\n")
+			os.Exit(1)
+		}
+		var err error
+		if sc, err = scenario.Load(scenarioFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading scenario file %s : %s\n", scenarioFile, err.Error())
+			os.Exit(1)
+		}
+		// Scenario requests are spread across the clusters, unless a single cluster is specified
+		if len(singleClusterName) == 0 && totalClusters <= 0 {
+			fmt.Fprintf(os.Stderr, "-clusters must be greater than 0 with -scenario.\n")
+			os.Exit(1)
+		}
+	} else if action == config.ActionUnspecified {
 		fmt.Fprintf(os.Stderr, "Must specify -action.\n")
 		os.Exit(1)
 	}
+	scenarioClusters := totalClusters
 
 	if action == config.ActionCreateSubnet {
 		if privateVLAN == publicVLAN {
@@ -99,6 +129,17 @@ func main() {
 		}
 	}
 
+	if loadProfile.Type != schedule.ProfileUnspecified {
+		if err := loadProfile.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s.\n", err.Error())
+			os.Exit(1)
+		}
+		if action == config.ActionAlignClusters {
+			fmt.Fprintf(os.Stderr, "-loadProfile not supported for %s action.\n", action)
+			os.Exit(1)
+		}
+	}
+
 	if (action == config.ActionCreateWorkerPool) || (action == config.ActionResizeWorkerPool) {
 		if poolSize == 0 {
 			fmt.Fprintf(os.Stderr, "Specify a valid ( >0 ) worker pool size.\n")
@@ -156,10 +197,8 @@ func main() {
 
 	if action == config.ActionAlignClusters {
 		// See what is in carrier before deciding the action required.
-		requestJobs := make(chan request.Data, 1)
-		requestCompleted := make(chan request.Data, 1)
-		request.InitRequests(machineType, kubeVersion, workerPoolName, &conf, false, false, false, nil, nil, 1, &requestJobs, &requestCompleted)
-		requestJobs <- request.Data{
+		alignClient := request.NewClient(&conf, request.Options{MachineType: machineType, KubeVersion: kubeVersion, WorkerPoolName: workerPoolName})
+		requestData := alignClient.PerformRequest(request.Data{
 			Action:         config.ActionGetClusters,
 			ClusterName:    "",
 			RequestNum:     1,
@@ -167,8 +206,7 @@ func main() {
 			WorkerPoolName: "",
 			ZoneID:         "",
 			PoolSize:       0,
-			TotalWorkers:   1}
-		requestData := <-requestCompleted
+			TotalWorkers:   1}, true)
 
 		if requestData.ActionFailed {
 			fmt.Println("Request to find existing clusters failed")
@@ -231,7 +269,15 @@ func main() {
 	}
 
 	totalRequests := totalRequestsPerUser * requestsPerAction
-	if numThreads == 0 {
+
+	// An open-loop load profile determines the number of requests from its rate and duration
+	var arrivals []time.Duration
+	if loadProfile.Type != schedule.ProfileUnspecified {
+		arrivals = loadProfile.Arrivals()
+		totalRequests = len(arrivals)
+		fmt.Printf("%s\tLoad profile: %s, %d requests over %s\n", time.Now().Format(time.StampMilli), loadProfile.Type, totalRequests, loadProfile.Duration)
+	}
+	if numThreads == 0 && sc == nil {
 		// Default to processing ALL requests in parallel
 		numThreads = totalUsers * totalRequests
 	}
@@ -273,35 +319,86 @@ func main() {
 		zoneID = conf.Location.Datacenter
 	}
 
+	if sc != nil {
+		client := request.NewClient(&conf, request.Options{
+			MachineType:    machineType,
+			KubeVersion:    kubeVersion,
+			WorkerPoolName: workerPoolName,
+			Verbose:        verbose,
+			Debug:          debug,
+			Monitor:        monitor,
+			MockDeploy:     mockDeploy,
+			MockBootstrap:  mockBootstrap,
+		})
+		runScenario(client, sc, numThreads, request.Data{
+			ClusterName:    singleClusterName,
+			WorkerID:       workerID,
+			WorkerPoolName: workerPoolName,
+			ZoneID:         zoneID,
+			PoolSize:       poolSize,
+		}, clusterPrefix, scenarioClusters, sendMetrics, testName, dbKey)
+		return
+	}
+
 	requestJobs := make(chan request.Data, totalUsers*totalRequests)
 	requestCompleted := make(chan request.Data, totalUsers*totalRequests)
-	request.InitRequests(machineType, kubeVersion, workerPoolName, &conf, verbose, debug, monitor, mockDeploy, mockBootstrap, numThreads, &requestJobs, &requestCompleted)
+	client := request.NewClient(&conf, request.Options{
+		MachineType:    machineType,
+		KubeVersion:    kubeVersion,
+		WorkerPoolName: workerPoolName,
+		Verbose:        verbose,
+		Debug:          debug,
+		Monitor:        monitor,
+		MockDeploy:     mockDeploy,
+		MockBootstrap:  mockBootstrap,
+	})
+	client.Start(numThreads, requestJobs, requestCompleted)
 
 	var requestMetrics = make(metrics.ArmadaMetrics, totalRequests, totalRequests)
 
-	// For each user
-	startTime := time.Now()
-	for userID := 1; userID <= totalUsers; userID++ {
-		// For each cluster
-		for requestNum := 1; requestNum <= totalRequests; requestNum++ {
-			// Generate a unique cluster name
-			var clusterName = ""
-
-			if action.HasCluster() {
-				if len(singleClusterName) > 0 {
-					clusterName = singleClusterName
+	// Generates a unique cluster name for each request
+	getClusterName := func(requestNum int) string {
+		if action.HasCluster() {
+			if len(singleClusterName) > 0 {
+				return singleClusterName
+			} else if existingClustersIndex > 0 && action == config.ActionDeleteCluster {
+				return existingClusters[requestNum-1]
+			}
+			return fmt.Sprintf("%s%d", clusterPrefix, startRequestNum+requestNum-1)
+		}
+		return ""
+	}
 
-				} else if existingClustersIndex > 0 && action == config.ActionDeleteCluster {
-					clusterName = existingClusters[requestNum-1]
-				} else {
-					clusterName = fmt.Sprintf("%s%d", clusterPrefix, startRequestNum+requestNum-1)
-				}
+	startTime := time.Now()
+	if loadProfile.Type != schedule.ProfileUnspecified {
+		// Issue requests at the profile's arrival times regardless of how quickly earlier requests complete.
+		// Requests that don't create or delete a cluster are spread across the existing clusters.
+		go schedule.Dispatch(arrivals, requestJobs, func(n int) request.Data {
+			requestNum := n + 1
+			clusterNum := requestNum
+			if totalClusters > 0 && action != config.ActionCreateCluster && action != config.ActionDeleteCluster {
+				clusterNum = n%totalClusters + 1
 			}
+			return request.Data{
+				Action:         action,
+				ClusterName:    getClusterName(clusterNum),
+				RequestNum:     requestNum,
+				WorkerID:       workerID,
+				WorkerPoolName: workerPoolName,
+				ZoneID:         zoneID,
+				PoolSize:       poolSize,
+				TotalWorkers:   totalWorkers}
+		})
+	}
 
+	// For each user
+	for userID := 1; userID <= totalUsers && loadProfile.Type == schedule.ProfileUnspecified; userID++ {
+		// For each cluster
+		for requestNum := 1; requestNum <= totalRequests; requestNum++ {
 			// Send the request to a pool of API request workers
 			requestJobs <- request.Data{
 				Action:         action,
-				ClusterName:    clusterName,
+				ClusterName:    getClusterName(requestNum),
 				RequestNum:     requestNum,
 				WorkerID:       workerID,
 				WorkerPoolName: workerPoolName,
@@ -357,3 +454,34 @@ func main() {
 		metrics.WriteArmadaMetrics(action, totalWorkers, &requestMetrics, testName, dbKey)
 	}
 }
+
+// runScenario runs a mixed workload scenario, reporting the latencies of each action in each phase
+func runScenario(client *request.Client, sc *scenario.Scenario, numThreads int, defaults request.Data, clusterPrefix string, totalClusters int,
+	sendMetrics bool, testName string, dbKey string) {
+	runner := scenario.Runner{
+		Client:     client,
+		NumThreads: numThreads,
+		Defaults:   defaults,
+	}
+
+	// Unless a single cluster was specified, spread requests across the clusters
+	if len(defaults.ClusterName) == 0 {
+		runner.ClusterName = func(n int) string {
+			return fmt.Sprintf("%s%d", clusterPrefix, n%totalClusters+1)
+		}
+	}
+
+	startTime := time.Now()
+	results := runner.Run(sc)
+	fmt.Printf("%s\tScenario: %s, Total Duration: %.3fs\n", time.Now().Format(time.StampMilli), sc.Name, time.Since(startTime).Seconds())
+	results.Print(sc, os.Stdout)
+
+	// If requested, send metrics to Bluemix Metrics service
+	if sendMetrics {
+		for phase, actions := range results {
+			for action, actionMetrics := range actions {
+				metrics.WriteArmadaPhaseMetrics(phase, action, defaults.TotalWorkers, &actionMetrics, testName, dbKey)
+			}
+		}
+	}
+}
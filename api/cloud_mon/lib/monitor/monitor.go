@@ -24,6 +24,7 @@ import (
 )
 
 var (
+	client        *request.Client
 	clientset     *kubernetes.Clientset
 	clusterConfig cluster.Cluster
 	orgID         string
@@ -58,8 +59,8 @@ func NumberOfTests() int {
 	return 5
 }
 
-// Run executes the specified tests
-func Run(inConf *config.Config, inClusterPrefix string, inTotalWorkers int,
+// Run executes the specified tests using the specified API client
+func Run(inClient *request.Client, inClusterPrefix string, inTotalWorkers int,
 	inActiveApp string, inBackgroundApp string, inTestsToRun []bool) {
 	fmt.Println("Configuring test environment")
 
@@ -69,7 +70,8 @@ func Run(inConf *config.Config, inClusterPrefix string, inTotalWorkers int,
 	totalWorkers = inTotalWorkers
 	backgroundApp = inBackgroundApp
 	activeApp = inActiveApp
-	conf = inConf
+	client = inClient
+	conf = inClient.Config()
 	testsToRun = inTestsToRun
 
 	configPath = config.GetConfigPath()
@@ -80,7 +82,7 @@ func Run(inConf *config.Config, inClusterPrefix string, inTotalWorkers int,
 	clusterName = fmt.Sprintf("%s%d", clusterPrefix, 1)
 
 	// Check if cluster exists, if not then create
-	cls, err := cluster.GetClusters()
+	cls, err := cluster.GetClusters(client)
 	if err != nil {
 		panic(err)
 	}
@@ -96,7 +98,7 @@ func Run(inConf *config.Config, inClusterPrefix string, inTotalWorkers int,
 	}
 
 	if clusterConfig.Name == "" {
-		clusterConfig, err = cluster.CreateCluster(clusterName, totalWorkers)
+		clusterConfig, err = cluster.CreateCluster(client, clusterName, totalWorkers)
 		if err != nil {
 			panic(err)
 		}
@@ -188,7 +190,7 @@ func apiServiceAccessible() string {
 	var result = "failed"
 
 	api := request.Data{Action: config.ActionGetDatacenters}
-	r := request.PerformRequest(api, true)
+	r := client.PerformRequest(api, true)
 
 	if r.StatusCode == http.StatusOK {
 		var dat []string
@@ -207,7 +209,7 @@ func apiServiceFunctional() string {
 	}
 	var result = "failed"
 
-	cl, err := cluster.CreateCluster(clusterPrefix+"2", 1)
+	cl, err := cluster.CreateCluster(client, clusterPrefix+"2", 1)
 	if err != nil {
 		fmt.Println(err)
 		result = "failed: err on create"
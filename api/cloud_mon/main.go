@@ -85,8 +85,9 @@ func main() {
 
 	requestJobs := make(chan request.Data, totalUsers*totalClusters)
 	requestCompleted := make(chan request.Data, totalUsers*totalClusters)
-	request.InitRequests(machineType, "", "", &conf, verbose, debug, false, nil, nil, numThreads, &requestJobs, &requestCompleted)
+	client := request.NewClient(&conf, request.Options{MachineType: machineType, Verbose: verbose, Debug: debug})
+	client.Start(numThreads, requestJobs, requestCompleted)
 
 	// start the test
-	monitor.Run(&conf, clusterPrefix, totalWorkers, activeApp, backgroundApp, testsToRun)
+	monitor.Run(client, clusterPrefix, totalWorkers, activeApp, backgroundApp, testsToRun)
 }
@@ -50,7 +50,8 @@ var numThreads int               // Number of concurrent API requests
 var frozenClusters int           // Number of clusters that didn't respond to multiple operations and so are locked out of future operations
 
 // encyptionKey is the key used to decrypt sensitive data from the configuration file(s).
-// It's value is baked in the executable at build time
+// It's value is baked in the executable at build time, and may be a comma separated list of keys (current key first)
+// so that binaries built during a key rotation can still read configs encrypted with the previous key
 var encryptionKey string
 
 // ClusterChurnState  defines information tracking churn clusters
@@ -61,11 +62,11 @@ type ClusterChurnState struct {
 	deleteFailures    int
 }
 
-func getExistingClusters() []map[string]interface{} {
+func getExistingClusters(client *request.Client) []map[string]interface{} {
 	requestData := request.Data{
 		Action: config.ActionGetClusters}
 
-	result := request.PerformRequest(requestData, true)
+	result := client.PerformRequest(requestData, true)
 
 	if result.ActionFailed {
 		fmt.Println("Request to find existing clusters failed")
@@ -80,13 +81,13 @@ func getExistingClusters() []map[string]interface{} {
 	return dat
 }
 
-func getClusterState(name string) (map[string]interface{}, request.Data, error) {
+func getClusterState(client *request.Client, name string) (map[string]interface{}, request.Data, error) {
 	var err error
 	requestData := request.Data{
 		Action:      config.ActionGetCluster,
 		ClusterName: name}
 
-	result := request.PerformRequest(requestData, true)
+	result := client.PerformRequest(requestData, true)
 
 	if result.ActionFailed && verbose {
 		fmt.Println("Request to get cluster state failed", result.ClusterName, result.StatusCode, result.Status)
@@ -101,13 +102,13 @@ func getClusterState(name string) (map[string]interface{}, request.Data, error)
 	return dat, result, err
 }
 
-func getClusterWorkers(name string) ([]interface{}, request.Data, error) {
+func getClusterWorkers(client *request.Client, name string) ([]interface{}, request.Data, error) {
 	var err error
 	requestData := request.Data{
 		Action:      config.ActionGetClusterWorkers,
 		ClusterName: name}
 
-	result := request.PerformRequest(requestData, true)
+	result := client.PerformRequest(requestData, true)
 
 	if result.ActionFailed && verbose {
 		fmt.Println("Request to get cluster workers failed", result.ClusterName, result.StatusCode, result.Status)
@@ -143,12 +144,12 @@ func getUpgradeVersion(currentVersion string, upgradeVersion string) string {
 	return upgradeVersion
 }
 
-func getKubeVersions() (string, string) {
+func getKubeVersions(client *request.Client) (string, string) {
 	var major float64
 	var minor float64
 
 	requestData := request.Data{Action: config.ActionGetVersions}
-	result := request.PerformRequest(requestData, true)
+	result := client.PerformRequest(requestData, true)
 
 	if result.ActionFailed {
 		fmt.Println("Request to find kube versions failed")
@@ -362,8 +363,8 @@ func main() {
 	var createKubeVersion string
 
 	if len(defaultKubeVersion) == 0 || followKubeDefaultVersion {
-		request.InitRequests(machineType, defaultKubeVersion, "", &conf, verbose, debug, monitor, nil, nil, 0, nil, nil)
-		defaultKube, nextKube := getKubeVersions()
+		versionClient := request.NewClient(&conf, request.Options{MachineType: machineType, KubeVersion: defaultKubeVersion, Verbose: verbose, Debug: debug, Monitor: monitor})
+		defaultKube, nextKube := getKubeVersions(versionClient)
 		if followKubeDefaultVersion {
 			upgradeKubeVersion = nextKube
 			createKubeVersion = ""
@@ -374,14 +375,15 @@ func main() {
 	} else {
 		createKubeVersion = defaultKubeVersion
 	}
-	request.InitRequests(machineType, createKubeVersion, "", &conf, verbose, debug, monitor, nil, nil, numThreads, &requestJobs, &requestCompleted)
+	client := request.NewClient(&conf, request.Options{MachineType: machineType, KubeVersion: createKubeVersion, Verbose: verbose, Debug: debug, Monitor: monitor})
+	client.Start(numThreads, requestJobs, requestCompleted)
 	fmt.Println("Create kube version:", defaultKubeVersion, "upgrade kube version:", upgradeKubeVersion)
 
 	startRequestNum = 1
 	var existingClustersIndex int
 
 	if action == config.ActionChurnClusters {
-		dat := getExistingClusters()
+		dat := getExistingClusters(client)
 		// See what is in carrier before deciding the action required.
 		var maxIndex int
 		existingClusters = make([]ClusterChurnState, totalClusters)
@@ -528,7 +530,7 @@ churnClusters:
 					// Check if cluster exists and in good state
 					var receivedStatusInternalServerError bool
 					for {
-						data, response, err = getClusterState(cluster.name)
+						data, response, err = getClusterState(client, cluster.name)
 						if (err == nil && response.StatusCode != http.StatusInternalServerError) || response.StatusCode == http.StatusNotFound {
 							break
 						}
@@ -553,12 +555,12 @@ churnClusters:
 						if data["masterStatus"].(string) == "Ready" || data["masterStatus"].(string) == "VPN server configuration update in progress." || data["masterStatus"].(string) == "VPN server configuration update requested." {
 							existingClusters[requestData.RequestNum].masterKubeVersion = data["masterKubeVersion"].(string)
 							if followKubeDefaultVersion && !strings.HasPrefix(existingClusters[requestData.RequestNum].masterKubeVersion, strings.Trim(defaultKubeVersion, "_openshift")) {
-								defaultKube, nextKube := getKubeVersions()
+								defaultKube, nextKube := getKubeVersions(client)
 								// There may be hundreds of clusters with the old default kube version, so suppress future updates
 								if defaultKube != defaultKubeVersion {
 									defaultKubeVersion = defaultKube
 									upgradeKubeVersion = nextKube
-									request.SetKubeVersion(defaultKubeVersion)
+									client.SetKubeVersion(defaultKubeVersion)
 									fmt.Println("Create kube version:", defaultKubeVersion, "upgrade kube version:", upgradeKubeVersion, " - update due to kube default version change")
 								}
 							}
@@ -572,7 +574,7 @@ churnClusters:
 							}
 							if totalWorkers > 0 {
 								// Also need to check Worker BOM
-								workerData, _, err := getClusterWorkers(cluster.name)
+								workerData, _, err := getClusterWorkers(client, cluster.name)
 								if err == nil {
 									worker := workerData[0]
 									workerDetails := worker.(map[string]interface{})
@@ -605,7 +607,7 @@ churnClusters:
 
 			case ChurnUpdate:
 				for {
-					data, response, err = getClusterState(cluster.name)
+					data, response, err = getClusterState(client, cluster.name)
 					if err == nil || response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusConflict {
 						break
 					}
@@ -649,7 +651,7 @@ churnClusters:
 				}
 
 			case ChurnDelete:
-				data, response, err = getClusterState(cluster.name)
+				data, response, err = getClusterState(client, cluster.name)
 				if response.StatusCode == http.StatusNotFound {
 					existingClusters[requestData.RequestNum] = ClusterChurnState{churnState: ChurnNoCluster}
 
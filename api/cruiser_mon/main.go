@@ -89,7 +89,8 @@ var (
 )
 
 // encyptionKey is the key used to decrypt sensitive data from the configuration file(s).
-// It's value is baked in the executable at build time
+// It's value is baked in the executable at build time, and may be a comma separated list of keys (current key first)
+// so that binaries built during a key rotation can still read configs encrypted with the previous key
 var encryptionKey string
 
 // main ...
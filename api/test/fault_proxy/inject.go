@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type ruleKey struct{}
+
+// injection records a fault which was injected into a request
+type injection struct {
+	Time        string `json:"time"`
+	RequestID   string `json:"requestID,omitempty"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Rule        string `json:"rule"`
+	Fault       string `json:"fault"`
+	DelayMS     int64  `json:"delayMS"`
+	Status      int    `json:"status,omitempty"`
+	FailureType string `json:"failureType,omitempty"`
+	Burst       bool   `json:"burst,omitempty"` // Injected because the rule is in a burst, rather than by probability
+}
+
+type faultProxy struct {
+	rules []rule
+	proxy *httputil.ReverseProxy
+
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+
+	logMu sync.Mutex
+	log   *json.Encoder
+}
+
+func newFaultProxy(target *url.URL, rules []rule, seed int64, logOut io.Writer) *faultProxy {
+	fp := &faultProxy{
+		rules: rules,
+		proxy: httputil.NewSingleHostReverseProxy(target),
+		rnd:   rand.New(rand.NewSource(seed)),
+		log:   json.NewEncoder(logOut),
+	}
+
+	// The Armada API is served with virtual hosting, so the Host header must be the target's
+	director := fp.proxy.Director
+	fp.proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = target.Host
+	}
+	fp.proxy.ModifyResponse = fp.truncate
+	// Flush every write, so that the start of a truncated body reaches the client before the connection is closed
+	fp.proxy.FlushInterval = -1
+	return fp
+}
+
+// choose returns the first matching rule which fires, or is in a burst, or nil if the request should be proxied
+// unchanged. burst is true if the rule was chosen because it is in a burst.
+func (fp *faultProxy) choose(r *http.Request) (fr *rule, burst bool) {
+	fp.rndMu.Lock()
+	defer fp.rndMu.Unlock()
+
+	now := time.Now()
+	for i := range fp.rules {
+		fr := &fp.rules[i]
+		if !fr.matches(r.Method, r.URL.Path) {
+			continue
+		}
+		if fr.inBurst(now) {
+			return fr, true
+		}
+		if fp.rnd.Float64() < fr.Probability {
+			if fr.bursts() {
+				fr.startBurst(now)
+			}
+			return fr, false
+		}
+	}
+	return nil, false
+}
+
+func (fp *faultProxy) delay(fr *rule) time.Duration {
+	if fr.DelayMax.Duration <= fr.Delay.Duration {
+		return fr.Delay.Duration
+	}
+
+	fp.rndMu.Lock()
+	defer fp.rndMu.Unlock()
+	return fr.Delay.Duration + time.Duration(fp.rnd.Int63n(int64(fr.DelayMax.Duration-fr.Delay.Duration)))
+}
+
+func (fp *faultProxy) record(r *http.Request, fr *rule, burst bool, delay time.Duration, status int) {
+	inj := injection{
+		Time:        time.Now().UTC().Format(time.RFC3339Nano),
+		RequestID:   r.Header.Get("X-Request-ID"),
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Rule:        fr.Name,
+		Fault:       fr.Fault,
+		DelayMS:     delay.Milliseconds(),
+		Status:      status,
+		FailureType: fr.failureType(),
+		Burst:       burst,
+	}
+
+	fp.logMu.Lock()
+	defer fp.logMu.Unlock()
+	if err := fp.log.Encode(inj); err != nil {
+		log.Println("Failed to log injection:", err)
+	}
+}
+
+func (fp *faultProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fr, burst := fp.choose(r)
+	if fr == nil {
+		if verbose {
+			fmt.Println("proxy", r.Method, r.URL.String())
+		}
+		fp.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	d := fp.delay(fr)
+	if d > 0 {
+		time.Sleep(d)
+	}
+
+	status := fr.Status
+	switch fr.Fault {
+	case faultTokenExpired:
+		status = http.StatusUnauthorized
+	case faultDelay, faultTruncate, faultReset:
+		status = 0
+	}
+	fp.record(r, fr, burst, d, status)
+
+	switch fr.Fault {
+	case faultDelay:
+		fp.proxy.ServeHTTP(w, r)
+	case faultTruncate:
+		fp.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ruleKey{}, fr)))
+	case faultStatus:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fr.Status)
+		w.Write([]byte(fr.Body))
+	case faultCloudflare:
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Header().Set("Server", "cloudflare")
+		w.WriteHeader(fr.Status)
+		fmt.Fprintf(w, cloudflarePage, fr.Status)
+	case faultBackend:
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.WriteHeader(fr.Status)
+		w.Write([]byte(backendPage))
+	case faultTokenExpired:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{
+			"incidentID":  r.Header.Get("X-Request-ID"),
+			"code":        "E0000",
+			"description": "The IAM token that is included in the request has expired.",
+			"type":        "Authentication",
+		})
+	case faultReset:
+		reset(w)
+	}
+}
+
+// truncate replaces the response body of requests chosen for the truncate fault, so that the connection is
+// closed after the configured fraction of the body has been sent. The Content-Length header reports the full body.
+func (fp *faultProxy) truncate(resp *http.Response) error {
+	fr, ok := resp.Request.Context().Value(ruleKey{}).(*rule)
+	if !ok {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	resp.Body = ioutil.NopCloser(io.MultiReader(
+		bytes.NewReader(body[:int(float64(len(body))*fr.Fraction)]),
+		errReader{},
+	))
+	return nil
+}
+
+// errReader fails the body copy, which makes the reverse proxy abort the connection
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("truncated by fault injection")
+}
+
+// reset closes the client connection with a TCP RST rather than a FIN
+func reset(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+const cloudflarePage = `<!DOCTYPE html>
+<html>
+<head><title>%[1]d: Web server is returning an unknown error</title></head>
+<body>
+<h1>Error %[1]d</h1>
+<p>Web server is returning an unknown error</p>
+<p>Performance &amp; security by <a href="https://www.cloudflare.com">cloudflare</a></p>
+</body>
+</html>
+`
+
+const backendPage = `<!DOCTYPE html>
+<html>
+<head><title>Internal Server Error</title></head>
+<body>
+<h1>Internal Server Error</h1>
+<p>A backend issue occurred. Try again later.</p>
+</body>
+</html>
+`
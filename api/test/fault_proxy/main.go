@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+var verbose bool
+
+func main() {
+	var port int
+	var target, rulesFile, logFile string
+	var seed int64
+
+	flag.IntVar(&port, "port", 8080, "port to listen on")
+	flag.StringVar(&target, "target", "", "URL of the Armada API to proxy to, e.g. https://containers.test.cloud.ibm.com")
+	flag.StringVar(&rulesFile, "rules", "", "rule file defining the faults to inject")
+	flag.StringVar(&logFile, "log", "", "file to log injected faults to as JSON lines (default stdout)")
+	flag.Int64Var(&seed, "seed", time.Now().UnixNano(), "random seed, for reproducible runs")
+	flag.BoolVar(&verbose, "verbose", false, "verbose logging output")
+	flag.Parse()
+
+	if len(target) == 0 || len(rulesFile) == 0 {
+		fmt.Println("-target and -rules must be specified")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		log.Fatalf("Invalid target %s: %s", target, err.Error())
+	}
+
+	rules, err := loadRules(rulesFile)
+	if err != nil {
+		log.Fatalf("Failed to load rules from %s: %s", rulesFile, err.Error())
+	}
+
+	var logOut io.Writer = os.Stdout
+	if len(logFile) > 0 {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %s", logFile, err.Error())
+		}
+		defer f.Close()
+		logOut = f
+	}
+
+	addr := fmt.Sprintf(":%d", port)
+	fmt.Printf("Fault injection proxy listening on %s, forwarding to %s with %d rules (seed %d)\n", addr, targetURL, len(rules), seed)
+	log.Fatal(http.ListenAndServe(addr, newFaultProxy(targetURL, rules, seed, logOut)))
+}
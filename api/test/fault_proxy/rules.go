@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Supported faults
+const (
+	faultDelay        = "delay"        // Delay the request, then proxy it
+	faultStatus       = "status"       // Return the configured status code and body without proxying
+	faultCloudflare   = "cloudflare"   // Return a Cloudflare HTML error page
+	faultBackend      = "backend"      // Return an HTML "A backend issue occurred" error page
+	faultTokenExpired = "tokenExpired" // Return a 401 as if the IAM token has expired
+	faultTruncate     = "truncate"     // Proxy the request, but close the connection part way through the response body
+	faultReset        = "reset"        // Reset the connection without responding
+)
+
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// rule defines a fault which is injected into a proportion of matching requests
+type rule struct {
+	Name        string   `toml:"name"`
+	Method      string   `toml:"method"`      // Empty matches all methods
+	Path        string   `toml:"path"`        // Regular expression matched against the request path
+	Probability float64  `toml:"probability"` // Fraction of matching requests the fault is injected into
+	Fault       string   `toml:"fault"`
+	Delay       duration `toml:"delay"`     // Added before any fault, or the only effect of the delay fault
+	DelayMax    duration `toml:"delay_max"` // If set, the delay is uniformly distributed between delay and delay_max
+	Status      int      `toml:"status"`    // status, cloudflare and backend faults
+	Body        string   `toml:"body"`      // status fault
+	Fraction    float64  `toml:"fraction"`  // truncate fault, the fraction of the response body to send
+
+	// Once the rule fires, the fault is injected into every following matching request until burst_length
+	// requests or burst_duration have passed, whichever is first. Either may be set alone.
+	BurstLength   int      `toml:"burst_length"`
+	BurstDuration duration `toml:"burst_duration"`
+
+	path *regexp.Regexp
+
+	// Current burst, guarded by the proxy's rndMu
+	burstLeft  int
+	burstUntil time.Time
+}
+
+type ruleFile struct {
+	Rules []rule `toml:"rule"`
+}
+
+// loadRules reads and validates a rule file
+func loadRules(path string) ([]rule, error) {
+	var rf ruleFile
+	if _, err := toml.DecodeFile(path, &rf); err != nil {
+		return nil, err
+	}
+
+	for i := range rf.Rules {
+		r := &rf.Rules[i]
+		if len(r.Name) == 0 {
+			r.Name = fmt.Sprintf("rule%d", i+1)
+		}
+
+		var err error
+		if r.path, err = regexp.Compile(r.Path); err != nil {
+			return nil, fmt.Errorf("%s: invalid path: %s", r.Name, err.Error())
+		}
+		if r.Probability < 0 || r.Probability > 1 {
+			return nil, fmt.Errorf("%s: probability must be between 0 and 1", r.Name)
+		}
+		if r.DelayMax.Duration > 0 && r.DelayMax.Duration < r.Delay.Duration {
+			return nil, fmt.Errorf("%s: delay_max is less than delay", r.Name)
+		}
+		if r.BurstLength < 0 || r.BurstDuration.Duration < 0 {
+			return nil, fmt.Errorf("%s: burst_length and burst_duration must not be negative", r.Name)
+		}
+
+		switch r.Fault {
+		case faultDelay, faultTokenExpired, faultReset:
+		case faultStatus:
+			if r.Status == 0 {
+				return nil, fmt.Errorf("%s: status is required", r.Name)
+			}
+			// The body is returned as JSON, which clients parse
+			if len(r.Body) == 0 {
+				r.Body = "{}"
+			} else if !json.Valid([]byte(r.Body)) {
+				return nil, fmt.Errorf("%s: body must be JSON", r.Name)
+			}
+		case faultCloudflare:
+			if r.Status == 0 {
+				r.Status = 520
+			}
+		case faultBackend:
+			if r.Status == 0 {
+				r.Status = 500
+			}
+		case faultTruncate:
+			if r.Fraction <= 0 || r.Fraction >= 1 {
+				r.Fraction = 0.5
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown fault %q", r.Name, r.Fault)
+		}
+	}
+	return rf.Rules, nil
+}
+
+// matches returns true if the rule applies to the request
+func (r *rule) matches(method, path string) bool {
+	return (len(r.Method) == 0 || strings.EqualFold(r.Method, method)) && r.path.MatchString(path)
+}
+
+// bursts returns true if the rule injects bursts of faults, rather than drawing each request independently
+func (r *rule) bursts() bool {
+	return r.BurstLength > 0 || r.BurstDuration.Duration > 0
+}
+
+// startBurst starts a burst after the rule fires
+func (r *rule) startBurst(now time.Time) {
+	r.burstLeft = r.BurstLength
+	r.burstUntil = now.Add(r.BurstDuration.Duration)
+}
+
+// inBurst returns true if the rule is in a burst, counting the request against the burst length
+func (r *rule) inBurst(now time.Time) bool {
+	if !r.bursts() || r.burstUntil.IsZero() {
+		return false
+	}
+	if (r.BurstLength > 0 && r.burstLeft <= 0) || (r.BurstDuration.Duration > 0 && !now.Before(r.burstUntil)) {
+		r.burstUntil = time.Time{}
+		return false
+	}
+	r.burstLeft--
+	return true
+}
+
+// failureType is how api/armada-perf-client classifies the fault, for correlating the proxy log with the client results.
+// Faults which don't produce a classified failure return an empty string.
+func (r *rule) failureType() string {
+	switch r.Fault {
+	case faultCloudflare:
+		return "FailureCloudflare"
+	case faultBackend:
+		return "FailureBackendIssue"
+	}
+	return ""
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Master operations
+const (
+	masterDeploy = iota
+	masterUpdate
+	masterDelete
+)
+
+// Worker operations
+const (
+	workerProvision = iota
+	workerReload
+	workerReboot
+	workerDelete
+)
+
+// Worker states
+const (
+	stateProvisionPending = "provision_pending"
+	stateProvisioning     = "provisioning"
+	stateProvisioned      = "provisioned"
+	stateDeploying        = "deploying"
+	stateNormal           = "normal"
+	stateReloading        = "reloading"
+	stateRebooting        = "rebooting"
+	stateDeleting         = "deleting"
+	stateDeleted          = "deleted"
+)
+
+const waitingForMaster = "Waiting for master to be deployed"
+
+// simulator holds the state of all simulated clusters and Satellite locations.
+// States aren't advanced by timers, instead they are derived from the time elapsed since each operation started.
+type simulator struct {
+	mu        sync.Mutex
+	conf      simConfig
+	clusters  map[string]*cluster  // Key is cluster id
+	locations map[string]*location // Key is location id
+	nextID    int
+
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+}
+
+type cluster struct {
+	ID            string
+	Name          string
+	Provider      string
+	DataCenter    string
+	MachineType   string
+	KubeVersion   string
+	TargetVersion string
+	Created       time.Time
+	Pools         []*workerPool
+	Workers       []*worker
+
+	op      int
+	opStart time.Time
+	opTime  time.Duration
+}
+
+type workerPool struct {
+	ID          string
+	Name        string
+	MachineType string
+	Isolation   string
+	SizePerZone int
+	Zones       []poolZone
+	Labels      map[string]string
+}
+
+type poolZone struct {
+	ID          string `json:"id"`
+	PrivateVlan string `json:"privateVlan,omitempty"`
+	PublicVlan  string `json:"publicVlan,omitempty"`
+	SubnetID    string `json:"subnetID,omitempty"`
+}
+
+type worker struct {
+	ID          string
+	Pool        *workerPool // nil for standalone workers
+	Zone        poolZone
+	MachineType string
+	KubeVersion string
+	Isolation   string
+	PrivateIP   string
+	PublicIP    string
+
+	op        int
+	opStart   time.Time
+	opTime    time.Duration
+	provision time.Duration
+}
+
+type location struct {
+	ID       string
+	Name     string
+	Location string
+	Created  time.Time
+	deploy   time.Duration
+}
+
+func newSimulator(conf simConfig, seed int64) *simulator {
+	return &simulator{
+		conf:      conf,
+		clusters:  make(map[string]*cluster),
+		locations: make(map[string]*location),
+		rnd:       rand.New(rand.NewSource(seed)), // #nosec G404
+	}
+}
+
+// id generates a unique resource id with the specified prefix
+func (s *simulator) id(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s%08x", prefix, s.nextID)
+}
+
+// jitter randomly varies an operation's duration by up to the configured fraction
+func (s *simulator) jitter(d duration) time.Duration {
+	s.rndMu.Lock()
+	defer s.rndMu.Unlock()
+	return time.Duration(float64(d.Duration) * (1 + s.conf.Timers.Jitter*(2*s.rnd.Float64()-1)))
+}
+
+// reap removes clusters and workers whose deletion has completed, and completes master updates
+func (s *simulator) reap(now time.Time) {
+	for id, c := range s.clusters {
+		if c.op == masterDelete && now.Sub(c.opStart) >= c.opTime {
+			delete(s.clusters, id)
+			continue
+		}
+		if c.op == masterUpdate && now.Sub(c.opStart) >= c.opTime && c.KubeVersion != c.TargetVersion {
+			c.KubeVersion = c.TargetVersion
+		}
+
+		workers := c.Workers[:0]
+		for _, w := range c.Workers {
+			if w.op != workerDelete || now.Sub(w.opStart) < w.opTime+s.conf.Timers.DeletedRetention.Duration {
+				workers = append(workers, w)
+			}
+		}
+		c.Workers = workers
+	}
+}
+
+// findCluster looks up a cluster by name or id
+func (s *simulator) findCluster(nameOrID string) *cluster {
+	if c, ok := s.clusters[nameOrID]; ok {
+		return c
+	}
+	for _, c := range s.clusters {
+		if c.Name == nameOrID {
+			return c
+		}
+	}
+	return nil
+}
+
+// sortedClusters returns the clusters in creation order
+func (s *simulator) sortedClusters() []*cluster {
+	clusters := make([]*cluster, 0, len(s.clusters))
+	for _, c := range s.clusters {
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].ID < clusters[j].ID })
+	return clusters
+}
+
+// createCluster creates a cluster with a default worker pool, starting deployment of its master and workers
+func (s *simulator) createCluster(name, provider, kubeVersion, machineType string, workers int, zones []poolZone, now time.Time) (*cluster, error) {
+	if s.findCluster(name) != nil {
+		return nil, fmt.Errorf("The cluster name '%s' is already in use", name)
+	}
+	if len(zones) == 0 {
+		zones = []poolZone{{ID: s.conf.Zones[0]}}
+	}
+	if len(kubeVersion) == 0 {
+		kubeVersion = s.conf.KubeVersions[len(s.conf.KubeVersions)-1]
+	}
+
+	c := &cluster{
+		ID:            s.id("c"),
+		Name:          name,
+		Provider:      provider,
+		DataCenter:    zones[0].ID,
+		MachineType:   machineType,
+		KubeVersion:   kubeVersion,
+		TargetVersion: kubeVersion,
+		Created:       now,
+		op:            masterDeploy,
+		opStart:       now,
+		opTime:        s.jitter(s.conf.Timers.MasterDeploy),
+	}
+	s.clusters[c.ID] = c
+	s.createPool(c, "default", machineType, "", workers, zones, nil, now)
+	return c, nil
+}
+
+// masterStatus returns the master state and status of a cluster
+func (s *simulator) masterStatus(c *cluster, now time.Time) (state string, status string) {
+	elapsed := now.Sub(c.opStart)
+	requested := elapsed < c.opTime/10
+	inProgress := elapsed < c.opTime
+
+	switch c.op {
+	case masterDeploy:
+		switch {
+		case requested:
+			return "deploying", "Deploy requested."
+		case inProgress:
+			return "deploying", "Deploy in progress."
+		}
+	case masterUpdate:
+		switch {
+		case requested:
+			return "updating", "Version update requested."
+		case inProgress:
+			return "updating", "Version update in progress."
+		}
+	case masterDelete:
+		if requested {
+			return "deleting", "Delete requested."
+		}
+		return "deleting", "Delete in progress."
+	}
+	return "deployed", "Ready"
+}
+
+// masterReady returns when the cluster master was first ready, if it has been
+func (c *cluster) masterReady(now time.Time) (time.Time, bool) {
+	switch c.op {
+	case masterDeploy:
+		ready := c.opStart.Add(c.opTime)
+		return ready, !now.Before(ready)
+	case masterUpdate:
+		return c.Created, true
+	}
+	return time.Time{}, false
+}
+
+// clusterState returns the overall state of a cluster
+func (s *simulator) clusterState(c *cluster, now time.Time) string {
+	masterState, _ := s.masterStatus(c, now)
+	switch masterState {
+	case "deploying", "deleting":
+		return masterState
+	}
+	for _, w := range c.Workers {
+		if st, _ := s.workerState(c, w, now); st == stateNormal {
+			return stateNormal
+		}
+	}
+	return "pending"
+}
+
+// workerCount returns the number of workers which haven't been deleted
+func (c *cluster) workerCount() int {
+	var n int
+	for _, w := range c.Workers {
+		if w.op != workerDelete {
+			n++
+		}
+	}
+	return n
+}
+
+// addWorker starts provisioning a new worker
+func (s *simulator) addWorker(c *cluster, p *workerPool, zone poolZone, machineType, isolation string, now time.Time) *worker {
+	n := s.nextID + 1
+	w := &worker{
+		ID:          fmt.Sprintf("kube-%s-%s-w%d", c.ID, zone.ID, n),
+		Pool:        p,
+		Zone:        zone,
+		MachineType: machineType,
+		KubeVersion: c.KubeVersion,
+		Isolation:   isolation,
+		PrivateIP:   fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff),
+		PublicIP:    fmt.Sprintf("169.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff),
+		op:          workerProvision,
+		opStart:     now,
+		provision:   s.jitter(s.conf.Timers.WorkerProvision),
+		opTime:      s.jitter(s.conf.Timers.WorkerDeploy),
+	}
+	s.nextID++
+	c.Workers = append(c.Workers, w)
+	return w
+}
+
+// startWorkerOp starts a reload, reboot or delete of a worker
+func (s *simulator) startWorkerOp(w *worker, op int, now time.Time) {
+	if w.op == workerDelete {
+		return
+	}
+	timers := map[int]duration{
+		workerReload: s.conf.Timers.WorkerReload,
+		workerReboot: s.conf.Timers.WorkerReboot,
+		workerDelete: s.conf.Timers.WorkerDelete,
+	}
+	w.op = op
+	w.opStart = now
+	w.opTime = s.jitter(timers[op])
+}
+
+// workerState returns the state and status of a worker
+func (s *simulator) workerState(c *cluster, w *worker, now time.Time) (state string, status string) {
+	elapsed := now.Sub(w.opStart)
+
+	switch w.op {
+	case workerProvision:
+		switch {
+		case elapsed < w.provision/10:
+			return stateProvisionPending, "Preparing to provision"
+		case elapsed < w.provision:
+			return stateProvisioning, "Provisioning"
+		}
+
+		// Workers can't be deployed until the master is ready
+		provisioned := w.opStart.Add(w.provision)
+		ready, ok := c.masterReady(now)
+		if !ok {
+			return stateProvisioned, waitingForMaster
+		}
+		if ready.Before(provisioned) {
+			ready = provisioned
+		}
+		if now.Sub(ready) < w.opTime {
+			return stateDeploying, "Deploying"
+		}
+	case workerReload:
+		if elapsed < w.opTime {
+			return stateReloading, "Reloading"
+		}
+	case workerReboot:
+		if elapsed < w.opTime {
+			return stateRebooting, "Rebooting"
+		}
+	case workerDelete:
+		if elapsed < w.opTime {
+			return stateDeleting, "Deleting"
+		}
+		return stateDeleted, "Deleted"
+	}
+	return stateNormal, "Ready"
+}
+
+// findWorker looks up a worker in a cluster
+func (c *cluster) findWorker(id string) *worker {
+	for _, w := range c.Workers {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// findWorker looks up a worker in any cluster
+func (s *simulator) findWorker(id string) (*cluster, *worker) {
+	for _, c := range s.clusters {
+		if w := c.findWorker(id); w != nil {
+			return c, w
+		}
+	}
+	return nil, nil
+}
+
+// findPool looks up a worker pool by name or id
+func (c *cluster) findPool(nameOrID string) *workerPool {
+	for _, p := range c.Pools {
+		if p.ID == nameOrID || p.Name == nameOrID {
+			return p
+		}
+	}
+	return nil
+}
+
+// createPool creates a worker pool, and starts provisioning its workers
+func (s *simulator) createPool(c *cluster, name, machineType, isolation string, sizePerZone int, zones []poolZone, labels map[string]string, now time.Time) (*workerPool, error) {
+	if c.findPool(name) != nil {
+		return nil, fmt.Errorf("The worker pool name '%s' is already in use", name)
+	}
+	p := &workerPool{
+		ID:          fmt.Sprintf("%s-%s", c.ID, s.id("")),
+		Name:        name,
+		MachineType: machineType,
+		Isolation:   isolation,
+		SizePerZone: sizePerZone,
+		Zones:       zones,
+		Labels:      labels,
+	}
+	c.Pools = append(c.Pools, p)
+	s.reconcilePool(c, p, now)
+	return p, nil
+}
+
+// removePool deletes a worker pool and its workers
+func (s *simulator) removePool(c *cluster, p *workerPool, now time.Time) {
+	p.SizePerZone = 0
+	s.reconcilePool(c, p, now)
+	for i := range c.Pools {
+		if c.Pools[i] == p {
+			c.Pools = append(c.Pools[:i], c.Pools[i+1:]...)
+			break
+		}
+	}
+}
+
+// addZone adds a zone to a worker pool, and starts provisioning its workers
+func (s *simulator) addZone(c *cluster, p *workerPool, zone poolZone, now time.Time) error {
+	for _, z := range p.Zones {
+		if z.ID == zone.ID {
+			return fmt.Errorf("The zone '%s' is already in worker pool '%s'", zone.ID, p.Name)
+		}
+	}
+	p.Zones = append(p.Zones, zone)
+	s.reconcilePool(c, p, now)
+	return nil
+}
+
+// removeZone removes a zone from a worker pool, deleting its workers
+func (s *simulator) removeZone(c *cluster, p *workerPool, zoneID string, now time.Time) error {
+	for i, z := range p.Zones {
+		if z.ID == zoneID {
+			p.Zones = append(p.Zones[:i], p.Zones[i+1:]...)
+			s.reconcilePool(c, p, now)
+			return nil
+		}
+	}
+	return fmt.Errorf("The zone '%s' isn't in worker pool '%s'", zoneID, p.Name)
+}
+
+// reconcilePool adds or deletes workers so that each zone of the pool has the required number of workers.
+// The newest workers are deleted first.
+func (s *simulator) reconcilePool(c *cluster, p *workerPool, now time.Time) {
+	inPool := make(map[string]bool)
+	for _, z := range p.Zones {
+		inPool[z.ID] = true
+	}
+
+	active := make(map[string][]*worker)
+	for _, w := range c.Workers {
+		if w.Pool != p || w.op == workerDelete {
+			continue
+		}
+		if !inPool[w.Zone.ID] {
+			s.startWorkerOp(w, workerDelete, now)
+			continue
+		}
+		active[w.Zone.ID] = append(active[w.Zone.ID], w)
+	}
+
+	for _, z := range p.Zones {
+		workers := active[z.ID]
+		for i := len(workers); i < p.SizePerZone; i++ {
+			s.addWorker(c, p, z, p.MachineType, p.Isolation, now)
+		}
+		for i := len(workers) - 1; i >= p.SizePerZone; i-- {
+			s.startWorkerOp(workers[i], workerDelete, now)
+		}
+	}
+}
+
+// locationState returns the state and status of a Satellite location
+func (l *location) state(now time.Time) (string, string) {
+	if now.Sub(l.Created) < l.deploy {
+		return "deploying", "Deploying Satellite location control plane"
+	}
+	return "action required", "R0012: The location control plane does not have hosts in all 3 zones. Add available hosts to your location for the control plane."
+}
+
+// count parses a worker count, which the Armada API accepts as either a number or a string
+type count int
+
+func (n *count) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), "\"")
+	if len(s) == 0 {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.Atoi(s)
+	*n = count(v)
+	return err
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, r, http.StatusBadRequest, "E0002", "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+
+	if verbose {
+		fmt.Println(r.Method, status, "\n\thttp://"+r.Host+r.URL.String()+" : "+string(body))
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05-0700")
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// v1Cluster is the cluster representation returned by the v1 API
+type v1Cluster struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	Region            string `json:"region"`
+	DataCenter        string `json:"dataCenter"`
+	Location          string `json:"location"`
+	ServerURL         string `json:"serverURL"`
+	State             string `json:"state"`
+	CreatedDate       string `json:"createdDate"`
+	ModifiedDate      string `json:"modifiedDate"`
+	WorkerCount       int    `json:"workerCount"`
+	IsPaid            bool   `json:"isPaid"`
+	MasterKubeVersion string `json:"masterKubeVersion"`
+	TargetVersion     string `json:"targetVersion"`
+	MasterStatus      string `json:"masterStatus"`
+	MasterState       string `json:"masterState"`
+	Provider          string `json:"provider"`
+	IngressHostname   string `json:"ingressHostname"`
+	IngressSecretName string `json:"ingressSecretName"`
+}
+
+// v1Worker is the worker representation returned by the v1 API
+type v1Worker struct {
+	ID           string `json:"id"`
+	State        string `json:"state"`
+	Status       string `json:"status"`
+	PrivateVlan  string `json:"privateVlan"`
+	PublicVlan   string `json:"publicVlan"`
+	PrivateIP    string `json:"privateIP"`
+	PublicIP     string `json:"publicIP"`
+	MachineType  string `json:"machineType"`
+	ErrorMessage string `json:"errorMessage"`
+	Billing      string `json:"billing"`
+	Isolation    string `json:"isolation"`
+	KubeVersion  string `json:"kubeVersion"`
+	PoolID       string `json:"poolid"`
+	PoolName     string `json:"poolName"`
+	Location     string `json:"location"`
+}
+
+// v1WorkerPool is the worker pool representation returned by the v1 API
+type v1WorkerPool struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	SizePerZone int               `json:"sizePerZone"`
+	MachineType string            `json:"machineType"`
+	Isolation   string            `json:"isolation"`
+	Labels      map[string]string `json:"labels"`
+	State       string            `json:"state"`
+	Zones       []v1PoolZone      `json:"zones"`
+}
+
+type v1PoolZone struct {
+	poolZone
+	WorkerCount int `json:"workerCount"`
+}
+
+func (s *simulator) v1Cluster(c *cluster, now time.Time) v1Cluster {
+	masterState, masterStatus := s.masterStatus(c, now)
+	return v1Cluster{
+		ID:                c.ID,
+		Name:              c.Name,
+		Region:            "us-south",
+		DataCenter:        c.DataCenter,
+		Location:          c.DataCenter,
+		ServerURL:         "https://" + c.ID + ".mock.containers.cloud.ibm.com:30000",
+		State:             s.clusterState(c, now),
+		CreatedDate:       formatTime(c.Created),
+		ModifiedDate:      formatTime(c.opStart),
+		WorkerCount:       c.workerCount(),
+		IsPaid:            c.MachineType != "free",
+		MasterKubeVersion: c.KubeVersion,
+		TargetVersion:     c.TargetVersion,
+		MasterStatus:      masterStatus,
+		MasterState:       masterState,
+		Provider:          c.Provider,
+		IngressHostname:   c.Name + ".us-south.mock.containers.appdomain.cloud",
+		IngressSecretName: c.Name,
+	}
+}
+
+func (s *simulator) v1Worker(c *cluster, w *worker, now time.Time) v1Worker {
+	state, status := s.workerState(c, w, now)
+	vw := v1Worker{
+		ID:          w.ID,
+		State:       state,
+		Status:      status,
+		PrivateVlan: w.Zone.PrivateVlan,
+		PublicVlan:  w.Zone.PublicVlan,
+		PrivateIP:   w.PrivateIP,
+		PublicIP:    w.PublicIP,
+		MachineType: w.MachineType,
+		Billing:     "hourly",
+		Isolation:   w.Isolation,
+		KubeVersion: w.KubeVersion,
+		Location:    w.Zone.ID,
+	}
+	if w.Pool != nil {
+		vw.PoolID = w.Pool.ID
+		vw.PoolName = w.Pool.Name
+	}
+	return vw
+}
+
+func (s *simulator) v1WorkerPool(c *cluster, p *workerPool) v1WorkerPool {
+	vp := v1WorkerPool{
+		ID:          p.ID,
+		Name:        p.Name,
+		SizePerZone: p.SizePerZone,
+		MachineType: p.MachineType,
+		Isolation:   p.Isolation,
+		Labels:      p.Labels,
+		State:       "active",
+	}
+	for _, z := range p.Zones {
+		vz := v1PoolZone{poolZone: z}
+		for _, w := range c.Workers {
+			if w.Pool == p && w.Zone.ID == z.ID && w.op != workerDelete {
+				vz.WorkerCount++
+			}
+		}
+		vp.Zones = append(vp.Zones, vz)
+	}
+	return vp
+}
+
+// handleV1Clusters serves /v1/clusters and everything below it:
+//
+//	/v1/clusters
+//	/v1/clusters/{cluster}
+//	/v1/clusters/{cluster}/config[/admin]
+//	/v1/clusters/{cluster}/workers[/{worker}]
+//	/v1/clusters/{cluster}/workerpools[/{pool}[/zones[/{zone}]]]
+func (s *simulator) handleV1Clusters(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.reap(now)
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/clusters"), "/"), "/")
+	if len(parts[0]) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			clusters := make([]v1Cluster, 0, len(s.clusters))
+			for _, c := range s.sortedClusters() {
+				clusters = append(clusters, s.v1Cluster(c, now))
+			}
+			writeJSON(w, r, http.StatusOK, clusters)
+		case http.MethodPost:
+			s.v1CreateCluster(w, r, now)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+		}
+		return
+	}
+
+	c := s.findCluster(parts[0])
+	if c == nil {
+		writeError(w, r, http.StatusNotFound, "A0006", "The specified cluster could not be found.")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.v1ClusterOp(w, r, c, now)
+	case parts[1] == "config":
+		if r.Method != http.MethodGet {
+			writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+			return
+		}
+		body, err := clusterConfigZip(c)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, "E0005", err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	case parts[1] == "workers":
+		s.v1Workers(w, r, c, parts[2:], now)
+	case parts[1] == "workerpools":
+		s.v1WorkerPools(w, r, c, parts[2:], now)
+	default:
+		writeError(w, r, http.StatusNotFound, "E0004", "Not found")
+	}
+}
+
+// clusterConfigZip returns the cluster configuration download, laid out as Armada does - a kubeConfig<id>
+// directory containing the kubeconfig
+func clusterConfigZip(c *cluster) ([]byte, error) {
+	dir := "kubeConfig" + c.ID + "/"
+	kubeconfig := "apiVersion: v1\nkind: Config\ncurrent-context: " + c.Name + "\nclusters:\n- name: " + c.Name +
+		"\n  cluster:\n    server: https://" + c.ID + ".mock.containers.cloud.ibm.com:30000\n" +
+		"contexts:\n- name: " + c.Name + "\n  context:\n    cluster: " + c.Name + "\n    user: admin\n" +
+		"users:\n- name: admin\n  user:\n    token: mock\n"
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create(dir); err != nil {
+		return nil, err
+	}
+	f, err := zw.Create(dir + "kube-config-" + c.Name + ".yml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write([]byte(kubeconfig)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *simulator) v1CreateCluster(w http.ResponseWriter, r *http.Request, now time.Time) {
+	var req struct {
+		Name          string `json:"name"`
+		DataCenter    string `json:"dataCenter"`
+		MachineType   string `json:"machineType"`
+		MasterVersion string `json:"masterVersion"`
+		WorkerNum     count  `json:"workerNum"`
+		PrivateVlan   string `json:"privateVlan"`
+		PublicVlan    string `json:"publicVlan"`
+		Isolation     string `json:"isolation"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if len(req.Name) == 0 {
+		writeError(w, r, http.StatusBadRequest, "E0002", "The cluster name must be specified")
+		return
+	}
+	if len(req.DataCenter) == 0 {
+		req.DataCenter = s.conf.Zones[0]
+	}
+
+	c, err := s.createCluster(req.Name, "classic", req.MasterVersion, req.MachineType, int(req.WorkerNum),
+		[]poolZone{{ID: req.DataCenter, PrivateVlan: req.PrivateVlan, PublicVlan: req.PublicVlan}}, now)
+	if err != nil {
+		writeError(w, r, http.StatusConflict, "E0007", err.Error())
+		return
+	}
+	c.Pools[0].Isolation = req.Isolation
+	for _, wk := range c.Workers {
+		wk.Isolation = req.Isolation
+	}
+	writeJSON(w, r, http.StatusCreated, map[string]string{"id": c.ID})
+}
+
+// v1ClusterOp gets, updates or deletes a cluster
+func (s *simulator) v1ClusterOp(w http.ResponseWriter, r *http.Request, c *cluster, now time.Time) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, r, http.StatusOK, s.v1Cluster(c, now))
+
+	case http.MethodPut:
+		var req struct {
+			Action  string `json:"action"`
+			Version string `json:"version"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if req.Action != "update" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if len(req.Version) == 0 {
+			req.Version = s.conf.KubeVersions[len(s.conf.KubeVersions)-1]
+		}
+		if state, _ := s.masterStatus(c, now); state != "deployed" {
+			writeError(w, r, http.StatusConflict, "E0008", "The cluster master is busy, try again later")
+			return
+		}
+		if strings.HasPrefix(req.Version, c.KubeVersion) {
+			writeError(w, r, http.StatusBadRequest, "E0009", "The master is already up to date for the specified version")
+			return
+		}
+		c.TargetVersion = req.Version
+		c.op = masterUpdate
+		c.opStart = now
+		c.opTime = s.jitter(s.conf.Timers.MasterUpdate)
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		s.deleteCluster(c, now)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+	}
+}
+
+// deleteCluster starts deletion of a cluster's master and workers
+func (s *simulator) deleteCluster(c *cluster, now time.Time) {
+	if c.op == masterDelete {
+		return
+	}
+	c.op = masterDelete
+	c.opStart = now
+	c.opTime = s.jitter(s.conf.Timers.MasterDelete)
+	for _, wk := range c.Workers {
+		s.startWorkerOp(wk, workerDelete, now)
+	}
+}
+
+// v1Workers serves /v1/clusters/{cluster}/workers[/{worker}]
+func (s *simulator) v1Workers(w http.ResponseWriter, r *http.Request, c *cluster, parts []string, now time.Time) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			workers := make([]v1Worker, 0, len(c.Workers))
+			for _, wk := range c.Workers {
+				workers = append(workers, s.v1Worker(c, wk, now))
+			}
+			writeJSON(w, r, http.StatusOK, workers)
+		case http.MethodPost:
+			// Standalone workers, which don't belong to a worker pool
+			var req struct {
+				MachineType string `json:"machineType"`
+				PrivateVlan string `json:"privateVlan"`
+				PublicVlan  string `json:"publicVlan"`
+				WorkerNum   count  `json:"workerNum"`
+				Isolation   string `json:"isolation"`
+			}
+			if !readJSON(w, r, &req) {
+				return
+			}
+			for i := 0; i < int(req.WorkerNum); i++ {
+				s.addWorker(c, nil, poolZone{ID: c.DataCenter, PrivateVlan: req.PrivateVlan, PublicVlan: req.PublicVlan}, req.MachineType, req.Isolation, now)
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+		}
+		return
+	}
+
+	wk := c.findWorker(parts[0])
+	if wk == nil {
+		writeError(w, r, http.StatusNotFound, "A0008", "The specified worker node could not be found.")
+		return
+	}
+	s.v1WorkerOp(w, r, c, wk, now)
+}
+
+// v1WorkerOp gets, reloads, reboots or deletes a worker
+func (s *simulator) v1WorkerOp(w http.ResponseWriter, r *http.Request, c *cluster, wk *worker, now time.Time) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, r, http.StatusOK, s.v1Worker(c, wk, now))
+
+	case http.MethodPut:
+		var req struct {
+			Action string `json:"action"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		switch req.Action {
+		case "reload", "update":
+			s.startWorkerOp(wk, workerReload, now)
+			wk.KubeVersion = c.KubeVersion
+		case "reboot":
+			s.startWorkerOp(wk, workerReboot, now)
+		default:
+			writeError(w, r, http.StatusBadRequest, "E0002", "Unsupported worker action: "+req.Action)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		s.startWorkerOp(wk, workerDelete, now)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+	}
+}
+
+// handleV1Workers serves /v1/workers/{worker}
+func (s *simulator) handleV1Workers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.reap(now)
+
+	c, wk := s.findWorker(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/workers"), "/"))
+	if wk == nil {
+		writeError(w, r, http.StatusNotFound, "A0008", "The specified worker node could not be found.")
+		return
+	}
+	s.v1WorkerOp(w, r, c, wk, now)
+}
+
+// v1WorkerPools serves /v1/clusters/{cluster}/workerpools[/{pool}[/zones[/{zone}]]]
+func (s *simulator) v1WorkerPools(w http.ResponseWriter, r *http.Request, c *cluster, parts []string, now time.Time) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			pools := make([]v1WorkerPool, 0, len(c.Pools))
+			for _, p := range c.Pools {
+				pools = append(pools, s.v1WorkerPool(c, p))
+			}
+			writeJSON(w, r, http.StatusOK, pools)
+		case http.MethodPost:
+			var req struct {
+				Name        string            `json:"name"`
+				MachineType string            `json:"machineType"`
+				SizePerZone count             `json:"sizePerZone"`
+				Isolation   string            `json:"isolation"`
+				Labels      map[string]string `json:"labels"`
+			}
+			if !readJSON(w, r, &req) {
+				return
+			}
+			p, err := s.createPool(c, req.Name, req.MachineType, req.Isolation, int(req.SizePerZone), nil, req.Labels, now)
+			if err != nil {
+				writeError(w, r, http.StatusConflict, "E0007", err.Error())
+				return
+			}
+			writeJSON(w, r, http.StatusCreated, s.v1WorkerPool(c, p))
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+		}
+		return
+	}
+
+	p := c.findPool(parts[0])
+	if p == nil {
+		writeError(w, r, http.StatusNotFound, "G0004", "The specified worker pool could not be found.")
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, r, http.StatusOK, s.v1WorkerPool(c, p))
+		case http.MethodPatch:
+			var req struct {
+				SizePerZone count  `json:"sizePerZone"`
+				State       string `json:"state"`
+			}
+			if !readJSON(w, r, &req) {
+				return
+			}
+			if req.State == "resizing" {
+				p.SizePerZone = int(req.SizePerZone)
+			}
+			s.reconcilePool(c, p, now)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			s.removePool(c, p, now)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+		}
+		return
+	}
+
+	if parts[1] != "zones" {
+		writeError(w, r, http.StatusNotFound, "E0004", "Not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var zone poolZone
+		if !readJSON(w, r, &zone) {
+			return
+		}
+		if err := s.addZone(c, p, zone, now); err != nil {
+			writeError(w, r, http.StatusConflict, "E0007", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		if err := s.removeZone(c, p, parts[2], now); err != nil {
+			writeError(w, r, http.StatusNotFound, "G0005", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+	}
+}
+
+// handleV1Properties serves the static v1 property APIs (versions, zones, machine types, etc.)
+func (s *simulator) handleV1Properties(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+	type kubeVersion struct {
+		Major   int  `json:"major"`
+		Minor   int  `json:"minor"`
+		Patch   int  `json:"patch"`
+		Default bool `json:"default"`
+	}
+	var versions []kubeVersion
+	for i, v := range s.conf.KubeVersions {
+		var kv kubeVersion
+		if _, err := fmt.Sscanf(v, "%d.%d.%d", &kv.Major, &kv.Minor, &kv.Patch); err == nil {
+			kv.Default = i == len(s.conf.KubeVersions)-1
+			versions = append(versions, kv)
+		}
+	}
+
+	switch {
+	case path == "/kube-versions":
+		writeJSON(w, r, http.StatusOK, versions)
+	case path == "/versions":
+		writeJSON(w, r, http.StatusOK, map[string]interface{}{"kubernetes": versions, "openshift": []kubeVersion{}})
+	case path == "/zones" || path == "/datacenters":
+		var zones []map[string]string
+		for _, z := range s.conf.Zones {
+			zones = append(zones, map[string]string{"id": z, "metro": "dal"})
+		}
+		writeJSON(w, r, http.StatusOK, zones)
+	case path == "/regions":
+		writeJSON(w, r, http.StatusOK, []map[string]string{{"name": "us-south", "alias": "us-south", "cfURL": "api.ng.bluemix.net"}})
+	case strings.HasSuffix(path, "/machine-types"):
+		var machineTypes []map[string]string
+		for _, mt := range s.conf.MachineTypes {
+			machineTypes = append(machineTypes, map[string]string{"name": mt})
+		}
+		writeJSON(w, r, http.StatusOK, machineTypes)
+	case strings.HasSuffix(path, "/vlans"):
+		writeJSON(w, r, http.StatusOK, []map[string]interface{}{
+			{"id": "1111111", "type": "private", "properties": map[string]string{"vlan_number": "1111"}},
+			{"id": "2222222", "type": "public", "properties": map[string]string{"vlan_number": "2222"}},
+		})
+	case path == "/subnets":
+		writeJSON(w, r, http.StatusOK, []interface{}{})
+	default:
+		writeError(w, r, http.StatusNotFound, "E0004", "Not found")
+	}
+}
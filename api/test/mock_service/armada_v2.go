@@ -0,0 +1,503 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// v2Cluster is the cluster representation returned by the v2 API
+type v2Cluster struct {
+	ID                string          `json:"id"`
+	Name              string          `json:"name"`
+	Region            string          `json:"region"`
+	ResourceGroupName string          `json:"resourceGroupName"`
+	State             string          `json:"state"`
+	CreatedDate       string          `json:"createdDate"`
+	MasterKubeVersion string          `json:"masterKubeVersion"`
+	TargetVersion     string          `json:"targetVersion"`
+	WorkerCount       int             `json:"workerCount"`
+	Location          string          `json:"location"`
+	Provider          string          `json:"provider"`
+	WorkerZones       []string        `json:"workerZones"`
+	Lifecycle         v2ClusterStatus `json:"lifecycle"`
+}
+
+type v2ClusterStatus struct {
+	MasterStatus             string `json:"masterStatus"`
+	MasterStatusModifiedDate string `json:"masterStatusModifiedDate"`
+	MasterHealth             string `json:"masterHealth"`
+	MasterState              string `json:"masterState"`
+}
+
+// v2Worker is the worker representation returned by the v2 API
+type v2Worker struct {
+	ID                string            `json:"id"`
+	Provider          string            `json:"provider"`
+	Flavor            string            `json:"flavor"`
+	Location          string            `json:"location"`
+	PoolID            string            `json:"poolID"`
+	PoolName          string            `json:"poolName"`
+	NetworkInterfaces []v2Interface     `json:"networkInterfaces"`
+	Health            v2WorkerHealth    `json:"health"`
+	Lifecycle         v2WorkerLifecycle `json:"lifecycle"`
+	KubeVersion       v2WorkerVersion   `json:"kubeVersion"`
+}
+
+type v2Interface struct {
+	SubnetID  string `json:"subnetID"`
+	IPAddress string `json:"ipAddress"`
+	Primary   bool   `json:"primary"`
+}
+
+type v2WorkerHealth struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+type v2WorkerLifecycle struct {
+	DesiredState     string `json:"desiredState"`
+	ActualState      string `json:"actualState"`
+	Message          string `json:"message"`
+	PendingOperation string `json:"pendingOperation"`
+}
+
+type v2WorkerVersion struct {
+	Actual  string `json:"actual"`
+	Desired string `json:"desired"`
+}
+
+// v2WorkerPool is the worker pool representation returned by the v2 API
+type v2WorkerPool struct {
+	ID          string            `json:"id"`
+	PoolName    string            `json:"poolName"`
+	Flavor      string            `json:"flavor"`
+	WorkerCount int               `json:"workerCount"`
+	Provider    string            `json:"provider"`
+	Isolation   string            `json:"isolation"`
+	Labels      map[string]string `json:"labels"`
+	Zones       []v2PoolZone      `json:"zones"`
+	Lifecycle   v2PoolLifecycle   `json:"lifecycle"`
+}
+
+type v2PoolZone struct {
+	ID          string `json:"id"`
+	WorkerCount int    `json:"workerCount"`
+}
+
+type v2PoolLifecycle struct {
+	DesiredState string `json:"desiredState"`
+	ActualState  string `json:"actualState"`
+}
+
+// v2Location is the Satellite location representation returned by the v2 API
+type v2Location struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Location    string `json:"location"`
+	Provider    string `json:"provider"`
+	State       string `json:"state"`
+	Message     string `json:"message"`
+	CreatedDate string `json:"createdDate"`
+}
+
+func (s *simulator) v2Cluster(c *cluster, now time.Time) v2Cluster {
+	masterState, masterStatus := s.masterStatus(c, now)
+	masterHealth := "normal"
+	if masterStatus != "Ready" {
+		masterHealth = "pending"
+	}
+
+	vc := v2Cluster{
+		ID:                c.ID,
+		Name:              c.Name,
+		Region:            "us-south",
+		ResourceGroupName: "default",
+		State:             s.clusterState(c, now),
+		CreatedDate:       formatTime(c.Created),
+		MasterKubeVersion: c.KubeVersion,
+		TargetVersion:     c.TargetVersion,
+		WorkerCount:       c.workerCount(),
+		Location:          c.DataCenter,
+		Provider:          c.Provider,
+		Lifecycle: v2ClusterStatus{
+			MasterStatus:             masterStatus,
+			MasterStatusModifiedDate: formatTime(c.opStart),
+			MasterHealth:             masterHealth,
+			MasterState:              masterState,
+		},
+	}
+	for _, p := range c.Pools {
+		for _, z := range p.Zones {
+			if !contains(vc.WorkerZones, z.ID) {
+				vc.WorkerZones = append(vc.WorkerZones, z.ID)
+			}
+		}
+	}
+	return vc
+}
+
+func (s *simulator) v2Worker(c *cluster, w *worker, now time.Time) v2Worker {
+	state, status := s.workerState(c, w, now)
+
+	vw := v2Worker{
+		ID:       w.ID,
+		Provider: c.Provider,
+		Flavor:   w.MachineType,
+		Location: w.Zone.ID,
+		NetworkInterfaces: []v2Interface{
+			{SubnetID: w.Zone.SubnetID, IPAddress: w.PrivateIP, Primary: true},
+		},
+		Health:      v2WorkerHealth{State: "pending", Message: status},
+		Lifecycle:   v2WorkerLifecycle{DesiredState: "deployed", ActualState: state, Message: status},
+		KubeVersion: v2WorkerVersion{Actual: w.KubeVersion, Desired: c.KubeVersion},
+	}
+	if w.Pool != nil {
+		vw.PoolID = w.Pool.ID
+		vw.PoolName = w.Pool.Name
+	}
+
+	// The v2 API reports fewer states, with the detail in the health and message
+	switch state {
+	case stateProvisionPending:
+		vw.Lifecycle.ActualState = stateProvisioning
+	case stateProvisioned:
+		if status == waitingForMaster {
+			vw.Lifecycle.Message = "Master is deploying"
+		}
+	case stateReloading, stateRebooting:
+		vw.Lifecycle.ActualState = stateDeploying
+		vw.Lifecycle.PendingOperation = strings.TrimSuffix(state, "ing")
+	case stateNormal:
+		vw.Lifecycle.ActualState = "deployed"
+		vw.Health.State = stateNormal
+	case stateDeleting:
+		vw.Lifecycle.DesiredState = stateDeleted
+	case stateDeleted:
+		vw.Lifecycle.DesiredState = stateDeleted
+		vw.Health.State = stateDeleted
+	}
+	return vw
+}
+
+func (s *simulator) v2WorkerPool(c *cluster, p *workerPool) v2WorkerPool {
+	vp := v2WorkerPool{
+		ID:          p.ID,
+		PoolName:    p.Name,
+		Flavor:      p.MachineType,
+		WorkerCount: p.SizePerZone,
+		Provider:    c.Provider,
+		Isolation:   p.Isolation,
+		Labels:      p.Labels,
+		Lifecycle:   v2PoolLifecycle{DesiredState: "active", ActualState: "active"},
+	}
+	for _, vz := range s.v1WorkerPool(c, p).Zones {
+		vp.Zones = append(vp.Zones, v2PoolZone{ID: vz.ID, WorkerCount: vz.WorkerCount})
+	}
+	return vp
+}
+
+// v2Zone identifies a zone in v2 requests
+type v2Zone struct {
+	ID       string `json:"id"`
+	SubnetID string `json:"subnetID"`
+}
+
+func toPoolZones(zones []v2Zone) []poolZone {
+	var pz []poolZone
+	for _, z := range zones {
+		pz = append(pz, poolZone{ID: z.ID, SubnetID: z.SubnetID})
+	}
+	return pz
+}
+
+// handleV2 serves the v2 cluster, worker pool, worker and Satellite location APIs.
+// Unlike v1, the v2 API identifies resources with query parameters, and all updates are POSTs.
+func (s *simulator) handleV2(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.reap(now)
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2")
+	query := r.URL.Query()
+
+	// Provider specific paths (e.g. /vpc/createCluster) work the same way for each provider
+	provider := "classic"
+	for _, p := range []string{"classic", "vpc", "satellite"} {
+		if strings.HasPrefix(path, "/"+p+"/") {
+			provider = p
+			path = strings.TrimPrefix(path, "/"+p)
+			break
+		}
+	}
+	if provider == "vpc" {
+		provider = "vpc-gen2"
+	}
+
+	if r.Method == http.MethodGet {
+		s.v2Get(w, r, path, provider, query.Get, now)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "E0001", "Method not allowed")
+		return
+	}
+
+	switch path {
+	case "/createCluster":
+		var req struct {
+			Name        string `json:"name"`
+			KubeVersion string `json:"kubeVersion"`
+			Controller  string `json:"controller"`
+			WorkerPool  struct {
+				Flavor      string   `json:"flavor"`
+				WorkerCount count    `json:"workerCount"`
+				Zones       []v2Zone `json:"zones"`
+			} `json:"workerPool"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		c, err := s.createCluster(req.Name, provider, req.KubeVersion, req.WorkerPool.Flavor, int(req.WorkerPool.WorkerCount), toPoolZones(req.WorkerPool.Zones), now)
+		if err != nil {
+			writeError(w, r, http.StatusConflict, "E0007", err.Error())
+			return
+		}
+		writeJSON(w, r, http.StatusCreated, map[string]string{"clusterID": c.ID, "id": c.ID})
+
+	case "/createController":
+		var req struct {
+			Name     string `json:"name"`
+			Location string `json:"location"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, l := range s.locations {
+			if l.Name == req.Name {
+				writeError(w, r, http.StatusConflict, "E0007", "The location name '"+req.Name+"' is already in use")
+				return
+			}
+		}
+		l := &location{ID: s.id("l"), Name: req.Name, Location: req.Location, Created: now, deploy: s.jitter(s.conf.Timers.LocationDeploy)}
+		s.locations[l.ID] = l
+		writeJSON(w, r, http.StatusCreated, map[string]string{"id": l.ID, "name": l.Name})
+
+	case "/removeController":
+		var req struct {
+			Controller string `json:"controller"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		l := s.findLocation(req.Controller)
+		if l == nil {
+			writeError(w, r, http.StatusNotFound, "M0004", "The specified location could not be found.")
+			return
+		}
+		delete(s.locations, l.ID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v2PoolOp(w, r, path, now)
+	}
+}
+
+// v2Get serves the v2 GET APIs
+func (s *simulator) v2Get(w http.ResponseWriter, r *http.Request, path string, provider string, param func(string) string, now time.Time) {
+	switch path {
+	case "/getClusters":
+		clusters := make([]v2Cluster, 0, len(s.clusters))
+		for _, c := range s.sortedClusters() {
+			// Unqualified requests list all clusters
+			if !strings.HasPrefix(r.URL.Path, "/v2/getClusters") && c.Provider != provider {
+				continue
+			}
+			clusters = append(clusters, s.v2Cluster(c, now))
+		}
+		writeJSON(w, r, http.StatusOK, clusters)
+		return
+
+	case "/getControllers":
+		locations := make([]v2Location, 0, len(s.locations))
+		for _, l := range s.locations {
+			locations = append(locations, s.v2Location(l, now))
+		}
+		sort.Slice(locations, func(i, j int) bool { return locations[i].ID < locations[j].ID })
+		writeJSON(w, r, http.StatusOK, locations)
+		return
+
+	case "/getController":
+		l := s.findLocation(param("controller"))
+		if l == nil {
+			writeError(w, r, http.StatusNotFound, "M0004", "The specified location could not be found.")
+			return
+		}
+		writeJSON(w, r, http.StatusOK, s.v2Location(l, now))
+		return
+	}
+
+	c := s.findCluster(param("cluster"))
+	if c == nil {
+		writeError(w, r, http.StatusNotFound, "A0006", "The specified cluster could not be found.")
+		return
+	}
+
+	switch path {
+	case "/getCluster":
+		writeJSON(w, r, http.StatusOK, s.v2Cluster(c, now))
+
+	case "/getWorkers":
+		workers := make([]v2Worker, 0, len(c.Workers))
+		for _, wk := range c.Workers {
+			if pool := param("pool"); len(pool) == 0 || (wk.Pool != nil && (wk.Pool.ID == pool || wk.Pool.Name == pool)) {
+				workers = append(workers, s.v2Worker(c, wk, now))
+			}
+		}
+		writeJSON(w, r, http.StatusOK, workers)
+
+	case "/getWorker":
+		wk := c.findWorker(param("worker"))
+		if wk == nil {
+			writeError(w, r, http.StatusNotFound, "A0008", "The specified worker node could not be found.")
+			return
+		}
+		writeJSON(w, r, http.StatusOK, s.v2Worker(c, wk, now))
+
+	case "/getWorkerPools":
+		pools := make([]v2WorkerPool, 0, len(c.Pools))
+		for _, p := range c.Pools {
+			pools = append(pools, s.v2WorkerPool(c, p))
+		}
+		writeJSON(w, r, http.StatusOK, pools)
+
+	case "/getWorkerPool":
+		p := c.findPool(param("workerpool"))
+		if p == nil {
+			writeError(w, r, http.StatusNotFound, "G0004", "The specified worker pool could not be found.")
+			return
+		}
+		writeJSON(w, r, http.StatusOK, s.v2WorkerPool(c, p))
+
+	default:
+		writeError(w, r, http.StatusNotFound, "E0004", "Not found")
+	}
+}
+
+// v2PoolOp serves the v2 APIs which update worker pools and workers
+func (s *simulator) v2PoolOp(w http.ResponseWriter, r *http.Request, path string, now time.Time) {
+	var req struct {
+		Cluster     string            `json:"cluster"`
+		WorkerPool  string            `json:"workerpool"`
+		Name        string            `json:"name"`
+		Flavor      string            `json:"flavor"`
+		WorkerCount count             `json:"workerCount"`
+		Size        count             `json:"size"`
+		Zones       []v2Zone          `json:"zones"`
+		Labels      map[string]string `json:"labels"`
+		ID          string            `json:"id"`
+		SubnetID    string            `json:"subnetID"`
+		Zone        string            `json:"zone"`
+		WorkerID    string            `json:"workerID"`
+		Update      bool              `json:"update"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	c := s.findCluster(req.Cluster)
+	if c == nil {
+		writeError(w, r, http.StatusNotFound, "A0006", "The specified cluster could not be found.")
+		return
+	}
+
+	if path == "/createWorkerPool" {
+		if _, err := s.createPool(c, req.Name, req.Flavor, "", int(req.WorkerCount), toPoolZones(req.Zones), req.Labels, now); err != nil {
+			writeError(w, r, http.StatusConflict, "E0007", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	if path == "/replaceWorker" {
+		wk := c.findWorker(req.WorkerID)
+		if wk == nil || wk.op == workerDelete {
+			writeError(w, r, http.StatusNotFound, "A0008", "The specified worker node could not be found.")
+			return
+		}
+		// The replacement is provisioned in the same pool and zone
+		s.startWorkerOp(wk, workerDelete, now)
+		s.addWorker(c, wk.Pool, wk.Zone, wk.MachineType, wk.Isolation, now)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	p := c.findPool(req.WorkerPool)
+	if p == nil {
+		writeError(w, r, http.StatusNotFound, "G0004", "The specified worker pool could not be found.")
+		return
+	}
+
+	switch path {
+	case "/createWorkerPoolZone":
+		if err := s.addZone(c, p, poolZone{ID: req.ID, SubnetID: req.SubnetID}, now); err != nil {
+			writeError(w, r, http.StatusConflict, "E0007", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case "/removeWorkerPoolZone":
+		if err := s.removeZone(c, p, req.Zone, now); err != nil {
+			writeError(w, r, http.StatusNotFound, "G0005", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "/resizeWorkerPool":
+		p.SizePerZone = int(req.Size)
+		s.reconcilePool(c, p, now)
+		w.WriteHeader(http.StatusNoContent)
+	case "/rebalanceWorkerPool":
+		s.reconcilePool(c, p, now)
+		w.WriteHeader(http.StatusNoContent)
+	case "/removeWorkerPool":
+		s.removePool(c, p, now)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusNotFound, "E0004", "Not found")
+	}
+}
+
+func (s *simulator) v2Location(l *location, now time.Time) v2Location {
+	state, message := l.state(now)
+	return v2Location{
+		ID:          l.ID,
+		Name:        l.Name,
+		Location:    l.Location,
+		Provider:    "satellite",
+		State:       state,
+		Message:     message,
+		CreatedDate: formatTime(l.Created),
+	}
+}
+
+// findLocation looks up a Satellite location by name or id
+func (s *simulator) findLocation(nameOrID string) *location {
+	for _, l := range s.locations {
+		if l.ID == nameOrID || l.Name == nameOrID {
+			return l
+		}
+	}
+	return nil
+}
+
+func contains(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// simConfig defines the behaviour of the Armada API simulator
+type simConfig struct {
+	Timers       timersConfig `toml:"timers"`
+	Faults       faultsConfig `toml:"faults"`
+	Zones        []string     `toml:"zones"`
+	KubeVersions []string     `toml:"kube_versions"`
+	MachineTypes []string     `toml:"machine_types"`
+}
+
+// timersConfig defines how long each simulated operation takes
+type timersConfig struct {
+	MasterDeploy     duration `toml:"master_deploy"`
+	MasterUpdate     duration `toml:"master_update"`
+	MasterDelete     duration `toml:"master_delete"`
+	WorkerProvision  duration `toml:"worker_provision"`
+	WorkerDeploy     duration `toml:"worker_deploy"`
+	WorkerReload     duration `toml:"worker_reload"`
+	WorkerReboot     duration `toml:"worker_reboot"`
+	WorkerDelete     duration `toml:"worker_delete"`
+	LocationDeploy   duration `toml:"location_deploy"`
+	DeletedRetention duration `toml:"deleted_retention"` // How long deleted workers are still reported
+
+	// Each operation's duration is randomly varied by up to this fraction (e.g. 0.2 gives +/- 20%)
+	Jitter float64 `toml:"jitter"`
+}
+
+// latencyConfig defines a distribution of response latencies
+type latencyConfig struct {
+	Distribution string   `toml:"distribution"` // constant, uniform, normal, lognormal or exponential
+	Mean         duration `toml:"mean"`
+	StdDev       duration `toml:"stddev"` // normal distribution
+	Sigma        float64  `toml:"sigma"`  // lognormal distribution, the standard deviation of the underlying normal distribution
+	Min          duration `toml:"min"`    // uniform distribution
+	Max          duration `toml:"max"`    // uniform distribution
+}
+
+// faultRule defines the latency and error rate of requests. Method and Path are only used by route rules.
+type faultRule struct {
+	Method    string        `toml:"method"`
+	Path      string        `toml:"path"` // Path prefix, e.g. /v1/clusters
+	Latency   latencyConfig `toml:"latency"`
+	ErrorRate float64       `toml:"error_rate"` // Fraction of requests which fail
+	ErrorCode int           `toml:"error_code"` // HTTP status code of failed requests
+}
+
+// faultsConfig defines the default latency and error rate, with overrides for specific routes.
+// The first matching route is used.
+type faultsConfig struct {
+	Default faultRule   `toml:"default"`
+	Routes  []faultRule `toml:"route"`
+}
+
+// defaultConfig returns a configuration with timers which roughly match a classic cluster, scaled down
+func defaultConfig() simConfig {
+	return simConfig{
+		Timers: timersConfig{
+			MasterDeploy:     duration{2 * time.Minute},
+			MasterUpdate:     duration{2 * time.Minute},
+			MasterDelete:     duration{30 * time.Second},
+			WorkerProvision:  duration{2 * time.Minute},
+			WorkerDeploy:     duration{1 * time.Minute},
+			WorkerReload:     duration{1 * time.Minute},
+			WorkerReboot:     duration{30 * time.Second},
+			WorkerDelete:     duration{30 * time.Second},
+			LocationDeploy:   duration{1 * time.Minute},
+			DeletedRetention: duration{1 * time.Minute},
+			Jitter:           0.2,
+		},
+		Faults: faultsConfig{
+			Default: faultRule{
+				Latency:   latencyConfig{Distribution: "constant"},
+				ErrorCode: 500,
+			},
+		},
+		Zones:        []string{"dal10", "dal12", "dal13"},
+		KubeVersions: []string{"1.25.10", "1.26.5", "1.27.2"},
+		MachineTypes: []string{"free", "u3c.2x4", "b3c.4x16", "b3c.16x64"},
+	}
+}
+
+// loadConfig reads the simulator configuration file, on top of the defaults
+func loadConfig(path string) (simConfig, error) {
+	conf := defaultConfig()
+	if len(path) > 0 {
+		if _, err := toml.DecodeFile(path, &conf); err != nil {
+			return conf, err
+		}
+	}
+	// Clusters default to the first zone and the latest version
+	if len(conf.Zones) == 0 {
+		return conf, errors.New("zones must not be empty")
+	}
+	if len(conf.KubeVersions) == 0 {
+		return conf, errors.New("kube_versions must not be empty")
+	}
+	for i := range conf.Faults.Routes {
+		if conf.Faults.Routes[i].ErrorCode == 0 {
+			conf.Faults.Routes[i].ErrorCode = conf.Faults.Default.ErrorCode
+		}
+	}
+	return conf, nil
+}
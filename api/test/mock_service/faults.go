@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sample returns a latency from the distribution
+func (s *simulator) sample(l latencyConfig) time.Duration {
+	s.rndMu.Lock()
+	defer s.rndMu.Unlock()
+
+	var d float64
+	switch strings.ToLower(l.Distribution) {
+	case "uniform":
+		d = float64(l.Min.Duration) + s.rnd.Float64()*float64(l.Max.Duration-l.Min.Duration)
+	case "normal":
+		d = float64(l.Mean.Duration) + s.rnd.NormFloat64()*float64(l.StdDev.Duration)
+	case "lognormal":
+		// Choose the location of the underlying normal distribution so that the mean is as configured
+		mu := math.Log(float64(l.Mean.Duration)) - l.Sigma*l.Sigma/2
+		d = math.Exp(mu + s.rnd.NormFloat64()*l.Sigma)
+	case "exponential":
+		d = s.rnd.ExpFloat64() * float64(l.Mean.Duration)
+	default:
+		d = float64(l.Mean.Duration)
+	}
+	if d < 0 || math.IsNaN(d) {
+		return 0
+	}
+	return time.Duration(d)
+}
+
+// failed returns true if a request should fail, given the error rate
+func (s *simulator) failed(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	s.rndMu.Lock()
+	defer s.rndMu.Unlock()
+	return s.rnd.Float64() < rate
+}
+
+// rule returns the fault rule for a request
+func (s *simulator) rule(r *http.Request) faultRule {
+	for _, fr := range s.conf.Faults.Routes {
+		if (len(fr.Method) == 0 || strings.EqualFold(fr.Method, r.Method)) && strings.HasPrefix(r.URL.Path, fr.Path) {
+			return fr
+		}
+	}
+	return s.conf.Faults.Default
+}
+
+// inject delays the request by the configured latency, and fails it at the configured error rate
+func (s *simulator) inject(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fr := s.rule(r)
+		if d := s.sample(fr.Latency); d > 0 {
+			time.Sleep(d)
+		}
+		if s.failed(fr.ErrorRate) {
+			writeError(w, r, fr.ErrorCode, "E0000", "Simulated failure")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// apiError matches the error responses of the Armada API
+type apiError struct {
+	IncidentID  string `json:"incidentID"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	RecoveryCLI string `json:"recoveryCLI,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, description string) {
+	writeJSON(w, r, status, apiError{
+		IncidentID:  r.Header.Get("X-Request-Id"),
+		Code:        code,
+		Description: description,
+		Type:        http.StatusText(status),
+	})
+
+	if verbose {
+		fmt.Println("error", status, "\n\thttp://"+r.Host+r.URL.String()+" : "+description)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
 var verbose bool
@@ -116,11 +117,22 @@ func returnSoftlayer(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
-	const PORT = 80
+	var port int
+	var configFile string
+	var seed int64
 
 	flag.BoolVar(&verbose, "verbose", false, "verbose logging output")
+	flag.IntVar(&port, "port", 80, "port to listen on")
+	flag.StringVar(&configFile, "config", "", "Armada API simulator configuration file (TOML). Defaults are used if not specified")
+	flag.Int64Var(&seed, "seed", time.Now().UnixNano(), "random number seed for simulated latencies, errors and operation durations")
 	flag.Parse()
 
+	conf, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatalf("Error loading simulator configuration file %s : %s\n", configFile, err.Error())
+	}
+	sim := newSimulator(conf, seed)
+
 	// Use rooted subtree pattern to match all requests
 	http.HandleFunc("/", returnCode200)
 	http.HandleFunc("/apikeys/", returnAPIKey)
@@ -135,8 +147,17 @@ func main() {
 	http.HandleFunc("/oauth/token", returnToken) // BSS Token
 	http.HandleFunc("/SoftLayer_Account.json", returnSoftlayer)
 
-	addr := fmt.Sprintf("%s%d", ":", PORT)
-	fmt.Printf("Mock Bluemix Authentication Service listening on %s\n", addr)
+	// Stateful Armada API simulator
+	http.HandleFunc("/v1/clusters", sim.inject(sim.handleV1Clusters))
+	http.HandleFunc("/v1/clusters/", sim.inject(sim.handleV1Clusters))
+	http.HandleFunc("/v1/workers/", sim.inject(sim.handleV1Workers))
+	for _, p := range []string{"/v1/kube-versions", "/v1/versions", "/v1/zones", "/v1/datacenters", "/v1/datacenters/", "/v1/regions", "/v1/subnets"} {
+		http.HandleFunc(p, sim.inject(sim.handleV1Properties))
+	}
+	http.HandleFunc("/v2/", sim.inject(sim.handleV2))
+
+	addr := fmt.Sprintf("%s%d", ":", port)
+	fmt.Printf("Mock Bluemix Authentication Service and Armada API listening on %s\n", addr)
 
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
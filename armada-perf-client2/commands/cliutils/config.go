@@ -25,6 +25,7 @@ type ArmadaConfig struct {
 	SatLink  *SatLinkConfig
 	VPC      *VPCConfig
 	IBMCloud *IBMCloudConfig
+	Retry    *RetryConfig
 }
 
 // IKSConfig defines metadata for accessing the 
@@ -43,6 +44,18 @@ type VPCConfig struct {
 	Endpoint string `toml:"endpoint"`
 }
 
+// RetryConfig defines the retry and backoff policy for Armada API requests. Unset values take the defaults.
+type RetryConfig struct {
+	MaxAttempts      int      `toml:"max_attempts"`       // Including the first attempt
+	BaseDelay        string   `toml:"base_delay"`         // Backoff before the first retry, doubled for each subsequent retry (e.g. "1s")
+	MaxDelay         string   `toml:"max_delay"`          // Upper limit on the backoff between retries
+	Jitter           float64  `toml:"jitter"`             // Fraction of each backoff which is randomized (0 - 1)
+	Methods          []string `toml:"methods"`            // HTTP methods which are retried
+	Statuses         []int    `toml:"statuses"`           // HTTP status codes which are retried
+	Budget           int      `toml:"budget"`             // Maximum number of retries across all requests made by the command, 0 is unlimited
+	IgnoreRetryAfter bool     `toml:"ignore_retry_after"` // Don't wait for the duration specified by a Retry-After response header
+}
+
 // IBMCloudConfig defines metadata for accessing IBM Cloud
 type IBMCloudConfig struct {
 	AccessToken  string
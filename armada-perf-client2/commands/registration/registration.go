@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2019, 2022 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2019, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets, irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -71,5 +71,17 @@ func CLICommands() []cli.Command {
 	commands = append(commands, satCommands...)
 	commands = append(commands, obCommands...)
 
+	return withRetryFlags(commands)
+}
+
+// withRetryFlags adds the retry flags to each command which makes API requests, so the retry policy can be set per command
+func withRetryFlags(commands []cli.Command) []cli.Command {
+	for i := range commands {
+		if len(commands[i].Subcommands) > 0 {
+			commands[i].Subcommands = withRetryFlags(commands[i].Subcommands)
+			continue
+		}
+		commands[i].Flags = append(commands[i].Flags, models.RetryFlags...)
+	}
 	return commands
 }
@@ -39,6 +39,7 @@ func main() {
 		models.MetricsFlagName: new(metrics.Data),
 	}
 
+	app.Flags = append([]cli.Flag{models.HistogramDirFlag}, models.RetryFlags...)
 	app.Before = metrics.Initialize
 	app.Commands = registration.CLICommands()
 	app.After = metrics.WriteMetrics
@@ -12,23 +12,20 @@ package metrics
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/urfave/cli"
 	"github.ibm.com/alchemy-containers/armada-performance/armada-perf-client2/models"
 	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
 )
 
 var debug = true
 
-type stateTimes struct {
-	minTime   time.Duration
-	maxTime   time.Duration
-	totalTime time.Duration
-}
-
 // WorkerStateTimes tracks worker state transition times
 // Map: Key is worker state (e.g. provisioning, reloading, deploying, etc.)
 type WorkerStateTimes map[string]time.Duration
@@ -43,7 +40,11 @@ type Data struct {
 	H       Hosts
 	P       WorkerPools
 	E       Endpoints
+	R       Retries
 	Data    map[string]interface{}
+
+	// HistogramDir is the directory the raw duration histograms are dumped to, for offline merging across runs
+	HistogramDir string
 }
 
 // Clusters holds metrics data for a set of clusters - assume multiple clusters have the same number of workers
@@ -76,6 +77,51 @@ type Worker struct {
 	Failed    bool
 }
 
+// Retries holds metrics data for API request retries, to distinguish soft failures (recovered by retrying)
+// from hard failures (which were still failing when the retry policy gave up)
+type Retries struct {
+	mutex           sync.Mutex
+	Requests        int
+	Retries         int
+	Reasons         map[string]int // Retries by reason, e.g. status_503 or connection
+	Delay           time.Duration  // Total backoff between retries
+	SoftFailures    int
+	HardFailures    int
+	BudgetExhausted int
+}
+
+// RecordRetry records a request being retried
+func (r *Retries) RecordRetry(reason string, delay time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.Reasons == nil {
+		r.Reasons = make(map[string]int)
+	}
+	r.Retries++
+	r.Reasons[reason]++
+	r.Delay += delay
+}
+
+// RecordRequest records the outcome of a request after any retries. failed is true if the request was retried, or
+// had a transient failure, and still failed.
+func (r *Retries) RecordRequest(retries int, failed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Requests++
+	if failed {
+		r.HardFailures++
+	} else if retries > 0 {
+		r.SoftFailures++
+	}
+}
+
+// RecordBudgetExhausted records a request not being retried because the retry budget has been used up
+func (r *Retries) RecordBudgetExhausted() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.BudgetExhausted++
+}
+
 // WorkerPools holds metrics data for a set of worker pools
 type WorkerPools []time.Duration
 
@@ -96,6 +142,7 @@ type Host struct {
 func Initialize(c *cli.Context) error {
 	md := c.App.Metadata[models.MetricsFlagName].(*Data)
 	md.Data = make(map[string]interface{})
+	md.HistogramDir = c.GlobalString(models.HistogramDirFlagName)
 	return nil
 }
 
@@ -114,23 +161,13 @@ func WriteMetrics(c *cli.Context) error {
 			}
 		}
 
-		sm := make(map[string]*stateTimes)
+		sm := make(map[string]*histogram.Histogram)
 		for _, w := range md.W {
 			for s, d := range w.Durations {
-				if val, ok := sm[s]; ok {
-					if d < val.minTime {
-						val.minTime = d
-					}
-					if d > val.maxTime {
-						val.maxTime = d
-					}
-					val.totalTime += d
-				} else {
-					sm[s] = new(stateTimes)
-					sm[s].minTime = d
-					sm[s].maxTime = d
-					sm[s].totalTime = d
+				if _, ok := sm[s]; !ok {
+					sm[s] = histogram.New()
 				}
+				sm[s].Record(d)
 			}
 
 			if w.Failed {
@@ -155,20 +192,22 @@ func WriteMetrics(c *cli.Context) error {
 		}
 		metricsPrefix = strings.Join([]string{metricsPrefix, md.Command}, ".")
 
-		// Generate min, mean and max state transition times across all workers.
+		// Generate min, mean, max and percentile state transition times across all workers.
 		for state, d := range sm {
 			bm = append(bm, metricsservice.BluemixMetric{
 				Name:  strings.Join([]string{metricsPrefix, state, "duration", "min"}, "."),
-				Value: d.minTime.Seconds(),
+				Value: d.Min().Seconds(),
 			})
 			bm = append(bm, metricsservice.BluemixMetric{
 				Name:  strings.Join([]string{metricsPrefix, state, "duration", "max"}, "."),
-				Value: d.maxTime.Seconds(),
+				Value: d.Max().Seconds(),
 			})
 			bm = append(bm, metricsservice.BluemixMetric{
 				Name:  strings.Join([]string{metricsPrefix, state, "duration", "mean"}, "."),
-				Value: d.totalTime.Seconds() / float64(workerCount),
+				Value: d.Total().Seconds() / float64(workerCount),
 			})
+			bm = appendPercentiles(bm, strings.Join([]string{metricsPrefix, state, "duration"}, "."), d)
+			md.dumpHistogram(strings.Join([]string{metricsPrefix, state, "duration"}, "."), d)
 		}
 
 		// Add worker deploy failures if appropriate
@@ -181,12 +220,12 @@ func WriteMetrics(c *cli.Context) error {
 
 		// Process cluster metrics
 		if len(md.C.Duration) > 0 {
-			bm = processDurations(bm, md.C.Duration, metricsPrefix)
+			bm = md.processDurations(bm, md.C.Duration, metricsPrefix)
 		}
 
 		// Process Worker Pool metrics
 		if len(md.P) > 0 {
-			bm = processDurations(bm, md.P, metricsPrefix)
+			bm = md.processDurations(bm, md.P, metricsPrefix)
 		}
 
 		// Process Satellite Location metrics
@@ -200,12 +239,12 @@ func WriteMetrics(c *cli.Context) error {
 				ld = append(ld, l.Duration)
 			}
 
-			bm = processDurations(bm, ld, mp)
+			bm = md.processDurations(bm, ld, mp)
 		}
 
 		// Process Satellite Endpoint metrics
 		if len(md.E) > 0 {
-			bm = processDurations(bm, md.E, metricsPrefix)
+			bm = md.processDurations(bm, md.E, metricsPrefix)
 		}
 
 		// Process Satellite Host metrics
@@ -218,14 +257,19 @@ func WriteMetrics(c *cli.Context) error {
 			for _, h := range md.H.Hosts {
 				hd = append(hd, h.Duration)
 			}
-			bm = processDurations(bm, hd, mp)
+			bm = md.processDurations(bm, hd, mp)
 		}
 		if md.H.Location.Duration != 0 {
 			mp := metricsPrefix
 			if md.H.Location.CoreOS {
 				mp = strings.Join([]string{metricsPrefix, "coreos"}, ".")
 			}
-			bm = processDurations(bm, []time.Duration{md.H.Location.Duration}, strings.Join([]string{mp, "location"}, "."))
+			bm = md.processDurations(bm, []time.Duration{md.H.Location.Duration}, strings.Join([]string{mp, "location"}, "."))
+		}
+
+		// Process API request retries
+		if md.R.Requests > 0 {
+			bm = md.R.appendMetrics(bm, metricsPrefix)
 		}
 
 		// Finally add in any command specific metrics
@@ -246,35 +290,85 @@ func WriteMetrics(c *cli.Context) error {
 	return nil
 }
 
-func processDurations(bm []metricsservice.BluemixMetric, md []time.Duration, mp string) []metricsservice.BluemixMetric {
-	var cd stateTimes
-
-	if len(md) == 0 {
+func (md *Data) processDurations(bm []metricsservice.BluemixMetric, durations []time.Duration, mp string) []metricsservice.BluemixMetric {
+	if len(durations) == 0 {
 		return bm
 	}
 
-	for i, d := range md {
-		if i == 0 || d > cd.maxTime {
-			cd.maxTime = d
-		}
-		if i == 0 || d < cd.minTime {
-			cd.minTime = d
-		}
-		cd.totalTime += d
+	h := histogram.New()
+	for _, d := range durations {
+		h.Record(d)
 	}
 
 	bm = append(bm, metricsservice.BluemixMetric{
 		Name:  strings.Join([]string{mp, "duration", "min"}, "."),
-		Value: cd.minTime.Seconds(),
+		Value: h.Min().Seconds(),
 	})
 	bm = append(bm, metricsservice.BluemixMetric{
 		Name:  strings.Join([]string{mp, "duration", "max"}, "."),
-		Value: cd.maxTime.Seconds(),
+		Value: h.Max().Seconds(),
 	})
 	bm = append(bm, metricsservice.BluemixMetric{
 		Name:  strings.Join([]string{mp, "duration", "mean"}, "."),
-		Value: cd.totalTime.Seconds() / float64(len(md)),
+		Value: h.Mean().Seconds(),
 	})
+	bm = appendPercentiles(bm, strings.Join([]string{mp, "duration"}, "."), h)
+	md.dumpHistogram(strings.Join([]string{mp, "duration"}, "."), h)
 
 	return bm
 }
+
+func (r *Retries) appendMetrics(bm []metricsservice.BluemixMetric, mp string) []metricsservice.BluemixMetric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	bm = append(bm, metricsservice.BluemixMetric{
+		Name:  strings.Join([]string{mp, "retries"}, "."),
+		Value: r.Retries,
+	})
+	bm = append(bm, metricsservice.BluemixMetric{
+		Name:  strings.Join([]string{mp, "retries", "delay"}, "."),
+		Value: r.Delay.Seconds(),
+	})
+	for reason, n := range r.Reasons {
+		bm = append(bm, metricsservice.BluemixMetric{
+			Name:  strings.Join([]string{mp, "retries", reason}, "."),
+			Value: n,
+		})
+	}
+	bm = append(bm, metricsservice.BluemixMetric{
+		Name:  strings.Join([]string{mp, "soft-failures"}, "."),
+		Value: r.SoftFailures,
+	})
+	bm = append(bm, metricsservice.BluemixMetric{
+		Name:  strings.Join([]string{mp, "hard-failures"}, "."),
+		Value: r.HardFailures,
+	})
+	bm = append(bm, metricsservice.BluemixMetric{
+		Name:  strings.Join([]string{mp, "retry-budget-exhausted"}, "."),
+		Value: r.BudgetExhausted,
+	})
+	return bm
+}
+
+// appendPercentiles adds the reported percentiles of a histogram, e.g. <prefix>.p99
+func appendPercentiles(bm []metricsservice.BluemixMetric, mp string, h *histogram.Histogram) []metricsservice.BluemixMetric {
+	for _, p := range histogram.Percentiles {
+		bm = append(bm, metricsservice.BluemixMetric{
+			Name:  strings.Join([]string{mp, strings.ToLower(histogram.PercentileName(p))}, "."),
+			Value: h.Percentile(p).Seconds(),
+		})
+	}
+	return bm
+}
+
+// dumpHistogram writes the raw histogram to the histogram directory, if requested
+func (md *Data) dumpHistogram(name string, h *histogram.Histogram) {
+	if len(md.HistogramDir) == 0 {
+		return
+	}
+	path := filepath.Join(md.HistogramDir, histogram.DumpFileName(name))
+	if err := h.WriteFile(path, name); err != nil {
+		fmt.Println("Failed to write histogram", path, ":", err)
+	}
+}
@@ -92,6 +92,7 @@ const (
 	ForceFlagName                        = "force"
 	ForceDeleteStorageFlagName           = "force-delete-storage"
 	HardwareFlagName                     = "hardware" // used to indicate the the level of classic hardware isolation (dedicated or shared)
+	HistogramDirFlagName                 = "histogram-dir"
 	HostFlagName                         = "host"
 	HostLabelFlagName                    = "host-label"
 	ImageFlagName                        = "image"
@@ -121,6 +122,14 @@ const (
 	ProvisionedFlagName                  = "provisioned"
 	ProviderIDFlagName                   = "provider"
 	ReloadFlagName                       = "reload"
+	RetryBaseDelayFlagName               = "retry-base-delay"
+	RetryBudgetFlagName                  = "retry-budget"
+	RetryIgnoreRetryAfterFlagName        = "retry-ignore-retry-after"
+	RetryJitterFlagName                  = "retry-jitter"
+	RetryMaxAttemptsFlagName             = "retry-max-attempts"
+	RetryMaxDelayFlagName                = "retry-max-delay"
+	RetryMethodsFlagName                 = "retry-methods"
+	RetryStatusesFlagName                = "retry-statuses"
 	ServiceSubnetFlagName                = "service-subnet"
 	SizePerZoneFlagName                  = "size-per-zone"
 	SourceProtocolFlagName               = "source-protocol"
@@ -210,6 +219,50 @@ var (
 		Usage:    "Sends metrics to our metrics service (currently influx db).",
 	}
 
+	// HistogramDirFlag is used to request that the raw duration histograms are dumped to a directory, for offline merging across runs
+	HistogramDirFlag = cli.StringFlag{
+		Name:     HistogramDirFlagName,
+		Required: false,
+		Usage:    "Directory to dump raw duration histograms to when sending metrics.",
+	}
+
+	// RetryFlags override the retry and backoff policy for Armada API requests from the configuration file.
+	// They are registered globally and on every command, so can be specified for an individual command.
+	RetryFlags = []cli.Flag{
+		cli.IntFlag{
+			Name:  RetryMaxAttemptsFlagName,
+			Usage: "Maximum number of attempts for each API request, including the first.",
+		},
+		cli.DurationFlag{
+			Name:  RetryBaseDelayFlagName,
+			Usage: "Backoff before the first retry, doubled for each subsequent retry.",
+		},
+		cli.DurationFlag{
+			Name:  RetryMaxDelayFlagName,
+			Usage: "Maximum backoff between retries.",
+		},
+		cli.Float64Flag{
+			Name:  RetryJitterFlagName,
+			Usage: "Fraction of each backoff which is randomized, between 0 and 1.",
+		},
+		cli.BoolFlag{
+			Name:  RetryIgnoreRetryAfterFlagName,
+			Usage: "Ignore Retry-After headers, and only use the backoff.",
+		},
+		cli.StringSliceFlag{
+			Name:  RetryMethodsFlagName,
+			Usage: "HTTP method which is retried (e.g. GET). Repeat for multiple methods.",
+		},
+		cli.IntSliceFlag{
+			Name:  RetryStatusesFlagName,
+			Usage: "HTTP status code which is retried (e.g. 503). Repeat for multiple status codes.",
+		},
+		cli.IntFlag{
+			Name:  RetryBudgetFlagName,
+			Usage: "Maximum number of retries across all requests made by the command. 0 is unlimited.",
+		},
+	}
+
 	// NetworkFlag is used to retrieve the Calico network configuration. Only valid in conjunction with the AdminFlag.
 	NetworkFlag = cli.BoolFlag{
 		Name:  NetworkFlagName,
@@ -29,13 +29,13 @@ import (
 	"github.com/IBM-Cloud/ibm-cloud-cli-sdk/common/rest"
 
 	v2 "github.ibm.com/alchemy-containers/armada-api-model/json/v2"
-	"github.ibm.com/alchemy-containers/armada-circuit/protect"
 	nlbDNSModel "github.ibm.com/alchemy-containers/armada-dns-model/v2/dns"
 	errModel "github.ibm.com/alchemy-containers/armada-model/errors"
 	apiModelCommon "github.ibm.com/alchemy-containers/armada-model/model/api/json"
 	apiModelV1 "github.ibm.com/alchemy-containers/armada-model/model/api/json/v1"
 
 	"github.ibm.com/alchemy-containers/armada-performance/armada-perf-client2/commands/cliutils"
+	"github.ibm.com/alchemy-containers/armada-performance/armada-perf-client2/metrics"
 	"github.ibm.com/alchemy-containers/armada-performance/armada-perf-client2/models"
 
 	jwt "github.com/golang-jwt/jwt/v4"
@@ -57,6 +57,8 @@ type ArmadaEndpoint struct {
 	Locations         []string
 	Locale            string
 	Version           string
+	Retry             RetryPolicy
+	Metrics           *metrics.Data
 }
 
 // ListerEndpoint allows other methods to remain unaware of which endpoint implementation is in use for list calls.
@@ -272,14 +274,6 @@ func (endpoint *ArmadaEndpoint) handleError(resp *http.Response, respErr error,
 	return nil
 }
 
-var retryStatuses = []int{
-	http.StatusInternalServerError, // 500
-	http.StatusRequestTimeout,      // 408
-	http.StatusBadGateway,          // 502
-	http.StatusServiceUnavailable,  // 503
-	http.StatusGatewayTimeout,      // 504
-}
-
 // generateRequestID returns a request ID. If it fails to generate, returns an empty string.
 func generateRequestID() string {
 	id, idErr := uuid.NewRandom()
@@ -306,50 +300,78 @@ func wrapConnectionError(requestID string, err error) error {
 	return errors.New(errString)
 }
 
+// requestWithRetries makes the request, retrying transient failures according to the endpoint's retry policy.
+// Retries, and whether the request ultimately succeeded, are recorded in the metrics.
 func (endpoint *ArmadaEndpoint) requestWithRetries(req *rest.Request, successV interface{}, errorV interface{}) (*http.Response, error) {
 	var resp *http.Response
+	var err error
+	var reason string
+	var transient bool
 	requestID := ""
-	retrier := protect.NewRetrier(nil)
-	ctx := context.Background()
-	retrierErr := retrier.Do(
-		ctx,
-		protect.Doer(func() error {
-			requestID = generateRequestID()
-			req.Set(requestIDHeader, requestID)
-			if debug {
-				fmt.Println("Running retry request.", requestIDHeader+":", requestID)
-			}
-			var err error
-			resp, err = endpoint.client().Do(req, successV, errorV)
+	retries := 0
+	policy := endpoint.Retry
 
-			if resp != nil && (resp.StatusCode >= 200 && resp.StatusCode < 300) {
-				return nil
-			}
-			return err
-		}),
-		protect.DoRetry(func(err error) bool {
-			httpReq, buildErr := req.Build()
-			if buildErr != nil {
-				return true
-			}
+	method := http.MethodGet
+	if httpReq, buildErr := req.Build(); buildErr == nil {
+		method = httpReq.Method
+	}
 
-			if httpReq.Method != http.MethodGet {
-				return false
-			}
-			if resp != nil {
-				for _, code := range retryStatuses {
-					if resp.StatusCode == code {
-						return true
-					}
-				}
-				return false
+	for attempt := 1; ; attempt++ {
+		requestID = generateRequestID()
+		req.Set(requestIDHeader, requestID)
+		if debug && attempt > 1 {
+			fmt.Println("Running retry request.", requestIDHeader+":", requestID)
+		}
+
+		resp, err = endpoint.client().Do(req, successV, errorV)
+		if resp != nil && (resp.StatusCode >= 200 && resp.StatusCode < 300) {
+			err = nil
+		}
+		if err == nil {
+			transient = false
+			break
+		}
+
+		reason, transient = policy.transient(resp, err)
+		if !transient || attempt >= policy.attempts() || !policy.retryMethod(method) || endpoint.Context.Err() != nil {
+			break
+		}
+		if !policy.Budget.take() {
+			if verbose {
+				fmt.Println("Retry budget exhausted, not retrying.", requestIDHeader+":", requestID)
 			}
-			return true
-		}))
-	if retrierErr != nil && resp == nil {
-		retrierErr = wrapConnectionError(requestID, retrierErr)
+			endpoint.retryMetrics().RecordBudgetExhausted()
+			break
+		}
+
+		delay := policy.backoff(attempt, resp)
+		if verbose {
+			fmt.Printf("Retrying %s request after %s (%v).\n", method, reason, delay)
+		}
+		endpoint.retryMetrics().RecordRetry(reason, delay)
+		retries++
+
+		select {
+		case <-time.After(delay):
+		case <-endpoint.Context.Done():
+		}
+	}
+
+	// A request which still fails after being retried is a hard failure, whether or not its final error is transient
+	endpoint.retryMetrics().RecordRequest(retries, err != nil && (retries > 0 || transient))
+
+	if err != nil && resp == nil {
+		err = wrapConnectionError(requestID, err)
 	}
-	return resp, retrierErr
+	return resp, err
+}
+
+// retryMetrics returns where retries are recorded, which is discarded if the endpoint isn't associated with the command's metrics
+func (endpoint *ArmadaEndpoint) retryMetrics() *metrics.Retries {
+	if endpoint.Metrics == nil {
+		return new(metrics.Retries)
+	}
+	return &endpoint.Metrics.R
 }
 
 // GetAPIVersion returns the default API version to use with request
@@ -378,6 +400,13 @@ func GetArmadaEndpoint(c *cli.Context, opts ...EndpointOpt) *ArmadaEndpoint {
 			Context:      context.Background(),
 		},
 		Version: c.App.Version,
+		Retry:   NewRetryPolicy(c),
+	}
+	if md, ok := c.App.Metadata[models.MetricsFlagName].(*metrics.Data); ok {
+		endpoint.Metrics = md
+	}
+	if debug {
+		fmt.Println("Retry policy:", endpoint.Retry)
 	}
 
 	return &endpoint
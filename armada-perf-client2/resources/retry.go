@@ -0,0 +1,287 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package resources
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.ibm.com/alchemy-containers/armada-performance/armada-perf-client2/commands/cliutils"
+	"github.ibm.com/alchemy-containers/armada-performance/armada-perf-client2/models"
+)
+
+// Default retry policy
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 1 * time.Second
+	defaultRetryMaxDelay    = 30 * time.Second
+	defaultRetryJitter      = 0.5
+)
+
+var defaultRetryMethods = []string{http.MethodGet, http.MethodHead, http.MethodDelete}
+
+var retryStatuses = []int{
+	http.StatusInternalServerError, // 500
+	http.StatusRequestTimeout,      // 408
+	http.StatusTooManyRequests,     // 429
+	http.StatusBadGateway,          // 502
+	http.StatusServiceUnavailable,  // 503
+	http.StatusGatewayTimeout,      // 504
+}
+
+// RetryPolicy defines which failed API requests are retried, and how long to back off between attempts
+type RetryPolicy struct {
+	MaxAttempts      int // Including the first attempt
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	Jitter           float64 // Fraction of each backoff which is randomized
+	Methods          []string
+	Statuses         []int
+	HonourRetryAfter bool
+
+	// Budget is shared by all endpoints, so that it limits the total retries made by a command
+	Budget *RetryBudget
+}
+
+// RetryBudget limits the total number of retries. A nil budget is unlimited.
+type RetryBudget struct {
+	mutex     sync.Mutex
+	remaining int
+}
+
+var retryBudget *RetryBudget
+var retryBudgetOnce sync.Once
+
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var jitterMutex sync.Mutex
+
+// DefaultRetryPolicy returns the policy used if neither the configuration file nor the command flags override it
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      defaultRetryMaxAttempts,
+		BaseDelay:        defaultRetryBaseDelay,
+		MaxDelay:         defaultRetryMaxDelay,
+		Jitter:           defaultRetryJitter,
+		Methods:          defaultRetryMethods,
+		Statuses:         retryStatuses,
+		HonourRetryAfter: true,
+	}
+}
+
+// NewRetryPolicy returns the retry policy from the configuration file, overridden by any retry flags specified for the command
+func NewRetryPolicy(c *cli.Context) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	budget := 0
+
+	if rc := cliutils.GetArmadaConfig().Retry; rc != nil {
+		if rc.MaxAttempts > 0 {
+			policy.MaxAttempts = rc.MaxAttempts
+		}
+		if d, err := time.ParseDuration(rc.BaseDelay); err == nil {
+			policy.BaseDelay = d
+		}
+		if d, err := time.ParseDuration(rc.MaxDelay); err == nil {
+			policy.MaxDelay = d
+		}
+		if rc.Jitter > 0 && rc.Jitter <= 1 {
+			policy.Jitter = rc.Jitter
+		}
+		if len(rc.Methods) > 0 {
+			policy.Methods = rc.Methods
+		}
+		if len(rc.Statuses) > 0 {
+			policy.Statuses = rc.Statuses
+		}
+		policy.HonourRetryAfter = !rc.IgnoreRetryAfter
+		budget = rc.Budget
+	}
+
+	if isSet(c, models.RetryMaxAttemptsFlagName) {
+		policy.MaxAttempts = intFlag(c, models.RetryMaxAttemptsFlagName)
+	}
+	if isSet(c, models.RetryBaseDelayFlagName) {
+		policy.BaseDelay = durationFlag(c, models.RetryBaseDelayFlagName)
+	}
+	if isSet(c, models.RetryMaxDelayFlagName) {
+		policy.MaxDelay = durationFlag(c, models.RetryMaxDelayFlagName)
+	}
+	if isSet(c, models.RetryJitterFlagName) {
+		if j := float64Flag(c, models.RetryJitterFlagName); j >= 0 && j <= 1 {
+			policy.Jitter = j
+		}
+	}
+	if isSet(c, models.RetryIgnoreRetryAfterFlagName) {
+		policy.HonourRetryAfter = !boolFlag(c, models.RetryIgnoreRetryAfterFlagName)
+	}
+	if isSet(c, models.RetryMethodsFlagName) {
+		policy.Methods = stringSliceFlag(c, models.RetryMethodsFlagName)
+	}
+	if isSet(c, models.RetryStatusesFlagName) {
+		policy.Statuses = intSliceFlag(c, models.RetryStatusesFlagName)
+	}
+	if isSet(c, models.RetryBudgetFlagName) {
+		budget = intFlag(c, models.RetryBudgetFlagName)
+	}
+
+	// The budget is created by the first endpoint, and shared by all subsequent endpoints
+	retryBudgetOnce.Do(func() {
+		if budget > 0 {
+			retryBudget = &RetryBudget{remaining: budget}
+		}
+	})
+	policy.Budget = retryBudget
+
+	return policy
+}
+
+// attempts returns the maximum number of attempts, which is at least one
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// retryMethod returns true if requests with the HTTP method may be retried
+func (p RetryPolicy) retryMethod(method string) bool {
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// transient returns true if a request failed with a connection error or a retryable status code,
+// along with the reason for recording in the metrics
+func (p RetryPolicy) transient(resp *http.Response, err error) (string, bool) {
+	if resp == nil {
+		return "connection", err != nil
+	}
+	for _, code := range p.Statuses {
+		if resp.StatusCode == code {
+			return "status_" + strconv.Itoa(code), true
+		}
+	}
+	return "", false
+}
+
+// backoff returns how long to wait before the next attempt. The backoff doubles for each attempt up to the maximum,
+// with the jitter fraction of it randomized. A longer Retry-After from the server is honoured.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && d > 0 {
+		jitterMutex.Lock()
+		d = time.Duration(float64(d) * (1 - p.Jitter + jitterRand.Float64()*p.Jitter))
+		jitterMutex.Unlock()
+	}
+
+	if p.HonourRetryAfter && resp != nil {
+		if ra, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && ra > d {
+			d = ra
+		}
+	}
+	return d
+}
+
+// take uses one retry from the budget, returning false if it has been exhausted
+func (b *RetryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date
+func parseRetryAfter(h string) (time.Duration, bool) {
+	if len(h) == 0 {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+func (p RetryPolicy) String() string {
+	return fmt.Sprintf("attempts=%d backoff=%v-%v jitter=%.2f methods=%v statuses=%v retry-after=%t",
+		p.MaxAttempts, p.BaseDelay, p.MaxDelay, p.Jitter, p.Methods, p.Statuses, p.HonourRetryAfter)
+}
+
+// The retry flags may be specified on the command, or globally
+func isSet(c *cli.Context, name string) bool {
+	return c.IsSet(name) || c.GlobalIsSet(name)
+}
+
+func intFlag(c *cli.Context, name string) int {
+	if c.IsSet(name) {
+		return c.Int(name)
+	}
+	return c.GlobalInt(name)
+}
+
+func durationFlag(c *cli.Context, name string) time.Duration {
+	if c.IsSet(name) {
+		return c.Duration(name)
+	}
+	return c.GlobalDuration(name)
+}
+
+func float64Flag(c *cli.Context, name string) float64 {
+	if c.IsSet(name) {
+		return c.Float64(name)
+	}
+	return c.GlobalFloat64(name)
+}
+
+func boolFlag(c *cli.Context, name string) bool {
+	if c.IsSet(name) {
+		return c.Bool(name)
+	}
+	return c.GlobalBool(name)
+}
+
+func stringSliceFlag(c *cli.Context, name string) []string {
+	if c.IsSet(name) {
+		return c.StringSlice(name)
+	}
+	return c.GlobalStringSlice(name)
+}
+
+func intSliceFlag(c *cli.Context, name string) []int {
+	if c.IsSet(name) {
+		return c.IntSlice(name)
+	}
+	return c.GlobalIntSlice(name)
+}
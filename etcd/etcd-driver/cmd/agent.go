@@ -0,0 +1,179 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// agentLeaseTTL is the TTL (in seconds) of an agent's registration
+const agentLeaseTTL = 30
+
+var (
+	agentCoordPrefix string
+	agentRunID       string
+	agentID          string
+)
+
+func init() {
+	patternCmd.Flags().StringVar(&agentCoordPrefix, "coordinator-prefix", "", "Run as an agent of the 'coordinate' command, which uses keys under this prefix. The pattern flags are taken from the coordinator")
+	patternCmd.Flags().StringVar(&agentRunID, "run-id", "default", "The run to join, when running as an agent")
+	patternCmd.Flags().StringVar(&agentID, "agent-id", "", "Identifies the agent to the coordinator (defaults to <ip>-<pid>)")
+}
+
+// coordinatedAgent is a pattern driver which is taking part in a coordinated run
+type coordinatedAgent struct {
+	kv      KV
+	keys    coordKeys
+	id      string
+	lease   LeaseID
+	plan    coordPlan
+	seq     int
+	stop    chan bool
+	started time.Time
+}
+
+// joinCoordinatedRun registers the agent with the coordinator, then waits for the plan and applies it to the pattern
+// flags. It returns nil if the driver isn't running as an agent.
+func joinCoordinatedRun() *coordinatedAgent {
+	if len(agentCoordPrefix) == 0 {
+		return nil
+	}
+	if len(agentID) == 0 {
+		agentID = fmt.Sprintf("%s-%d", getIP(), os.Getpid())
+	}
+
+	a := &coordinatedAgent{
+		kv:   mustCreateConn(false),
+		keys: newCoordKeys(agentCoordPrefix, agentRunID),
+		id:   agentID,
+		stop: make(chan bool),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	lease, err := a.kv.Grant(ctx, agentLeaseTTL)
+	cancel()
+	if err != nil {
+		log.Fatalf("Failed to get a lease for the agent registration: %v", err)
+	}
+	a.lease = lease
+	go a.keepAlive()
+
+	host, _ := os.Hostname()
+	if err := coordPut(a.kv, a.keys.agent(a.id), coordAgent{ID: a.id, Host: host, Registered: time.Now()}, a.lease); err != nil {
+		log.Fatalf("Failed to register with the coordinator: %v", err)
+	}
+	log.Printf("Registered as agent %s under %s, waiting for the test plan", a.id, a.keys.agents())
+
+	coordWait("the test plan", coordTimeout, func() (bool, error) {
+		kvs, err := coordGet(a.kv, a.keys.plan(), false)
+		if err != nil || len(kvs) == 0 {
+			return false, err
+		}
+		return true, json.Unmarshal(kvs[0].Value, &a.plan)
+	})
+
+	if _, ok := a.plan.Partitions[a.id]; !ok {
+		log.Fatalf("Agent %s isn't part of run %s", a.id, a.plan.RunID)
+	}
+	if err := patFlags.Parse(a.plan.Args); err != nil {
+		log.Fatalf("Invalid pattern flags in the test plan: %v", err)
+	}
+	patStatsInterval = a.plan.StatsInterval
+	patTestEndKey = a.plan.TestEndKey
+	patDoNotExit = false
+	if patLevelChurn < 0 && patValChurn < 0 && getRate < 0 && patWatchCountsPerLevel == "" {
+		log.Fatal("Error: a coordinated run must churn, get or watch")
+	}
+	log.Printf("Received the test plan for run %s: %v", a.plan.RunID, a.plan.Args)
+
+	return a
+}
+
+// keepAlive keeps the registration alive until the agent leaves the run
+func (a *coordinatedAgent) keepAlive() {
+	ticker := time.NewTicker(agentLeaseTTL * time.Second / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+			if _, err := a.kv.KeepAliveOnce(ctx, a.lease); err != nil {
+				log.Printf("Failed to renew the agent registration: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// keyPrefix returns the prefix of the agent's partition of the key space
+func (a *coordinatedAgent) keyPrefix() string {
+	return a.plan.Partitions[a.id].KeyPrefix
+}
+
+// waitForStart tells the coordinator the keys are loaded, then waits for the start barrier
+func (a *coordinatedAgent) waitForStart() {
+	if err := coordPut(a.kv, a.keys.agentReady(a.id), true, a.lease); err != nil {
+		log.Fatalf("Failed to tell the coordinator the agent is ready: %v", err)
+	}
+
+	var start coordStart
+	coordWait("the start barrier", coordTimeout, func() (bool, error) {
+		kvs, err := coordGet(a.kv, a.keys.start(), false)
+		if err != nil || len(kvs) == 0 {
+			return false, err
+		}
+		return true, json.Unmarshal(kvs[0].Value, &start)
+	})
+	log.Printf("Starting at %v", start.StartAt.Format(time.StampMilli))
+	time.Sleep(time.Until(start.StartAt))
+	a.started = time.Now()
+}
+
+// sendInterval sends the stats for an interval to the coordinator
+func (a *coordinatedAgent) sendInterval(interval time.Duration, stats churnStats) {
+	a.seq++
+	iv := coordInterval{Agent: a.id, Seq: a.seq, Interval: interval, KeySpace: keySpace, Watchers: treeWatchers, Stats: stats.message()}
+	if err := coordPut(a.kv, a.keys.agentStats(a.id, a.seq), iv, a.lease); err != nil {
+		log.Printf("Failed to send interval %d stats to the coordinator: %v", a.seq, err)
+	}
+}
+
+// leave sends the final stats to the coordinator, and removes the registration
+func (a *coordinatedAgent) leave(engine *PatternEngine, stats churnStats) {
+	final := coordFinal{
+		Agent:     a.id,
+		Duration:  time.Since(a.started),
+		KeySpace:  keySpace,
+		Watchers:  treeWatchers,
+		Stats:     stats.message(),
+		Latencies: engine.latencies,
+	}
+	// Not leased, so the coordinator still gets the stats if the registration expires
+	if err := coordPut(a.kv, a.keys.agentFinal(a.id), final, 0); err != nil {
+		log.Printf("Failed to send the final stats to the coordinator: %v", err)
+	}
+
+	close(a.stop)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	defer cancel()
+	if err := a.kv.Revoke(ctx, a.lease); err != nil {
+		log.Printf("Failed to remove the agent registration: %v", err)
+	}
+	a.kv.Close()
+}
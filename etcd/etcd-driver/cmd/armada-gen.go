@@ -12,7 +12,6 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
-	v3 "go.etcd.io/etcd/client/v3"
 )
 
 var armadaCmd = &cobra.Command{
@@ -55,7 +54,7 @@ func armadaGenFunc(cmd *cobra.Command, args []string) {
 	totalClients := uint(1)
 
 	clients := mustCreateClients(totalClients, totalConns)
-	requests := make(chan v3.Op, totalClients)
+	requests := make(chan Op, totalClients)
 
 	for i := range clients {
 		wg.Add(1)
@@ -0,0 +1,240 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Backend is a key/value store that the pattern engine can drive.
+// A backend hands out connections (KV), which are shared by clients in the same way as etcd gRPC connections.
+type Backend interface {
+	// Connect opens a new connection. If allEndpoints is false, the backend may choose a single endpoint (round-robin).
+	Connect(allEndpoints bool) (KV, error)
+	// Close releases any resources held by the backend itself
+	Close() error
+}
+
+// KV is a connection to a backend, covering the operations used by the etcd-driver tests
+type KV interface {
+	// Do performs a put, get (including ranges) or delete
+	Do(ctx context.Context, op Op) (OpResponse, error)
+	// Txn performs the then ops if all the comparisons succeed, otherwise the else ops, as a single transaction
+	Txn(ctx context.Context, cmps []Compare, then []Op, els []Op) (TxnResponse, error)
+	// NewWatcher creates an independent watch stream
+	NewWatcher() Watcher
+
+	Grant(ctx context.Context, ttl int64) (LeaseID, error)
+	KeepAliveOnce(ctx context.Context, id LeaseID) (ttl int64, err error)
+	Revoke(ctx context.Context, id LeaseID) error
+
+	Close() error
+}
+
+// Watcher is a watch stream, which may carry many watches
+type Watcher interface {
+	Watch(ctx context.Context, key string, prefix bool) WatchChan
+	// WatchFromRevision is Watch, starting at rev rather than the current revision
+	WatchFromRevision(ctx context.Context, key string, prefix bool, rev int64) WatchChan
+	Close() error
+}
+
+// LeaseID identifies a lease granted by a backend
+type LeaseID int64
+
+// OpType is the type of a key/value operation
+type OpType int
+
+// Key/value operation types
+const (
+	OpPut OpType = iota
+	OpGet
+	OpDelete
+)
+
+func (t OpType) String() string {
+	switch t {
+	case OpPut:
+		return "Put"
+	case OpGet:
+		return "Get"
+	case OpDelete:
+		return "Delete"
+	}
+	return "Other"
+}
+
+// Op is a backend independent key/value operation
+type Op struct {
+	Type  OpType
+	Key   string
+	Value string
+	Lease LeaseID // Put only
+
+	Prefix       bool   // Get and Delete all keys with the prefix Key
+	End          string // Get and Delete the range [Key, End)
+	Serializable bool   // Get only
+	KeysOnly     bool   // Get only
+	Limit        int64  // Get only - the maximum number of keys to return (0 is no limit)
+	Revision     int64  // Get only - read the keys as they were at this revision (0 is the current revision)
+	PrevKV       bool   // Delete only - return the deleted key/values
+}
+
+// OpPutKV creates a put operation
+func OpPutKV(key, value string) Op {
+	return Op{Type: OpPut, Key: key, Value: value}
+}
+
+// OpGetKV creates a get operation. If prefix is true, all keys with the prefix are returned.
+func OpGetKV(key string, prefix, serializable, keysOnly bool) Op {
+	return Op{Type: OpGet, Key: key, Prefix: prefix, Serializable: serializable, KeysOnly: keysOnly}
+}
+
+// OpRangeKV creates a get operation for the keys in the range [key, end)
+func OpRangeKV(key, end string) Op {
+	return Op{Type: OpGet, Key: key, End: end}
+}
+
+// OpDeleteKV creates a delete operation. If prefix is true, all keys with the prefix are deleted.
+func OpDeleteKV(key string, prefix bool) Op {
+	return Op{Type: OpDelete, Key: key, Prefix: prefix}
+}
+
+// KeyValue is a key/value pair returned by a get
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+
+	ModRevision int64 // The revision of the last put of the key
+}
+
+// OpResponse is the result of an operation
+type OpResponse struct {
+	Count   int64      // Get - the number of keys in the range, which may be more than len(Kvs) if keys only or limited
+	More    bool       // Get - there are more keys in the range than the limit
+	Kvs     []KeyValue // Get
+	Deleted int64      // Delete
+	PrevKvs []KeyValue // Delete with PrevKV
+
+	Revision int64 // The store revision after the operation
+}
+
+// Compare is a transaction condition, that the key was last put at ModRevision. A ModRevision of 0 is the
+// condition that the key doesn't exist.
+type Compare struct {
+	Key         string
+	ModRevision int64
+}
+
+// TxnResponse is the result of a transaction
+type TxnResponse struct {
+	Succeeded bool         // The comparisons succeeded, so the then ops were performed
+	Responses []OpResponse // The responses to the then or else ops
+	Revision  int64        // The store revision after the transaction
+}
+
+// EventType is the type of a watch event
+type EventType int
+
+// Watch event types
+const (
+	EventPut EventType = iota
+	EventDelete
+)
+
+func (t EventType) String() string {
+	if t == EventDelete {
+		return "DELETE"
+	}
+	return "PUT"
+}
+
+// Event is a watch event
+type Event struct {
+	Type  EventType
+	Key   []byte
+	Value []byte
+	Lease LeaseID // Put only
+
+	Revision int64 // The revision of the put or delete
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s : %s", e.Type, e.Key, e.Value)
+}
+
+// WatchResponse is a batch of watch events, or a watch error
+type WatchResponse struct {
+	Events   []Event
+	Canceled bool
+	Err      error
+
+	// CompactRevision is set when the watch was canceled because its start revision has been compacted.
+	// It's the earliest revision the watch can be restarted from.
+	CompactRevision int64
+}
+
+// WatchChan delivers watch responses. It is closed when the watch ends.
+type WatchChan <-chan WatchResponse
+
+// backendFactories holds the available backends, by name. Backends register themselves in init().
+var backendFactories = map[string]func() (Backend, error){}
+
+var (
+	backendName   string
+	activeBackend Backend
+)
+
+func registerBackend(name string, factory func() (Backend, error)) {
+	backendFactories[name] = factory
+}
+
+func backendNames() string {
+	names := make([]string, 0, len(backendFactories))
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// getBackend returns the backend selected by --backend, creating it on first use
+func getBackend() Backend {
+	if activeBackend == nil {
+		factory, ok := backendFactories[backendName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown backend %q, available backends: %s\n", backendName, backendNames())
+			os.Exit(1)
+		}
+		var err error
+		activeBackend, err = factory()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start %s backend: %v\n", backendName, err)
+			os.Exit(1)
+		}
+	}
+	return activeBackend
+}
+
+// closeBackend releases the backend, if one was created. For the embedded backend this stops the server.
+func closeBackend() {
+	if activeBackend != nil {
+		if err := activeBackend.Close(); err != nil {
+			log.Printf("Failed to close %s backend: %v", backendName, err)
+		}
+		activeBackend = nil
+	}
+}
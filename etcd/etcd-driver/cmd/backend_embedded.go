@@ -0,0 +1,133 @@
+//go:build embedetcd
+
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+// The embedded backend links in the etcd server, so is only built with the embedetcd tag:
+//   go build -tags embedetcd
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	v3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3client"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
+	"go.etcd.io/etcd/server/v3/proxy/grpcproxy/adapter"
+)
+
+const embeddedBackendName = "embedded"
+
+var (
+	embeddedDir        string
+	embeddedClientURL  string
+	embeddedPeerURL    string
+	embeddedStartLimit time.Duration
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&embeddedDir, "embedded-dir", "", "Data directory for the embedded etcd server (default is a temporary directory, removed on exit)")
+	RootCmd.PersistentFlags().StringVar(&embeddedClientURL, "embedded-client-url", "http://127.0.0.1:23790", "Client URL for the embedded etcd server")
+	RootCmd.PersistentFlags().StringVar(&embeddedPeerURL, "embedded-peer-url", "http://127.0.0.1:23800", "Peer URL for the embedded etcd server")
+	RootCmd.PersistentFlags().DurationVar(&embeddedStartLimit, "embedded-start-timeout", 60*time.Second, "Time to wait for the embedded etcd server to start")
+
+	addToFileExclude("embedded-dir")
+	addToFileExclude("embedded-client-url")
+	addToFileExclude("embedded-peer-url")
+	addToFileExclude("embedded-start-timeout")
+
+	registerBackend(embeddedBackendName, newEmbeddedBackend)
+}
+
+// embeddedBackend runs a single member etcd server in process. Connections use the server directly,
+// rather than gRPC over the network, so results only include client-side overhead and the server itself.
+type embeddedBackend struct {
+	etcd   *embed.Etcd
+	dir    string
+	tmpDir bool
+}
+
+func newEmbeddedBackend() (Backend, error) {
+	b := &embeddedBackend{dir: embeddedDir}
+	if len(b.dir) == 0 {
+		dir, err := ioutil.TempDir("", "etcd-driver-embedded")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %v", err)
+		}
+		b.dir = dir
+		b.tmpDir = true
+	}
+
+	clientURL, err := url.Parse(embeddedClientURL)
+	if err != nil {
+		b.removeDir()
+		return nil, fmt.Errorf("bad embedded-client-url: %v", err)
+	}
+	peerURL, err := url.Parse(embeddedPeerURL)
+	if err != nil {
+		b.removeDir()
+		return nil, fmt.Errorf("bad embedded-peer-url: %v", err)
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Dir = b.dir
+	cfg.LogLevel = "error"
+	cfg.LCUrls = []url.URL{*clientURL}
+	cfg.ACUrls = []url.URL{*clientURL}
+	cfg.LPUrls = []url.URL{*peerURL}
+	cfg.APUrls = []url.URL{*peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	b.etcd, err = embed.StartEtcd(cfg)
+	if err != nil {
+		b.removeDir()
+		return nil, err
+	}
+
+	select {
+	case <-b.etcd.Server.ReadyNotify():
+		log.Printf("Embedded etcd server started, data directory %s", b.dir)
+	case err = <-b.etcd.Err():
+		b.Close()
+		return nil, err
+	case <-time.After(embeddedStartLimit):
+		b.Close()
+		return nil, fmt.Errorf("embedded etcd server didn't start within %v", embeddedStartLimit)
+	}
+	return b, nil
+}
+
+func (b *embeddedBackend) Connect(allEndpoints bool) (KV, error) {
+	client := v3client.New(b.etcd.Server)
+	// The client has no gRPC connection to open watch streams on, so each watcher calls the server directly
+	newWatcher := func() v3.Watcher {
+		return v3.NewWatchFromWatchClient(adapter.WatchServerToWatchClient(v3rpc.NewWatchServer(b.etcd.Server)), client)
+	}
+	return &etcdKV{client: client, newWatcher: newWatcher}, nil
+}
+
+func (b *embeddedBackend) Close() error {
+	b.etcd.Close()
+	return b.removeDir()
+}
+
+func (b *embeddedBackend) removeDir() error {
+	if b.tmpDir {
+		return os.RemoveAll(b.dir)
+	}
+	return nil
+}
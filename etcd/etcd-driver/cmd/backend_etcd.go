@@ -0,0 +1,280 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"context"
+	cryptoTLS "crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+const etcdBackendName = "etcd"
+
+func init() {
+	registerBackend(etcdBackendName, func() (Backend, error) {
+		return &etcdBackend{}, nil
+	})
+}
+
+// etcdBackend connects to the etcd cluster specified by --endpoints
+type etcdBackend struct {
+	// dialTotal counts the number of connections so that endpoints
+	// can be handed out in round-robin order
+	dialTotal int
+}
+
+func (b *etcdBackend) Connect(allEndpoints bool) (KV, error) {
+	var theEndpoints []string
+	if !allEndpoints {
+		endpoint := endpoints[b.dialTotal%len(endpoints)]
+		b.dialTotal++
+		theEndpoints = []string{endpoint}
+	} else {
+		theEndpoints = endpoints
+	}
+	cfg := v3.Config{Endpoints: theEndpoints}
+
+	if !tls.Empty() && len(userNamePassword) == 0 {
+		cfgtls, err := tls.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("bad tls config: %v", err)
+		}
+		cfg.TLS = cfgtls
+	} else if len(userNamePassword) > 0 {
+		split := strings.Split(userNamePassword, ":")
+		if len(split) != 2 {
+			return nil, fmt.Errorf("bad username:password parameter")
+		}
+		cfg.Username = split[0]
+		cfg.Password = split[1] // pragma: allowlist secret
+
+		caPath := tls.TrustedCAFile
+		if caPath != "" {
+			// #nosec G304
+			caCert, err := ioutil.ReadFile(caPath)
+			if err != nil {
+				log.Fatalf("Error occurred reading file: %s", err.Error())
+			}
+			caCertPool := x509.NewCertPool()
+			caCertPool.AppendCertsFromPEM(caCert)
+			tlsConfig := &cryptoTLS.Config{
+				RootCAs: caCertPool,
+			}
+			cfg.TLS = tlsConfig
+		}
+	}
+
+	client, err := v3.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("dial error: %v", err)
+	}
+	return &etcdKV{client: client}, nil
+}
+
+func (b *etcdBackend) Close() error {
+	return nil
+}
+
+// etcdKV implements KV using the etcd v3 client. It is also used by the embedded backend.
+type etcdKV struct {
+	client *v3.Client
+	// newWatcher creates a watch stream, for clients without a gRPC connection. By default a stream is opened on the connection.
+	newWatcher func() v3.Watcher
+}
+
+func (kv *etcdKV) Do(ctx context.Context, op Op) (OpResponse, error) {
+	var resp OpResponse
+	r, err := kv.client.Do(ctx, toEtcdOp(op))
+	if err != nil {
+		return resp, err
+	}
+
+	if g := r.Get(); g != nil {
+		resp = fromEtcdGet(g)
+	} else if d := r.Del(); d != nil {
+		resp = fromEtcdDelete(d)
+	} else if p := r.Put(); p != nil {
+		resp.Revision = p.Header.Revision
+	}
+	return resp, nil
+}
+
+func fromEtcdGet(g *v3.GetResponse) OpResponse {
+	resp := OpResponse{Count: g.Count, More: g.More, Revision: g.Header.Revision}
+	resp.Kvs = make([]KeyValue, len(g.Kvs))
+	for i, ekv := range g.Kvs {
+		resp.Kvs[i] = KeyValue{Key: ekv.Key, Value: ekv.Value, ModRevision: ekv.ModRevision}
+	}
+	return resp
+}
+
+func fromEtcdDelete(d *v3.DeleteResponse) OpResponse {
+	resp := OpResponse{Deleted: d.Deleted, Revision: d.Header.Revision}
+	if len(d.PrevKvs) > 0 {
+		resp.PrevKvs = make([]KeyValue, len(d.PrevKvs))
+		for i, ekv := range d.PrevKvs {
+			resp.PrevKvs[i] = KeyValue{Key: ekv.Key, Value: ekv.Value, ModRevision: ekv.ModRevision}
+		}
+	}
+	return resp
+}
+
+func (kv *etcdKV) Txn(ctx context.Context, cmps []Compare, then []Op, els []Op) (TxnResponse, error) {
+	ecmps := make([]v3.Cmp, len(cmps))
+	for i, c := range cmps {
+		ecmps[i] = v3.Compare(v3.ModRevision(c.Key), "=", c.ModRevision)
+	}
+	ethen := make([]v3.Op, len(then))
+	for i, op := range then {
+		ethen[i] = toEtcdOp(op)
+	}
+	eels := make([]v3.Op, len(els))
+	for i, op := range els {
+		eels[i] = toEtcdOp(op)
+	}
+
+	var resp TxnResponse
+	r, err := kv.client.Txn(ctx).If(ecmps...).Then(ethen...).Else(eels...).Commit()
+	if err != nil {
+		return resp, err
+	}
+	resp.Succeeded = r.Succeeded
+	resp.Revision = r.Header.Revision
+	resp.Responses = make([]OpResponse, len(r.Responses))
+	for i, er := range r.Responses {
+		if g := er.GetResponseRange(); g != nil {
+			resp.Responses[i] = fromEtcdGet((*v3.GetResponse)(g))
+		} else if d := er.GetResponseDeleteRange(); d != nil {
+			resp.Responses[i] = fromEtcdDelete((*v3.DeleteResponse)(d))
+		} else if p := er.GetResponsePut(); p != nil {
+			resp.Responses[i].Revision = p.Header.Revision
+		}
+	}
+	return resp, nil
+}
+
+func toEtcdOp(op Op) v3.Op {
+	var opts []v3.OpOption
+	if op.Prefix {
+		opts = append(opts, v3.WithPrefix())
+	} else if len(op.End) > 0 {
+		opts = append(opts, v3.WithRange(op.End))
+	}
+
+	switch op.Type {
+	case OpPut:
+		if op.Lease != 0 {
+			return v3.OpPut(op.Key, op.Value, v3.WithLease(v3.LeaseID(op.Lease)))
+		}
+		return v3.OpPut(op.Key, op.Value)
+	case OpDelete:
+		if op.PrevKV {
+			opts = append(opts, v3.WithPrevKV())
+		}
+		return v3.OpDelete(op.Key, opts...)
+	default:
+		if op.Limit > 0 {
+			opts = append(opts, v3.WithLimit(op.Limit))
+		}
+		if op.Revision > 0 {
+			opts = append(opts, v3.WithRev(op.Revision))
+		}
+		if op.Serializable {
+			opts = append(opts, v3.WithSerializable())
+		}
+		if op.KeysOnly {
+			opts = append(opts, v3.WithKeysOnly())
+		}
+		return v3.OpGet(op.Key, opts...)
+	}
+}
+
+func (kv *etcdKV) NewWatcher() Watcher {
+	if kv.newWatcher != nil {
+		return &etcdWatcher{watcher: kv.newWatcher()}
+	}
+	return &etcdWatcher{watcher: v3.NewWatcher(kv.client)}
+}
+
+func (kv *etcdKV) Grant(ctx context.Context, ttl int64) (LeaseID, error) {
+	resp, err := kv.client.Grant(ctx, ttl)
+	if err != nil {
+		return 0, err
+	}
+	return LeaseID(resp.ID), nil
+}
+
+func (kv *etcdKV) KeepAliveOnce(ctx context.Context, id LeaseID) (int64, error) {
+	resp, err := kv.client.KeepAliveOnce(ctx, v3.LeaseID(id))
+	if err != nil {
+		return 0, err
+	}
+	return resp.TTL, nil
+}
+
+func (kv *etcdKV) Revoke(ctx context.Context, id LeaseID) error {
+	_, err := kv.client.Revoke(ctx, v3.LeaseID(id))
+	return err
+}
+
+func (kv *etcdKV) Close() error {
+	return kv.client.Close()
+}
+
+type etcdWatcher struct {
+	watcher v3.Watcher
+}
+
+func (w *etcdWatcher) Watch(ctx context.Context, key string, prefix bool) WatchChan {
+	return w.WatchFromRevision(ctx, key, prefix, 0)
+}
+
+func (w *etcdWatcher) WatchFromRevision(ctx context.Context, key string, prefix bool, rev int64) WatchChan {
+	var opts []v3.OpOption
+	if prefix {
+		opts = append(opts, v3.WithPrefix())
+	}
+	if rev > 0 {
+		opts = append(opts, v3.WithRev(rev))
+	}
+	wch := w.watcher.Watch(ctx, key, opts...)
+
+	ch := make(chan WatchResponse)
+	go func() {
+		defer close(ch)
+		for wresp := range wch {
+			resp := WatchResponse{Canceled: wresp.Canceled, Err: wresp.Err(), CompactRevision: wresp.CompactRevision}
+			resp.Events = make([]Event, len(wresp.Events))
+			for i, ev := range wresp.Events {
+				resp.Events[i] = Event{Key: ev.Kv.Key, Value: ev.Kv.Value, Lease: LeaseID(ev.Kv.Lease), Revision: ev.Kv.ModRevision}
+				if ev.Type == v3.EventTypeDelete {
+					resp.Events[i].Type = EventDelete
+				}
+			}
+			select {
+			case ch <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (w *etcdWatcher) Close() error {
+	return w.watcher.Close()
+}
@@ -0,0 +1,527 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
+)
+
+var coordinateCmd = &cobra.Command{
+	Use:   "coordinate [flags] -- [pattern flags]",
+	Short: "Coordinate a test run by several pattern drivers",
+	Long: "Wait for 'pattern --coordinator-prefix' agents to register, send them the pattern flags with a partition of the key space each, " +
+		"start them together, and merge their stats into a single report",
+
+	Run: coordinateCmdFunc,
+}
+
+var (
+	coordPrefix        string
+	coordRunID         string
+	coordAgents        int
+	coordDuration      time.Duration
+	coordStartDelay    time.Duration
+	coordStatsInterval int
+	coordPartitionFmt  string
+	coordTimeout       time.Duration
+)
+
+func init() {
+	RootCmd.AddCommand(coordinateCmd)
+	coordinateCmd.Flags().StringVar(&coordPrefix, "coordinator-prefix", "/etcd-driver/coordinator", "Prefix of the keys used to coordinate the agents")
+	coordinateCmd.Flags().StringVar(&coordRunID, "run-id", "default", "Identifies the run, so that several runs can be coordinated at once. The agents must use the same run id")
+	coordinateCmd.Flags().IntVar(&coordAgents, "agents", 0, "Number of agents to wait for (required)")
+	coordinateCmd.Flags().DurationVar(&coordDuration, "duration", 0, "How long the agents run the test for, once started (required)")
+	coordinateCmd.Flags().DurationVar(&coordStartDelay, "start-delay", 5*time.Second, "Delay between releasing the start barrier and the agents starting, so that they all see it in time")
+	coordinateCmd.Flags().IntVar(&coordStatsInterval, "stats-interval", 10, "The interval at which the agents send stats (in seconds)")
+	coordinateCmd.Flags().StringVar(&coordPartitionFmt, "partition-prefix", "/agent-%03d", "Format of the key prefix which gives each agent its own partition of the key space. It is passed the agent index")
+	coordinateCmd.Flags().DurationVar(&coordTimeout, "coordinator-timeout", 10*time.Minute, "Maximum time to wait for the agents at each stage of the run")
+
+	addToFileExclude("coordinator-prefix")
+	addToFileExclude("run-id")
+	addToFileExclude("coordinator-timeout")
+	addToFileExclude("agent-id")
+}
+
+// coordKeys are the keys used to coordinate a run. They are all under <coordinator-prefix>/<run-id>.
+type coordKeys string
+
+func newCoordKeys(prefix string, runID string) coordKeys {
+	return coordKeys(strings.TrimSuffix(prefix, "/") + "/" + runID)
+}
+
+func (k coordKeys) run() string                 { return string(k) + "/" }
+func (k coordKeys) agents() string              { return string(k) + "/agents/" }
+func (k coordKeys) agent(id string) string      { return k.agents() + id }
+func (k coordKeys) plan() string                { return string(k) + "/plan" }
+func (k coordKeys) ready() string               { return string(k) + "/ready/" }
+func (k coordKeys) agentReady(id string) string { return k.ready() + id }
+func (k coordKeys) start() string               { return string(k) + "/start" }
+func (k coordKeys) stats() string               { return string(k) + "/stats/" }
+func (k coordKeys) final() string               { return string(k) + "/final/" }
+func (k coordKeys) agentFinal(id string) string { return k.final() + id }
+func (k coordKeys) testEnd() string             { return string(k) + "/end" }
+
+func (k coordKeys) agentStats(id string, seq int) string {
+	return fmt.Sprintf("%s%s/%06d", k.stats(), id, seq)
+}
+
+// coordAgent is an agent's registration. It is put with a lease, so that it disappears if the agent dies.
+type coordAgent struct {
+	ID         string    `json:"id"`
+	Host       string    `json:"host"`
+	Registered time.Time `json:"registered"`
+}
+
+// coordPartition is the part of the key space an agent generates keys in: [KeyPrefix, RangeEnd)
+type coordPartition struct {
+	Index     int    `json:"index"`
+	KeyPrefix string `json:"keyPrefix"`
+	RangeEnd  string `json:"rangeEnd"`
+}
+
+// coordPlan is the test plan shared by the agents
+type coordPlan struct {
+	RunID         string                    `json:"runId"`
+	Args          []string                  `json:"args"`
+	StatsInterval int                       `json:"statsInterval"`
+	TestEndKey    string                    `json:"testEndKey"`
+	Partitions    map[string]coordPartition `json:"partitions"`
+}
+
+// coordStart releases the start barrier. The agents start at StartAt.
+type coordStart struct {
+	StartAt time.Time `json:"startAt"`
+}
+
+// coordInterval is the stats for one interval of an agent's run
+type coordInterval struct {
+	Agent    string        `json:"agent"`
+	Seq      int           `json:"seq"`
+	Interval time.Duration `json:"interval"`
+	KeySpace int           `json:"keySpace"`
+	Watchers int           `json:"watchers"`
+	Stats    statsMessage  `json:"stats"`
+}
+
+// coordFinal is the stats for the whole of an agent's run
+type coordFinal struct {
+	Agent     string        `json:"agent"`
+	Duration  time.Duration `json:"duration"`
+	KeySpace  int           `json:"keySpace"`
+	Watchers  int           `json:"watchers"`
+	Stats     statsMessage  `json:"stats"`
+	Latencies opLatencies   `json:"latencies"`
+}
+
+// statsMessage is churnStats in a form which can be sent between drivers
+type statsMessage struct {
+	KeysPut              uint32 `json:"keysPut"`
+	KeysPutTotTime       int64  `json:"keysPutTotTime"`
+	KeysPutMinTime       int64  `json:"keysPutMinTime"`
+	KeysPutMaxTime       int64  `json:"keysPutMaxTime"`
+	BytesPut             int64  `json:"bytesPut"`
+	KeysDel              uint32 `json:"keysDel"`
+	ClientDels           uint32 `json:"clientDels"`
+	KeysDelTotTime       int64  `json:"keysDelTotTime"`
+	KeysDelMinTime       int64  `json:"keysDelMinTime"`
+	KeysDelMaxTime       int64  `json:"keysDelMaxTime"`
+	KeysGet              uint32 `json:"keysGet"`
+	ClientGets           uint32 `json:"clientGets"`
+	KeysGetTotTime       int64  `json:"keysGetTotTime"`
+	KeysGetMinTime       int64  `json:"keysGetMinTime"`
+	KeysGetMaxTime       int64  `json:"keysGetMaxTime"`
+	BytesGet             int64  `json:"bytesGet"`
+	Errors               uint32 `json:"errors"`
+	Reconnects           uint32 `json:"reconnects"`
+	ReconnectsTotTime    int64  `json:"reconnectsTotTime"`
+	ReconnectsMinTime    int64  `json:"reconnectsMinTime"`
+	ReconnectsMaxTime    int64  `json:"reconnectsMaxTime"`
+	WatchEventCount      int64  `json:"watchEventCount"`
+	KeysPrefixGet        uint32 `json:"keysPrefixGet"`
+	ClientPrefixGets     uint32 `json:"clientPrefixGets"`
+	KeysPrefixGetTotTime int64  `json:"keysPrefixGetTotTime"`
+	KeysPrefixGetMinTime int64  `json:"keysPrefixGetMinTime"`
+	KeysPrefixGetMaxTime int64  `json:"keysPrefixGetMaxTime"`
+	BytesPrefixGet       int64  `json:"bytesPrefixGet"`
+}
+
+func (e churnStats) message() statsMessage {
+	return statsMessage{
+		KeysPut: e.keysPut, KeysPutTotTime: e.keysPutTotTime, KeysPutMinTime: e.keysPutMinTime, KeysPutMaxTime: e.keysPutMaxTime, BytesPut: e.bytesPut,
+		KeysDel: e.keysDel, ClientDels: e.clientDels, KeysDelTotTime: e.keysDelTotTime, KeysDelMinTime: e.keysDelMinTime, KeysDelMaxTime: e.keysDelMaxTime,
+		KeysGet: e.keysGet, ClientGets: e.clientGets, KeysGetTotTime: e.keysGetTotTime, KeysGetMinTime: e.keysGetMinTime, KeysGetMaxTime: e.keysGetMaxTime, BytesGet: e.bytesGet,
+		Errors:     e.errors,
+		Reconnects: e.reconnects, ReconnectsTotTime: e.reconnectsTotTime, ReconnectsMinTime: e.reconnectsMinTime, ReconnectsMaxTime: e.reconnectsMaxTime,
+		WatchEventCount: e.watchEventCount,
+		KeysPrefixGet:   e.keysPrefixGet, ClientPrefixGets: e.clientPrefixGets, KeysPrefixGetTotTime: e.keysPrefixGetTotTime, KeysPrefixGetMinTime: e.keysPrefixGetMinTime, KeysPrefixGetMaxTime: e.keysPrefixGetMaxTime, BytesPrefixGet: e.bytesPrefixGet,
+	}
+}
+
+func (m statsMessage) churnStats() churnStats {
+	return churnStats{
+		keysPut: m.KeysPut, keysPutTotTime: m.KeysPutTotTime, keysPutMinTime: m.KeysPutMinTime, keysPutMaxTime: m.KeysPutMaxTime, bytesPut: m.BytesPut,
+		keysDel: m.KeysDel, clientDels: m.ClientDels, keysDelTotTime: m.KeysDelTotTime, keysDelMinTime: m.KeysDelMinTime, keysDelMaxTime: m.KeysDelMaxTime,
+		keysGet: m.KeysGet, clientGets: m.ClientGets, keysGetTotTime: m.KeysGetTotTime, keysGetMinTime: m.KeysGetMinTime, keysGetMaxTime: m.KeysGetMaxTime, bytesGet: m.BytesGet,
+		errors:     m.Errors,
+		reconnects: m.Reconnects, reconnectsTotTime: m.ReconnectsTotTime, reconnectsMinTime: m.ReconnectsMinTime, reconnectsMaxTime: m.ReconnectsMaxTime,
+		watchEventCount: m.WatchEventCount,
+		keysPrefixGet:   m.KeysPrefixGet, clientPrefixGets: m.ClientPrefixGets, keysPrefixGetTotTime: m.KeysPrefixGetTotTime, keysPrefixGetMinTime: m.KeysPrefixGetMinTime, keysPrefixGetMaxTime: m.KeysPrefixGetMaxTime, bytesPrefixGet: m.BytesPrefixGet,
+	}
+}
+
+// prefixRangeEnd returns the end of the range of keys with the prefix
+func prefixRangeEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// The prefix is all 0xff, so the range is every key from the prefix onwards
+	return "\x00"
+}
+
+func coordPut(kv KV, key string, v interface{}, lease LeaseID) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	op := OpPutKV(key, string(data))
+	op.Lease = lease
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	defer cancel()
+	_, err = kv.Do(ctx, op)
+	return err
+}
+
+// coordGet gets the json values of a key, or all the keys with a prefix
+func coordGet(kv KV, key string, prefix bool) ([]KeyValue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	defer cancel()
+	resp, err := kv.Do(ctx, OpGetKV(key, prefix, false, false))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Kvs, nil
+}
+
+func coordDelete(kv KV, key string, prefix bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	defer cancel()
+	_, err := kv.Do(ctx, OpDeleteKV(key, prefix))
+	return err
+}
+
+// coordWait polls until done returns true. Errors are logged and retried, as the etcd under test may be struggling.
+func coordWait(what string, timeout time.Duration, done func() (bool, error)) {
+	deadline := time.Now().Add(timeout)
+	lastLog := time.Time{}
+	for {
+		ok, err := done()
+		if err != nil {
+			log.Printf("Error while waiting for %s: %v", what, err)
+		} else if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Fatalf("Timed out after %v waiting for %s", timeout, what)
+		}
+		if time.Since(lastLog) > 30*time.Second {
+			log.Printf("Waiting for %s", what)
+			lastLog = time.Now()
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// coordWaitForAll waits until there is a key under the prefix for each of the agents
+func coordWaitForAll(kv KV, what string, prefix string, agents []string) map[string][]byte {
+	values := make(map[string][]byte)
+	coordWait(what, coordTimeout, func() (bool, error) {
+		kvs, err := coordGet(kv, prefix, true)
+		if err != nil {
+			return false, err
+		}
+		for _, item := range kvs {
+			values[strings.TrimPrefix(string(item.Key), prefix)] = item.Value
+		}
+		for _, id := range agents {
+			if _, ok := values[id]; !ok {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	return values
+}
+
+// coordIntervals merges the interval stats from the agents. An interval is reported once every agent has sent it.
+type coordIntervals struct {
+	mutex     sync.Mutex
+	agents    int
+	received  map[int]map[string]coordInterval
+	reported  int
+	statKeys  []string
+	stats     map[string]string
+	startTime time.Time
+}
+
+func (ci *coordIntervals) add(iv coordInterval) {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	if iv.Seq <= ci.reported {
+		return
+	}
+	if ci.received[iv.Seq] == nil {
+		ci.received[iv.Seq] = make(map[string]coordInterval)
+	}
+	ci.received[iv.Seq][iv.Agent] = iv
+
+	// Report the intervals in order, as they complete
+	for {
+		next := ci.received[ci.reported+1]
+		if len(next) < ci.agents {
+			return
+		}
+		var merged churnStats
+		var interval time.Duration
+		keySpace = 0
+		treeWatchers = 0
+		for _, a := range next {
+			merged.merge(a.Stats.churnStats())
+			if a.Interval > interval {
+				interval = a.Interval
+			}
+			keySpace += a.KeySpace
+			treeWatchers += a.Watchers
+		}
+		ci.reported++
+		delete(ci.received, ci.reported)
+		intervalStart := ci.startTime.Add(time.Duration(ci.reported-1) * time.Duration(coordStatsInterval) * time.Second)
+		patPrintStats(nil, intervalStart, interval, ci.stats, ci.statKeys, merged, "pattern-coordinated-interval")
+	}
+}
+
+// setupCoordinatedStatsKeys adds the latency percentiles to the pattern stats
+func setupCoordinatedStatsKeys() ([]string, map[string]string) {
+	statKeys, stats := setupPatternStatsKeys()
+	for _, op := range []string{"Puts", "Dels", "Gets", "Prefix gets"} {
+		for _, p := range histogram.Percentiles {
+			statKeys = append(statKeys, fmt.Sprintf("%s %s RT(μs)", op, histogram.PercentileName(p)))
+		}
+	}
+	// writeFile sizes the csv columns from the stats, so they must all be present
+	for _, k := range statKeys {
+		stats[k] = ""
+	}
+	return statKeys, stats
+}
+
+func coordinateCmdFunc(cmd *cobra.Command, args []string) {
+
+	setupProfiling()
+	setupCsvFile()
+
+	if coordAgents <= 0 {
+		log.Fatal("Error: agents is required")
+	}
+	if coordDuration <= 0 {
+		log.Fatal("Error: duration is required")
+	}
+	if coordStatsInterval <= 0 {
+		log.Fatal("Error: stats-interval must be greater than 0")
+	}
+
+	// Check the pattern flags before sending them to the agents. They're also written to the csv file with the merged stats.
+	if err := patFlags.Parse(args); err != nil {
+		log.Fatalf("Error: invalid pattern flags: %v", err)
+	}
+	if len(patPattern) == 0 {
+		log.Fatal("Error: the pattern flags must include --pattern")
+	}
+
+	keys := newCoordKeys(coordPrefix, coordRunID)
+	kv := mustCreateConn(false)
+	defer kv.Close()
+
+	// Clear out any state from a previous run with the same id. The agents may already have registered.
+	for _, stale := range []string{keys.plan(), keys.start(), keys.testEnd()} {
+		if err := coordDelete(kv, stale, false); err != nil {
+			log.Fatalf("Failed to clear previous run: %v", err)
+		}
+	}
+	for _, stale := range []string{keys.ready(), keys.stats(), keys.final()} {
+		if err := coordDelete(kv, stale, true); err != nil {
+			log.Fatalf("Failed to clear previous run: %v", err)
+		}
+	}
+
+	// Wait for the agents to register
+	log.Printf("Waiting for %d agents to register under %s", coordAgents, keys.agents())
+	var agents []string
+	coordWait(fmt.Sprintf("%d agents to register", coordAgents), coordTimeout, func() (bool, error) {
+		kvs, err := coordGet(kv, keys.agents(), true)
+		if err != nil {
+			return false, err
+		}
+		agents = agents[:0]
+		for _, item := range kvs {
+			agents = append(agents, strings.TrimPrefix(string(item.Key), keys.agents()))
+		}
+		return len(agents) >= coordAgents, nil
+	})
+	sort.Strings(agents)
+	if len(agents) > coordAgents {
+		log.Printf("WARNING: %d agents registered, only the first %d will be used: %v", len(agents), coordAgents, agents[coordAgents:])
+		agents = agents[:coordAgents]
+	}
+
+	// Send the plan, giving each agent its own partition of the key space
+	plan := coordPlan{
+		RunID:         coordRunID,
+		Args:          args,
+		StatsInterval: coordStatsInterval,
+		TestEndKey:    keys.testEnd(),
+		Partitions:    make(map[string]coordPartition),
+	}
+	for i, id := range agents {
+		keyPrefix := fmt.Sprintf(coordPartitionFmt, i)
+		plan.Partitions[id] = coordPartition{Index: i, KeyPrefix: keyPrefix, RangeEnd: prefixRangeEnd(keyPrefix + "/")}
+		log.Printf("Agent %s: keys [%s/, %q)", id, keyPrefix, plan.Partitions[id].RangeEnd)
+	}
+	if err := coordPut(kv, keys.plan(), plan, 0); err != nil {
+		log.Fatalf("Failed to send the test plan: %v", err)
+	}
+
+	// Wait for the agents to load their keys, then release the start barrier
+	coordWaitForAll(kv, "the agents to be ready", keys.ready(), agents)
+	start := coordStart{StartAt: time.Now().Add(coordStartDelay)}
+	statKeys, stats := setupCoordinatedStatsKeys()
+	intervals := &coordIntervals{
+		agents:    len(agents),
+		received:  make(map[int]map[string]coordInterval),
+		statKeys:  statKeys,
+		stats:     stats,
+		startTime: start.StartAt,
+	}
+
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	watcher := kv.NewWatcher()
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		for wresp := range watcher.Watch(watchCtx, keys.stats(), true) {
+			for _, ev := range wresp.Events {
+				if ev.Type != EventPut {
+					continue
+				}
+				var iv coordInterval
+				if err := json.Unmarshal(ev.Value, &iv); err != nil {
+					log.Printf("Ignoring invalid stats from %s: %v", ev.Key, err)
+					continue
+				}
+				intervals.add(iv)
+			}
+		}
+	}()
+
+	if err := coordPut(kv, keys.start(), start, 0); err != nil {
+		log.Fatalf("Failed to start the agents: %v", err)
+	}
+	log.Printf("%d agents will start at %v, and run for %v", len(agents), start.StartAt.Format(time.StampMilli), coordDuration)
+
+	// Run the test, then signal the end
+	time.Sleep(time.Until(start.StartAt.Add(coordDuration)))
+	if err := coordPut(kv, keys.testEnd(), true, 0); err != nil {
+		log.Fatalf("Failed to end the test: %v", err)
+	}
+	log.Print("Test end sent to the agents")
+
+	finals := coordWaitForAll(kv, "the agents' final stats", keys.final(), agents)
+	cancelWatch()
+	watcher.Close()
+	// Wait for the interval stats to finish, as they share the key space and watcher counts with the final stats
+	<-watchDone
+
+	// Merge the final stats
+	var merged churnStats
+	latencies := newOpLatencies()
+	var totalTime time.Duration
+	keySpace = 0
+	treeWatchers = 0
+	for _, id := range agents {
+		var f coordFinal
+		if err := json.Unmarshal(finals[id], &f); err != nil {
+			log.Fatalf("Invalid final stats from %s: %v", id, err)
+		}
+		merged.merge(f.Stats.churnStats())
+		if err := latencies.merge(f.Latencies); err != nil {
+			log.Printf("Failed to merge latencies from %s: %v", id, err)
+		}
+		if f.Duration > totalTime {
+			totalTime = f.Duration
+		}
+		keySpace += f.KeySpace
+		treeWatchers += f.Watchers
+	}
+
+	fmt.Printf("\nCoordinated run %s, %d agents:\n", coordRunID, len(agents))
+	printLatencyPercentiles(latencies)
+	addLatencyPercentiles(stats, latencies)
+	patPrintStats(nil, start.StartAt, totalTime, stats, statKeys, merged, "pattern-coordinated-summary")
+
+	if err := coordDelete(kv, keys.run(), true); err != nil {
+		log.Printf("Failed to clean up the coordination keys: %v", err)
+	}
+}
+
+// latencyHistograms returns the latencies in the same order as the stats keys
+func latencyHistograms(l opLatencies) []*histogram.Histogram {
+	return []*histogram.Histogram{l.Puts, l.Deletes, l.Gets, l.PrefixGets}
+}
+
+func printLatencyPercentiles(l opLatencies) {
+	names := []string{"Puts", "Deletes", "Gets", "Prefix gets"}
+	for i, h := range latencyHistograms(l) {
+		if h.TotalCount() == 0 {
+			continue
+		}
+		fmt.Printf("  %s latency:", names[i])
+		for _, p := range histogram.Percentiles {
+			fmt.Printf("  %s %4.4f secs.", histogram.PercentileName(p), h.Percentile(p).Seconds())
+		}
+		fmt.Println()
+	}
+}
+
+func addLatencyPercentiles(stats map[string]string, l opLatencies) {
+	names := []string{"Puts", "Dels", "Gets", "Prefix gets"}
+	for i, h := range latencyHistograms(l) {
+		for _, p := range histogram.Percentiles {
+			key := fmt.Sprintf("%s %s RT(μs)", names[i], histogram.PercentileName(p))
+			stats[key] = strconv.FormatInt(h.Percentile(p).Microseconds(), 10)
+		}
+	}
+}
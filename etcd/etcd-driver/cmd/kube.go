@@ -0,0 +1,683 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var kubeCmd = &cobra.Command{
+	Use:   "kube",
+	Short: "Generate a kube-apiserver style load",
+	Long: "Read and write objects the way the kube-apiserver does. Creates, updates and deletes are transactions which compare the mod revision " +
+		"and retry on conflict, lists are paginated, and watches start from the revision of a list",
+
+	Run: kubeCmdFunc,
+}
+
+var (
+	kubeResources  []string
+	kubeNamespaces int
+	kubeMix        string
+	kubeDuration   time.Duration
+	kubeRate       int
+	kubePageSize   int64
+	kubeMaxRetries int
+	kubeInitialPct int
+	kubeWatch      bool
+	kubeKeyPrefix  string
+	kubeSample     bool
+)
+
+// The verbs, in the order they're reported
+const (
+	kubeCreate = "create"
+	kubeGet    = "get"
+	kubeList   = "list"
+	kubeUpdate = "update"
+	kubeDelete = "delete"
+)
+
+var kubeVerbNames = []string{kubeCreate, kubeGet, kubeList, kubeUpdate, kubeDelete}
+
+func init() {
+	RootCmd.AddCommand(kubeCmd)
+	kubeCmd.Flags().StringSliceVar(&kubeResources, "resources", []string{"pods:1000:2048-8192:50", "configmaps:200:4096:20", "leases:100:256:30"},
+		"Resource types, as name:objects:size:weight. The size of an object is in bytes, and can be a range min-max. The weight is the share of the requests for the resource type")
+	kubeCmd.Flags().IntVar(&kubeNamespaces, "namespaces", 10, "Number of namespaces the objects are spread across")
+	kubeCmd.Flags().StringVar(&kubeMix, "mix", "create=5,get=30,list=5,update=55,delete=5", "The share of the requests for each verb")
+	kubeCmd.Flags().DurationVar(&kubeDuration, "duration", time.Minute, "How long to generate load for")
+	kubeCmd.Flags().IntVar(&kubeRate, "rate", 0, "Total requests per second (0 is no limit)")
+	kubeCmd.Flags().Int64Var(&kubePageSize, "page-size", 500, "Maximum number of objects returned by each request of a list (0 is no limit)")
+	kubeCmd.Flags().IntVar(&kubeMaxRetries, "max-retries", 5, "Maximum number of times an update or delete is retried after a conflict")
+	kubeCmd.Flags().IntVar(&kubeInitialPct, "initial-pct", 50, "% of each resource type's objects created before the load starts")
+	kubeCmd.Flags().BoolVar(&kubeWatch, "watch", true, "List and watch each resource type, like an informer. The watch events keep the object revisions used for updates up to date")
+	kubeCmd.Flags().StringVar(&kubeKeyPrefix, "key-prefix", "/registry", "Prefix of the object keys")
+	kubeCmd.Flags().BoolVar(&kubeSample, "sample", false, "Print the average latency and throughput for each second")
+}
+
+// kubeResource is a resource type, and the objects of that type. The object revisions are the driver's view of the
+// objects, like the kube-apiserver's watch cache, so updates can conflict when the view is out of date.
+type kubeResource struct {
+	name    string
+	objects int
+	minSize int
+	maxSize int
+	weight  int
+	prefix  string
+
+	mutex sync.Mutex
+	revs  []int64 // The mod revision of each object, 0 if it doesn't exist
+	index map[string]int
+}
+
+func parseKubeResource(spec string) (*kubeResource, error) {
+	fields := strings.Split(spec, ":")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("resource %q isn't name:objects:size:weight", spec)
+	}
+	r := &kubeResource{name: fields[0], prefix: kubeKeyPrefix + "/" + fields[0] + "/"}
+
+	var err error
+	if r.objects, err = strconv.Atoi(fields[1]); err != nil || r.objects <= 0 {
+		return nil, fmt.Errorf("resource %q has an invalid number of objects", spec)
+	}
+	sizes := strings.SplitN(fields[2], "-", 2)
+	if r.minSize, err = strconv.Atoi(sizes[0]); err != nil || r.minSize < 0 {
+		return nil, fmt.Errorf("resource %q has an invalid size", spec)
+	}
+	r.maxSize = r.minSize
+	if len(sizes) > 1 {
+		if r.maxSize, err = strconv.Atoi(sizes[1]); err != nil || r.maxSize < r.minSize {
+			return nil, fmt.Errorf("resource %q has an invalid size", spec)
+		}
+	}
+	if r.weight, err = strconv.Atoi(fields[3]); err != nil || r.weight < 0 {
+		return nil, fmt.Errorf("resource %q has an invalid weight", spec)
+	}
+
+	r.revs = make([]int64, r.objects)
+	r.index = make(map[string]int, r.objects)
+	for i := 0; i < r.objects; i++ {
+		r.index[r.key(i)] = i
+	}
+	return r, nil
+}
+
+func (r *kubeResource) key(i int) string {
+	return fmt.Sprintf("%sns-%03d/%s-%06d", r.prefix, i%kubeNamespaces, r.name, i)
+}
+
+func (r *kubeResource) value() string {
+	size := r.minSize
+	if r.maxSize > r.minSize {
+		size += rand.Intn(r.maxSize - r.minSize + 1)
+	}
+	return string(mustRandBytes(size))
+}
+
+// pick returns a random object which exists, or doesn't exist, and its revision
+func (r *kubeResource) pick(exists bool) (int, int64, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	start := rand.Intn(r.objects)
+	for j := 0; j < r.objects; j++ {
+		i := (start + j) % r.objects
+		if (r.revs[i] != 0) == exists {
+			return i, r.revs[i], true
+		}
+	}
+	return 0, 0, false
+}
+
+func (r *kubeResource) set(i int, rev int64) {
+	r.mutex.Lock()
+	r.revs[i] = rev
+	r.mutex.Unlock()
+}
+
+// setKey updates the revision of the object with the key, if it's one of the resource's objects
+func (r *kubeResource) setKey(key string, rev int64) {
+	if i, ok := r.index[key]; ok {
+		r.set(i, rev)
+	}
+}
+
+// kubeVerb holds the results for a verb
+type kubeVerb struct {
+	name    string
+	weight  int
+	results chan result
+	report  *report
+	donec   <-chan struct{}
+
+	conflicts int64 // Transactions whose comparison failed
+	retries   int64 // Transactions retried after a conflict
+	exhausted int64 // Requests which gave up after kubeMaxRetries retries
+}
+
+// kubeLoad holds the state shared by the kube threads
+type kubeLoad struct {
+	resources []*kubeResource
+	verbs     map[string]*kubeVerb
+	results   chan result
+
+	listPages   int64
+	listObjects int64
+	watchEvents int64
+	relists     int64
+}
+
+func parseKubeMix(mix string) (map[string]*kubeVerb, error) {
+	verbs := make(map[string]*kubeVerb)
+	for _, name := range kubeVerbNames {
+		verbs[name] = &kubeVerb{name: name}
+	}
+	for _, item := range strings.Split(mix, ",") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("mix %q isn't verb=weight", item)
+		}
+		verb, ok := verbs[strings.TrimSpace(parts[0])]
+		if !ok {
+			return nil, fmt.Errorf("unknown verb %q, the verbs are %s", parts[0], strings.Join(kubeVerbNames, ", "))
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("verb %q has an invalid weight", parts[0])
+		}
+		verb.weight = weight
+	}
+	return verbs, nil
+}
+
+func kubeCmdFunc(cmd *cobra.Command, args []string) {
+
+	setupProfiling()
+	setupCsvFile()
+
+	if kubeNamespaces <= 0 {
+		log.Fatal("Error: namespaces must be greater than 0")
+	}
+	k := &kubeLoad{}
+	totalWeight := 0
+	for _, spec := range kubeResources {
+		r, err := parseKubeResource(spec)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		k.resources = append(k.resources, r)
+		totalWeight += r.weight
+	}
+	if totalWeight == 0 {
+		log.Fatal("Error: at least one resource must have a weight")
+	}
+	var err error
+	if k.verbs, err = parseKubeMix(kubeMix); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	totalWeight = 0
+	for _, v := range k.verbs {
+		totalWeight += v.weight
+	}
+	if totalWeight == 0 {
+		log.Fatal("Error: at least one verb must have a weight")
+	}
+
+	clients := mustCreateClients(totalClients, totalConns)
+
+	k.createInitialObjects(clients)
+
+	watchCtx, cancelWatches := context.WithCancel(context.Background())
+	var watchWg sync.WaitGroup
+	if kubeWatch {
+		for i, r := range k.resources {
+			watchWg.Add(1)
+			go k.listAndWatch(watchCtx, clients[i%len(clients)], r, &watchWg)
+		}
+	}
+
+	// Report each verb, and all the requests
+	runStart := time.Now()
+	for _, name := range kubeVerbNames {
+		v := k.verbs[name]
+		v.results = make(chan result, len(clients))
+		v.report = newReport(v.results)
+		r := v.report
+		v.donec = wrapReport(func() { r.collect(runStart) })
+	}
+	k.results = make(chan result, len(clients))
+	donec, rep := printReport(k.results)
+
+	requests := make(chan struct{}, len(clients))
+	for i := range clients {
+		wg.Add(1)
+		go k.doKubeOps(clients[i], requests)
+	}
+	k.schedule(requests)
+	wg.Wait()
+
+	close(k.results)
+	for _, v := range k.verbs {
+		close(v.results)
+	}
+	<-donec
+	for _, v := range k.verbs {
+		<-v.donec
+	}
+	cancelWatches()
+	watchWg.Wait()
+
+	k.print()
+	if kubeSample {
+		rep.printSecondSample()
+	}
+	k.writeSummary(cmd, rep)
+}
+
+// createInitialObjects creates the objects which exist when the load starts
+func (k *kubeLoad) createInitialObjects(clients []KV) {
+	type object struct {
+		r *kubeResource
+		i int
+	}
+	objects := make(chan object, len(clients))
+	var createWg sync.WaitGroup
+	for _, client := range clients {
+		createWg.Add(1)
+		go func(client KV) {
+			defer createWg.Done()
+			for o := range objects {
+				if _, err := k.create(client, o.r, o.i); err != nil {
+					log.Printf("Failed to create %s: %v", o.r.key(o.i), err)
+				}
+			}
+		}(client)
+	}
+
+	st := time.Now()
+	total := 0
+	for _, r := range k.resources {
+		n := r.objects * kubeInitialPct / 100
+		for _, i := range rand.Perm(r.objects)[:n] {
+			objects <- object{r: r, i: i}
+		}
+		total += n
+	}
+	close(objects)
+	createWg.Wait()
+	log.Printf("Created %v initial objects in %v", total, time.Since(st))
+}
+
+// schedule queues the requests at the rate, until the duration is up
+func (k *kubeLoad) schedule(requests chan<- struct{}) {
+	defer close(requests)
+
+	deadline := time.Now().Add(kubeDuration)
+	var ticker *time.Ticker
+	if kubeRate > 0 {
+		ticker = time.NewTicker(time.Second / time.Duration(kubeRate))
+		defer ticker.Stop()
+	}
+	for time.Now().Before(deadline) {
+		if ticker != nil {
+			<-ticker.C
+		}
+		requests <- struct{}{}
+	}
+}
+
+func (k *kubeLoad) pickResource() *kubeResource {
+	total := 0
+	for _, r := range k.resources {
+		total += r.weight
+	}
+	n := rand.Intn(total)
+	for _, r := range k.resources {
+		if n < r.weight {
+			return r
+		}
+		n -= r.weight
+	}
+	return k.resources[len(k.resources)-1]
+}
+
+func (k *kubeLoad) pickVerb() string {
+	total := 0
+	for _, v := range k.verbs {
+		total += v.weight
+	}
+	n := rand.Intn(total)
+	for _, name := range kubeVerbNames {
+		if n < k.verbs[name].weight {
+			return name
+		}
+		n -= k.verbs[name].weight
+	}
+	return kubeGet
+}
+
+func (k *kubeLoad) doKubeOps(client KV, requests <-chan struct{}) {
+	defer wg.Done()
+
+	for range requests {
+		r := k.pickResource()
+		verb := k.pickVerb()
+
+		// A create needs an object which doesn't exist, the other verbs (except list) need one which does.
+		// If there isn't one, do the opposite.
+		var i int
+		var rev int64
+		if verb != kubeList {
+			var ok bool
+			if i, rev, ok = r.pick(verb != kubeCreate); !ok {
+				if verb == kubeCreate {
+					verb = kubeUpdate
+				} else {
+					verb = kubeCreate
+				}
+				i, rev, _ = r.pick(verb != kubeCreate)
+			}
+		}
+		if verbose {
+			if verb == kubeList {
+				log.Printf("Requesting %s %s", verb, r.prefix)
+			} else {
+				log.Printf("Requesting %s %s", verb, r.key(i))
+			}
+		}
+
+		st := time.Now()
+		var err error
+		switch verb {
+		case kubeCreate:
+			var created bool
+			if created, err = k.create(client, r, i); err == nil && !created {
+				atomic.AddInt64(&k.verbs[kubeCreate].conflicts, 1)
+			}
+		case kubeGet:
+			err = k.get(client, r, i)
+		case kubeList:
+			_, err = k.list(client, r)
+		case kubeUpdate:
+			err = k.guaranteedUpdate(client, r, i, rev, kubeUpdate)
+		case kubeDelete:
+			err = k.guaranteedUpdate(client, r, i, rev, kubeDelete)
+		}
+
+		res := result{duration: time.Since(st), happened: time.Now()}
+		if err != nil {
+			res.errStr = verb + ": " + err.Error()
+		}
+		k.verbs[verb].results <- res
+		k.results <- res
+	}
+}
+
+func kubeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+}
+
+// create creates the object if it doesn't exist. It returns false if the object already exists.
+func (k *kubeLoad) create(client KV, r *kubeResource, i int) (bool, error) {
+	key := r.key(i)
+	ctx, cancel := kubeContext()
+	defer cancel()
+	resp, err := client.Txn(ctx, []Compare{{Key: key, ModRevision: 0}}, []Op{OpPutKV(key, r.value())}, []Op{OpGetKV(key, false, false, false)})
+	if err != nil {
+		return false, err
+	}
+	if resp.Succeeded {
+		r.set(i, resp.Revision)
+		return true, nil
+	}
+
+	// The object already exists, which the kube-apiserver doesn't retry
+	if kvs := resp.Responses[0].Kvs; len(kvs) > 0 {
+		r.set(i, kvs[0].ModRevision)
+	}
+	return false, nil
+}
+
+// guaranteedUpdate updates or deletes the object, if it hasn't changed since rev. On a conflict, it retries with the
+// current object, which is returned by the failed transaction, like the kube-apiserver's GuaranteedUpdate.
+func (k *kubeLoad) guaranteedUpdate(client KV, r *kubeResource, i int, rev int64, verb string) error {
+	key := r.key(i)
+	v := k.verbs[verb]
+	for attempt := 0; ; attempt++ {
+		op := OpPutKV(key, r.value())
+		if verb == kubeDelete {
+			op = OpDeleteKV(key, false)
+		}
+		ctx, cancel := kubeContext()
+		resp, err := client.Txn(ctx, []Compare{{Key: key, ModRevision: rev}}, []Op{op}, []Op{OpGetKV(key, false, false, false)})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if resp.Succeeded {
+			if verb == kubeDelete {
+				r.set(i, 0)
+			} else {
+				r.set(i, resp.Revision)
+			}
+			return nil
+		}
+
+		atomic.AddInt64(&v.conflicts, 1)
+		kvs := resp.Responses[0].Kvs
+		if len(kvs) == 0 {
+			// The object has been deleted, so there's nothing to retry
+			r.set(i, 0)
+			return nil
+		}
+		rev = kvs[0].ModRevision
+		r.set(i, rev)
+		if attempt >= kubeMaxRetries {
+			atomic.AddInt64(&v.exhausted, 1)
+			return nil
+		}
+		atomic.AddInt64(&v.retries, 1)
+	}
+}
+
+func (k *kubeLoad) get(client KV, r *kubeResource, i int) error {
+	ctx, cancel := kubeContext()
+	defer cancel()
+	resp, err := client.Do(ctx, OpGetKV(r.key(i), false, false, false))
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) > 0 {
+		r.set(i, resp.Kvs[0].ModRevision)
+	} else {
+		r.set(i, 0)
+	}
+	return nil
+}
+
+// list lists all the objects of the resource type, a page at a time. The pages after the first are read at the
+// revision of the first, so the list is consistent. It returns the revision of the list.
+func (k *kubeLoad) list(client KV, r *kubeResource) (int64, error) {
+	key := r.prefix
+	end := prefixRangeEnd(r.prefix)
+	var rev int64
+	for {
+		op := OpRangeKV(key, end)
+		op.Limit = kubePageSize
+		op.Revision = rev
+
+		ctx, cancel := kubeContext()
+		resp, err := client.Do(ctx, op)
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		atomic.AddInt64(&k.listPages, 1)
+		atomic.AddInt64(&k.listObjects, int64(len(resp.Kvs)))
+		if rev == 0 {
+			rev = resp.Revision
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return rev, nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// listAndWatch lists the resource type, then watches it from the revision of the list, keeping the object revisions
+// up to date. If the watch fails, e.g. because the revision has been compacted, it lists again.
+func (k *kubeLoad) listAndWatch(ctx context.Context, client KV, r *kubeResource, done *sync.WaitGroup) {
+	defer done.Done()
+	watcher := client.NewWatcher()
+	defer watcher.Close()
+
+	for ctx.Err() == nil {
+		rev, err := k.list(client, r)
+		if err != nil {
+			log.Printf("ERROR: List of %s failed: %v", r.name, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for wresp := range watcher.WatchFromRevision(ctx, r.prefix, true, rev+1) {
+			if wresp.Err != nil {
+				if ctx.Err() == nil {
+					log.Printf("ERROR: Watch of %s failed, will list again: %v", r.name, wresp.Err)
+				}
+				break
+			}
+			atomic.AddInt64(&k.watchEvents, int64(len(wresp.Events)))
+			for _, ev := range wresp.Events {
+				if ev.Type == EventDelete {
+					r.setKey(string(ev.Key), 0)
+				} else {
+					r.setKey(string(ev.Key), ev.Revision)
+				}
+			}
+		}
+		if ctx.Err() == nil {
+			atomic.AddInt64(&k.relists, 1)
+		}
+	}
+}
+
+// txnAttempts is the number of transactions for a verb, including retries
+func (v *kubeVerb) txnAttempts() int64 {
+	return int64(len(v.report.lats)) + atomic.LoadInt64(&v.retries)
+}
+
+func (v *kubeVerb) errors() int {
+	errors := 0
+	for _, n := range v.report.errorDist {
+		errors += n
+	}
+	return errors
+}
+
+// average returns the average latency, or 0 if no requests succeeded
+func (v *kubeVerb) average() float64 {
+	if len(v.report.lats) == 0 {
+		return 0
+	}
+	return v.report.average
+}
+
+// latencies returns the 50th, 90th and 99th percentile latencies
+func (v *kubeVerb) latencies() []float64 {
+	sort.Float64s(v.report.lats)
+	return v.report.extractLatencies([]int{50, 90, 99})
+}
+
+func (k *kubeLoad) print() {
+	fmt.Printf("\nVerbs:\n")
+	fmt.Printf("  Verb\tRequests\tErrors\tRequests/sec\tAverage\t50%%\t90%%\t99%%\tConflicts\tRetries\tRetries exhausted\n")
+	for _, name := range kubeVerbNames {
+		v := k.verbs[name]
+		r := v.report
+		if len(r.lats)+v.errors() == 0 {
+			continue
+		}
+		pctls := v.latencies()
+		fmt.Printf("  %s\t%d\t%d\t%4.4f\t%4.4f\t%4.4f\t%4.4f\t%4.4f\t%d\t%d\t%d\n", name, len(r.lats), v.errors(), r.rps, v.average(),
+			pctls[0], pctls[1], pctls[2], atomic.LoadInt64(&v.conflicts), atomic.LoadInt64(&v.retries), atomic.LoadInt64(&v.exhausted))
+	}
+
+	conflicts, attempts := k.conflicts()
+	fmt.Printf("\nKube:\n")
+	fmt.Printf("  Conflicts:\t%d\n", conflicts)
+	if attempts > 0 {
+		fmt.Printf("  Conflict rate:\t%4.4f\n", float64(conflicts)/float64(attempts))
+	}
+	fmt.Printf("  List pages:\t%d\n", atomic.LoadInt64(&k.listPages))
+	fmt.Printf("  Objects listed:\t%d\n", atomic.LoadInt64(&k.listObjects))
+	fmt.Printf("  Watch events:\t%d\n", atomic.LoadInt64(&k.watchEvents))
+	fmt.Printf("  Relists:\t%d\n", atomic.LoadInt64(&k.relists))
+}
+
+// conflicts returns the number of conflicts, and the number of transactions
+func (k *kubeLoad) conflicts() (int64, int64) {
+	var conflicts, attempts int64
+	for _, name := range []string{kubeCreate, kubeUpdate, kubeDelete} {
+		v := k.verbs[name]
+		conflicts += atomic.LoadInt64(&v.conflicts)
+		attempts += v.txnAttempts()
+	}
+	return conflicts, attempts
+}
+
+func (k *kubeLoad) writeSummary(cmd *cobra.Command, rep *report) {
+	stats, keys := rep.extractStats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+
+	for _, name := range kubeVerbNames {
+		v := k.verbs[name]
+		pctls := v.latencies()
+		stats[name+" requests"] = fmt.Sprint(len(v.report.lats))
+		stats[name+" errors"] = fmt.Sprint(v.errors())
+		stats[name+" requests/sec"] = fmt.Sprintf("%4.4f", v.report.rps)
+		stats[name+" average (secs)"] = fmt.Sprintf("%4.4f", v.average())
+		stats[name+" 99th percentile (secs)"] = fmt.Sprintf("%4.4f", pctls[2])
+		keys = append(keys, name+" requests", name+" errors", name+" requests/sec", name+" average (secs)", name+" 99th percentile (secs)")
+	}
+	for _, name := range []string{kubeCreate, kubeUpdate, kubeDelete} {
+		v := k.verbs[name]
+		stats[name+" conflicts"] = fmt.Sprint(atomic.LoadInt64(&v.conflicts))
+		stats[name+" retries"] = fmt.Sprint(atomic.LoadInt64(&v.retries))
+		keys = append(keys, name+" conflicts", name+" retries")
+	}
+
+	conflicts, attempts := k.conflicts()
+	rate := 0.0
+	if attempts > 0 {
+		rate = float64(conflicts) / float64(attempts)
+	}
+	stats["conflict rate"] = fmt.Sprintf("%4.4f", rate)
+	stats["list pages"] = fmt.Sprint(atomic.LoadInt64(&k.listPages))
+	stats["watch events"] = fmt.Sprint(atomic.LoadInt64(&k.watchEvents))
+	keys = append(keys, "conflict rate", "list pages", "watch events")
+
+	writeSummaryToFile(cmd.Flags(), "kube", stats, keys)
+}
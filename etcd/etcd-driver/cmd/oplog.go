@@ -0,0 +1,158 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operations in an op log
+const (
+	opLogPut    = "put"
+	opLogGet    = "get"
+	opLogDelete = "delete"
+	opLogWatch  = "watch"
+)
+
+// opRecord is an entry in an op log, written by 'record' and read by 'replay'.
+// Values aren't recorded, only their size, so that op logs can be taken from production carriers.
+type opRecord struct {
+	Time      time.Time `json:"time"`
+	Op        string    `json:"op"`
+	Key       string    `json:"key"`
+	Prefix    bool      `json:"prefix,omitempty"`
+	ValueSize int       `json:"valueSize,omitempty"`
+	Lease     int64     `json:"lease,omitempty"`
+}
+
+func validOpLogOp(op string) bool {
+	switch op {
+	case opLogPut, opLogGet, opLogDelete, opLogWatch:
+		return true
+	}
+	return false
+}
+
+// opLogWriter writes an op log as JSON lines
+type opLogWriter struct {
+	enc   *json.Encoder
+	count int
+}
+
+func newOpLogWriter(w io.Writer) *opLogWriter {
+	return &opLogWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *opLogWriter) write(rec opRecord) error {
+	w.count++
+	return w.enc.Encode(rec)
+}
+
+// opLogReader reads an op log one record at a time, so that large logs don't need to be held in memory
+type opLogReader struct {
+	dec  *json.Decoder
+	line int
+}
+
+func newOpLogReader(r io.Reader) *opLogReader {
+	return &opLogReader{dec: json.NewDecoder(r)}
+}
+
+// next returns the next record, or io.EOF at the end of the log
+func (r *opLogReader) next() (opRecord, error) {
+	var rec opRecord
+	err := r.dec.Decode(&rec)
+	if err != nil {
+		if err == io.EOF {
+			return rec, err
+		}
+		return rec, fmt.Errorf("op log record %d: %v", r.line+1, err)
+	}
+	r.line++
+	if !validOpLogOp(rec.Op) {
+		return rec, fmt.Errorf("op log record %d: unknown op %q", r.line, rec.Op)
+	}
+	return rec, nil
+}
+
+// readTrace reads a trace file, returning the records in time order.
+// Each line of the trace is 'time,op,key[,valueSize[,lease[,prefix]]]', where time is RFC3339 or Unix seconds,
+// and op is put, get, delete or watch. Lines starting with '#' are ignored.
+func readTrace(r io.Reader) ([]opRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var records []opRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("trace line %d: expected at least time,op,key", line)
+		}
+
+		var rec opRecord
+		if rec.Time, err = parseTraceTime(fields[0]); err != nil {
+			return nil, fmt.Errorf("trace line %d: %v", line, err)
+		}
+		rec.Op = strings.ToLower(fields[1])
+		if !validOpLogOp(rec.Op) {
+			return nil, fmt.Errorf("trace line %d: unknown op %q", line, fields[1])
+		}
+		rec.Key = fields[2]
+		if len(fields) > 3 && len(fields[3]) > 0 {
+			if rec.ValueSize, err = strconv.Atoi(fields[3]); err != nil {
+				return nil, fmt.Errorf("trace line %d: bad value size: %v", line, err)
+			}
+		}
+		if len(fields) > 4 && len(fields[4]) > 0 {
+			if rec.Lease, err = strconv.ParseInt(fields[4], 0, 64); err != nil {
+				return nil, fmt.Errorf("trace line %d: bad lease: %v", line, err)
+			}
+		}
+		if len(fields) > 5 && len(fields[5]) > 0 {
+			if rec.Prefix, err = strconv.ParseBool(fields[5]); err != nil {
+				return nil, fmt.Errorf("trace line %d: bad prefix: %v", line, err)
+			}
+		}
+		records = append(records, rec)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+func parseTraceTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad time %q, expected RFC3339 or Unix seconds", s)
+	}
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+}
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2017, 2021 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -26,7 +26,6 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"log"
 	"math/rand"
@@ -38,7 +37,7 @@ import (
 	"time"
 
 	regen "github.com/zach-klippenstein/goregen"
-	v3 "go.etcd.io/etcd/client/v3"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
 	"golang.org/x/net/context"
 )
 
@@ -84,6 +83,10 @@ type PatternEngine struct {
 
 	stats         churnStats
 	intervalStats churnStats
+	latencies     opLatencies
+
+	// Prefix added to every key, to give a coordinated agent its own partition of the key space
+	keyPrefix string
 
 	// Store the keys so they can be accessed by the watchers
 	// Will support a maximum of 10 levels
@@ -158,6 +161,84 @@ func (e *churnStats) reset() {
 	e.watchEventCount = 0
 }
 
+// merge adds the stats from another driver
+func (e *churnStats) merge(o churnStats) {
+	e.keysPut += o.keysPut
+	e.keysPutTotTime += o.keysPutTotTime
+	e.keysPutMinTime = minTime(e.keysPutMinTime, o.keysPutMinTime)
+	e.keysPutMaxTime = maxTime(e.keysPutMaxTime, o.keysPutMaxTime)
+	e.bytesPut += o.bytesPut
+
+	e.keysDel += o.keysDel
+	e.clientDels += o.clientDels
+	e.keysDelTotTime += o.keysDelTotTime
+	e.keysDelMinTime = minTime(e.keysDelMinTime, o.keysDelMinTime)
+	e.keysDelMaxTime = maxTime(e.keysDelMaxTime, o.keysDelMaxTime)
+
+	e.keysGet += o.keysGet
+	e.clientGets += o.clientGets
+	e.keysGetTotTime += o.keysGetTotTime
+	e.keysGetMinTime = minTime(e.keysGetMinTime, o.keysGetMinTime)
+	e.keysGetMaxTime = maxTime(e.keysGetMaxTime, o.keysGetMaxTime)
+	e.bytesGet += o.bytesGet
+
+	e.errors += o.errors
+
+	e.reconnects += o.reconnects
+	e.reconnectsTotTime += o.reconnectsTotTime
+	e.reconnectsMinTime = minTime(e.reconnectsMinTime, o.reconnectsMinTime)
+	e.reconnectsMaxTime = maxTime(e.reconnectsMaxTime, o.reconnectsMaxTime)
+
+	e.watchEventCount += o.watchEventCount
+
+	e.keysPrefixGet += o.keysPrefixGet
+	e.clientPrefixGets += o.clientPrefixGets
+	e.keysPrefixGetTotTime += o.keysPrefixGetTotTime
+	e.keysPrefixGetMinTime = minTime(e.keysPrefixGetMinTime, o.keysPrefixGetMinTime)
+	e.keysPrefixGetMaxTime = maxTime(e.keysPrefixGetMaxTime, o.keysPrefixGetMaxTime)
+	e.bytesPrefixGet += o.bytesPrefixGet
+}
+
+// minTime returns the minimum of two response times, where 0 means no responses
+func minTime(a int64, b int64) int64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a int64, b int64) int64 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// opLatencies holds the response time distribution of each type of request, so that percentiles can be reported
+// across coordinated drivers
+type opLatencies struct {
+	Puts       *histogram.Histogram `json:"puts"`
+	Deletes    *histogram.Histogram `json:"deletes"`
+	Gets       *histogram.Histogram `json:"gets"`
+	PrefixGets *histogram.Histogram `json:"prefixGets"`
+}
+
+func newOpLatencies() opLatencies {
+	return opLatencies{Puts: histogram.New(), Deletes: histogram.New(), Gets: histogram.New(), PrefixGets: histogram.New()}
+}
+
+func (l opLatencies) merge(o opLatencies) error {
+	for _, pair := range [][2]*histogram.Histogram{{l.Puts, o.Puts}, {l.Deletes, o.Deletes}, {l.Gets, o.Gets}, {l.PrefixGets, o.PrefixGets}} {
+		if pair[1] == nil {
+			continue
+		}
+		if err := pair[0].Merge(pair[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type patternGenerator struct {
 	expectedKeys int
 	keysAdded    int
@@ -215,6 +296,7 @@ func (e *PatternEngine) GetKeySpace() int {
 // ResetStats clears data from pervious operations
 func (e *PatternEngine) ResetStats() {
 	e.stats.reset()
+	e.latencies = newOpLatencies()
 	startTime = time.Now()
 }
 
@@ -229,7 +311,7 @@ func (e *PatternEngine) GetKeyPrefixes(prefixLevel int) []string {
 		e.keyList[prefixLevel] = []string{""}
 		gen := patternGenerator{expectedKeys: 0, keysAdded: 0}
 		for _, rules := range e.keyRules {
-			gen.constructKey("", rules, 0, prefixLevel, func(level int, key string, value string) {
+			gen.constructKey(e.keyPrefix, rules, 0, prefixLevel, func(level int, key string, value string) {
 				if level == prefixLevel {
 					if e.keyList[level][0] == "" {
 						e.keyList[level][0] = key
@@ -244,7 +326,7 @@ func (e *PatternEngine) GetKeyPrefixes(prefixLevel int) []string {
 }
 
 // GenerateKeys creates the keys and puts them to etcd
-func (e *PatternEngine) GenerateKeys(keys int, requests chan v3.Op, rate int) {
+func (e *PatternEngine) GenerateKeys(keys int, requests chan Op, rate int) {
 	go func() {
 		gen := patternGenerator{expectedKeys: keys, keysAdded: 0}
 		var ticker *time.Ticker
@@ -256,14 +338,14 @@ func (e *PatternEngine) GenerateKeys(keys int, requests chan v3.Op, rate int) {
 		log.Print("Generating and adding key/values")
 		for ok := true; ok; {
 			for _, rules := range e.keyRules {
-				gen.constructKey("", rules, 0, -1, func(level int, key string, value string) {
+				gen.constructKey(e.keyPrefix, rules, 0, -1, func(level int, key string, value string) {
 					if rate > 0 {
 						<-ticker.C
 					}
 					if verbose {
 						log.Printf("Requesting put (GenerateKeys) for %v : %v", key, value)
 					}
-					requests <- v3.OpPut(key, value)
+					requests <- OpPutKV(key, value)
 				})
 
 				if verbose {
@@ -283,7 +365,7 @@ func (e *PatternEngine) GenerateKeys(keys int, requests chan v3.Op, rate int) {
 }
 
 // ChurnValues puts new values for existing keys to create load
-func (e *PatternEngine) ChurnValues(rate int, clients []*v3.Client) {
+func (e *PatternEngine) ChurnValues(rate int, clients []KV) {
 	if len(e.keyRules) > 1 {
 		log.Print("Failure: ChurnValues() can't be used with more than 1 pattern")
 		os.Exit(1)
@@ -313,14 +395,14 @@ func (e *PatternEngine) ChurnValues(rate int, clients []*v3.Client) {
 			if verbose {
 				log.Printf("Requesting put (ChurnValues) for %v : %v", key, value)
 			}
-			requests <- v3.OpPut(key, value)
+			requests <- OpPutKV(key, value)
 		}
 		close(requests)
 	}()
 }
 
 // ChurnLevel puts and deletes key/values below a certain level in the tree
-func (e *PatternEngine) ChurnLevel(level int, percent int, rate int, clients []*v3.Client) {
+func (e *PatternEngine) ChurnLevel(level int, percent int, rate int, clients []KV) {
 	if len(e.keyRules) > 1 {
 		log.Print("Failure: ChurnLevel() can't be used with more than 1 pattern")
 		os.Exit(1)
@@ -372,7 +454,7 @@ func (e *PatternEngine) ChurnLevel(level int, percent int, rate int, clients []*
 				if verbose {
 					log.Printf("Requesting delete prefix (ChurnLevel) for %v", prefix)
 				}
-				requests <- v3.OpDelete(prefix, v3.WithPrefix())
+				requests <- OpDeleteKV(prefix, true)
 
 				churnRef[indx] = p
 			} else {
@@ -382,7 +464,7 @@ func (e *PatternEngine) ChurnLevel(level int, percent int, rate int, clients []*
 					if verbose {
 						log.Printf("Requesting put (ChurnLevel) for %v:%v", key, value)
 					}
-					requests <- v3.OpPut(key, value)
+					requests <- OpPutKV(key, value)
 				})
 				churnRef[indx] = -1
 			}
@@ -402,7 +484,7 @@ func (e *PatternEngine) ChurnLevel(level int, percent int, rate int, clients []*
 }
 
 // GetLevel gets all keys/values for randomly selected prefixes at a certain level
-func (e *PatternEngine) GetLevel(serializable bool, keysOnly bool, level int, rate int, clients []*v3.Client) {
+func (e *PatternEngine) GetLevel(serializable bool, keysOnly bool, level int, rate int, clients []KV) {
 	if len(e.keyRules) > 1 {
 		log.Print("Failure: GetLevel() can't be used with more than 1 pattern")
 		os.Exit(1)
@@ -438,25 +520,14 @@ func (e *PatternEngine) GetLevel(serializable bool, keysOnly bool, level int, ra
 			if verbose {
 				log.Printf("Requesting Get prefix (GetLevel) for %v", prefix)
 			}
-			var ops []v3.OpOption
-			if !isLeaf {
-				ops = make([]v3.OpOption, 1)
-				ops[0] = v3.WithPrefix()
-			}
-			if serializable {
-				ops = append(ops, v3.WithSerializable())
-			}
-			if keysOnly {
-				ops = append(ops, v3.WithKeysOnly())
-			}
-			requests <- v3.OpGet(prefix, ops...)
+			requests <- OpGetKV(prefix, !isLeaf, serializable, keysOnly)
 		}
 		close(requests)
 	}()
 }
 
 // GetPrefix gets all keys/values for requested prefix
-func (e *PatternEngine) GetPrefix(serializable bool, keysOnly bool, prefix string, rate time.Duration, startDelay int64, requests chan v3.Op, stop chan bool) {
+func (e *PatternEngine) GetPrefix(serializable bool, keysOnly bool, prefix string, rate time.Duration, startDelay int64, requests chan Op, stop chan bool) {
 
 	go func() {
 		startTicker := time.NewTicker(time.Second * time.Duration(startDelay))
@@ -478,15 +549,7 @@ func (e *PatternEngine) GetPrefix(serializable bool, keysOnly bool, prefix strin
 				if verbose {
 					log.Printf("Requesting Get prefix for %v", prefix)
 				}
-				ops := make([]v3.OpOption, 1)
-				ops[0] = v3.WithPrefix()
-				if serializable {
-					ops = append(ops, v3.WithSerializable())
-				}
-				if keysOnly {
-					ops = append(ops, v3.WithKeysOnly())
-				}
-				requests <- v3.OpGet(prefix, ops...)
+				requests <- OpGetKV(prefix, true, serializable, keysOnly)
 			case <-stop:
 				break
 			}
@@ -511,9 +574,9 @@ func (e *PatternEngine) StopAllActivity() {
 }
 
 // SetupRequestChannels setup for pipeline of etcd requests
-func (e *PatternEngine) SetupRequestChannels(clients []*v3.Client, prefixGets bool) chan v3.Op {
+func (e *PatternEngine) SetupRequestChannels(clients []KV, prefixGets bool) chan Op {
 
-	requests := make(chan v3.Op, len(clients))
+	requests := make(chan Op, len(clients))
 
 	for i := range clients {
 		wg.Add(1)
@@ -527,18 +590,20 @@ func (e *PatternEngine) WaitForTestEnd(testEndKey string) {
 	// Wait for test end signal to be written to etcd
 	// Could probably re-use existing clients, but will use our own just in case
 	testEndClient := mustCreateClients(1, 1)
+	watcher := testEndClient[0].NewWatcher()
+	defer watcher.Close()
 	log.Printf("Created test end watch for %v", testEndKey)
 
 TestEndLoop:
 	for {
-		rch := testEndClient[0].Watch(context.Background(), testEndKey)
+		rch := watcher.Watch(context.Background(), testEndKey, false)
 		for wresp := range rch {
 			if wresp.Canceled {
 				log.Printf("Test end watch canceled and will be recreated")
 				break
 			} else {
 				for _, ev := range wresp.Events {
-					if bytes.Compare(ev.Kv.Value, []byte("true")) == 0 {
+					if bytes.Compare(ev.Value, []byte("true")) == 0 {
 						log.Printf("Test End detected")
 						e.StopAllActivity()
 						break TestEndLoop
@@ -727,22 +792,19 @@ func (e *PatternEngine) getChurnIntervalStats() (stats churnStats) {
 	return e.intervalStats
 }
 
-func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int, prefixGets bool) {
+func (e *PatternEngine) doOps(client KV, requests <-chan Op, clientNum int, prefixGets bool) {
 	defer wg.Done()
 	useCount := 0
-	var localCon *v3.Client
 	if etcdReconnectCount > 0 {
-		localCon = mustCreateConn(true)
-		client = localCon.KV
+		client = mustCreateConn(true)
 	}
 
 	for op := range requests {
 		if etcdReconnectCount > 0 {
 			if useCount == etcdReconnectCount {
 				conSt := time.Now()
-				localCon.Close()
-				localCon = mustCreateConn(true)
-				client = localCon.KV
+				client.Close()
+				client = mustCreateConn(true)
 				useCount = 0
 				// Store as Microseconds
 				conRespTime := time.Since(conSt).Nanoseconds() / 1000
@@ -779,6 +841,11 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 			ctx = context.Background()
 		}
 
+		if watchMutations != nil && op.Type == OpDelete {
+			// The deleted keys are needed to verify the delete events
+			op.PrevKV = true
+		}
+
 		st := time.Now()
 		resp, err := client.Do(ctx, op)
 
@@ -790,24 +857,19 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 		}
 
 		if err != nil {
-			opType := "Other"
-			if op.IsPut() {
-				opType = "Put"
-			} else if op.IsGet() {
-				opType = "Get"
-			} else if op.IsDelete() {
-				opType = "Delete"
-			}
-			log.Printf("Client %v operation (doOps - %s) error: %s after %v microseconds", clientNum, opType, err.Error(), respTime)
+			log.Printf("Client %v operation (doOps - %s) error: %s after %v microseconds", clientNum, op.Type, err.Error(), respTime)
 			atomic.AddUint32(&e.stats.errors, 1)
 			atomic.AddUint32(&e.intervalStats.errors, 1)
 		} else {
-			if resp.Put() != nil {
+			if watchMutations != nil && op.Type != OpGet {
+				watchMutations.record(op, resp, st)
+			}
+			if op.Type == OpPut {
 				putStatsMutex.Lock()
 				e.stats.keysPut = e.stats.keysPut + 1
 				e.intervalStats.keysPut = e.intervalStats.keysPut + 1
-				e.stats.bytesPut = e.stats.bytesPut + int64(binary.Size(op.KeyBytes())) + int64(binary.Size(op.ValueBytes()))
-				e.intervalStats.bytesPut = e.intervalStats.bytesPut + int64(binary.Size(op.KeyBytes)) + int64(binary.Size(op.ValueBytes()))
+				e.stats.bytesPut = e.stats.bytesPut + int64(len(op.Key)) + int64(len(op.Value))
+				e.intervalStats.bytesPut = e.intervalStats.bytesPut + int64(len(op.Key)) + int64(len(op.Value))
 				e.stats.keysPutTotTime = e.stats.keysPutTotTime + respTime
 				e.intervalStats.keysPutTotTime = e.intervalStats.keysPutTotTime + respTime
 				if e.stats.keysPutMinTime == 0 || respTime < e.stats.keysPutMinTime {
@@ -823,15 +885,16 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 					e.intervalStats.keysPutMaxTime = respTime
 				}
 				putStatsMutex.Unlock()
+				e.latencies.Puts.Record(time.Duration(respTime) * time.Microsecond)
 				if verbose {
 					log.Printf("Put completed for client %v after %v microseconds", clientNum, respTime)
 				}
-			} else if resp.Del() != nil {
+			} else if op.Type == OpDelete {
 				delStatsMutex.Lock()
 				e.stats.clientDels = e.stats.clientDels + 1
 				e.intervalStats.clientDels = e.intervalStats.clientDels + 1
-				e.stats.keysDel = e.stats.keysDel + uint32(resp.Del().Deleted)
-				e.intervalStats.keysDel = e.intervalStats.keysDel + uint32(resp.Del().Deleted)
+				e.stats.keysDel = e.stats.keysDel + uint32(resp.Deleted)
+				e.intervalStats.keysDel = e.intervalStats.keysDel + uint32(resp.Deleted)
 				e.stats.keysDelTotTime = e.stats.keysDelTotTime + respTime
 				e.intervalStats.keysDelTotTime = e.intervalStats.keysDelTotTime + respTime
 				if e.stats.keysDelMinTime == 0 || respTime < e.stats.keysDelMinTime {
@@ -847,13 +910,14 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 					e.intervalStats.keysDelMaxTime = respTime
 				}
 				delStatsMutex.Unlock()
+				e.latencies.Deletes.Record(time.Duration(respTime) * time.Microsecond)
 				if verbose {
-					log.Printf("Delete completed for client %v after %v microseconds, %v keys were deleted", clientNum, respTime, uint32(resp.Del().Deleted))
+					log.Printf("Delete completed for client %v after %v microseconds, %v keys were deleted", clientNum, respTime, uint32(resp.Deleted))
 				}
-			} else if resp.Get() != nil {
+			} else if op.Type == OpGet {
 				var bytesGet int64
 				if fullGetRead {
-					for _, kv := range resp.Get().Kvs {
+					for _, kv := range resp.Kvs {
 						value := kv.Value
 						key := kv.Key
 						bytesGet = bytesGet + int64(len(value)) + int64(len(key))
@@ -870,8 +934,8 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 					e.intervalStats.bytesPrefixGet = e.intervalStats.bytesPrefixGet + bytesGet
 					e.stats.clientPrefixGets = e.stats.clientPrefixGets + 1
 					e.intervalStats.clientPrefixGets = e.intervalStats.clientPrefixGets + 1
-					e.stats.keysPrefixGet = e.stats.keysPrefixGet + uint32(resp.Get().Count)
-					e.intervalStats.keysPrefixGet = e.intervalStats.keysPrefixGet + uint32(resp.Get().Count)
+					e.stats.keysPrefixGet = e.stats.keysPrefixGet + uint32(resp.Count)
+					e.intervalStats.keysPrefixGet = e.intervalStats.keysPrefixGet + uint32(resp.Count)
 					e.stats.keysPrefixGetTotTime = e.stats.keysPrefixGetTotTime + respTime
 					e.intervalStats.keysPrefixGetTotTime = e.intervalStats.keysPrefixGetTotTime + respTime
 					if e.stats.keysPrefixGetMinTime == 0 || respTime < e.stats.keysPrefixGetMinTime {
@@ -891,8 +955,8 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 					e.intervalStats.bytesGet = e.intervalStats.bytesGet + bytesGet
 					e.stats.clientGets = e.stats.clientGets + 1
 					e.intervalStats.clientGets = e.intervalStats.clientGets + 1
-					e.stats.keysGet = e.stats.keysGet + uint32(resp.Get().Count)
-					e.intervalStats.keysGet = e.intervalStats.keysGet + uint32(resp.Get().Count)
+					e.stats.keysGet = e.stats.keysGet + uint32(resp.Count)
+					e.intervalStats.keysGet = e.intervalStats.keysGet + uint32(resp.Count)
 					e.stats.keysGetTotTime = e.stats.keysGetTotTime + respTime
 					e.intervalStats.keysGetTotTime = e.intervalStats.keysGetTotTime + respTime
 					if e.stats.keysGetMinTime == 0 || respTime < e.stats.keysGetMinTime {
@@ -909,8 +973,13 @@ func (e *PatternEngine) doOps(client v3.KV, requests <-chan v3.Op, clientNum int
 					}
 				}
 				getStatsMutex.Unlock()
+				if prefixGets {
+					e.latencies.PrefixGets.Record(time.Duration(respTime) * time.Microsecond)
+				} else {
+					e.latencies.Gets.Record(time.Duration(respTime) * time.Microsecond)
+				}
 				if verbose {
-					log.Printf("Get completed for client %v after %v microseconds, %v keys were retrieved", clientNum, respTime, resp.Get().Count)
+					log.Printf("Get completed for client %v after %v microseconds, %v keys were retrieved", clientNum, respTime, resp.Count)
 				}
 			}
 		}
@@ -0,0 +1,150 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record an op log from a live watch or a trace file",
+	Long: "Record a timestamped op log of puts, gets, deletes and watches, which can be re-issued by 'replay'. " +
+		"The log is captured from a watch on a live etcd, or converted from a trace file.",
+
+	Run: recordCmdFunc,
+}
+
+var (
+	recOutput    string
+	recPrefix    string
+	recTraceFile string
+	recDuration  time.Duration
+	recMaxOps    int
+)
+
+func init() {
+	RootCmd.AddCommand(recordCmd)
+	recordCmd.Flags().StringVar(&recOutput, "output", "", "File to write the op log to (required)")
+	recordCmd.Flags().StringVar(&recPrefix, "prefix", "/", "Key prefix to watch when recording from a live etcd")
+	recordCmd.Flags().StringVar(&recTraceFile, "trace-file", "", "Convert this trace file rather than watching a live etcd. Lines are 'time,op,key[,valueSize[,lease[,prefix]]]'")
+	recordCmd.Flags().DurationVar(&recDuration, "duration", 0, "How long to record from a live etcd (0 records until interrupted)")
+	recordCmd.Flags().IntVar(&recMaxOps, "max-ops", 0, "Maximum number of ops to record from a live etcd (0 is no limit)")
+}
+
+func recordCmdFunc(cmd *cobra.Command, args []string) {
+	if len(recOutput) == 0 {
+		log.Fatal("Error: output is required")
+	}
+
+	file, err := os.Create(recOutput)
+	if err != nil {
+		log.Fatalf("Failed to create op log: %v", err)
+	}
+	defer file.Close()
+	out := bufio.NewWriter(file)
+	defer out.Flush()
+	w := newOpLogWriter(out)
+
+	if len(recTraceFile) > 0 {
+		recordTrace(w)
+	} else {
+		recordWatch(w)
+	}
+	log.Printf("Recorded %d ops to %s", w.count, recOutput)
+}
+
+// recordTrace converts a trace file to an op log
+func recordTrace(w *opLogWriter) {
+	trace, err := os.Open(recTraceFile)
+	if err != nil {
+		log.Fatalf("Failed to open trace file: %v", err)
+	}
+	defer trace.Close()
+
+	records, err := readTrace(bufio.NewReader(trace))
+	if err != nil {
+		log.Fatalf("Failed to read trace file: %v", err)
+	}
+	for _, rec := range records {
+		if err := w.write(rec); err != nil {
+			log.Fatalf("Failed to write op log: %v", err)
+		}
+	}
+}
+
+// recordWatch records the puts and deletes seen by a watch on the prefix.
+// Gets and watches aren't visible to a watch, so they are only in op logs converted from a trace.
+func recordWatch(w *opLogWriter) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if recDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, recDuration)
+		defer cancel()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case <-sig:
+			log.Print("Interrupted, stopping recording")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	client := mustCreateConn(true)
+	defer client.Close()
+	watcher := client.NewWatcher()
+	defer watcher.Close()
+
+	log.Printf("Recording ops for prefix %v", recPrefix)
+	wch := watcher.Watch(ctx, recPrefix, true)
+	for wresp := range wch {
+		if wresp.Err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Watch error, prefix: %v, - %v", recPrefix, wresp.Err)
+			}
+			return
+		}
+
+		now := time.Now().UTC()
+		for _, ev := range wresp.Events {
+			rec := opRecord{Time: now, Key: string(ev.Key)}
+			if ev.Type == EventDelete {
+				rec.Op = opLogDelete
+			} else {
+				rec.Op = opLogPut
+				rec.ValueSize = len(ev.Value)
+				rec.Lease = int64(ev.Lease)
+			}
+			if verbose {
+				log.Printf("Recording %v", ev)
+			}
+			if err := w.write(rec); err != nil {
+				log.Fatalf("Failed to write op log: %v", err)
+			}
+			if recMaxOps > 0 && w.count >= recMaxOps {
+				return
+			}
+		}
+	}
+}
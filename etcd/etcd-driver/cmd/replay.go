@@ -0,0 +1,296 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay an op log",
+	Long:  "Re-issue the ops in an op log written by 'record', with the original timing or at a multiple of it",
+
+	Run: replayCmdFunc,
+}
+
+var (
+	repInput        string
+	repSpeed        float64
+	repKeyPrefix    string
+	repLeaseTTL     int64
+	repSerializable bool
+	repSample       bool
+)
+
+func init() {
+	RootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringVar(&repInput, "input", "", "Op log to replay (required)")
+	replayCmd.Flags().Float64Var(&repSpeed, "speed", 1, "Replay speed as a multiple of the original timing (0 replays as fast as possible)")
+	replayCmd.Flags().StringVar(&repKeyPrefix, "key-prefix", "", "Prefix added to every key, to keep replayed keys apart from existing keys")
+	replayCmd.Flags().Int64Var(&repLeaseTTL, "lease-ttl", 60, "TTL (in seconds) of the leases granted for recorded leases")
+	replayCmd.Flags().BoolVar(&repSerializable, "serializable-gets", false, "Pass the WithSerializable option to the gets")
+	replayCmd.Flags().BoolVar(&repSample, "sample", false, "Print the average latency and throughput for each second")
+
+	addToFileExclude("input")
+	addToFileExclude("sample")
+}
+
+// replayOp is an op scheduled for replay
+type replayOp struct {
+	rec opRecord
+	due time.Time
+}
+
+// replayLeases maps the leases in the op log to leases granted during replay.
+// A lease is granted the first time it is used, and kept alive while it is in use.
+type replayLeases struct {
+	mutex  sync.Mutex
+	client KV
+	leases map[int64]*replayLease
+}
+
+type replayLease struct {
+	id        LeaseID
+	refreshed time.Time
+}
+
+func (rl *replayLeases) get(recorded int64) (LeaseID, error) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	defer cancel()
+
+	lease, ok := rl.leases[recorded]
+	if !ok {
+		id, err := rl.client.Grant(ctx, repLeaseTTL)
+		if err != nil {
+			return 0, err
+		}
+		lease = &replayLease{id: id, refreshed: time.Now()}
+		rl.leases[recorded] = lease
+	} else if time.Since(lease.refreshed) > time.Duration(repLeaseTTL)*time.Second/2 {
+		if _, err := rl.client.KeepAliveOnce(ctx, lease.id); err != nil {
+			return 0, err
+		}
+		lease.refreshed = time.Now()
+	}
+	return lease.id, nil
+}
+
+// replayer holds the state shared by the replay threads
+type replayer struct {
+	results chan result
+	leases  *replayLeases
+
+	watchers    []Watcher
+	watchCtx    context.Context
+	watchWg     sync.WaitGroup
+	watchCount  int64
+	watchEvents int64
+
+	maxLag int64 // nanoseconds
+	ops    int64
+}
+
+func replayCmdFunc(cmd *cobra.Command, args []string) {
+
+	setupProfiling()
+	setupCsvFile()
+
+	if len(repInput) == 0 {
+		log.Fatal("Error: input is required")
+	}
+	if repSpeed < 0 {
+		log.Fatal("Error: speed can't be negative")
+	}
+
+	file, err := os.Open(repInput)
+	if err != nil {
+		log.Fatalf("Failed to open op log: %v", err)
+	}
+	defer file.Close()
+	reader := newOpLogReader(bufio.NewReader(file))
+
+	clients := mustCreateClients(totalClients, totalConns)
+
+	watchCtx, cancelWatches := context.WithCancel(context.Background())
+	rp := &replayer{
+		results:  make(chan result, len(clients)),
+		leases:   &replayLeases{client: clients[0], leases: make(map[int64]*replayLease)},
+		watchCtx: watchCtx,
+	}
+	for i := range clients {
+		rp.watchers = append(rp.watchers, clients[i].NewWatcher())
+	}
+
+	requests := make(chan replayOp, len(clients))
+	for i := range clients {
+		wg.Add(1)
+		go rp.doReplayOps(clients[i], requests)
+	}
+
+	donec, rep := printReport(rp.results)
+	rp.schedule(reader, requests)
+	wg.Wait()
+	close(rp.results)
+	<-donec
+
+	cancelWatches()
+	for _, w := range rp.watchers {
+		w.Close()
+	}
+	rp.watchWg.Wait()
+
+	maxLag := time.Duration(atomic.LoadInt64(&rp.maxLag))
+	fmt.Printf("\nReplay:\n")
+	fmt.Printf("  Ops:\t%d\n", atomic.LoadInt64(&rp.ops))
+	fmt.Printf("  Speed:\t%v\n", repSpeed)
+	fmt.Printf("  Max schedule lag:\t%4.4f secs.\n", maxLag.Seconds())
+	fmt.Printf("  Watches:\t%d\n", atomic.LoadInt64(&rp.watchCount))
+	fmt.Printf("  Watch events:\t%d\n", atomic.LoadInt64(&rp.watchEvents))
+	if repSample {
+		rep.printSecondSample()
+	}
+
+	stats, keys := rep.extractStats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+	stats["ops"] = fmt.Sprint(atomic.LoadInt64(&rp.ops))
+	errors := 0
+	for _, n := range rep.errorDist {
+		errors += n
+	}
+	stats["errors"] = fmt.Sprint(errors)
+	stats["max schedule lag (secs)"] = fmt.Sprintf("%4.4f", maxLag.Seconds())
+	stats["watch events"] = fmt.Sprint(atomic.LoadInt64(&rp.watchEvents))
+	keys = append(keys, "ops", "errors", "max schedule lag (secs)", "watch events")
+	writeSummaryToFile(cmd.Flags(), "replay", stats, keys)
+}
+
+// schedule reads the op log and queues each op when it is due.
+// The time between ops is the original time between them, divided by the replay speed.
+func (rp *replayer) schedule(reader *opLogReader, requests chan<- replayOp) {
+	defer close(requests)
+
+	var first time.Time
+	start := time.Now()
+	for {
+		rec, err := reader.next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Failed to read op log: %v", err)
+		}
+
+		if first.IsZero() {
+			first = rec.Time
+		}
+		due := start
+		if repSpeed > 0 {
+			due = start.Add(time.Duration(float64(rec.Time.Sub(first)) / repSpeed))
+			if d := time.Until(due); d > 0 {
+				time.Sleep(d)
+			}
+		}
+		requests <- replayOp{rec: rec, due: due}
+	}
+}
+
+func (rp *replayer) doReplayOps(client KV, requests <-chan replayOp) {
+	defer wg.Done()
+
+	for req := range requests {
+		if repSpeed > 0 {
+			rp.recordLag(time.Since(req.due))
+		}
+		atomic.AddInt64(&rp.ops, 1)
+
+		rec := req.rec
+		key := repKeyPrefix + rec.Key
+		var op Op
+		switch rec.Op {
+		case opLogPut:
+			op = OpPutKV(key, string(mustRandBytes(rec.ValueSize)))
+			if rec.Lease != 0 {
+				lease, err := rp.leases.get(rec.Lease)
+				if err != nil {
+					rp.results <- result{errStr: "lease: " + err.Error(), happened: time.Now()}
+					continue
+				}
+				op.Lease = lease
+			}
+		case opLogGet:
+			op = OpGetKV(key, rec.Prefix, repSerializable, false)
+		case opLogDelete:
+			op = OpDeleteKV(key, rec.Prefix)
+		case opLogWatch:
+			rp.startWatch(key, rec.Prefix)
+			continue
+		}
+
+		if verbose {
+			log.Printf("Replaying %s %s", rec.Op, key)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+		st := time.Now()
+		_, err := client.Do(ctx, op)
+		cancel()
+
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		rp.results <- result{errStr: errStr, duration: time.Since(st), happened: time.Now()}
+	}
+}
+
+// startWatch opens a watch, which lasts until the end of the replay, and counts its events
+func (rp *replayer) startWatch(key string, prefix bool) {
+	n := atomic.AddInt64(&rp.watchCount, 1)
+	wch := rp.watchers[int(n)%len(rp.watchers)].Watch(rp.watchCtx, key, prefix)
+
+	rp.watchWg.Add(1)
+	go func() {
+		defer rp.watchWg.Done()
+		for wresp := range wch {
+			if wresp.Err != nil {
+				if rp.watchCtx.Err() == nil {
+					log.Printf("ERROR: Watch error, key: %v, - %v", key, wresp.Err)
+				}
+				return
+			}
+			atomic.AddInt64(&rp.watchEvents, int64(len(wresp.Events)))
+		}
+	}()
+}
+
+func (rp *replayer) recordLag(lag time.Duration) {
+	for {
+		max := atomic.LoadInt64(&rp.maxLag)
+		if int64(lag) <= max || atomic.CompareAndSwapInt64(&rp.maxLag, max, int64(lag)) {
+			return
+		}
+	}
+}
@@ -77,11 +77,7 @@ type report struct {
 }
 
 func printReport(results chan result) (<-chan struct{}, *report) {
-	r := &report{
-		results:   results,
-		errorDist: make(map[string]int),
-		sps:       newSecondPoints(),
-	}
+	r := newReport(results)
 	return wrapReport(func() {
 		r.finalize()
 		r.print()
@@ -89,11 +85,7 @@ func printReport(results chan result) (<-chan struct{}, *report) {
 }
 
 func printRate(results chan result) (<-chan struct{}, *report) {
-	r := &report{
-		results:   results,
-		errorDist: make(map[string]int),
-		sps:       newSecondPoints(),
-	}
+	r := newReport(results)
 	return wrapReport(func() {
 		r.finalize()
 		fmt.Printf(" Requests/sec:\t%4.4f\n", r.rps)
@@ -109,9 +101,24 @@ func wrapReport(f func()) <-chan struct{} {
 	return donec
 }
 
+func newReport(results chan result) *report {
+	return &report{
+		results:   results,
+		errorDist: make(map[string]int),
+		sps:       newSecondPoints(),
+	}
+}
+
 func (r *report) finalize() {
 	st := time.Now()
 	startTime = st
+	r.collect(st)
+	duration = r.total
+}
+
+// collect reads the results until the results channel is closed, and calculates the stats. The total time is
+// measured from st.
+func (r *report) collect(st time.Time) {
 	for res := range r.results {
 		if res.errStr != "" {
 			r.errorDist[res.errStr]++
@@ -122,7 +129,6 @@ func (r *report) finalize() {
 		}
 	}
 	r.total = time.Since(st)
-	duration = r.total
 
 	r.rps = float64(len(r.lats)) / r.total.Seconds()
 	r.average = r.avgTotal / float64(len(r.lats))
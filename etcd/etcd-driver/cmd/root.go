@@ -40,6 +40,9 @@ var RootCmd = &cobra.Command{
 	Use:   "etcd-pattern",
 	Short: "Etcd key/value generator",
 	Long:  "A tool for generating etcd key/value pairs based on a pattern",
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		closeBackend()
+	},
 }
 
 var (
@@ -85,6 +88,7 @@ func init() {
 	RootCmd.PersistentFlags().StringVar(&cpuProfPath, "cpuprofile", "", "the path of file for storing cpu profile result")
 	RootCmd.PersistentFlags().StringVar(&memProfPath, "memprofile", "", "the path of file for storing heap profile result")
 	RootCmd.PersistentFlags().StringVar(&userNamePassword, "user", "", "username:password for authentication")
+	RootCmd.PersistentFlags().StringVar(&backendName, "backend", etcdBackendName, "Key/value store backend to drive (etcd, or embedded when built with -tags embedetcd)")
 
 	// If a new parmeter isn't added to the exclude list then the output csv file column layout will change
 	addToFileExclude("cacert")
@@ -96,6 +100,7 @@ func init() {
 	addToFileExclude("cpuprofile")
 	addToFileExclude("memprofile")
 	addToFileExclude("user")
+	addToFileExclude("backend")
 }
 
 func setupProfiling() {
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2017, 2021 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -21,7 +21,6 @@ import (
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
-	v3 "go.etcd.io/etcd/client/v3"
 )
 
 var patternCmd = &cobra.Command{
@@ -94,6 +93,12 @@ func init() {
 	patternCmd.Flags().BoolVar(&treeWatchBranches, "watch-with-prefix", false, "Whether to specify 'WithPrefix' on the watch (match exact key or also sub-keys)")
 	patternCmd.Flags().StringVar(&patWatchCountsPerLevel, "watch-counts-per-level", "", "The number of watchers for each level, separated by ','. Level 0 should be the first digit, followed by level 1 etc. 'n' equates to all available keys at that level. Ex: '0,0,0,n,0,0'")
 	patternCmd.Flags().DurationVar(&watchPrefixGetInterval, "watch-prefix-get-interval", watchPrefixGetInterval, "The duration between requests to get keys being watched")
+	// Verification parameters
+	patternCmd.Flags().BoolVar(&watchVerify, "verify-watches", false, "Record the revision of every put and delete during churn, and check that the watchers receive them all, in order")
+	patternCmd.Flags().StringVar(&watchMutationLog, "mutation-log", "", "File to write the recorded puts and deletes to, for 'watch-tree --verify-watches'")
+	patternCmd.Flags().DurationVar(&watchVerifyDrain, "verify-drain", 5*time.Second, "Time to wait for watch events in flight after the test ends, before verifying")
+	addToFileExclude("mutation-log")
+	addToFileExclude("verify-drain")
 
 	patFlags = patternCmd.Flags()
 }
@@ -151,6 +156,8 @@ func patternCmdFunc(cmd *cobra.Command, args []string) {
 	setupProfiling()
 	setupCsvFile()
 
+	agent := joinCoordinatedRun()
+
 	if len(patPattern) == 0 {
 		log.Fatal("Error: pattern is empty")
 	}
@@ -159,7 +166,7 @@ func patternCmdFunc(cmd *cobra.Command, args []string) {
 		patTotal = 0
 	}
 
-	clients := make([]*v3.Client, totalClients)
+	clients := make([]KV, totalClients)
 	if etcdReconnectCount == 0 {
 		clients = mustCreateClients(totalClients, totalConns)
 	}
@@ -179,6 +186,9 @@ func patternCmdFunc(cmd *cobra.Command, args []string) {
 			engine.setValueSpec(a, b)
 		}
 	}
+	if agent != nil {
+		engine.keyPrefix = agent.keyPrefix()
+	}
 
 	statKeys, stats := setupPatternStatsKeys()
 
@@ -197,21 +207,28 @@ func patternCmdFunc(cmd *cobra.Command, args []string) {
 		patPrintStats(engine, time.Now(), totalTime, stats, statKeys, rampStats, "pattern-ramp")
 	}
 
+	if agent != nil {
+		agent.waitForStart()
+	}
+
 	treeKeyLevels = engine.MaxLevels
 	watchCountsLevels := setupLevelWatchCounts(engine, patWatchCountsPerLevel)
 
 	if patLevelChurn >= 0 || patValChurn >= 0 || getRate >= 0 || watchCountsLevels > 0 {
 		engine.ResetStats()
 		engine.ResetIntervalStats()
+		if watchVerify {
+			watchMutations = newMutationRecorder()
+		}
 		churnStartTime := time.Now()
 		lastIntervalTime = churnStartTime
 
 		// Divy out clients favoring churn and watch
 		// TODO might want to add a parameters to handle this explicitly
-		var getClients []*v3.Client
-		var valChurnClients []*v3.Client
-		var levelChurnClients []*v3.Client
-		var watchClients []*v3.Client
+		var getClients []KV
+		var valChurnClients []KV
+		var levelChurnClients []KV
+		var watchClients []KV
 		if len(clients) > 4 && (patLevelChurn >= 0 || watchCountsLevels > 0) {
 			currentAvailable := 0
 			if getRate >= 0 {
@@ -283,6 +300,9 @@ func patternCmdFunc(cmd *cobra.Command, args []string) {
 					intervalTime := t.Sub(lastIntervalTime)
 
 					patPrintStats(engine, t, intervalTime, stats, statKeys, intervalStats, "pattern-interval")
+					if agent != nil {
+						agent.sendInterval(intervalTime, intervalStats)
+					}
 					lastIntervalTime = t
 					engine.ResetIntervalStats()
 				}
@@ -295,10 +315,29 @@ func patternCmdFunc(cmd *cobra.Command, args []string) {
 		endStats := engine.getChurnStats()
 		totalTime := currentTime.Sub(churnStartTime)
 		patPrintStats(engine, startTime, totalTime, stats, statKeys, endStats, "pattern-summary")
+		if agent != nil {
+			agent.leave(engine, endStats)
+		}
+
+		verified := true
+		if watchVerify {
+			if len(watchMutationLog) > 0 {
+				if err := watchMutations.writeFile(watchMutationLog); err != nil {
+					log.Fatalf("Failed to write the mutation log: %v", err)
+				}
+				log.Printf("Mutation log written to %v", watchMutationLog)
+			}
+			if watchCountsLevels > 0 {
+				verified = finishWatchVerification(watchMutations.sorted())
+			}
+		}
 
 		if patDoNotExit {
 			engine.WaitForTestEnd("/donotexit")
 		}
+		if !verified {
+			os.Exit(1)
+		}
 	}
 }
 
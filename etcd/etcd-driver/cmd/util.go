@@ -26,15 +26,8 @@ package cmd
 
 import (
 	"crypto/rand"
-	cryptoTLS "crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
-	"strings"
-
-	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 // Endpoints are distributed via round-robin to connections.
@@ -43,70 +36,22 @@ import (
 // - In the case of 'pattern' there are multiple tests, each of which creates
 //   a thread per client.
 
-var (
-	// dialTotal counts the number of mustCreateConn calls so that endpoint
-	// connections can be handed out in round-robin order
-	dialTotal int
-)
-
-func mustCreateConn(allEndpoints bool) *clientv3.Client {
-	var theEndpoints []string
-	if !allEndpoints {
-		endpoint := endpoints[dialTotal%len(endpoints)]
-		dialTotal++
-		theEndpoints = []string{endpoint}
-	} else {
-		theEndpoints = endpoints
-	}
-	cfg := clientv3.Config{Endpoints: theEndpoints}
-
-	if !tls.Empty() && len(userNamePassword) == 0 {
-		cfgtls, err := tls.ClientConfig()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "bad tls config: %v\n", err)
-			os.Exit(1)
-		}
-		cfg.TLS = cfgtls
-	} else if len(userNamePassword) > 0 {
-		split := strings.Split(userNamePassword, ":")
-		if len(split) != 2 {
-			fmt.Fprintf(os.Stderr, "bad username:password parameter\n")
-			os.Exit(1)
-		}
-		cfg.Username = split[0]
-		cfg.Password = split[1] // pragma: allowlist secret
-
-		caPath := tls.TrustedCAFile
-		if caPath != "" {
-			// #nosec G304
-			caCert, err := ioutil.ReadFile(caPath)
-			if err != nil {
-				log.Fatalf("Error occurred reading file: %s", err.Error())
-			}
-			caCertPool := x509.NewCertPool()
-			caCertPool.AppendCertsFromPEM(caCert)
-			tlsConfig := &cryptoTLS.Config{
-				RootCAs: caCertPool,
-			}
-			cfg.TLS = tlsConfig
-		}
-	}
-
-	client, err := clientv3.New(cfg)
+func mustCreateConn(allEndpoints bool) KV {
+	client, err := getBackend().Connect(allEndpoints)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "dial error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 	return client
 }
 
-func mustCreateClients(totalClients, totalConns uint) []*clientv3.Client {
-	conns := make([]*clientv3.Client, totalConns)
+func mustCreateClients(totalClients, totalConns uint) []KV {
+	conns := make([]KV, totalConns)
 	for i := range conns {
 		conns[i] = mustCreateConn(false)
 	}
 
-	clients := make([]*clientv3.Client, totalClients)
+	clients := make([]KV, totalClients)
 	for i := range clients {
 		clients[i] = conns[i%int(totalConns)]
 	}
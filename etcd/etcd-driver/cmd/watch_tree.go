@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2017, 2021 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -36,8 +36,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	v3 "go.etcd.io/etcd/client/v3"
-
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/net/context"
@@ -84,8 +82,8 @@ var (
 	watchPrefixGetInterval time.Duration
 	watchDoNotExit         bool
 
-	getRequests     chan v3.Op
-	watcherStreams  []v3.Watcher
+	getRequests     chan Op
+	watcherStreams  []Watcher
 	watchStatsMutex sync.Mutex
 )
 
@@ -102,6 +100,12 @@ func init() {
 	watchTreeCmd.Flags().IntVar(&watchStatsInterval, "stats-interval", -1, "The interval at which stats will be displayed (in seconds)")
 	watchTreeCmd.Flags().DurationVar(&watchPrefixGetInterval, "watch-prefix-get-interval", watchPrefixGetInterval, "The duration between requests to get keys being watched")
 	watchTreeCmd.Flags().BoolVar(&watchDoNotExit, "do-not-exit", false, "Don't exit the program after final statistics are published")
+	// Verification parameters
+	watchTreeCmd.Flags().BoolVar(&watchVerify, "verify-watches", false, "Check that the watchers receive every put and delete in the mutation log, in order")
+	watchTreeCmd.Flags().StringVar(&watchMutationLog, "mutation-log", "", "The mutation log written by 'pattern --verify-watches' (required with --verify-watches)")
+	watchTreeCmd.Flags().DurationVar(&watchMutationLogLimit, "mutation-log-timeout", time.Minute, "Time to wait for the mutation log to be written after the test ends")
+	watchTreeCmd.Flags().DurationVar(&watchVerifyDrain, "verify-drain", 5*time.Second, "Time to wait for watch events in flight after the test ends, before verifying")
+	addToFileExclude("mutation-log-timeout")
 
 	treeFlags = watchTreeCmd.Flags()
 }
@@ -111,6 +115,10 @@ func watchTreeFunc(cmd *cobra.Command, args []string) {
 	setupProfiling()
 	setupCsvFile()
 
+	if watchVerify && len(watchMutationLog) == 0 {
+		log.Fatal("Error: mutation-log is required with verify-watches")
+	}
+
 	// stats
 	statKeys := make([]string, 3)
 	stats := make(map[string]string)
@@ -164,19 +172,24 @@ func watchTreeFunc(cmd *cobra.Command, args []string) {
 	// Wait for test end signal to be written to etcd
 	// Could probably re-use existing clients, but will use our own just in case
 	testEndClient := mustCreateClients(1, 1)
-	rch := testEndClient[0].Watch(context.Background(), treeTestEndKey)
+	rch := testEndClient[0].NewWatcher().Watch(context.Background(), treeTestEndKey, false)
 	log.Printf("Created test end watch for %v", treeTestEndKey)
 
 TestEndLoop:
 	for wresp := range rch {
 		for _, ev := range wresp.Events {
-			if bytes.Compare(ev.Kv.Value, []byte("true")) == 0 {
+			if bytes.Compare(ev.Value, []byte("true")) == 0 {
 				log.Print("Test End detected")
 				break TestEndLoop
 			}
 		}
 	}
 
+	// Mutations after the watches are stopped can't be received, so aren't verified
+	if watchVerify {
+		watchVerifyStopRev = currentRevision(clients[0])
+		log.Printf("Verifying watches to revision %v", watchVerifyStopRev)
+	}
 	thePatternEngine.StopAllActivity()
 
 	fmt.Printf("Total Watch Events recieved: %v\n", thePatternEngine.stats.watchEventCount)
@@ -194,9 +207,21 @@ TestEndLoop:
 
 	writeSummaryToFile(treeFlags, "watch-tree", stats, statKeys)
 
+	verified := true
+	if watchVerify {
+		mutations, err := readMutationLog(watchMutationLog, watchMutationLogLimit)
+		if err != nil {
+			log.Fatalf("Failed to read the mutation log: %v", err)
+		}
+		verified = finishWatchVerification(mutations)
+	}
+
 	if watchDoNotExit {
 		thePatternEngine.WaitForTestEnd("/donotexit")
 	}
+	if !verified {
+		os.Exit(1)
+	}
 }
 
 func setupLevelWatchCounts(patternEngine *PatternEngine, watchCountsPerLevel string) int {
@@ -229,7 +254,7 @@ func setupLevelWatchCounts(patternEngine *PatternEngine, watchCountsPerLevel str
 	return 0
 }
 
-func setupWatches(patternEngine *PatternEngine, clients []*v3.Client) {
+func setupWatches(patternEngine *PatternEngine, clients []KV) {
 	watchedTrees := make([]string, treeWatchers)
 	log.Printf("Total number of watchers: %v", treeWatchers)
 
@@ -284,9 +309,15 @@ func setupWatches(patternEngine *PatternEngine, clients []*v3.Client) {
 
 	requests := make(chan string, len(clients))
 
-	watcherStreams = make([]v3.Watcher, treeWatchers)
+	if watchVerify {
+		// Start all the watches from the same revision, so none of them miss mutations made while they're created
+		watchVerifyStartRev = currentRevision(clients[0]) + 1
+		log.Printf("Verifying watches from revision %v", watchVerifyStartRev)
+	}
+
+	watcherStreams = make([]Watcher, treeWatchers)
 	for i := range watcherStreams {
-		watcherStreams[i] = v3.NewWatcher(clients[i%len(clients)])
+		watcherStreams[i] = clients[i%len(clients)].NewWatcher()
 	}
 
 	atomic.StoreInt32(&nrWatchTreeCompleted, int32(0))
@@ -303,7 +334,7 @@ func setupWatches(patternEngine *PatternEngine, clients []*v3.Client) {
 		close(requests)
 	}()
 
-	//var getRequests chan v3.Op
+	//var getRequests chan Op
 	if watchPrefixGetInterval > 0 {
 		getRequests = patternEngine.SetupRequestChannels(clients, true)
 		for i := 0; i < treeWatchers; i++ {
@@ -324,19 +355,19 @@ func tearDownWatches() {
 	}
 }
 
-func getWatchChannel(patternEngine *PatternEngine, stream v3.Watcher, prefix string) v3.WatchChan {
-	var wch v3.WatchChan
+func getWatchChannel(patternEngine *PatternEngine, stream Watcher, prefix string, rev int64) WatchChan {
+	var wch WatchChan
 
 	if treeWatchBranches {
 		if verbose {
 			fmt.Printf("Watching WithPrefix for %v\n", prefix)
 		}
-		wch = stream.Watch(context.Background(), prefix, v3.WithPrefix())
+		wch = stream.WatchFromRevision(context.Background(), prefix, true, rev)
 	} else {
 		if verbose {
 			fmt.Printf("Watching WithoutPrefix for %v\n", prefix)
 		}
-		wch = stream.Watch(context.Background(), prefix)
+		wch = stream.WatchFromRevision(context.Background(), prefix, false, rev)
 	}
 	if wch == nil {
 		fmt.Printf("could not open watch channel for  %v\n", prefix)
@@ -344,7 +375,7 @@ func getWatchChannel(patternEngine *PatternEngine, stream v3.Watcher, prefix str
 	return wch
 }
 
-func doWatchTree(patternEngine *PatternEngine, stream v3.Watcher, requests <-chan string) {
+func doWatchTree(patternEngine *PatternEngine, stream Watcher, requests <-chan string) {
 	for prefix := range requests {
 		go recvWatchTreeChan(patternEngine, stream, prefix)
 	}
@@ -356,16 +387,30 @@ func doWatchTree(patternEngine *PatternEngine, stream v3.Watcher, requests <-cha
 }
 
 // Part that handles the watch Events
-func recvWatchTreeChan(patternEngine *PatternEngine, stream v3.Watcher, prefix string) { //(byte){
+func recvWatchTreeChan(patternEngine *PatternEngine, stream Watcher, prefix string) { //(byte){
+	var verifier *watchVerifier
+	if watchVerify {
+		verifier = newWatchVerifier(prefix, treeWatchBranches, watchVerifyStartRev)
+	}
+
 	for {
-		wch := getWatchChannel(patternEngine, stream, prefix)
+		// When verifying, a reacquired watch carries on from the last event received rather than the current revision
+		var rev int64
+		if verifier != nil {
+			rev = verifier.nextRevision()
+		}
+		wch := getWatchChannel(patternEngine, stream, prefix, rev)
 		for r := range wch {
-			if r.Err() != nil {
+			if r.Err != nil {
 				if r.Canceled {
-					log.Printf("ERROR: Watch canceld, will reacquire, prefix: %v, - %v", prefix, r.Err())
+					if verifier != nil && r.CompactRevision > 0 {
+						log.Printf("ERROR: Watch revisions compacted, prefix: %v, restarting from revision %v", prefix, r.CompactRevision)
+						verifier.compact(r.CompactRevision)
+					}
+					log.Printf("ERROR: Watch canceld, will reacquire, prefix: %v, - %v", prefix, r.Err)
 					break
 				} else {
-					log.Printf("ERROR: Watch error, prefix: %v, - %v", prefix, r.Err())
+					log.Printf("ERROR: Watch error, prefix: %v, - %v", prefix, r.Err)
 					return
 				}
 			}
@@ -374,6 +419,9 @@ func recvWatchTreeChan(patternEngine *PatternEngine, stream v3.Watcher, prefix s
 				firstWatchTime = time.Now()
 			}
 			lastWatchTime = time.Now()
+			if verifier != nil {
+				verifier.add(r.Events, lastWatchTime)
+			}
 
 			watchStatsMutex.Lock()
 			patternEngine.stats.watchEventCount = patternEngine.stats.watchEventCount + 1
@@ -412,7 +460,7 @@ func printStats(patternEngine *PatternEngine, timeNow time.Time) (time.Duration,
 }
 
 // Probably not needed, but keeping code in case we want to do some puts
-func doPutForWatchTree(ctx context.Context, client v3.KV, requests <-chan v3.Op) {
+func doPutForWatchTree(ctx context.Context, client KV, requests <-chan Op) {
 	for op := range requests {
 		_, err := client.Do(ctx, op)
 		if err != nil {
@@ -0,0 +1,387 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
+)
+
+// maxVerifyErrors is the number of each type of verification error that is logged
+const maxVerifyErrors = 10
+
+var (
+	watchVerify           bool
+	watchMutationLog      string
+	watchMutationLogLimit time.Duration
+	watchVerifyDrain      time.Duration
+
+	// watchVerifyStartRev is the revision the verified watches start from
+	watchVerifyStartRev int64
+	// watchVerifyStopRev is the last revision before the verified watches were stopped, or 0 if they are still running
+	watchVerifyStopRev  int64
+	watchVerifiers      []*watchVerifier
+	watchVerifiersMutex sync.Mutex
+
+	// watchMutations records the puts and deletes, when the put side is verifying watches
+	watchMutations *mutationRecorder
+)
+
+// mutation is a put or delete of a single key, with the revision it was given
+type mutation struct {
+	Revision int64     `json:"rev"`
+	Type     EventType `json:"type"`
+	Key      string    `json:"key"`
+	Sent     time.Time `json:"sent"` // When the request was sent, which the watch delivery lag is measured from
+}
+
+// mutationRecorder records the mutations made by the put side
+type mutationRecorder struct {
+	mutex     sync.Mutex
+	mutations []mutation
+}
+
+func newMutationRecorder() *mutationRecorder {
+	return &mutationRecorder{}
+}
+
+// record records the keys changed by an operation. Deletes must be done with PrevKV, so the deleted keys are known.
+func (r *mutationRecorder) record(op Op, resp OpResponse, sent time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch op.Type {
+	case OpPut:
+		r.mutations = append(r.mutations, mutation{Revision: resp.Revision, Type: EventPut, Key: op.Key, Sent: sent})
+	case OpDelete:
+		for _, kv := range resp.PrevKvs {
+			r.mutations = append(r.mutations, mutation{Revision: resp.Revision, Type: EventDelete, Key: string(kv.Key), Sent: sent})
+		}
+	}
+}
+
+// sorted returns the mutations in revision order
+func (r *mutationRecorder) sorted() []mutation {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	mutations := make([]mutation, len(r.mutations))
+	copy(mutations, r.mutations)
+	sort.SliceStable(mutations, func(i, j int) bool { return mutations[i].Revision < mutations[j].Revision })
+	return mutations
+}
+
+// writeFile writes the mutation log, a mutation per line. It's written to a temporary file and renamed, so that a
+// watch-tree waiting for the log never reads a partial file.
+func (r *mutationRecorder) writeFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, m := range r.sorted() {
+		if err := enc.Encode(m); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readMutationLog waits for the mutation log to be written, then reads it
+func readMutationLog(path string, limit time.Duration) ([]mutation, error) {
+	deadline := time.Now().Add(limit)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("mutation log %s wasn't written within %v", path, limit)
+		}
+		time.Sleep(time.Second)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mutations []mutation
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var m mutation
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, line, err)
+		}
+		mutations = append(mutations, m)
+	}
+	return mutations, scanner.Err()
+}
+
+// currentRevision returns the current revision of the store
+func currentRevision(client KV) int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(clientTimeout)*time.Second)
+	defer cancel()
+	resp, err := client.Do(ctx, OpGetKV("/", false, false, true))
+	if err != nil {
+		log.Fatalf("Failed to get the current revision: %v", err)
+	}
+	return resp.Revision
+}
+
+type eventID struct {
+	revision int64
+	key      string
+}
+
+// revisionRange is the revisions [from, to)
+type revisionRange struct {
+	from int64
+	to   int64
+}
+
+// watchVerifier checks the events received by a single watcher
+type watchVerifier struct {
+	key    string
+	prefix bool
+
+	mutex      sync.Mutex
+	lastRev    int64
+	received   map[eventID]time.Time
+	duplicates []eventID
+	outOfOrder []eventID
+	compacted  []revisionRange
+}
+
+// newWatchVerifier creates a verifier for a watch, and registers it to be checked at the end of the test
+func newWatchVerifier(key string, prefix bool, startRev int64) *watchVerifier {
+	v := &watchVerifier{key: key, prefix: prefix, lastRev: startRev - 1, received: make(map[eventID]time.Time)}
+	watchVerifiersMutex.Lock()
+	watchVerifiers = append(watchVerifiers, v)
+	watchVerifiersMutex.Unlock()
+	return v
+}
+
+// nextRevision is the revision the watch should be (re)started from, so that no events are skipped
+func (v *watchVerifier) nextRevision() int64 {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.lastRev + 1
+}
+
+// add checks the order of received events. Events in a response must have revisions later than the events already
+// received, although a delete of several keys gives several events with the same revision.
+func (v *watchVerifier) add(events []Event, received time.Time) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for _, ev := range events {
+		id := eventID{revision: ev.Revision, key: string(ev.Key)}
+		if _, ok := v.received[id]; ok {
+			v.duplicates = append(v.duplicates, id)
+			continue
+		}
+		v.received[id] = received
+		if ev.Revision < v.lastRev {
+			v.outOfOrder = append(v.outOfOrder, id)
+			continue
+		}
+		v.lastRev = ev.Revision
+	}
+}
+
+// compact records that the events before compactRev couldn't be delivered, as they were compacted before the watch
+// could be restarted.
+func (v *watchVerifier) compact(compactRev int64) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if compactRev > v.lastRev+1 {
+		v.compacted = append(v.compacted, revisionRange{from: v.lastRev + 1, to: compactRev})
+		v.lastRev = compactRev - 1
+	}
+}
+
+func (v *watchVerifier) matches(key string) bool {
+	if v.prefix {
+		return strings.HasPrefix(key, v.key)
+	}
+	return key == v.key
+}
+
+func (v *watchVerifier) isCompacted(rev int64) bool {
+	for _, r := range v.compacted {
+		if rev >= r.from && rev < r.to {
+			return true
+		}
+	}
+	return false
+}
+
+// watchVerifyResult is the result of checking all the watchers against the mutations
+type watchVerifyResult struct {
+	watchers   int
+	expected   int
+	received   int
+	missing    int
+	duplicates int
+	outOfOrder int
+	compacted  int
+	unrecorded int
+	lag        *histogram.Histogram
+}
+
+func (r watchVerifyResult) passed() bool {
+	return r.missing == 0 && r.duplicates == 0 && r.outOfOrder == 0 && r.compacted == 0
+}
+
+// verifyWatches checks that each watcher received every mutation to the keys it watched, from the start revision
+// of the watches to the stop revision (if not 0). Mutations and events after the stop revision are ignored, as the
+// watches weren't running. The mutations must be sorted by revision.
+func verifyWatches(verifiers []*watchVerifier, mutations []mutation, startRev, stopRev int64) watchVerifyResult {
+	result := watchVerifyResult{watchers: len(verifiers), lag: histogram.New()}
+	logged := make(map[string]int)
+	logError := func(kind string, format string, args ...interface{}) {
+		if logged[kind] < maxVerifyErrors {
+			log.Printf("Watch verification: "+kind+": "+format, args...)
+		} else if logged[kind] == maxVerifyErrors {
+			log.Printf("Watch verification: further %s not logged", kind)
+		}
+		logged[kind]++
+	}
+
+	first := sort.Search(len(mutations), func(i int) bool { return mutations[i].Revision >= startRev })
+	if stopRev > 0 {
+		last := sort.Search(len(mutations), func(i int) bool { return mutations[i].Revision > stopRev })
+		if last < first {
+			last = first
+		}
+		mutations = mutations[:last]
+	}
+	watched := func(id eventID) bool { return stopRev <= 0 || id.revision <= stopRev }
+
+	recorded := make(map[eventID]bool)
+	for _, m := range mutations[first:] {
+		recorded[eventID{revision: m.Revision, key: m.Key}] = true
+	}
+
+	for _, v := range verifiers {
+		v.mutex.Lock()
+		for _, id := range v.duplicates {
+			if watched(id) {
+				result.duplicates++
+				logError("duplicates", "watch %s received revision %d for %s more than once", v.key, id.revision, id.key)
+			}
+		}
+		for _, id := range v.outOfOrder {
+			if watched(id) {
+				result.outOfOrder++
+				logError("out of order", "watch %s received revision %d for %s after a later revision", v.key, id.revision, id.key)
+			}
+		}
+
+		for _, m := range mutations[first:] {
+			if !v.matches(m.Key) {
+				continue
+			}
+			result.expected++
+			id := eventID{revision: m.Revision, key: m.Key}
+			if received, ok := v.received[id]; ok {
+				lag := received.Sub(m.Sent)
+				if lag < 0 {
+					lag = 0
+				}
+				result.lag.Record(lag)
+			} else if v.isCompacted(m.Revision) {
+				result.compacted++
+				logError("compacted", "watch %s lost revision %d for %s to compaction", v.key, m.Revision, m.Key)
+			} else {
+				result.missing++
+				logError("missing", "watch %s didn't receive revision %d (%s) for %s", v.key, m.Revision, m.Type, m.Key)
+			}
+		}
+
+		// Events for mutations by other drivers, or before the log was recorded, can't be checked
+		for id := range v.received {
+			if !watched(id) {
+				continue
+			}
+			result.received++
+			if !recorded[id] {
+				result.unrecorded++
+			}
+		}
+		v.mutex.Unlock()
+	}
+	return result
+}
+
+func (r watchVerifyResult) print() {
+	status := "PASS"
+	if !r.passed() {
+		status = "FAIL"
+	}
+	fmt.Printf("\nWatch verification: %s\n", status)
+	fmt.Printf("  Watchers: %v\n", r.watchers)
+	fmt.Printf("  Events expected: %v, received: %v\n", r.expected, r.received)
+	fmt.Printf("  Missing: %v\n", r.missing)
+	fmt.Printf("  Duplicates: %v\n", r.duplicates)
+	fmt.Printf("  Out of order: %v\n", r.outOfOrder)
+	fmt.Printf("  Lost to compaction: %v\n", r.compacted)
+	fmt.Printf("  Not in the mutation log: %v\n", r.unrecorded)
+	if r.lag.TotalCount() > 0 {
+		fmt.Printf("  Delivery lag:")
+		for _, p := range histogram.Percentiles {
+			fmt.Printf("  %s %4.4f secs.", histogram.PercentileName(p), r.lag.Percentile(p).Seconds())
+		}
+		fmt.Printf("  Max %4.4f secs.\n", r.lag.Max().Seconds())
+	}
+}
+
+// finishWatchVerification waits for events still in flight, then checks the watchers against the mutations and
+// prints the result. It returns false if the verification failed.
+func finishWatchVerification(mutations []mutation) bool {
+	log.Printf("Waiting %v for watch events in flight before verifying", watchVerifyDrain)
+	time.Sleep(watchVerifyDrain)
+
+	watchVerifiersMutex.Lock()
+	verifiers := watchVerifiers
+	watchVerifiersMutex.Unlock()
+
+	result := verifyWatches(verifiers, mutations, watchVerifyStartRev, watchVerifyStopRev)
+	result.print()
+	return result.passed()
+}
@@ -0,0 +1,400 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+// Keeps leases alive with KeepAlive streams, then stops renewing them and measures how late etcd revokes them
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var keepAliveCmd = &cobra.Command{
+	Use:   "keepalive",
+	Short: "Etcd lease keepalive and expiry accuracy test",
+	Long: `Grants leases with keys attached, keeps them alive with KeepAlive streams, then stops renewing them and
+measures how late etcd revokes each lease compared with its TTL`,
+
+	Run: keepAliveCmdFunc,
+}
+
+var (
+	kaLeases            int
+	kaTTL               int64
+	kaKeysPerLease      int
+	kaClients           int
+	kaGrantThreads      int
+	kaKeepAliveDuration time.Duration
+	kaExpiryTimeout     time.Duration
+	kaKeyPrefix         string
+)
+
+func init() {
+	rootCmd.AddCommand(keepAliveCmd)
+
+	keepAliveCmd.Flags().IntVar(&kaLeases, "leases", 10000, "The number of leases to grant")
+	keepAliveCmd.Flags().Int64Var(&kaTTL, "ttl", 30, "The TTL (in seconds) of each lease")
+	keepAliveCmd.Flags().IntVar(&kaKeysPerLease, "keys-per-lease", 1, "The number of keys attached to each lease")
+	keepAliveCmd.Flags().IntVar(&kaClients, "clients", 10, "The number of clients, each with its own KeepAlive stream, the leases are spread across")
+	keepAliveCmd.Flags().IntVar(&kaGrantThreads, "grant-threads", 100, "The number of threads granting leases and attaching keys")
+	keepAliveCmd.Flags().DurationVar(&kaKeepAliveDuration, "keepalive-duration", 5*time.Minute, "How long to keep the leases alive before letting them expire. 0 lets them expire as soon as they're granted")
+	keepAliveCmd.Flags().DurationVar(&kaExpiryTimeout, "expiry-timeout", 5*time.Minute, "How long after the last lease should have expired to wait for the leases to be revoked")
+	keepAliveCmd.Flags().StringVar(&kaKeyPrefix, "key-prefix", "/lease_test/keepalive/", "The prefix of the keys attached to the leases. Existing keys under the prefix are deleted")
+	keepAliveCmd.Flags().StringVar(&csvFile, "csv-file", "", "File to write csv results")
+	keepAliveCmd.Flags().StringVar(&fileComments, "file-comment", "", "Comment to add to results written to file")
+
+	addToFileExclude("cert")
+	addToFileExclude("key")
+	addToFileExclude("cacert")
+	addToFileExclude("csv-file")
+	addToFileExclude("help")
+}
+
+// kaLease tracks a single lease. The expected expiry is measured from when the grant or last keepalive response was
+// received, so the skew can be understated by up to a round trip.
+type kaLease struct {
+	id        clientv3.LeaseID
+	granted   bool
+	expiresAt time.Time
+	renewals  int
+	lost      bool
+	revokedAt time.Time
+}
+
+type kaResults struct {
+	granted       int
+	grantFailures int
+	renewals      int64
+	lost          int
+	revoked       int
+	notRevoked    int
+	early         int
+	skew          *histogram.Histogram
+	firstRevoke   time.Time
+	lastRevoke    time.Time
+	peakPerSecond int
+}
+
+var (
+	kaLeasesMutex sync.Mutex
+	kaLeaseStates []kaLease
+)
+
+func kaKey(lease, key int) string {
+	return fmt.Sprintf("%s%d/%d", kaKeyPrefix, lease, key)
+}
+
+// kaLeaseIndex returns the index of the lease a key is attached to
+func kaLeaseIndex(key string) (int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(key, kaKeyPrefix), "/", 2)
+	i, err := strconv.Atoi(parts[0])
+	if err != nil || i < 0 || i >= len(kaLeaseStates) {
+		return 0, false
+	}
+	return i, true
+}
+
+// watchRevocations records when the first key of each lease is deleted, which is when etcd revoked it
+func watchRevocations(ctx context.Context, rev int64) {
+	for wresp := range cli.Watch(ctx, kaKeyPrefix, clientv3.WithPrefix(), clientv3.WithRev(rev)) {
+		if err := wresp.Err(); err != nil {
+			log.Fatalf("Revocation watch failed: %v", err)
+		}
+		received := time.Now()
+
+		kaLeasesMutex.Lock()
+		for _, ev := range wresp.Events {
+			if ev.Type != clientv3.EventTypeDelete {
+				continue
+			}
+			if i, ok := kaLeaseIndex(string(ev.Kv.Key)); ok && kaLeaseStates[i].revokedAt.IsZero() {
+				kaLeaseStates[i].revokedAt = received
+			}
+		}
+		kaLeasesMutex.Unlock()
+	}
+}
+
+// grantLeases grants the leases and attaches the keys to them
+func grantLeases(clients []*clientv3.Client) {
+	indexes := make(chan int, kaGrantThreads)
+	var wg sync.WaitGroup
+	for t := 0; t < kaGrantThreads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				client := clients[i%len(clients)]
+				resp, err := client.Grant(context.TODO(), kaTTL)
+				if err != nil {
+					log.Print(err)
+					continue
+				}
+				granted := time.Now()
+				for k := 0; k < kaKeysPerLease; k++ {
+					if _, err := client.Put(context.TODO(), kaKey(i, k), "bar", clientv3.WithLease(resp.ID)); err != nil {
+						log.Print(err)
+					}
+				}
+
+				kaLeasesMutex.Lock()
+				kaLeaseStates[i].id = resp.ID
+				kaLeaseStates[i].granted = true
+				kaLeaseStates[i].expiresAt = granted.Add(time.Duration(resp.TTL) * time.Second)
+				kaLeasesMutex.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < kaLeases; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// keepLeasesAlive keeps the leases alive for the keepalive duration, then stops renewing them. It returns the
+// number of keepalive responses received.
+func keepLeasesAlive(clients []*clientv3.Client) int64 {
+	ctx, cancel := context.WithCancel(context.Background())
+	var renewals int64
+	var wg sync.WaitGroup
+
+	for i := range kaLeaseStates {
+		if !kaLeaseStates[i].granted {
+			continue
+		}
+		ch, err := clients[i%len(clients)].KeepAlive(ctx, kaLeaseStates[i].id)
+		if err != nil {
+			log.Printf("KeepAlive failed for lease %x: %v", kaLeaseStates[i].id, err)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for resp := range ch {
+				received := time.Now()
+				atomic.AddInt64(&renewals, 1)
+				kaLeasesMutex.Lock()
+				kaLeaseStates[i].expiresAt = received.Add(time.Duration(resp.TTL) * time.Second)
+				kaLeaseStates[i].renewals++
+				kaLeasesMutex.Unlock()
+			}
+			// The channel is closed early if the lease expired or the keepalive failed
+			if ctx.Err() == nil {
+				kaLeasesMutex.Lock()
+				kaLeaseStates[i].lost = true
+				kaLeasesMutex.Unlock()
+			}
+		}(i)
+	}
+
+	time.Sleep(kaKeepAliveDuration)
+	log.Printf("Stopping keepalives, %d responses received", atomic.LoadInt64(&renewals))
+	cancel()
+	wg.Wait()
+	return renewals
+}
+
+// waitForRevocations waits until every lease is revoked, or the expiry timeout has passed since the last lease should
+// have expired.
+func waitForRevocations() {
+	var deadline time.Time
+	kaLeasesMutex.Lock()
+	for _, l := range kaLeaseStates {
+		if l.granted && l.expiresAt.After(deadline) {
+			deadline = l.expiresAt
+		}
+	}
+	kaLeasesMutex.Unlock()
+	deadline = deadline.Add(kaExpiryTimeout)
+
+	log.Printf("Waiting for the leases to be revoked (until %v at the latest)", deadline.Format(time.StampMilli))
+	for time.Now().Before(deadline) {
+		outstanding := 0
+		kaLeasesMutex.Lock()
+		for _, l := range kaLeaseStates {
+			if l.granted && l.revokedAt.IsZero() {
+				outstanding++
+			}
+		}
+		kaLeasesMutex.Unlock()
+		if outstanding == 0 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// expiryResults compares when each lease was revoked with when it should have expired. Leases lost while they
+// were being kept alive are counted, but aren't included in the skew.
+func expiryResults() kaResults {
+	kaLeasesMutex.Lock()
+	defer kaLeasesMutex.Unlock()
+
+	r := kaResults{skew: histogram.New()}
+	perSecond := make(map[int64]int)
+	for _, l := range kaLeaseStates {
+		if !l.granted {
+			r.grantFailures++
+			continue
+		}
+		r.granted++
+		if l.lost {
+			r.lost++
+			continue
+		}
+		if l.revokedAt.IsZero() {
+			r.notRevoked++
+			continue
+		}
+
+		r.revoked++
+		skew := l.revokedAt.Sub(l.expiresAt)
+		if skew < 0 {
+			r.early++
+			skew = 0
+		}
+		r.skew.Record(skew)
+
+		if r.firstRevoke.IsZero() || l.revokedAt.Before(r.firstRevoke) {
+			r.firstRevoke = l.revokedAt
+		}
+		if l.revokedAt.After(r.lastRevoke) {
+			r.lastRevoke = l.revokedAt
+		}
+		perSecond[l.revokedAt.Unix()]++
+	}
+	for _, n := range perSecond {
+		if n > r.peakPerSecond {
+			r.peakPerSecond = n
+		}
+	}
+	return r
+}
+
+// revocationRate is the mean number of leases revoked per second, from the first revocation to the last
+func (r kaResults) revocationRate() float64 {
+	window := r.lastRevoke.Sub(r.firstRevoke).Seconds()
+	if window <= 0 {
+		return float64(r.revoked)
+	}
+	return float64(r.revoked) / window
+}
+
+func (r kaResults) print() {
+	fmt.Printf("\nLease keepalive and expiry results:\n")
+	fmt.Printf("  Leases granted: %v, grant failures: %v\n", r.granted, r.grantFailures)
+	fmt.Printf("  Keepalive responses: %v\n", r.renewals)
+	fmt.Printf("  Leases lost while kept alive: %v\n", r.lost)
+	fmt.Printf("  Revoked: %v, not revoked: %v, revoked before their TTL: %v\n", r.revoked, r.notRevoked, r.early)
+	if r.skew.TotalCount() > 0 {
+		fmt.Printf("  Expiry skew:")
+		for _, p := range histogram.Percentiles {
+			fmt.Printf("  %s %4.4f secs.", histogram.PercentileName(p), r.skew.Percentile(p).Seconds())
+		}
+		fmt.Printf("  Max %4.4f secs.\n", r.skew.Max().Seconds())
+	}
+	fmt.Printf("  Revocations/sec: %.1f, peak: %v\n", r.revocationRate(), r.peakPerSecond)
+}
+
+func (r kaResults) stats() (map[string]string, []string) {
+	stats := make(map[string]string)
+	var keys []string
+	add := func(name, value string) {
+		stats[name] = value
+		keys = append(keys, name)
+	}
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)
+	}
+
+	add("Leases Granted", strconv.Itoa(r.granted))
+	add("Grant Failures", strconv.Itoa(r.grantFailures))
+	add("KeepAlive Responses", strconv.FormatInt(r.renewals, 10))
+	add("Leases Lost", strconv.Itoa(r.lost))
+	add("Revoked", strconv.Itoa(r.revoked))
+	add("Not Revoked", strconv.Itoa(r.notRevoked))
+	add("Revoked Early", strconv.Itoa(r.early))
+	for _, p := range histogram.Percentiles {
+		add("Expiry Skew "+histogram.PercentileName(p)+" (ms)", ms(r.skew.Percentile(p)))
+	}
+	add("Expiry Skew Max (ms)", ms(r.skew.Max()))
+	add("Revocations/sec", strconv.FormatFloat(r.revocationRate(), 'f', 1, 64))
+	add("Peak Revocations/sec", strconv.Itoa(r.peakPerSecond))
+	return stats, keys
+}
+
+func keepAliveCmdFunc(cmd *cobra.Command, args []string) {
+	if kaLeases <= 0 || kaKeysPerLease <= 0 || kaClients <= 0 || kaGrantThreads <= 0 {
+		fmt.Fprintln(os.Stderr, "--leases, --keys-per-lease, --clients and --grant-threads must be greater than 0")
+		os.Exit(1)
+	}
+
+	cli = createClient(endpoints)
+	defer cli.Close()
+	clients := make([]*clientv3.Client, kaClients)
+	for i := range clients {
+		clients[i] = createClient(endpoints)
+		defer clients[i].Close()
+	}
+
+	resp, err := cli.Delete(context.TODO(), kaKeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		log.Fatalf("Failed to delete existing keys under %s: %v", kaKeyPrefix, err)
+	}
+	if resp.Deleted > 0 {
+		log.Printf("Deleted %d existing keys under %s", resp.Deleted, kaKeyPrefix)
+	}
+
+	kaLeaseStates = make([]kaLease, kaLeases)
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	go watchRevocations(watchCtx, resp.Header.Revision+1)
+
+	startTime := time.Now()
+	log.Printf("Granting %d leases with a TTL of %d seconds and %d keys each, over %d clients", kaLeases, kaTTL, kaKeysPerLease, kaClients)
+	grantLeases(clients)
+	log.Printf("Leases granted in %v", time.Since(startTime))
+
+	var renewals int64
+	if kaKeepAliveDuration > 0 {
+		log.Printf("Keeping the leases alive for %v", kaKeepAliveDuration)
+		renewals = keepLeasesAlive(clients)
+	}
+
+	waitForRevocations()
+	results := expiryResults()
+	results.renewals = renewals
+	duration := time.Since(startTime)
+
+	// Revoke anything etcd failed to, so the keys don't outlive the test
+	kaLeasesMutex.Lock()
+	for _, l := range kaLeaseStates {
+		if l.granted && l.revokedAt.IsZero() {
+			cli.Revoke(context.TODO(), l.id)
+		}
+	}
+	kaLeasesMutex.Unlock()
+
+	results.print()
+	stats, keys := results.stats()
+	writeFile(cmd.Flags(), "lease-keepalive", stats, keys, startTime, duration)
+}
@@ -63,15 +63,15 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&tls.KeyFile, "key", "", "Identify HTTPS client using this SSL key file")
 	rootCmd.PersistentFlags().StringVar(&tls.TrustedCAFile, "cacert", "", "Verify certificates of HTTPS-enabled servers using this CA bundle")
 
-	rootCmd.PersistentFlags().IntVar(&threads, "threads", 200, "The number of threads that will generate leases.")
-	rootCmd.PersistentFlags().IntVar(&leaseDurationSeconds, "lease-duration-seconds", 2000, "The duration of each lease")
-	rootCmd.PersistentFlags().IntVar(&rampDurationMinutes, "ramp-duration-minutes", 40, "The number of minutes to take to generate the full set of leases in etcd")
-	rootCmd.PersistentFlags().IntVar(&rampCycles, "ramp-cycles", 4, "The number ramp up cycles")
-	rootCmd.PersistentFlags().IntVar(&rampStartLeases, "ramp-start-leases", 250000, "The number of leases the first ramp up will create")
-	rootCmd.PersistentFlags().BoolVar(&rampAtConstantRate, "ramp-at-constant-rate", false, "False: maintain constant lease duration, adjust lease request rate. True: Adjust duration of lease, and use --fixed-lease-ticker-seconds for the lease request rate")
-	rootCmd.PersistentFlags().IntVar(&rampIncrementLeases, "ramp-increment-leases", 250000, "Number of additional leases to add with each ramp cycle")
-	rootCmd.PersistentFlags().IntVar(&fixedLeaseTickerSeconds, "fixed-lease-ticker-seconds", 1, "The duration, in seconds, between each thread adding a lease")
-	rootCmd.PersistentFlags().IntVar(&rampDelayMinutes, "ramp-delay-minutes", 10, "The delay (in minutes) between ramp cycles. If set to -1 then leases will be maintained indefinitely")
+	rootCmd.Flags().IntVar(&threads, "threads", 200, "The number of threads that will generate leases.")
+	rootCmd.Flags().IntVar(&leaseDurationSeconds, "lease-duration-seconds", 2000, "The duration of each lease")
+	rootCmd.Flags().IntVar(&rampDurationMinutes, "ramp-duration-minutes", 40, "The number of minutes to take to generate the full set of leases in etcd")
+	rootCmd.Flags().IntVar(&rampCycles, "ramp-cycles", 4, "The number ramp up cycles")
+	rootCmd.Flags().IntVar(&rampStartLeases, "ramp-start-leases", 250000, "The number of leases the first ramp up will create")
+	rootCmd.Flags().BoolVar(&rampAtConstantRate, "ramp-at-constant-rate", false, "False: maintain constant lease duration, adjust lease request rate. True: Adjust duration of lease, and use --fixed-lease-ticker-seconds for the lease request rate")
+	rootCmd.Flags().IntVar(&rampIncrementLeases, "ramp-increment-leases", 250000, "Number of additional leases to add with each ramp cycle")
+	rootCmd.Flags().IntVar(&fixedLeaseTickerSeconds, "fixed-lease-ticker-seconds", 1, "The duration, in seconds, between each thread adding a lease")
+	rootCmd.Flags().IntVar(&rampDelayMinutes, "ramp-delay-minutes", 10, "The delay (in minutes) between ramp cycles. If set to -1 then leases will be maintained indefinitely")
 	rootCmd.Flags().DurationVar(&startupDelay, "startup-delay", startupDelay, "The time to wait before leases are created")
 }
 
@@ -0,0 +1,79 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	csvFile      string
+	fileComments string
+	fileExclude  map[string]bool
+)
+
+func addToFileExclude(param string) {
+	if fileExclude == nil {
+		fileExclude = make(map[string]bool)
+	}
+	fileExclude[param] = true
+}
+
+// writeFile appends the results to the csv file, in the same format as etcd-driver: the test name, start time and
+// duration, followed by the stats and then the flags. The header is only written if the file is empty.
+func writeFile(flags *flag.FlagSet, testName string, stats map[string]string, keys []string, startTime time.Time, duration time.Duration) {
+	if len(csvFile) == 0 {
+		return
+	}
+
+	file, err := os.OpenFile(csvFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		fmt.Println("Error openning file", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"test", "startTime", "duration (ms)"}
+	results := []string{testName, startTime.Format("2006-01-02 15:04:05"), strconv.FormatInt(int64(duration/time.Millisecond), 10)}
+	for _, name := range keys {
+		header = append(header, name)
+		results = append(results, stats[name])
+	}
+	flags.VisitAll(func(flg *flag.Flag) {
+		if fileExclude[flg.Name] == false {
+			header = append(header, flg.Name)
+			if flg.Name == "endpoints" {
+				results = append(results, strconv.Itoa(len(endpoints)))
+			} else {
+				results = append(results, flg.Value.String())
+			}
+		}
+	})
+
+	info, _ := file.Stat()
+	if info.Size() == 0 {
+		if err := writer.Write(header); err != nil {
+			fmt.Println("File write error for header")
+		}
+	}
+	if err := writer.Write(results); err != nil {
+		fmt.Println("File write error for results")
+	}
+}
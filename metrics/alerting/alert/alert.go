@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2021, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2021, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets, irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -34,6 +34,8 @@ const (
 	Zscore
 	// Silenced highlights potential alerts that have been silenced by referencing an open issue
 	Silenced
+	// ChangePoint highlights alerts where the historical results have shifted, based on change-point detection
+	ChangePoint
 )
 
 func (s Severity) String() string {
@@ -46,10 +48,24 @@ func (s Severity) String() string {
 		return "ERROR"
 	case Zscore:
 		return "Z-SCORE"
+	case ChangePoint:
+		return "CHANGE-POINT"
+	case Silenced:
+		return "SILENCED"
 	}
 	return "UNKNOWN"
 }
 
+// ParseSeverity returns the severity with the name (as returned by String)
+func ParseSeverity(name string) (Severity, error) {
+	for s := Information; s <= ChangePoint; s++ {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity '%s'", name)
+}
+
 // Alert holds details of a test result alert
 type Alert struct {
 	Name                 string
@@ -65,25 +81,46 @@ type Alert struct {
 	InfoWarningThreshold float64
 	LeniencyThreshold    float64
 	Result               float64
+	ShiftBefore          float64 // Mean result before a change point
+	ShiftAfter           float64 // Mean result since a change point
+	ShiftStatistic       float64 // CUSUM sum or E-Divisive p-value for a change point
+}
+
+// Key identifies the test result an alert is for, so it can be tracked across runs
+func (a Alert) Key() string {
+	return strings.Join([]string{a.EnvName, a.Name, a.Alert.Name, a.MachineType, a.KubeVersion, a.OperatingSystem}, "|")
+}
+
+// Threshold returns the threshold which triggered the alert. For change points, it is the minimum shift percentage.
+func (a Alert) Threshold() float64 {
+	switch a.Sev {
+	case Error:
+		return a.Alert.Thresholds[a.MachineType].Error
+	case Warning:
+		return a.Alert.Thresholds[a.MachineType].Warn
+	case Information:
+		return a.LeniencyThreshold
+	case Zscore:
+		return a.Alert.Thresholds[a.MachineType].Zscore
+	case ChangePoint:
+		return a.Alert.ChangePoint.MinShift
+	}
+	return 0
 }
 
 func displayAlert(a Alert) {
-	var threshold float64
 	var colour string
 	var indent string
 
+	threshold := a.Threshold()
 	switch a.Sev {
 	case Error:
-		threshold = a.Alert.Thresholds[a.MachineType].Error
 		colour = config.ColourRed
 	case Warning:
-		threshold = a.Alert.Thresholds[a.MachineType].Warn
 		colour = config.ColourYellow
 	case Information:
-		threshold = a.LeniencyThreshold
 		colour = config.ColourGreen
-	case Zscore:
-		threshold = a.Alert.Thresholds[a.MachineType].Zscore
+	case Zscore, ChangePoint:
 		colour = config.ColourMagenta
 	}
 
@@ -107,7 +144,16 @@ func displayAlert(a Alert) {
 	}
 
 	fmt.Printf("\n%s\tTest: %s - %s\n", indent, a.Name, a.Alert.Name)
-	fmt.Printf("%s\tTimestamp: %s, Threshold: %.6g, Result: %.6g\n", indent, a.Timestamp, threshold, a.Result)
+	if a.Sev == ChangePoint {
+		method := a.Alert.ChangePoint.Method
+		if len(method) == 0 {
+			method = CUSUM
+		}
+		fmt.Printf("%s\tShift began: %s, Before: %.6g, After: %.6g, Shift: %+.6g (%+.1f%%)\n", indent, a.Timestamp, a.ShiftBefore, a.ShiftAfter, a.Result, a.ShiftPercent())
+		fmt.Printf("%s\tMethod: %s, Statistic: %.4g, Minimum shift: %.6g%%\n", indent, method, a.ShiftStatistic, threshold)
+	} else {
+		fmt.Printf("%s\tTimestamp: %s, Threshold: %.6g, Result: %.6g\n", indent, a.Timestamp, threshold, a.Result)
+	}
 	if a.Sev == Information {
 		fmt.Printf("%s\tWarningThreshold: %.6g\n", indent, a.InfoWarningThreshold)
 	}
@@ -224,6 +270,31 @@ func (a Alert) ProcessData(ac config.Alert, results influxdata.TestResults) []Al
 		}
 	}
 
+	// Check for a shift in the historical results
+	if ac.ChangePoint != nil && totalCount > config.ConfigData.Options.History.Minimum {
+		values, timestamps := chronological(results)
+		if cp, found := detectChangePoint(*ac.ChangePoint, ac.LimitType, values); found {
+			alt := Alert{
+				Name:            a.Name,
+				EnvName:         a.EnvName,
+				Carrier:         a.Carrier,
+				Owner:           a.Owner,
+				KubeVersion:     a.KubeVersion,
+				MachineType:     a.MachineType,
+				OperatingSystem: a.OperatingSystem,
+				Alert:           ac,
+				Timestamp:       time.Unix(timestamps[cp.Index], 0),
+				Sev:             ChangePoint,
+				Result:          cp.Shift(),
+				ShiftBefore:     cp.Before,
+				ShiftAfter:      cp.After,
+				ShiftStatistic:  cp.Statistic,
+			}
+			displayAlert(alt)
+			alerts = append(alerts, alt)
+		}
+	}
+
 	// Check for simple threshold based alert
 	if ac.Thresholds[a.MachineType].Warn > 0 || ac.Thresholds[a.MachineType].Error > 0 {
 		alt := Alert{
@@ -265,3 +336,8 @@ func (a Alert) ProcessData(ac config.Alert, results influxdata.TestResults) []Al
 
 	return alerts
 }
+
+// ShiftPercent returns the shift for a change point alert as a percentage of the mean before the change
+func (a Alert) ShiftPercent() float64 {
+	return changePoint{Before: a.ShiftBefore, After: a.ShiftAfter}.ShiftPercent()
+}
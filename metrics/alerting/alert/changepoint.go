@@ -0,0 +1,256 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package alert
+
+import (
+	"math"
+	"math/rand"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
+	influxdata "github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/influx"
+)
+
+// Change-point detection methods
+const (
+	CUSUM     = "cusum"
+	EDivisive = "edivisive"
+)
+
+// Change-point detection defaults
+const (
+	defaultCUSUMThreshold = 4.0
+	defaultCUSUMDrift     = 0.5
+	defaultPermutations   = 199
+	defaultSignificance   = 0.05
+	defaultMinSegment     = 3
+)
+
+// changePoint describes a shift in the mean of a series of results
+type changePoint struct {
+	Index     int     // Index of the first result after the shift began
+	Before    float64 // Mean of the results before the shift
+	After     float64 // Mean of the results from the shift onwards
+	Statistic float64 // cusum: the cumulative sum in standard deviations, edivisive: the p-value
+}
+
+// Shift returns the magnitude of the shift
+func (cp changePoint) Shift() float64 {
+	return cp.After - cp.Before
+}
+
+// ShiftPercent returns the magnitude of the shift as a percentage of the mean before it
+func (cp changePoint) ShiftPercent() float64 {
+	if cp.Before == 0 {
+		return 0
+	}
+	return 100 * cp.Shift() / math.Abs(cp.Before)
+}
+
+// chronological returns the values of the test results, oldest first. Influx returns the results newest first.
+func chronological(results influxdata.TestResults) ([]float64, []int64) {
+	all := make([]influxdata.TestResult, 0, len(results.Current)+len(results.Historical))
+	all = append(all, results.Current...)
+	all = append(all, results.Historical...)
+
+	values := make([]float64, len(all))
+	timestamps := make([]int64, len(all))
+	for i, r := range all {
+		values[len(all)-1-i] = r.Val
+		timestamps[len(all)-1-i] = r.Timestamp
+	}
+	return values, timestamps
+}
+
+// detectChangePoint looks for a shift in the results, in the direction which is a regression for the limit type
+func detectChangePoint(cfg config.ChangePoint, limitType string, values []float64) (changePoint, bool) {
+	minSegment := cfg.MinSegment
+	if minSegment <= 0 {
+		minSegment = defaultMinSegment
+	}
+	if len(values) < 2*minSegment {
+		return changePoint{}, false
+	}
+
+	var cp changePoint
+	var found bool
+	switch cfg.Method {
+	case "", CUSUM:
+		cp, found = cusum(cfg, limitType, values, minSegment)
+	case EDivisive:
+		cp, found = eDivisive(cfg, values, minSegment)
+	}
+	if !found {
+		return cp, false
+	}
+
+	// Only shifts in the direction of a regression are of interest
+	switch limitType {
+	case "floor":
+		found = cp.Shift() < 0
+	case "ceiling":
+		found = cp.Shift() > 0
+	default:
+		found = false
+	}
+	if found && cfg.MinShift > 0 && math.Abs(cp.ShiftPercent()) < cfg.MinShift {
+		found = false
+	}
+	return cp, found
+}
+
+// cusum runs a one-sided tabular CUSUM, in the direction of a regression, against the mean and standard deviation of
+// the baseline results. The shift began after the last point where the sum was zero, and an alarm is raised if the
+// sum exceeds the threshold and hasn't returned to zero by the latest result.
+func cusum(cfg config.ChangePoint, limitType string, values []float64, minSegment int) (changePoint, bool) {
+	threshold := cfg.Threshold
+	if threshold <= 0 {
+		threshold = defaultCUSUMThreshold
+	}
+	drift := cfg.Drift
+	if drift <= 0 {
+		drift = defaultCUSUMDrift
+	}
+	baseline := cfg.Baseline
+	if baseline <= 0 {
+		baseline = len(values) / 2
+	}
+	if baseline < minSegment {
+		baseline = minSegment
+	}
+	if baseline > len(values)-minSegment {
+		baseline = len(values) - minSegment
+	}
+
+	mean, sd := meanSD(values[:baseline])
+	if sd == 0 {
+		return changePoint{}, false
+	}
+
+	direction := 1.0
+	if limitType == "floor" {
+		direction = -1.0
+	}
+
+	sum := 0.0
+	start := 0
+	alarm := false
+	for i, v := range values {
+		sum = math.Max(0, sum+direction*(v-mean)/sd-drift)
+		if sum == 0 {
+			start = i + 1
+			alarm = false
+		} else if sum > threshold {
+			alarm = true
+		}
+	}
+	if !alarm || start >= len(values) {
+		return changePoint{}, false
+	}
+
+	after, _ := meanSD(values[start:])
+	return changePoint{Index: start, Before: mean, After: after, Statistic: sum}, true
+}
+
+// eDivisive finds the single most significant change point using the E-Divisive means energy statistic,
+// and tests its significance with a permutation test
+func eDivisive(cfg config.ChangePoint, values []float64, minSegment int) (changePoint, bool) {
+	permutations := cfg.Permutations
+	if permutations <= 0 {
+		permutations = defaultPermutations
+	}
+	significance := cfg.Significance
+	if significance <= 0 {
+		significance = defaultSignificance
+	}
+
+	index, q := bestEnergySplit(values, minSegment)
+	if index < 0 {
+		return changePoint{}, false
+	}
+
+	// Seeded so that the same results always generate the same alerts
+	rnd := rand.New(rand.NewSource(int64(len(values))))
+	permuted := make([]float64, len(values))
+	copy(permuted, values)
+	exceeded := 0
+	for p := 0; p < permutations; p++ {
+		rnd.Shuffle(len(permuted), func(i, j int) { permuted[i], permuted[j] = permuted[j], permuted[i] })
+		if _, pq := bestEnergySplit(permuted, minSegment); pq >= q {
+			exceeded++
+		}
+	}
+	pValue := float64(exceeded+1) / float64(permutations+1)
+	if pValue > significance {
+		return changePoint{}, false
+	}
+
+	before, _ := meanSD(values[:index])
+	after, _ := meanSD(values[index:])
+	return changePoint{Index: index, Before: before, After: after, Statistic: pValue}, true
+}
+
+// bestEnergySplit returns the split of the values which maximises the energy statistic, and the statistic
+func bestEnergySplit(values []float64, minSegment int) (int, float64) {
+	best := -1
+	bestQ := math.Inf(-1)
+	for tau := minSegment; tau <= len(values)-minSegment; tau++ {
+		if q := energy(values[:tau], values[tau:]); q > bestQ {
+			best = tau
+			bestQ = q
+		}
+	}
+	return best, bestQ
+}
+
+// energy returns the scaled energy distance between two samples
+func energy(x, y []float64) float64 {
+	m := float64(len(x))
+	n := float64(len(y))
+
+	between := 0.0
+	for _, a := range x {
+		for _, b := range y {
+			between += math.Abs(a - b)
+		}
+	}
+	between = 2 * between / (m * n)
+
+	return m * n / (m + n) * (between - withinDistance(x) - withinDistance(y))
+}
+
+// withinDistance returns the mean absolute distance between pairs of values in a sample
+func withinDistance(x []float64) float64 {
+	if len(x) < 2 {
+		return 0
+	}
+	total := 0.0
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			total += math.Abs(x[i] - x[j])
+		}
+	}
+	pairs := float64(len(x)*(len(x)-1)) / 2
+	return total / pairs
+}
+
+func meanSD(values []float64) (float64, float64) {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	mean := total / float64(len(values))
+
+	sd := 0.0
+	for _, v := range values {
+		sd += math.Pow(v-mean, 2)
+	}
+	return mean, math.Sqrt(sd / float64(len(values)))
+}
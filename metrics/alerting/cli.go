@@ -0,0 +1,159 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/state"
+)
+
+// runCommand runs an alert state command
+func runCommand(store *state.Store, args []string) error {
+	var run func(*state.Store, []string) error
+	switch args[0] {
+	case "list":
+		run = listAlerts
+	case "silence":
+		run = silenceAlerts
+	case "unsilence":
+		run = unsilenceAlerts
+	case "ack":
+		run = ackAlerts
+	case "unack":
+		run = unackAlerts
+	default:
+		return fmt.Errorf("unknown command '%s' - expected list, silence, unsilence, ack or unack", args[0])
+	}
+	return run(store, args[1:])
+}
+
+// listAlerts lists the alerts in the state
+func listAlerts(store *state.Store, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	all := fs.Bool("all", false, "Include resolved alerts")
+	fs.Parse(args)
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FINGERPRINT\tSTATUS\tSEVERITY\tSINCE\tLAST SEEN\tENVIRONMENT\tTEST\tALERT\tDETAILS\tNOTES")
+	for _, e := range store.Entries() {
+		if e.Status != state.StatusFiring && !*all && !e.Silenced(now) {
+			continue
+		}
+
+		var details []string
+		for _, d := range []string{e.MachineType, e.KubeVersion, e.OperatingSystem} {
+			if len(d) > 0 {
+				details = append(details, d)
+			}
+		}
+		var notes []string
+		if e.Ack != nil {
+			notes = append(notes, fmt.Sprintf("acked by %s", e.Ack.By))
+		}
+		if e.Silenced(now) {
+			notes = append(notes, fmt.Sprintf("silenced until %s by %s", e.Silence.Until.Format(time.RFC3339), e.Silence.By))
+		}
+		if e.Flapping {
+			notes = append(notes, "flapping")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Fingerprint, e.Status, e.Severity,
+			e.Since.Format("2006-01-02"), e.LastSeen.Format("2006-01-02"), e.Environment, e.Test, e.Alert,
+			strings.Join(details, ", "), strings.Join(notes, "; "))
+	}
+	return w.Flush()
+}
+
+// silenceAlerts suppresses all notifications for the alerts until the silence expires
+func silenceAlerts(store *state.Store, args []string) error {
+	fs := flag.NewFlagSet("silence", flag.ExitOnError)
+	duration := fs.Duration("for", 7*24*time.Hour, "How long to silence the alerts for")
+	comment := fs.String("comment", "", "Reason for silencing the alerts")
+	user := fs.String("user", currentUser(), "Who is silencing the alerts")
+	fs.Parse(args)
+
+	until := time.Now().UTC().Add(*duration)
+	return updateAlerts(store, fs.Args(), func(e *state.Entry) error {
+		e.Silence = &state.Silence{Until: until, By: *user, Comment: *comment}
+		fmt.Printf("Silenced %s until %s\n", e.Fingerprint, until.Format(time.RFC3339))
+		return nil
+	})
+}
+
+// unsilenceAlerts removes the silences for the alerts
+func unsilenceAlerts(store *state.Store, args []string) error {
+	return updateAlerts(store, args, func(e *state.Entry) error {
+		e.Silence = nil
+		fmt.Printf("Unsilenced %s\n", e.Fingerprint)
+		return nil
+	})
+}
+
+// ackAlerts acknowledges firing alerts, which stops reminders until they escalate or resolve
+func ackAlerts(store *state.Store, args []string) error {
+	fs := flag.NewFlagSet("ack", flag.ExitOnError)
+	comment := fs.String("comment", "", "Comment, e.g. an issue for the alerts")
+	user := fs.String("user", currentUser(), "Who is acknowledging the alerts")
+	fs.Parse(args)
+
+	now := time.Now().UTC()
+	return updateAlerts(store, fs.Args(), func(e *state.Entry) error {
+		if e.Status != state.StatusFiring {
+			return fmt.Errorf("alert %s is not firing", e.Fingerprint)
+		}
+		e.Ack = &state.Ack{At: now, By: *user, Comment: *comment}
+		fmt.Printf("Acknowledged %s\n", e.Fingerprint)
+		return nil
+	})
+}
+
+// unackAlerts removes the acknowledgements for the alerts
+func unackAlerts(store *state.Store, args []string) error {
+	return updateAlerts(store, args, func(e *state.Entry) error {
+		e.Ack = nil
+		fmt.Printf("Unacknowledged %s\n", e.Fingerprint)
+		return nil
+	})
+}
+
+// updateAlerts applies the update to the alerts with the fingerprints, and saves the state. Nothing is saved if
+// any of the alerts can't be found or updated.
+func updateAlerts(store *state.Store, fingerprints []string, update func(*state.Entry) error) error {
+	if len(fingerprints) == 0 {
+		return errors.New("no alert fingerprints specified")
+	}
+	for _, fp := range fingerprints {
+		e, err := store.Find(fp)
+		if err != nil {
+			return err
+		}
+		if err := update(e); err != nil {
+			return err
+		}
+	}
+	return store.Save()
+}
+
+// currentUser returns the name of the user running the command
+func currentUser() string {
+	if u := os.Getenv("USER"); len(u) > 0 {
+		return u
+	}
+	return "unknown"
+}
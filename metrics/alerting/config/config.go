@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2021, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2021, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets, irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -124,6 +124,8 @@ func (w *SlackNotification) UnmarshalYAML(unmarshal func(interface{}) error) err
 type Data struct {
 	InfluxDB     InfluxDB
 	Slack        Slack
+	Notifiers    map[string]Notifier // Additional notifiers, keyed on the name used in owner routes
+	State        State
 	Options      Options
 	Environments TestEnvironments
 	Tests        []Test
@@ -145,8 +147,16 @@ type TestEnvironment struct {
 type Owner struct {
 	Name   string
 	Slack  string
+	Email  string
 	Notify SlackNotification
 	Days   []Weekday
+	Routes []Route // Notifiers the environment's alerts are sent to, in addition to Slack
+}
+
+// Route sends an owner's alerts to a notifier
+type Route struct {
+	Notifier   string
+	Severities []string // Severities to send (e.g. ERROR, CHANGE-POINT). Defaults to the notifier's severities.
 }
 
 // InfluxDB holds configuration data for connecting to an influx db instance
@@ -165,6 +175,66 @@ type Slack struct {
 	ResultsURL string `yaml:"resultsURL"`
 }
 
+// Notifier holds configuration data for an alert notifier. Type selects the notifier, and the
+// section of the same name configures it.
+type Notifier struct {
+	Type       string   // webhook, smtp or github
+	Enabled    bool     // Disabled notifiers are skipped, even if they are routed to
+	Severities []string // Default severities for routes which don't specify any. Defaults to all but INFORMATION.
+	Webhook    *Webhook
+	SMTP       *SMTP   `yaml:"smtp"`
+	GitHub     *GitHub `yaml:"github"`
+}
+
+// Webhook holds configuration data for posting alerts as JSON to a URL
+type Webhook struct {
+	URL         string `yaml:"url"`
+	Headers     map[string]string
+	TokenEnvVar string `yaml:"tokenEnvVar"` // Optional environment variable holding a bearer token
+}
+
+// SMTP holds configuration data for emailing alerts. Each owner is sent their alerts, at their email address
+// and any configured addresses.
+type SMTP struct {
+	Host           string
+	Port           int
+	From           string
+	To             []string
+	Username       string
+	PasswordEnvVar string `yaml:"passwordEnvVar"`
+}
+
+// GitHub holds configuration data for tracking regressions as GitHub issues
+type GitHub struct {
+	APIURL      string `yaml:"apiURL"` // Defaults to https://api.github.com. For GitHub Enterprise, use https://<host>/api/v3
+	Repo        string // owner/repository
+	Labels      []string
+	TokenEnvVar string `yaml:"tokenEnvVar"` // Defaults to ARGONAUTS_ARM_PERF_ALERTS_GITHUB_TOKEN
+}
+
+// State holds configuration data for the persistent alert state, which tracks alerts across runs so that
+// notifiers are only sent changes
+type State struct {
+	File          string // Defaults to armada-perf-alerts/alert-state.json in the user configuration directory
+	RemindDays    int    `yaml:"remindDays"`    // Days between reminders for firing alerts which haven't been acknowledged. 0 disables reminders.
+	FlapHistory   int    `yaml:"flapHistory"`   // Number of runs checked for flapping (default 10)
+	FlapThreshold int    `yaml:"flapThreshold"` // Number of changes between firing and resolved, within the runs, for an alert to be flapping (default 4)
+	RetentionDays int    `yaml:"retentionDays"` // Days resolved alerts are kept (default 30)
+}
+
+// Path returns the path of the state file. The default is outside the repository, so that it isn't lost when the
+// repository is checked out again, e.g. by a Jenkins job.
+func (s State) Path() string {
+	if len(s.File) > 0 {
+		return s.File
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatalf("Failed to find the default alert state directory, set the state file : %s\n", err.Error())
+	}
+	return filepath.Join(dir, "armada-perf-alerts", "alert-state.json")
+}
+
 // History holds configuration data for determing the amount and age of historical results data
 type History struct {
 	Count   int // Maximum number of test data results to process
@@ -212,12 +282,26 @@ type Issue struct {
 	Issue           string
 }
 
+// ChangePoint configures change-point detection over the historical results for an alert.
+// Change-point alerts catch gradual drifts and step changes which stay within the simple thresholds.
+type ChangePoint struct {
+	Method       string  // cusum (default) or edivisive
+	Threshold    float64 // cusum: decision threshold in standard deviations (default 4)
+	Drift        float64 // cusum: allowed drift in standard deviations before the sum accumulates (default 0.5)
+	Baseline     int     // cusum: number of oldest results used to estimate the mean and standard deviation (default half the results)
+	Permutations int     // edivisive: number of permutations for the significance test (default 199)
+	Significance float64 // edivisive: p-value below which a change point is significant (default 0.05)
+	MinSegment   int     `yaml:"minSegment"` // Minimum number of results either side of a change point (default 3)
+	MinShift     float64 `yaml:"minShift"`   // Minimum shift, as a percentage of the mean before the change, to generate an alert (default 0)
+}
+
 // Alert defines a single alert
 type Alert struct {
-	Name       string
-	LimitType  string `yaml:"limitType"`
-	Issues     map[string]Issue
-	Thresholds map[string]Thresholds
+	Name        string
+	LimitType   string `yaml:"limitType"`
+	Issues      map[string]Issue
+	Thresholds  map[string]Thresholds
+	ChangePoint *ChangePoint `yaml:"changePoint"`
 }
 
 // getConfigPath returns the path of the yaml configuration config file
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2021, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2021, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets, irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -13,6 +13,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"math"
 	"sort"
 	"strings"
@@ -22,7 +23,9 @@ import (
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
 	influxdata "github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/influx"
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/jenkins"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/notify"
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/slack"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/state"
 )
 
 func outputEnvHeader(kv, mt, os string) {
@@ -47,6 +50,9 @@ func outputEnvHeader(kv, mt, os string) {
 func main() {
 	alerts := make(map[string][]alert.Alert)
 
+	// The results checked for alerts, so notifiers can tell when an alert has cleared
+	checked := make(map[string][]alert.Alert)
+
 	// Process the configuration file
 	conf := config.GetConfig()
 
@@ -54,6 +60,7 @@ func main() {
 	flag.BoolVar(&conf.Options.Verbose, "verbose", conf.Options.Verbose, "Detailed logging output")
 	flag.BoolVar(&conf.Options.Debug, "debug", conf.Options.Debug, "Debug output")
 	flag.BoolVar(&conf.Slack.Enabled, "slack", conf.Slack.Enabled, "Slack notifications")
+	flag.StringVar(&conf.State.File, "state", conf.State.Path(), "Alert state file")
 	flag.Parse()
 
 	// Ensure verbose logging if debug option specified
@@ -61,6 +68,27 @@ func main() {
 		conf.Options.Verbose = true
 	}
 
+	// The alert state tracks alerts across runs, so that only changes are notified
+	store, err := state.Load(conf.State)
+	if err != nil {
+		log.Fatalf("%s\n", err.Error())
+	}
+	defer store.Close()
+
+	// Alert state commands (list, silence, ack etc.)
+	if flag.NArg() > 0 {
+		if err := runCommand(store, flag.Args()); err != nil {
+			log.Fatalf("%s\n", err.Error())
+		}
+		return
+	}
+
+	// Create the notifiers before processing, so configuration errors are found early
+	notifiers, err := notify.New(conf)
+	if err != nil {
+		log.Fatalf("Invalid notifier configuration : %s", err.Error())
+	}
+
 	// Data is stored in an influx database
 	ic := influxdata.NewInfluxClient(conf.InfluxDB)
 
@@ -150,6 +178,7 @@ func main() {
 																			MachineType:     mt,
 																			OperatingSystem: os,
 																			Sev:             alert.Silenced,
+																			Alert:           a,
 																		},
 																	)
 																	continue alerts
@@ -209,8 +238,10 @@ func main() {
 													KubeVersion:       kv,
 													MachineType:       mt,
 													OperatingSystem:   os,
+													Alert:             a,
 													LeniencyThreshold: conf.Options.Leniency,
 												}
+												checked[name] = append(checked[name], ta)
 
 												// We don't want information alerts, so update threshold
 												if t.DisableInfo {
@@ -237,9 +268,50 @@ func main() {
 		}
 	}
 
+	// Notifiers are only sent the changes since the last run
+	transitions := store.Update(checked, alerts, time.Now().UTC())
+	report := notify.Report{
+		Alerts:   make(map[string][]alert.Alert),
+		Resolved: make(map[string][]alert.Alert),
+	}
+	// Every environment is reported, so owners who are always notified are sent a summary
+	for env := range alerts {
+		report.Alerts[env] = []alert.Alert{}
+	}
+	counts := make(map[state.Kind]int)
+	suppressed := 0
+	for _, t := range transitions {
+		if len(t.Suppressed) > 0 {
+			if conf.Options.Verbose {
+				fmt.Printf("%s%s: %s (%s), not notified: %s%s\n", config.ColourFaint, t.Entry.Fingerprint, t.Entry.Key, t.Kind, t.Suppressed, config.ColourReset)
+			}
+			suppressed++
+			continue
+		}
+		env := t.Entry.Environment
+		if t.Kind == state.Resolved {
+			report.Resolved[env] = append(report.Resolved[env], t.Alerts...)
+		} else {
+			report.Alerts[env] = append(report.Alerts[env], t.Alerts...)
+		}
+		counts[t.Kind]++
+	}
+	log.Printf("Alert state: %d new, %d escalated, %d reminders, %d resolved, %d suppressed\n",
+		counts[state.New], counts[state.Escalated], counts[state.Reminder], counts[state.Resolved], suppressed)
+
 	// Send alerts to Slack
 	if conf.Slack.Enabled {
-		failures := jenkins.Failures(conf)
-		slack.SendAlerts(conf, failures, alerts)
+		report.Failures = jenkins.Failures(conf)
+		notifiers.Add(notify.SlackName, slack.Notifier{})
+	}
+
+	// Send alerts to the notifiers. Every notifier is tried before failing. The state isn't saved if any fail, so
+	// the changes are sent again by the next run.
+	if err := notifiers.Notify(conf, report); err != nil {
+		log.Fatalf("%s\n", err.Error())
+	}
+
+	if err := store.Save(); err != nil {
+		log.Fatalf("%s\n", err.Error())
 	}
 }
@@ -0,0 +1,240 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/alert"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
+)
+
+const (
+	githubTokenEnvVar = "ARGONAUTS_ARM_PERF_ALERTS_GITHUB_TOKEN"
+	githubAPIURL      = "https://api.github.com"
+	githubLabel       = "perf-alert"
+)
+
+// Each issue body carries the key of the alerts it tracks, so the issue can be found on later runs
+var issueKeyRegexp = regexp.MustCompile(`<!-- perf-alert-key: (.*?) -->`)
+
+type githubIssue struct {
+	Number      int             `json:"number"`
+	HTMLURL     string          `json:"html_url"`
+	Body        string          `json:"body"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+// github tracks each regression as a GitHub issue. An issue is opened when a result first alerts, commented on
+// when it escalates or is reminded, and closed once the result no longer alerts.
+type github struct {
+	apiURL string
+	repo   string
+	labels []string
+	token  string
+	client *http.Client
+}
+
+func newGitHub(conf *config.GitHub) (Notifier, error) {
+	if conf == nil || len(conf.Repo) == 0 || !strings.Contains(conf.Repo, "/") {
+		return nil, errors.New("github repo (owner/repository) not configured")
+	}
+	g := &github{
+		apiURL: strings.TrimSuffix(conf.APIURL, "/"),
+		repo:   conf.Repo,
+		labels: conf.Labels,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if len(g.apiURL) == 0 {
+		g.apiURL = githubAPIURL
+	}
+	if len(g.labels) == 0 {
+		g.labels = []string{githubLabel}
+	}
+
+	tokenEnvVar := conf.TokenEnvVar
+	if len(tokenEnvVar) == 0 {
+		tokenEnvVar = githubTokenEnvVar
+	}
+	g.token = os.Getenv(tokenEnvVar)
+	if len(g.token) == 0 {
+		return nil, fmt.Errorf("GitHub token not provided. Check '%s' environment variable", tokenEnvVar)
+	}
+	return g, nil
+}
+
+// Notify opens, comments on and closes issues for the alerting results
+func (g *github) Notify(conf *config.Data, report Report) error {
+	open, err := g.openIssues()
+	if err != nil {
+		return err
+	}
+
+	// Group the alerts by the result they are for
+	alerting := make(map[string][]alert.Alert)
+	for _, e := range sortedEnvironments(report.Alerts) {
+		for _, a := range report.Alerts[e] {
+			alerting[a.Key()] = append(alerting[a.Key()], a)
+		}
+	}
+	keys := make([]string, 0, len(alerting))
+	for k := range alerting {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	now := time.Now().UTC().Format(time.RFC1123)
+	for _, key := range keys {
+		alerts := alerting[key]
+		var details strings.Builder
+		for _, a := range alerts {
+			fmt.Fprintf(&details, "```\n%s```\n", describe(a))
+		}
+
+		if issue, ok := open[key]; ok {
+			// Regression continues, and has escalated or is being reminded
+			body := fmt.Sprintf("Still alerting as of %s.\n\n%s", now, details.String())
+			if err := g.comment(issue.Number, body); err != nil {
+				return err
+			}
+			log.Printf("Commented on %s for %s\n", issue.HTMLURL, key)
+			continue
+		}
+
+		// New regression
+		a := alerts[0]
+		title := fmt.Sprintf("Performance alert: %s - %s (%s)", a.Name, a.Alert.Name, environment(a))
+		body := fmt.Sprintf("<!-- perf-alert-key: %s -->\nAlerting since %s for environment **%s** (owner: %s).\n\n%s\n", key, now, a.EnvName, a.Owner.Name, details.String())
+		if len(conf.Slack.ResultsURL) > 0 {
+			body += fmt.Sprintf("[Alert details](%s)\n\n", conf.Slack.ResultsURL)
+		}
+		body += "This issue will be closed automatically once the result no longer alerts. " +
+			"To silence the alert instead, add this issue to the alert's `issues` in the alerting configuration.\n"
+		issue, err := g.create(title, body)
+		if err != nil {
+			return err
+		}
+		log.Printf("Opened %s for %s\n", issue.HTMLURL, key)
+	}
+
+	// Close the issues for results which no longer alert
+	for _, e := range sortedEnvironments(report.Resolved) {
+		for _, r := range report.Resolved[e] {
+			key := r.Key()
+			issue, ok := open[key]
+			if !ok || len(alerting[key]) > 0 {
+				continue
+			}
+			if err := g.comment(issue.Number, fmt.Sprintf("No longer alerting as of %s. Closing.", now)); err != nil {
+				return err
+			}
+			if err := g.close(issue.Number); err != nil {
+				return err
+			}
+			delete(open, key)
+			log.Printf("Closed %s for %s\n", issue.HTMLURL, key)
+		}
+	}
+	return nil
+}
+
+// openIssues returns the open alert issues, keyed on the key of the alerts they track
+func (g *github) openIssues() (map[string]githubIssue, error) {
+	issues := make(map[string]githubIssue)
+	const perPage = 100
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("state", "open")
+		query.Set("labels", strings.Join(g.labels, ","))
+		query.Set("per_page", strconv.Itoa(perPage))
+		query.Set("page", strconv.Itoa(page))
+
+		var batch []githubIssue
+		if err := g.do(http.MethodGet, fmt.Sprintf("/repos/%s/issues?%s", g.repo, query.Encode()), nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, issue := range batch {
+			if len(issue.PullRequest) > 0 {
+				continue
+			}
+			if m := issueKeyRegexp.FindStringSubmatch(issue.Body); m != nil {
+				issues[m[1]] = issue
+			}
+		}
+		if len(batch) < perPage {
+			return issues, nil
+		}
+	}
+}
+
+func (g *github) create(title string, body string) (githubIssue, error) {
+	var issue githubIssue
+	req := map[string]interface{}{"title": title, "body": body, "labels": g.labels}
+	err := g.do(http.MethodPost, fmt.Sprintf("/repos/%s/issues", g.repo), req, &issue)
+	return issue, err
+}
+
+func (g *github) comment(number int, body string) error {
+	return g.do(http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", g.repo, number), map[string]string{"body": body}, nil)
+}
+
+func (g *github) close(number int) error {
+	return g.do(http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", g.repo, number), map[string]string{"state": "closed"}, nil)
+}
+
+// do sends a request to the GitHub API, decoding the response into out if it is set
+func (g *github) do(method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, g.apiURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "token "+g.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GitHub %s %s returned %s : %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
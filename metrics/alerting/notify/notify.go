@@ -0,0 +1,235 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/alert"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/jenkins"
+)
+
+// Notifier types, as configured in the notifiers section of the configuration
+const (
+	TypeWebhook = "webhook"
+	TypeSMTP    = "smtp"
+	TypeGitHub  = "github"
+)
+
+// SlackName is the name of the Slack notifier, which is configured in the slack section rather than as a notifier
+const SlackName = "slack"
+
+// defaultSeverities are sent to notifiers which don't configure any
+var defaultSeverities = []alert.Severity{alert.Warning, alert.Error, alert.Zscore, alert.ChangePoint}
+
+// Report holds the changes found by an alerting run. Alerts which are unchanged since the last run, or are
+// silenced, are not reported.
+type Report struct {
+	Alerts   map[string][]alert.Alert        // New, escalated and reminder alerts, keyed on environment name
+	Resolved map[string][]alert.Alert        // Results which have stopped alerting, with their last severity, keyed on environment name
+	Failures map[string]*jenkins.FailureData // Test failures, keyed on environment name. Only collected for Slack.
+}
+
+// Notifier sends the alerts in a report to an output channel
+type Notifier interface {
+	Notify(conf *config.Data, report Report) error
+}
+
+// routed is a configured notifier, and the severities it is sent by default. Unrouted notifiers are sent the
+// whole report.
+type routed struct {
+	Notifier
+	severities []alert.Severity
+	unrouted   bool
+}
+
+// Notifiers holds the enabled notifiers in the configuration, keyed on name
+type Notifiers map[string]routed
+
+// New creates the enabled notifiers in the configuration, and checks that every owner route refers to a notifier
+func New(conf *config.Data) (Notifiers, error) {
+	notifiers := make(Notifiers)
+	for name, nc := range conf.Notifiers {
+		if name == SlackName {
+			return nil, fmt.Errorf("notifier name '%s' is reserved - Slack is configured in the slack section", name)
+		}
+		severities, err := parseSeverities(nc.Severities, defaultSeverities)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %s", name, err.Error())
+		}
+		// Disabled notifiers aren't constructed, so needn't be fully configured (e.g. no GitHub token)
+		if !nc.Enabled {
+			continue
+		}
+
+		var n Notifier
+		switch nc.Type {
+		case TypeWebhook:
+			n, err = newWebhook(nc.Webhook)
+		case TypeSMTP:
+			n, err = newSMTP(nc.SMTP)
+		case TypeGitHub:
+			n, err = newGitHub(nc.GitHub)
+		default:
+			err = fmt.Errorf("unknown type '%s' - expected %s, %s or %s", nc.Type, TypeWebhook, TypeSMTP, TypeGitHub)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %s", name, err.Error())
+		}
+		notifiers[name] = routed{Notifier: n, severities: severities}
+	}
+
+	for envName, e := range conf.Environments {
+		for _, r := range e.Owner.Routes {
+			if _, ok := conf.Notifiers[r.Notifier]; !ok {
+				return nil, fmt.Errorf("environment %s: owner %s is routed to unknown notifier '%s'", envName, e.Owner.Name, r.Notifier)
+			}
+			if _, err := parseSeverities(r.Severities, nil); err != nil {
+				return nil, fmt.Errorf("environment %s: owner %s: %s", envName, e.Owner.Name, err.Error())
+			}
+		}
+	}
+	return notifiers, nil
+}
+
+// Add adds a notifier which is sent the whole report, regardless of owner routes (e.g. Slack)
+func (ns Notifiers) Add(name string, n Notifier) {
+	ns[name] = routed{Notifier: n, unrouted: true}
+}
+
+// Names returns the names of the notifiers, sorted
+func (ns Notifiers) Names() []string {
+	names := make([]string, 0, len(ns))
+	for name := range ns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Notify sends each notifier the alerts routed to it. Failures are returned once every notifier has been tried.
+func (ns Notifiers) Notify(conf *config.Data, report Report) error {
+	var failed []string
+	for _, name := range ns.Names() {
+		n := ns[name]
+		r := report
+		if !n.unrouted {
+			r = route(conf, name, n.severities, report)
+		}
+		if err := n.Notify(conf, r); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, err.Error()))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to notify %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// route returns the part of the report the environment owners route to the notifier - the alerts and resolved
+// results with the routed severities, for each environment whose owner has a route to the notifier
+func route(conf *config.Data, name string, defaults []alert.Severity, report Report) Report {
+	routedReport := Report{
+		Alerts:   make(map[string][]alert.Alert),
+		Resolved: make(map[string][]alert.Alert),
+		Failures: report.Failures,
+	}
+
+	for envName, e := range conf.Environments {
+		for _, r := range e.Owner.Routes {
+			if r.Notifier != name {
+				continue
+			}
+			// Severities have been checked by New
+			severities, _ := parseSeverities(r.Severities, defaults)
+			for _, a := range report.Alerts[envName] {
+				if containsSeverity(severities, a.Sev) {
+					routedReport.Alerts[envName] = append(routedReport.Alerts[envName], a)
+				}
+			}
+			for _, a := range report.Resolved[envName] {
+				if containsSeverity(severities, a.Sev) {
+					routedReport.Resolved[envName] = append(routedReport.Resolved[envName], a)
+				}
+			}
+			break
+		}
+	}
+	return routedReport
+}
+
+// parseSeverities parses the severity names, returning the defaults if there are none
+func parseSeverities(names []string, defaults []alert.Severity) ([]alert.Severity, error) {
+	if len(names) == 0 {
+		return defaults, nil
+	}
+	severities := make([]alert.Severity, 0, len(names))
+	for _, name := range names {
+		s, err := alert.ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		severities = append(severities, s)
+	}
+	return severities, nil
+}
+
+func containsSeverity(severities []alert.Severity, s alert.Severity) bool {
+	for _, x := range severities {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedEnvironments returns the environment names in the alerts, sorted for consistent output
+func sortedEnvironments(alerts map[string][]alert.Alert) []string {
+	envs := make([]string, 0, len(alerts))
+	for e := range alerts {
+		envs = append(envs, e)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// environment returns a description of the environment an alert is for
+func environment(a alert.Alert) string {
+	env := []string{a.Carrier}
+	if len(a.MachineType) > 0 {
+		env = append(env, "MachineType: "+a.MachineType)
+	}
+	if len(a.KubeVersion) > 0 {
+		env = append(env, "Version: "+strings.ReplaceAll(a.KubeVersion, "_", "."))
+	}
+	if len(a.OperatingSystem) > 0 {
+		env = append(env, "OS: "+a.OperatingSystem)
+	}
+	return strings.Join(env, ", ")
+}
+
+// describe returns a plain text description of an alert, in the same form as the console output
+func describe(a alert.Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ALERT - %s\n", a.Sev)
+	fmt.Fprintf(&b, "\tOwner: %s\n", a.Owner.Name)
+	fmt.Fprintf(&b, "\tEnvironment: %s\n", environment(a))
+	fmt.Fprintf(&b, "\tTest: %s - %s\n", a.Name, a.Alert.Name)
+	if a.Sev == alert.ChangePoint {
+		fmt.Fprintf(&b, "\tShift began: %s, Before: %.6g, After: %.6g, Shift: %+.6g (%+.1f%%)\n", a.Timestamp.UTC(), a.ShiftBefore, a.ShiftAfter, a.Result, a.ShiftPercent())
+	} else {
+		fmt.Fprintf(&b, "\tTimestamp: %s, Threshold: %.6g, Result: %.6g\n", a.Timestamp.UTC(), a.Threshold(), a.Result)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/alert"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
+)
+
+// smtpNotifier emails each owner the alerts for their environments
+type smtpNotifier struct {
+	conf     config.SMTP
+	password string
+}
+
+func newSMTP(conf *config.SMTP) (Notifier, error) {
+	if conf == nil || len(conf.Host) == 0 || len(conf.From) == 0 {
+		return nil, errors.New("smtp host and from address not configured")
+	}
+	n := &smtpNotifier{conf: *conf}
+	if n.conf.Port == 0 {
+		n.conf.Port = 25
+	}
+	if len(conf.PasswordEnvVar) > 0 {
+		n.password = os.Getenv(conf.PasswordEnvVar)
+	}
+	return n, nil
+}
+
+// Notify sends one email per owner with alerts or resolved results, to the owner's email address and the
+// configured addresses
+func (n *smtpNotifier) Notify(conf *config.Data, report Report) error {
+	// Group the alerts and resolved results by owner
+	byOwner := make(map[string][]alert.Alert)
+	resolvedByOwner := make(map[string][]alert.Alert)
+	owners := make(map[string]config.Owner)
+	var names []string
+	addOwner := func(o config.Owner) {
+		if _, ok := owners[o.Name]; !ok {
+			owners[o.Name] = o
+			names = append(names, o.Name)
+		}
+	}
+	for _, e := range sortedEnvironments(report.Alerts) {
+		for _, a := range report.Alerts[e] {
+			addOwner(a.Owner)
+			byOwner[a.Owner.Name] = append(byOwner[a.Owner.Name], a)
+		}
+	}
+	for _, e := range sortedEnvironments(report.Resolved) {
+		for _, a := range report.Resolved[e] {
+			addOwner(a.Owner)
+			resolvedByOwner[a.Owner.Name] = append(resolvedByOwner[a.Owner.Name], a)
+		}
+	}
+
+	var auth smtp.Auth
+	if len(n.conf.Username) > 0 {
+		auth = smtp.PlainAuth("", n.conf.Username, n.password, n.conf.Host)
+	}
+	addr := net.JoinHostPort(n.conf.Host, strconv.Itoa(n.conf.Port))
+
+	for _, name := range names {
+		to := append([]string{}, n.conf.To...)
+		if len(owners[name].Email) > 0 {
+			to = append(to, owners[name].Email)
+		}
+		if len(to) == 0 {
+			log.Printf("No email address for owner %s, or configured recipients. Alerts not emailed.\n", name)
+			continue
+		}
+
+		alerts := byOwner[name]
+		resolved := resolvedByOwner[name]
+		subject := fmt.Sprintf("Armada Performance Alert: %d alerts, %d resolved for %s", len(alerts), len(resolved), name)
+		var body strings.Builder
+		fmt.Fprintf(&body, "Hi %s,\r\n", name)
+		if len(alerts) > 0 {
+			body.WriteString("\r\nThe following automation alerts need investigating.\r\n")
+			if len(conf.Slack.ResultsURL) > 0 {
+				fmt.Fprintf(&body, "Details: %s\r\n", conf.Slack.ResultsURL)
+			}
+			for _, a := range alerts {
+				body.WriteString("\r\n")
+				body.WriteString(strings.ReplaceAll(describe(a), "\n", "\r\n"))
+			}
+		}
+		if len(resolved) > 0 {
+			body.WriteString("\r\nThe following results are no longer alerting.\r\n")
+			for _, a := range resolved {
+				fmt.Fprintf(&body, "\tRESOLVED - %s - %s - %s (%s)\r\n", a.Sev, a.Name, a.Alert.Name, environment(a))
+			}
+		}
+
+		msg := strings.Join([]string{
+			"From: " + n.conf.From,
+			"To: " + strings.Join(to, ", "),
+			"Subject: " + subject,
+			"Date: " + time.Now().Format(time.RFC1123Z),
+			"MIME-Version: 1.0",
+			"Content-Type: text/plain; charset=UTF-8",
+			"",
+			body.String(),
+		}, "\r\n")
+
+		if err := smtp.SendMail(addr, auth, n.conf.From, to, []byte(msg)); err != nil {
+			return fmt.Errorf("failed to email alerts for %s : %s", name, err.Error())
+		}
+		log.Printf("%d alerts, %d resolved emailed to %s\n", len(alerts), len(resolved), strings.Join(to, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/alert"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
+)
+
+// webhookAlert is an alert, as posted to a webhook
+type webhookAlert struct {
+	Key             string     `json:"key"`
+	Status          string     `json:"status"` // firing or resolved
+	Severity        string     `json:"severity"`
+	Environment     string     `json:"environment"`
+	Carrier         string     `json:"carrier"`
+	Owner           string     `json:"owner"`
+	Test            string     `json:"test"`
+	Alert           string     `json:"alert"`
+	MachineType     string     `json:"machineType,omitempty"`
+	KubeVersion     string     `json:"kubeVersion,omitempty"`
+	OperatingSystem string     `json:"operatingSystem,omitempty"`
+	Timestamp       *time.Time `json:"timestamp,omitempty"` // Firing alerts only
+	Threshold       float64    `json:"threshold"`
+	Result          float64    `json:"result"`
+	Text            string     `json:"text,omitempty"`
+}
+
+// webhook posts the alerts and resolved results routed to it as a JSON array
+type webhook struct {
+	conf   config.Webhook
+	token  string
+	client *http.Client
+}
+
+func newWebhook(conf *config.Webhook) (Notifier, error) {
+	if conf == nil || len(conf.URL) == 0 {
+		return nil, errors.New("webhook url not configured")
+	}
+	w := &webhook{conf: *conf, client: &http.Client{Timeout: 30 * time.Second}}
+	if len(conf.TokenEnvVar) > 0 {
+		w.token = os.Getenv(conf.TokenEnvVar)
+	}
+	return w, nil
+}
+
+// Notify posts the alerts and resolved results. Nothing is posted if there are none.
+func (w *webhook) Notify(conf *config.Data, report Report) error {
+	var payload []webhookAlert
+	for _, e := range sortedEnvironments(report.Alerts) {
+		for _, a := range report.Alerts[e] {
+			payload = append(payload, newWebhookAlert(a, "firing"))
+		}
+	}
+	for _, e := range sortedEnvironments(report.Resolved) {
+		for _, a := range report.Resolved[e] {
+			payload = append(payload, newWebhookAlert(a, "resolved"))
+		}
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(w.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s : %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	log.Printf("%d alerts posted to webhook %s\n", len(payload), w.conf.URL)
+	return nil
+}
+
+func newWebhookAlert(a alert.Alert, status string) webhookAlert {
+	wa := webhookAlert{
+		Key:             a.Key(),
+		Status:          status,
+		Severity:        a.Sev.String(),
+		Environment:     a.EnvName,
+		Carrier:         a.Carrier,
+		Owner:           a.Owner.Name,
+		Test:            a.Name,
+		Alert:           a.Alert.Name,
+		MachineType:     a.MachineType,
+		KubeVersion:     a.KubeVersion,
+		OperatingSystem: a.OperatingSystem,
+	}
+	if status == "firing" {
+		ts := a.Timestamp.UTC()
+		wa.Timestamp = &ts
+		wa.Threshold = a.Threshold()
+		wa.Result = a.Result
+		wa.Text = describe(a)
+	}
+	return wa
+}
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2021, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2021, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets, irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -21,6 +21,7 @@ import (
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/alert"
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/jenkins"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/notify"
 )
 
 const slackTokenEnvVar = "ARGONAUTS_ARM_PERF_ALERTS_SLACK_OAUTH_TOKEN"
@@ -30,17 +31,27 @@ const (
 	errorEmoji       = ":error:"
 	warningEmoji     = ":warning:"
 	zscoreEmoji      = ":ztop:"
+	changePointEmoji = ":chart_with_downwards_trend:"
 	informationEmoji = ":information_source:"
 	silencedEmoji    = ":silenced:"
+	resolvedEmoji    = ":white_check_mark:"
 	failedEmoji      = ":failed:"
 )
 
-// SendAlerts will send a summary of the supplied alerts to a Slack channel and optionally a user
-func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, alerts map[string][]alert.Alert) {
+// Notifier sends the alerts in a report, and the test failures, to Slack
+type Notifier struct{}
+
+// Notify sends a summary of the report to the Slack channel and the environment owners
+func (Notifier) Notify(conf *config.Data, report notify.Report) error {
+	return SendAlerts(conf, report.Failures, report.Alerts, report.Resolved)
+}
+
+// SendAlerts will send a summary of the supplied alerts, and resolved results, to a Slack channel and optionally a user
+func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, alerts map[string][]alert.Alert, resolved map[string][]alert.Alert) error {
 	testEnvs := conf.Environments
 	token := os.Getenv(slackTokenEnvVar)
 	if len(token) == 0 {
-		log.Fatalf("Slack token not provided. Check '%s' environment variable", slackTokenEnvVar)
+		return fmt.Errorf("Slack token not provided. Check '%s' environment variable", slackTokenEnvVar)
 	}
 
 	api := slack.New(token, slack.OptionDebug(false)) // Careful if setting the debug option to true. It will output the token.
@@ -48,11 +59,14 @@ func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, ale
 	blocks := make([]slack.Block, 0)
 
 	// Let's sort the map on the environment name to get a consistent ordering in the output
-	sortedEnvs := make([]string, len(alerts))
-	i := 0
+	sortedEnvs := make([]string, 0, len(alerts))
 	for n := range alerts {
-		sortedEnvs[i] = n
-		i++
+		sortedEnvs = append(sortedEnvs, n)
+	}
+	for n := range resolved {
+		if _, ok := alerts[n]; !ok {
+			sortedEnvs = append(sortedEnvs, n)
+		}
 	}
 	sort.Strings(sortedEnvs)
 
@@ -63,7 +77,7 @@ func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, ale
 		a := alerts[e]
 
 		// Store the number of alerts at the various severity levels
-		sevCount := make([]int, 5)
+		sevCount := make([]int, 6)
 		for _, x := range a {
 			sevCount[x.Sev]++
 		}
@@ -113,6 +127,15 @@ func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, ale
 			ownerActionRequired = true
 		}
 
+		// Change points... (shifts in the historical results)
+		if sevCount[alert.ChangePoint] > 0 {
+			countChangePointFieldName := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s \tChange Point: ", changePointEmoji), false, false)
+			countChangePointFieldValue := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%d", sevCount[alert.ChangePoint]), false, false)
+			fieldSlice = append(fieldSlice, countChangePointFieldName)
+			fieldSlice = append(fieldSlice, countChangePointFieldValue)
+			ownerActionRequired = true
+		}
+
 		// Informational... (alert thresholds that have been identified as being excessively lenient)
 		if sevCount[alert.Information] > 0 {
 			countInformationFieldName := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s \tInfo: ", informationEmoji), false, false)
@@ -130,6 +153,14 @@ func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, ale
 			fieldSlice = append(fieldSlice, countSilencedFieldValue)
 		}
 
+		// Resolved... (results which have stopped alerting since the last run)
+		if len(resolved[e]) > 0 {
+			countResolvedFieldName := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s \tResolved: ", resolvedEmoji), false, false)
+			countResolvedFieldValue := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%d", len(resolved[e])), false, false)
+			fieldSlice = append(fieldSlice, countResolvedFieldName)
+			fieldSlice = append(fieldSlice, countResolvedFieldValue)
+		}
+
 		// Construct section containg all alert details for the current environment
 		envBlocks = append(envBlocks, envHeader)
 		envBlocks = append(envBlocks, totalSection)
@@ -218,7 +249,7 @@ func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, ale
 					slack.MsgOptionAsUser(false), // Add this if you want that the bot would post message as a user, otherwise it will send response using the default slackbot
 				)
 				if err != nil {
-					log.Fatalf("Error alerting owner : %s\n", err)
+					return fmt.Errorf("Error alerting owner : %s", err.Error())
 				}
 			}
 		}
@@ -243,8 +274,9 @@ func SendAlerts(conf *config.Data, failures map[string]*jenkins.FailureData, ale
 		slack.MsgOptionAsUser(false), // Add this if you want that the bot would post message as a user, otherwise it will send response using the default slackbot
 	)
 	if err != nil {
-		log.Fatalf("Error sending alerts to slack : %s\n", err)
+		return fmt.Errorf("Error sending alerts to slack : %s", err.Error())
 	}
 
 	log.Printf("Message successfully sent to Slack channel %s at %s\n", channelID, timestamp)
+	return nil
 }
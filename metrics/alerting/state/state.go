@@ -0,0 +1,264 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/config"
+)
+
+// Defaults for the state configuration
+const (
+	defaultFlapHistory   = 10
+	defaultFlapThreshold = 4
+	defaultRetentionDays = 30
+)
+
+// stateVersion is the version of the state file format
+const stateVersion = 1
+
+// Status of an alert
+type Status string
+
+// Alert statuses
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+// History characters, recording the status of an alert in each run
+const (
+	historyFiring   = 'F'
+	historyResolved = 'R'
+)
+
+// Silence suppresses all notifications for an alert until it expires
+type Silence struct {
+	Until   time.Time `json:"until"`
+	By      string    `json:"by"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// Ack acknowledges a firing alert, stopping reminders. It is cleared when the alert escalates or resolves.
+type Ack struct {
+	At      time.Time `json:"at"`
+	By      string    `json:"by"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// Entry is the state of the alerts for a test result
+type Entry struct {
+	Fingerprint      string     `json:"fingerprint"`
+	Key              string     `json:"key"`
+	Environment      string     `json:"environment"`
+	Carrier          string     `json:"carrier"`
+	Owner            string     `json:"owner"`
+	Test             string     `json:"test"`
+	Alert            string     `json:"alert"`
+	MachineType      string     `json:"machineType,omitempty"`
+	KubeVersion      string     `json:"kubeVersion,omitempty"`
+	OperatingSystem  string     `json:"operatingSystem,omitempty"`
+	Status           Status     `json:"status"`
+	Severity         string     `json:"severity"`  // Highest severity of the alerts when last firing
+	Result           float64    `json:"result"`    // Result of the highest severity alert when last firing
+	FirstSeen        time.Time  `json:"firstSeen"` // First time the result alerted
+	Since            time.Time  `json:"since"`     // Start of the current, or last, period of firing
+	LastSeen         time.Time  `json:"lastSeen"`  // Last time the result alerted
+	LastChecked      time.Time  `json:"lastChecked"`
+	ResolvedAt       *time.Time `json:"resolvedAt,omitempty"`
+	Notified         bool       `json:"notified"`                   // Notifiers have been sent the alert, but not its resolution
+	NotifiedSeverity string     `json:"notifiedSeverity,omitempty"` // Highest severity notifiers have been sent
+	LastNotified     *time.Time `json:"lastNotified,omitempty"`
+	History          string     `json:"history"` // Status in recent runs, oldest first - F firing, R resolved
+	Flapping         bool       `json:"flapping"`
+	Silence          *Silence   `json:"silence,omitempty"`
+	Ack              *Ack       `json:"ack,omitempty"`
+}
+
+// Silenced returns whether the alert is silenced at the time
+func (e *Entry) Silenced(now time.Time) bool {
+	return e.Silence != nil && now.Before(e.Silence.Until)
+}
+
+// Store holds the state of the alerts, keyed on fingerprint
+type Store struct {
+	Version int               `json:"version"`
+	Updated time.Time         `json:"updated"`
+	Alerts  map[string]*Entry `json:"alerts"`
+
+	path string
+	conf config.State
+	lock *os.File
+}
+
+// Fingerprint returns the fingerprint for an alert key
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// Load locks and reads the state file. If the file doesn't exist, the state is empty. The lock is held until the
+// store is closed, so a run and the commands (silence, ack etc.) can't overwrite each other's changes.
+func Load(conf config.State) (*Store, error) {
+	if conf.FlapHistory <= 0 {
+		conf.FlapHistory = defaultFlapHistory
+	}
+	if conf.FlapThreshold <= 0 {
+		conf.FlapThreshold = defaultFlapThreshold
+	}
+	if conf.RetentionDays <= 0 {
+		conf.RetentionDays = defaultRetentionDays
+	}
+
+	s := &Store{Version: stateVersion, Alerts: make(map[string]*Entry), path: conf.Path(), conf: conf}
+	lock, err := lockState(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.lock = lock
+
+	b, err := ioutil.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("Failed to read alert state : %s", err.Error())
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("Failed to parse alert state %s : %s", s.path, err.Error())
+	}
+	if s.Version != stateVersion {
+		s.Close()
+		return nil, fmt.Errorf("Unsupported alert state version %d in %s", s.Version, s.path)
+	}
+	if s.Alerts == nil {
+		s.Alerts = make(map[string]*Entry)
+	}
+	return s, nil
+}
+
+// lockState takes the lock for the state file, waiting for any other run or command holding it. The lock is
+// released by closing the returned file.
+func lockState(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("Failed to lock alert state : %s", err.Error())
+	}
+	// #nosec G304
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to lock alert state : %s", err.Error())
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		log.Printf("Waiting for the alert state %s, which is locked by another run or command\n", path)
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Failed to lock alert state : %s", err.Error())
+	}
+	return f, nil
+}
+
+// Close releases the lock on the state file. The store can't be saved once closed.
+func (s *Store) Close() {
+	if s.lock != nil {
+		s.lock.Close()
+		s.lock = nil
+	}
+}
+
+// Save writes the state file. The file is replaced atomically, so a failed save leaves the previous state.
+func (s *Store) Save() error {
+	if s.lock == nil {
+		return errors.New("Failed to save alert state : the state is closed")
+	}
+	s.Updated = time.Now().UTC()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("Failed to save alert state : %s", err.Error())
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("Failed to save alert state : %s", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to save alert state : %s", err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to save alert state : %s", err.Error())
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("Failed to save alert state : %s", err.Error())
+	}
+	return nil
+}
+
+// Path returns the path of the state file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Entries returns the alerts, sorted by environment and key
+func (s *Store) Entries() []*Entry {
+	entries := make([]*Entry, 0, len(s.Alerts))
+	for _, e := range s.Alerts {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Environment != entries[j].Environment {
+			return entries[i].Environment < entries[j].Environment
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// Find returns the alert with the fingerprint, which may be abbreviated to any unique prefix
+func (s *Store) Find(fingerprint string) (*Entry, error) {
+	if e, ok := s.Alerts[fingerprint]; ok {
+		return e, nil
+	}
+	var found *Entry
+	for fp, e := range s.Alerts {
+		if len(fingerprint) > 0 && strings.HasPrefix(fp, fingerprint) {
+			if found != nil {
+				return nil, fmt.Errorf("fingerprint '%s' matches more than one alert", fingerprint)
+			}
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no alert with fingerprint '%s'", fingerprint)
+	}
+	return found, nil
+}
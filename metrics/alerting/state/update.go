@@ -0,0 +1,230 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets, irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/alerting/alert"
+)
+
+// Kind of transition
+type Kind string
+
+// Transitions which notifiers are sent
+const (
+	New       Kind = "new"       // The result has started alerting
+	Escalated Kind = "escalated" // The result is alerting at a higher severity than notifiers have been sent
+	Reminder  Kind = "reminder"  // The result is still alerting, and hasn't been acknowledged
+	Resolved  Kind = "resolved"  // The result has stopped alerting
+)
+
+// Transition is a change in the state of the alerts for a test result
+type Transition struct {
+	Kind   Kind
+	Entry  *Entry
+	Alerts []alert.Alert // The alerts for the result. For resolved transitions, the checked result, with the last severity notifiers were sent.
+	// Suppressed is why notifiers aren't sent the transition (e.g. silenced or flapping). Empty if they are.
+	Suppressed string
+}
+
+// Update records the results of a run, and returns the transitions. Checked holds the results which were checked
+// for alerts, and alerts the alerts they generated, both keyed on environment name. Results which weren't checked
+// (e.g. because there was no data) are left unchanged.
+func (s *Store) Update(checked map[string][]alert.Alert, alerts map[string][]alert.Alert, now time.Time) []Transition {
+	// Alerts silenced in the configuration, with an issue, are ignored
+	firing := make(map[string][]alert.Alert)
+	for _, as := range alerts {
+		for _, a := range as {
+			if a.Sev != alert.Silenced {
+				firing[a.Key()] = append(firing[a.Key()], a)
+			}
+		}
+	}
+
+	envs := make([]string, 0, len(checked))
+	for e := range checked {
+		envs = append(envs, e)
+	}
+	sort.Strings(envs)
+
+	var transitions []Transition
+	for _, env := range envs {
+		for _, c := range checked[env] {
+			key := c.Key()
+			fp := Fingerprint(key)
+			e, ok := s.Alerts[fp]
+			if !ok {
+				if len(firing[key]) == 0 {
+					// Nothing to track
+					continue
+				}
+				e = &Entry{
+					Fingerprint:     fp,
+					Key:             key,
+					Environment:     c.EnvName,
+					Test:            c.Name,
+					Alert:           c.Alert.Name,
+					MachineType:     c.MachineType,
+					KubeVersion:     c.KubeVersion,
+					OperatingSystem: c.OperatingSystem,
+					FirstSeen:       now,
+				}
+				s.Alerts[fp] = e
+			} else if e.LastChecked.Equal(now) {
+				// Already updated in this run
+				continue
+			}
+
+			if t, ok := s.update(e, c, firing[key], now); ok {
+				transitions = append(transitions, t)
+			}
+		}
+	}
+
+	s.prune(now)
+	return transitions
+}
+
+// update records the alerts for a result, and returns the transition, if any
+func (s *Store) update(e *Entry, c alert.Alert, current []alert.Alert, now time.Time) (Transition, bool) {
+	e.Carrier = c.Carrier
+	e.Owner = c.Owner.Name
+	e.LastChecked = now
+
+	t := Transition{Entry: e, Alerts: current}
+	if len(current) > 0 {
+		e.record(historyFiring, s.conf.FlapHistory, s.conf.FlapThreshold)
+		if e.Status != StatusFiring {
+			e.Status = StatusFiring
+			e.Since = now
+			e.ResolvedAt = nil
+			e.Ack = nil
+		}
+
+		top := current[0]
+		for _, a := range current[1:] {
+			if rank(a.Sev) > rank(top.Sev) {
+				top = a
+			}
+		}
+		e.Severity = top.Sev.String()
+		e.Result = top.Result
+		e.LastSeen = now
+
+		switch {
+		case !e.Notified:
+			t.Kind = New
+		case rank(top.Sev) > rankOf(e.NotifiedSeverity):
+			t.Kind = Escalated
+			e.Ack = nil
+		case e.Ack == nil && s.conf.RemindDays > 0 && e.LastNotified != nil &&
+			now.Sub(*e.LastNotified) >= time.Duration(s.conf.RemindDays)*24*time.Hour:
+			t.Kind = Reminder
+		}
+	} else {
+		e.record(historyResolved, s.conf.FlapHistory, s.conf.FlapThreshold)
+		if e.Status == StatusFiring {
+			e.Status = StatusResolved
+			e.ResolvedAt = &now
+			e.Ack = nil
+		}
+
+		// Notifiers are only sent the resolution of alerts they were sent
+		if e.Notified {
+			t.Kind = Resolved
+			resolved := c
+			if sev, err := alert.ParseSeverity(e.NotifiedSeverity); err == nil {
+				resolved.Sev = sev
+			}
+			t.Alerts = []alert.Alert{resolved}
+		}
+	}
+
+	if len(t.Kind) == 0 {
+		return t, false
+	}
+
+	switch {
+	case e.Silenced(now):
+		t.Suppressed = fmt.Sprintf("silenced until %s by %s", e.Silence.Until.Format(time.RFC3339), e.Silence.By)
+	case e.Flapping:
+		t.Suppressed = "flapping"
+	}
+	if len(t.Suppressed) == 0 {
+		if t.Kind == Resolved {
+			e.Notified = false
+			e.NotifiedSeverity = ""
+		} else {
+			e.Notified = true
+			e.NotifiedSeverity = e.Severity
+			e.LastNotified = &now
+		}
+	}
+	return t, true
+}
+
+// record adds the status of a run to the history, and updates whether the alert is flapping
+func (e *Entry) record(status byte, history int, threshold int) {
+	e.History += string(status)
+	if len(e.History) > history {
+		e.History = e.History[len(e.History)-history:]
+	}
+
+	changes := 0
+	for i := 1; i < len(e.History); i++ {
+		if e.History[i] != e.History[i-1] {
+			changes++
+		}
+	}
+	e.Flapping = changes >= threshold
+}
+
+// prune removes resolved alerts older than the retention period, and alerts which haven't been checked within it
+// (e.g. because the test was removed from the configuration). Expired silences are removed.
+func (s *Store) prune(now time.Time) {
+	retention := time.Duration(s.conf.RetentionDays) * 24 * time.Hour
+	for fp, e := range s.Alerts {
+		if e.Silence != nil && !e.Silenced(now) {
+			e.Silence = nil
+		}
+		if e.Silence != nil {
+			continue
+		}
+		expired := e.Status == StatusResolved && !e.Notified && e.ResolvedAt != nil && now.Sub(*e.ResolvedAt) > retention
+		if expired || now.Sub(e.LastChecked) > retention {
+			delete(s.Alerts, fp)
+		}
+	}
+}
+
+// rank orders the severities, for escalation. Breaching the error threshold is more severe than any other alert.
+func rank(s alert.Severity) int {
+	switch s {
+	case alert.Information:
+		return 1
+	case alert.Warning, alert.Zscore, alert.ChangePoint:
+		return 2
+	case alert.Error:
+		return 3
+	}
+	return 0
+}
+
+func rankOf(name string) int {
+	s, err := alert.ParseSeverity(name)
+	if err != nil {
+		return 0
+	}
+	return rank(s)
+}
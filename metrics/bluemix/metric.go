@@ -0,0 +1,144 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package metricsservice
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Standard tags. The environment tags are added to every metric, unless the metric sets them itself.
+const (
+	TagCarrierName     = "CarrierName"
+	TagMachineType     = "MachineType"
+	TagKubeVersion     = "KubeVersion"
+	TagOperatingSystem = "OperatingSystem"
+	TagTestName        = "TestName"
+	TagMetricName      = "MetricName"
+	TagMetricType      = "MetricType"
+	TagClusterName     = "ClusterName"
+	TagUnit            = "Unit"
+
+	// Node metadata, added to carrier metrics which identify a node
+	TagNode         = "Node"
+	TagZone         = "Zone"
+	TagInstanceType = "InstanceType"
+	TagWorkerPool   = "WorkerPool"
+)
+
+// Metric is a metric with an explicit measurement, tags and fields. Unlike BluemixMetric, nothing is inferred from
+// the name, so producers can tag metrics however they need to.
+type Metric struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]float64
+	Unit        string    // Optional, written as the Unit tag e.g. "seconds"
+	Timestamp   time.Time // Optional, defaults to the time the metric is written
+}
+
+// NewMetric returns a metric with a single field
+func NewMetric(measurement string, field string, value float64) Metric {
+	return Metric{
+		Measurement: measurement,
+		Tags:        make(map[string]string),
+		Fields:      map[string]float64{field: value},
+	}
+}
+
+// WithTag returns a copy of the metric with the tag set
+func (m Metric) WithTag(key string, value string) Metric {
+	tags := make(map[string]string, len(m.Tags)+1)
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+	tags[key] = value
+	m.Tags = tags
+	return m
+}
+
+// WithUnit returns a copy of the metric with the unit set
+func (m Metric) WithUnit(unit string) Metric {
+	m.Unit = unit
+	return m
+}
+
+// WithTimestamp returns a copy of the metric with the timestamp set
+func (m Metric) WithTimestamp(t time.Time) Metric {
+	m.Timestamp = t
+	return m
+}
+
+// point converts the metric to an Influx data point. The metric's own tags take precedence over the defaults.
+func (m Metric) point(defaultTags map[string]string, now time.Time) InfluxDataStruc {
+	tags := make(map[string]string, len(defaultTags)+len(m.Tags)+1)
+	for k, v := range defaultTags {
+		tags[k] = v
+	}
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+	if len(m.Unit) > 0 {
+		tags[TagUnit] = m.Unit
+	}
+
+	fields := make(map[string]interface{}, len(m.Fields))
+	for k, v := range m.Fields {
+		fields[k] = v
+	}
+
+	tstamp := m.Timestamp
+	if tstamp.IsZero() {
+		tstamp = now
+	}
+	return InfluxDataStruc{DBTableName: m.Measurement, Tags: tags, Fields: fields, TimeStamp: tstamp}
+}
+
+// newMetricBatch converts the metrics to a batch of data points, adding the environment tags
+func newMetricBatch(testName string, metrics []Metric, envTags map[string]string, bulk bool) MetricBatch {
+	batch := MetricBatch{TestName: testName, Bulk: bulk}
+	now := time.Now()
+	for _, m := range metrics {
+		batch.Points = append(batch.Points, m.point(envTags, now))
+	}
+	return batch
+}
+
+// environmentTags returns the tags which describe the environment the test ran in. The carrier name is taken from the
+// metrics root in metrics.toml, unless it is overridden by METRICS_ROOT_OVERRIDE.
+func environmentTags(testName string, metricsPrefix string, metricsRootName string, k8sVersionShort string) map[string]string {
+	var carrierName string
+	// Allow override of metrics root specified in metrics.toml file
+	if mro := os.Getenv("METRICS_ROOT_OVERRIDE"); len(mro) == 0 {
+		carrierName = "carrier_stage"
+		rootMetricNameParts := strings.Split(metricsRootName, ".")
+		for _, metricNameSubstring := range rootMetricNameParts {
+			if strings.Contains(metricNameSubstring, "carrier") || strings.Contains(metricNameSubstring, "satellite") {
+				carrierName = metricNameSubstring
+			}
+		}
+	} else {
+		carrierName = mro
+	}
+
+	// For Satellite clusters (but we'll generalize the suport) we need to support the identification of control plane configuration.
+	if ml := os.Getenv("METRICS_LOCATION"); len(ml) > 0 {
+		carrierName = strings.Join([]string{carrierName, ml}, "-")
+	}
+
+	return map[string]string{
+		TagCarrierName:     carrierName,
+		TagMachineType:     metricsPrefix,
+		TagKubeVersion:     k8sVersionShort,
+		TagOperatingSystem: os.Getenv("METRICS_OS"),
+		TagTestName:        testName,
+	}
+}
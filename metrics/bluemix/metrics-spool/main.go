@@ -0,0 +1,132 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	bm "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
+)
+
+var (
+	configDir string
+	spoolDir  string
+	sinkName  string
+	dbKey     string
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] inspect|replay|purge\n\n", filepath.Base(os.Args[0]))
+	fmt.Fprintln(flag.CommandLine.Output(), "  inspect - list the segments in the metrics spool")
+	fmt.Fprintln(flag.CommandLine.Output(), "  replay  - send the pending batches to the sinks configured in metrics.toml")
+	fmt.Fprintln(flag.CommandLine.Output(), "  purge   - remove the spooled batches without sending them")
+	fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func inspect(spool *bm.Spool) {
+	segments, err := spool.Inspect()
+	if err != nil {
+		log.Fatalf("Error reading spool: %s\n", err.Error())
+	}
+	if len(segments) == 0 {
+		fmt.Println("The spool is empty")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SINK\tSEGMENT\tBYTES\tBATCHES\tREPLAYED\tPOINTS\tCORRUPT\tOLDEST\tNEWEST")
+	pending := 0
+	for _, s := range segments {
+		if !bm.MatchSink(sinkName, s.Sink) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", s.Sink, filepath.Base(s.File), s.Bytes, s.Batches, s.Replayed, s.Points, s.Corrupt,
+			formatTime(s.Oldest), formatTime(s.Newest))
+		pending += s.Batches - s.Replayed
+	}
+	w.Flush()
+	fmt.Printf("\n%d batches pending\n", pending)
+}
+
+// Main - inspects, replays or purges the metrics spool
+func main() {
+	log.SetOutput(os.Stdout)
+
+	flag.StringVar(&configDir, "config", "", "Directory containing metrics.toml (defaults to the metrics/bluemix directory in the repo)")
+	flag.StringVar(&spoolDir, "dir", "", "Spool directory (overrides the dir in the [spool] section of metrics.toml)")
+	flag.StringVar(&sinkName, "sink", "", "Only process the spool for this sink type e.g. influxdb, or for a sink listed by inspect")
+	flag.StringVar(&dbKey, "dbkey", "", "Influxdb password")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(1)
+	}
+	command := flag.Arg(0)
+
+	if len(configDir) > 0 {
+		bm.SetConfigPath(configDir)
+	}
+	metricsCfg, ok := bm.ReadMetricsTomlFile()
+	if !ok {
+		os.Exit(1)
+	}
+
+	spoolCfg := bm.SpoolConfig{}
+	if metricsCfg.Spool != nil {
+		spoolCfg = *metricsCfg.Spool
+	}
+	if len(spoolDir) > 0 {
+		spoolCfg.Dir = spoolDir
+	}
+	spool, err := bm.OpenSpool(spoolCfg)
+	if err != nil {
+		log.Fatalf("Unable to open the metrics spool: %s\n", err.Error())
+	}
+
+	switch command {
+	case "inspect":
+		inspect(spool)
+	case "replay":
+		if len(dbKey) == 0 {
+			dbKey = os.Getenv("METRICS_DB_KEY")
+		}
+		n, err := spool.Replay(metricsCfg, dbKey, sinkName)
+		fmt.Printf("Replayed %d batches\n", n)
+		if err != nil {
+			log.Fatalf("Replay stopped: %s\n", err.Error())
+		}
+	case "purge":
+		n, err := spool.Purge(sinkName)
+		fmt.Printf("Removed %d segments\n", n)
+		if err != nil {
+			log.Fatalf("Purge failed: %s\n", err.Error())
+		}
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
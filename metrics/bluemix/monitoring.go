@@ -62,19 +62,19 @@ type metricsConfig struct {
 // ServiceConfig contains the metrics configuration data
 type ServiceConfig struct {
 	Metrics *metricsConfig
+	Sinks   []SinkConfig `toml:"sink"`
+	Spool   *SpoolConfig `toml:"spool"`
 }
 
 // BluemixMetric defines the structure required by the Bluemix Metrics service
 type BluemixMetric struct {
-	Name      string      `json:"name"`
-	Timestamp int64       `json:"timestamp,omitempty"`
-	Value     interface{} `json:"value"`
+	Name      string            `json:"name"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Value     interface{}       `json:"value"`
+	Tags      map[string]string `json:"tags,omitempty"` // Optional extra tags. Tags inferred from the name take precedence.
 }
 
-var metricsCfg ServiceConfig
 var configPath string
-var clusterNames []string
-var carrierMetrics = false
 
 // ReadMetricsTomlFile reads the the metrics.toml file
 func ReadMetricsTomlFile() (ServiceConfig, bool) {
@@ -113,29 +113,71 @@ func ReadMetricsTomlFile() (ServiceConfig, bool) {
 // WriteClusterCreateBluemixMetrics is a special version of WriteBluemix Metrics. It is used by createCluster which needs to pass in an extra parameter i.e. the cluster name.
 // The clusterName can then be added as a metric tag and removed from the metric name
 func WriteClusterCreateBluemixMetrics(metrics []BluemixMetric, addPrefix bool, testName string, dbKey string, theClusterNames []string) {
-	clusterNames = theClusterNames
-	WriteBluemixMetrics(metrics, addPrefix, testName, dbKey)
+	writeBluemixMetrics(metrics, testName, dbKey, legacyOptions{clusterNames: theClusterNames})
 }
 
 // WriteCarrierBluemixMetrics is a special version of WriteBluemix Metrics. It is used by Carrier metrics code and allows us to avoid writing the large number of Carrier metrics to the logs
-// The clusterName can then be added as a metric tag and removed from the metric name
 func WriteCarrierBluemixMetrics(metrics []BluemixMetric, addPrefix bool, testName string, dbKey string) {
-	carrierMetrics = true
-	WriteBluemixMetrics(metrics, addPrefix, testName, dbKey)
-	carrierMetrics = false
+	writeBluemixMetrics(metrics, testName, dbKey, legacyOptions{carrierMetrics: true})
 }
 
-// WriteBluemixMetrics sends the supplied metrics to the Bluemix metrics service
+// WriteBluemixMetrics sends the supplied metrics to the sinks configured in metrics.toml (by default, InfluxDB)
 // The InfluxDB password will be searched for:
 // 1. Passed in as parameter by caller
 // 2. From METRICS_DB_KEY Environment variable
 // 3. From metrics.toml
 func WriteBluemixMetrics(metrics []BluemixMetric, addPrefix bool, testName string, dbKey string) {
+	writeBluemixMetrics(metrics, testName, dbKey, legacyOptions{})
+}
+
+// WriteMetrics sends the supplied structured metrics to the sinks configured in metrics.toml. The environment tags
+// (carrier, machine type, kube version, OS and test name) are added to each metric unless it sets them itself.
+// The InfluxDB password is found in the same way as for WriteBluemixMetrics.
+func WriteMetrics(metrics []Metric, testName string, dbKey string) {
+	env, ok := readMetricsEnvironment(testName, dbKey)
+	if !ok {
+		return
+	}
+	if len(env.testName) == 0 {
+		log.Println("No test name specified. No metrics data will be sent.")
+		return
+	}
+
+	envTags := environmentTags(env.testName, env.metricsPrefix, env.cfg.Metrics.Root, env.k8sVersionShort)
+	fanOut(newSinks(env.cfg, env.dbKey), newMetricBatch(env.testName, metrics, envTags, false))
+}
+
+func writeBluemixMetrics(metrics []BluemixMetric, testName string, dbKey string, opts legacyOptions) {
+	env, ok := readMetricsEnvironment(testName, dbKey)
+	if !ok {
+		return
+	}
+
+	// Send the metrics to the sinks. The prefix and k8s version are passed as tags
+	opts.verbose = env.cfg.Metrics.InfluxdbVerbose
+	batch, ok := buildMetricBatch(metrics, env.testName, env.metricsPrefix, env.cfg.Metrics.Root, env.k8sVersionShort, opts)
+	if !ok {
+		return
+	}
+	fanOut(newSinks(env.cfg, env.dbKey), batch)
+}
+
+// metricsEnvironment holds the configuration and environment the metrics are written with
+type metricsEnvironment struct {
+	cfg             ServiceConfig
+	testName        string
+	dbKey           string
+	metricsPrefix   string
+	k8sVersionShort string
+}
+
+// readMetricsEnvironment reads metrics.toml, and the metrics settings from the environment
+func readMetricsEnvironment(testName string, dbKey string) (metricsEnvironment, bool) {
 	// Get metrics configuration data
-	var ok bool
-	if metricsCfg, ok = ReadMetricsTomlFile(); !ok {
+	metricsCfg, ok := ReadMetricsTomlFile()
+	if !ok {
 		log.Println("Unable to read metrics toml file - metrics will not be published")
-		return
+		return metricsEnvironment{}, false
 	}
 
 	// Get any user defined prefix and/or kubernetes server version info.
@@ -176,6 +218,11 @@ func WriteBluemixMetrics(metrics []BluemixMetric, addPrefix bool, testName strin
 		}
 	}
 
-	// Send the metrics to Influxdb. Must send them before we add the prefix and k8s version - which are better passed as tags
-	WriteInfluxdbData(metrics, correctedTestName, ump, metricsCfg.Metrics.Root, K8sVersionShort, metricsCfg.Metrics.InfluxdbHost, metricsCfg.Metrics.InfluxdbPort, metricsCfg.Metrics.InfluxdbName, metricsCfg.Metrics.InfluxdbUser, dbKey, metricsCfg.Metrics.InfluxdbVerbose, clusterNames, carrierMetrics)
+	return metricsEnvironment{
+		cfg:             metricsCfg,
+		testName:        correctedTestName,
+		dbKey:           dbKey,
+		metricsPrefix:   ump,
+		k8sVersionShort: K8sVersionShort,
+	}, true
 }
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2019, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2019, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -11,23 +11,14 @@
 package metricsservice
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
-	"net"
-	"os"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
-
-	_ "github.com/influxdata/influxdb1-client" // this is important because of the bug in go mod
-	client "github.com/influxdata/influxdb1-client/v2"
 )
 
-var writeToFile = false
-var firstTime = true
 var carrierMetricFields = []string{"cpu_pcnt_used", "memory_pcnt_used", "eth0_network_receive_private", "eth0_network_transmit_private", "eth1_network_receive_public", "eth1_network_transmit_public", "xvda_disk_pcnt_busy", "xvdb_disk_pcnt_busy", "xvdc_disk_pcnt_busy"}
 
 // InfluxMetricArray is the influx structure
@@ -52,146 +43,76 @@ func metricNameContains(aMetricName string, subs ...string) bool {
 	return true
 }
 
+// legacyOptions controls how the dotted names of BluemixMetrics are interpreted
+type legacyOptions struct {
+	clusterNames   []string // Cluster names to remove from the metric names, and add as the ClusterName tag
+	carrierMetrics bool     // Carrier metrics are named after the carrier metric fields they contain
+	verbose        bool
+}
+
 // WriteInfluxdbData obtain tag metadata and send the data to Influxdb.
 func WriteInfluxdbData(metrics []BluemixMetric, testName string, metricsPrefix string, metricsRootName string, k8sVersionShort string, host string, port string, dbName string, user string, pw string, verbose bool, clusterNames []string, carrierMetrics bool) {
-
-	var influxMetricArray InfluxMetricArray
-	var cruiserMetrics bool
-	var fullFileName string
-	var fileErr error
-	var bp client.BatchPoints
-	var httpClient client.Client
-	var err error
-	filePath := "/performance/metrics/"
-
-	if len(testName) == 0 {
-		log.Println("No test name specified. No metrics data will be sent to Influxdb.")
+	opts := legacyOptions{clusterNames: clusterNames, carrierMetrics: carrierMetrics, verbose: verbose}
+	batch, ok := buildMetricBatch(metrics, testName, metricsPrefix, metricsRootName, k8sVersionShort, opts)
+	if !ok {
 		return
 	}
 
-	if firstTime || strings.HasPrefix(testName, "dummycruisermaster") || strings.HasPrefix(testName, "cruiserchurn") {
-		// Check Influxdb connectivity - if we can't connect we will write the metrics to a file
-		// For long running tests (cruiser_mon & cruiserchurn check everytime, otherwise intermiitent issues can cause results
-		// to be writtento file forever)
-		firstTime = false
-		conn, err := net.DialTimeout("tcp", host+":"+port, time.Duration(5)*time.Second)
-		if conn != nil {
-			defer conn.Close()
-			log.Printf("Connection to Influxdb succeeded\n")
-			writeToFile = false
-		} else {
-			fmt.Printf("No connection available to Influxdb (expected if metrics are sent from a Cruiser), so writing metrics to a local file. Reason for failure: %s\n", err)
-			writeToFile = true
-		}
+	sink := newInfluxdbSink(host, port, dbName, user, pw, verbose, true)
+	if err := sink.Write(batch); err != nil {
+		log.Printf("Failed to send request to Influxdb.\nError: %s\n ", err.Error())
 	}
+}
 
-	if len(pw) == 0 {
-		log.Println("No DB password specified, so writing metrics to a local file. ")
-		writeToFile = true
+// buildMetricBatch converts the metrics to data points, with tags describing the environment
+func buildMetricBatch(metrics []BluemixMetric, testName string, metricsPrefix string, metricsRootName string, k8sVersionShort string, opts legacyOptions) (MetricBatch, bool) {
+	if len(testName) == 0 {
+		log.Println("No test name specified. No metrics data will be sent.")
+		return MetricBatch{TestName: testName}, false
 	}
 
-	if writeToFile {
-		fileName := testName
-
-		if _, dirErr := os.Stat(filePath); os.IsNotExist(dirErr) {
-			err := os.MkdirAll(filePath, 0664)
-			if err != nil {
-				log.Printf("Error creating Influxdb file directory: %s\n%s\n", filePath, err)
-				return
-			}
-		}
-
-		// Find the next unused incremental filename e.g. sysbench1.json, sysbench2.json ...
-		fileNum := 0
-		for {
-			fileNum++
-			fullFileName = filePath + fileName + strconv.Itoa(fileNum) + ".json"
-			if _, fileErr = os.Stat(fullFileName); fileErr != nil {
-				break
-			}
-		}
-
-		// We are looking for an unused filename, so IsNotExist should be true. For any other error, stop processing and report the cause.
-		if !os.IsNotExist(fileErr) {
-			log.Printf("Unexpected error when looking for Influxdb metrics filename. Last metrics name checked was:\nError %s\n" + fileErr.Error())
-			return
-		}
-		log.Printf("Writing metrics to file: %s\n", fullFileName)
-
-	} else {
-		// Write directly to Influxdb
-
-		httpClient, err = client.NewHTTPClient(client.HTTPConfig{
-			Addr:     "http://" + host + ":" + port,
-			Username: user,
-			Password: pw, // pragma: allowlist secret
-			Timeout:  300 * time.Second,
-		})
-		if err != nil {
-			log.Printf("Failed to create http client to Influxdb. No metric data will not be sent.\nError %s\n" + err.Error())
-			return
-		}
-		defer httpClient.Close()
-
-		bp, err = client.NewBatchPoints(client.BatchPointsConfig{
-			Database:  dbName,
-			Precision: "ms",
-		})
-
-		if err != nil {
-			log.Printf("Failed to create Influxdb batch point.\nError %s\n" + err.Error())
-			return
-		}
-
+	structured, bulk, err := fromLegacyMetrics(metrics, testName, opts)
+	if err != nil {
+		log.Printf("send-to-Influx.WriteToInfluxdb: \n" + err.Error())
+		return MetricBatch{TestName: testName}, false
 	}
+	batch := newMetricBatch(testName, structured, environmentTags(testName, metricsPrefix, metricsRootName, k8sVersionShort), bulk)
 
-	var carrierName string
-	// Allow override of metrics root specified in metrics.toml file
-	if mro := os.Getenv("METRICS_ROOT_OVERRIDE"); len(mro) == 0 {
-		carrierName = "carrier_stage"
-		rootMetricNameParts := strings.Split(metricsRootName, ".")
-		for _, metricNameSubstring := range rootMetricNameParts {
-			if strings.Contains(metricNameSubstring, "carrier") || strings.Contains(metricNameSubstring, "satellite") {
-				carrierName = metricNameSubstring
+	//Only print out custom metrics - exclude metrics-server data as they generate too many log entries
+	if opts.verbose && !opts.carrierMetrics {
+		for _, p := range batch.Points {
+			if p.Tags[TagMetricType] != "custom" {
+				continue
+			}
+			for _, v := range p.Fields {
+				log.Printf("DBTable=%s  CarrierName=%s  MachineType=%s  KubeVersion=%s  OperatingSystem=%s MetricName=%s  MetricValue=%v\n", p.DBTableName, p.Tags[TagCarrierName], p.Tags[TagMachineType], p.Tags[TagKubeVersion], p.Tags[TagOperatingSystem], p.Tags[TagMetricName], v)
 			}
 		}
-	} else {
-		carrierName = mro
-	}
-
-	// For Satellite clusters (but we'll generalize the suport) we need to support the identification of control plane configuration.
-	if ml := os.Getenv("METRICS_LOCATION"); len(ml) > 0 {
-		carrierName = strings.Join([]string{carrierName, ml}, "-")
 	}
+	return batch, true
+}
 
-	operatingSystem := os.Getenv("METRICS_OS")
-
-	tags := map[string]string{
-		"CarrierName":     carrierName,
-		"MachineType":     metricsPrefix,
-		"KubeVersion":     k8sVersionShort,
-		"OperatingSystem": operatingSystem,
-		"TestName":        testName,
-	}
+// fromLegacyMetrics converts BluemixMetrics to Metrics, inferring the field name and the MetricName, MetricType and
+// ClusterName tags from the dotted metric names. The measurement is the test name. It also returns true if any of the
+// metrics are cruiser or carrier metrics, which are too numerous to log or write to files.
+func fromLegacyMetrics(metrics []BluemixMetric, testName string, opts legacyOptions) ([]Metric, bool, error) {
+	bulk := opts.carrierMetrics
+	structured := make([]Metric, 0, len(metrics))
 
 	for _, ametric := range metrics {
 
 		metricFloatValue, err := interfaceToFloat64(ametric.Value)
 		if err != nil {
-			log.Printf("send-to-Influx.WriteToInfluxdb: \n" + err.Error())
-			return
+			return nil, bulk, err
 		}
 
 		metricName := ametric.Name
 		//If the clusterName is specified then we have been called from createCluster and we need to remove the cluster name from the metrics (because we can't use wild cards in Influxdb metric value fields)
 		clusterNameTag := ""
-		if len(clusterNames) > 0 {
-			for _, clusterName := range clusterNames {
-				if strings.Contains(metricName, clusterName) {
-					clusterNameTag = clusterName
-					metricName = strings.Replace(metricName, (clusterName + "."), "", 1)
-				}
-
+		for _, clusterName := range opts.clusterNames {
+			if strings.Contains(metricName, clusterName) {
+				clusterNameTag = clusterName
+				metricName = strings.Replace(metricName, (clusterName + "."), "", 1)
 			}
 		}
 
@@ -203,11 +124,9 @@ func WriteInfluxdbData(metrics []BluemixMetric, testName string, metricsPrefix s
 		shortMetricName := strings.Replace(metricNameGoodChars, (testNameGoodChars + "_"), "", 1)
 		fieldName := shortMetricName
 
-		dbTableName := testNameGoodChars
-		cruiserMetrics = false
 		metricType := "custom"
 
-		if carrierMetrics {
+		if opts.carrierMetrics {
 
 			for _, metricField := range carrierMetricFields {
 				if metricNameContains(shortMetricName, metricField) {
@@ -217,7 +136,7 @@ func WriteInfluxdbData(metrics []BluemixMetric, testName string, metricsPrefix s
 			}
 
 		} else {
-			cruiserMetrics = true
+			cruiserMetrics := true
 
 			if metricNameContains(shortMetricName, "cruiser_namespace_metrics_", "_cpu") {
 				metricType = "ns_cpu"
@@ -240,71 +159,30 @@ func WriteInfluxdbData(metrics []BluemixMetric, testName string, metricsPrefix s
 				cruiserMetricNameReplacer := strings.NewReplacer("cruiser_namespace_metrics_", "", "cruiser_node_metrics_", "", "cruiser_pod_metrics_", "")
 				shortMetricName = cruiserMetricNameReplacer.Replace(shortMetricName)
 				fieldName = metricType
+				bulk = true
 			}
 		}
 
-		tags["MetricName"] = shortMetricName
-		tags["MetricType"] = metricType
-		tags["ClusterName"] = clusterNameTag
-
-		fields := map[string]interface{}{
-			fieldName: metricFloatValue,
+		tags := make(map[string]string, len(ametric.Tags)+3)
+		for k, v := range ametric.Tags {
+			tags[k] = v
 		}
+		tags[TagMetricName] = shortMetricName
+		tags[TagMetricType] = metricType
+		tags[TagClusterName] = clusterNameTag
 
-		//Only print out custom metrics - exclude metrics-server data as they generate too many log entries
-		if verbose && !cruiserMetrics && !carrierMetrics {
-			log.Printf("DBTable=%s  CarrierName=%s  MachineType=%s  KubeVersion=%s  OperatingSystem=%s MetricName=%s  MetricValue=%v\n", dbTableName, carrierName, metricsPrefix, k8sVersionShort, operatingSystem, shortMetricName, metricFloatValue)
+		m := Metric{
+			Measurement: testNameGoodChars,
+			Tags:        tags,
+			Fields:      map[string]float64{fieldName: metricFloatValue},
 		}
-
-		if writeToFile {
-			influxMetricArray.InfluxMetricArray = append(influxMetricArray.InfluxMetricArray, InfluxDataStruc{dbTableName, tags, fields, time.Now()})
-		} else {
-			tstamp := time.Now()
-			if ametric.Timestamp > 0 {
-				tstamp = time.Unix(ametric.Timestamp, 0)
-			}
-			pt, err := client.NewPoint(dbTableName, tags, fields, tstamp)
-
-			if err != nil {
-				fmt.Println("Error creating Influxdb data point: ", err.Error())
-			} else {
-				bp.AddPoint(pt)
-			}
-		}
-
-	}
-
-	if writeToFile {
-		if !cruiserMetrics && !carrierMetrics { // Don't write cruiser or carrier metrics to files or we could end up writing large amounts of data. They should have direct access to the Influxdb anyway.
-			metricsFile, err := json.MarshalIndent(influxMetricArray, "", " ")
-			if err != nil {
-				log.Printf("Error marshalling metrics Data: %s\n", err.Error())
-			}
-			f, err := os.OpenFile(fullFileName, os.O_CREATE|os.O_WRONLY, 0600)
-			if err != nil {
-				log.Printf("Error creating or opening file: %s.\n%s\n", fullFileName, err.Error())
-				return
-			}
-			defer f.Close()
-			if _, err = f.Write(metricsFile); err != nil {
-				log.Printf("Error writing metrics data to file:\n %s\n", err.Error())
-			}
-		}
-	} else {
-		if err := httpClient.Write(bp); err != nil {
-			log.Printf("Failed to send request to Influxdb.\nError: %s\n ", err.Error())
-		} else {
-			if verbose && !cruiserMetrics && !carrierMetrics {
-				log.Println("Metrics successfully sent to influxdb")
-			}
-
-		}
-
-		if err := httpClient.Close(); err != nil {
-			log.Printf("Error closing Influxdb client.\nError: %s\n ", err.Error())
+		if ametric.Timestamp > 0 {
+			m.Timestamp = time.Unix(ametric.Timestamp, 0)
 		}
+		structured = append(structured, m)
 	}
 
+	return structured, bulk, nil
 }
 
 func interfaceToFloat64(interfaceValue interface{}) (float64, error) {
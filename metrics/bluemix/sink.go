@@ -0,0 +1,199 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package metricsservice
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	_ "github.com/influxdata/influxdb1-client" // this is important because of the bug in go mod
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+// Sink types which can be configured in metrics.toml
+const (
+	SinkInfluxdb    = "influxdb"
+	SinkInfluxdb2   = "influxdb2"
+	SinkPushgateway = "pushgateway"
+	SinkOpenMetrics = "openmetrics"
+	SinkStdout      = "stdout"
+)
+
+const defaultMetricsFilePath = "/performance/metrics/"
+
+// MetricBatch holds the data points for a set of metrics written by a test
+type MetricBatch struct {
+	TestName string
+	Points   []InfluxDataStruc
+	// Bulk is true for cruiser and carrier metrics. These are too large to log, or to write to local files.
+	Bulk bool
+}
+
+// Sink is a destination for metrics data
+type Sink interface {
+	// Name identifies the sink in log messages, and its spool. It is the sink type followed by its destination, so sinks
+	// of the same type are distinguished e.g. two influxdb2 buckets.
+	Name() string
+	// Write sends a batch of metrics to the sink
+	Write(batch MetricBatch) error
+}
+
+// SinkConfig defines a sink in metrics.toml. Multiple sinks can be configured, and each batch of metrics is written to all of them:
+//
+//	[[sink]]
+//	type = "pushgateway"
+//	url = "http://pushgateway:9091"
+//
+// If no sinks are configured, metrics are written to the InfluxDB v1 database defined in the [metrics] section.
+// Credentials are deliberately not read from the file for security reasons.
+type SinkConfig struct {
+	Type     string `toml:"type"`     // influxdb, influxdb2, pushgateway, openmetrics or stdout
+	URL      string `toml:"url"`      // influxdb2, pushgateway
+	Org      string `toml:"org"`      // influxdb2
+	Bucket   string `toml:"bucket"`   // influxdb2
+	TokenEnv string `toml:"tokenEnv"` // influxdb2: environment variable holding the API token (default INFLUXDB_TOKEN)
+	Job      string `toml:"job"`      // pushgateway: job name (default armada_performance)
+	Path     string `toml:"path"`     // openmetrics: directory the files are written to (default /performance/metrics/)
+	Verbose  bool   `toml:"verbose"`
+}
+
+// newSinks creates the sinks configured in metrics.toml. dbKey is the InfluxDB v1 password.
+// If a spool is configured, batches which can't be written to a sink are spooled.
+func newSinks(cfg ServiceConfig, dbKey string) []Sink {
+	m := cfg.Metrics
+	spool := getSpool(cfg.Spool)
+	if len(cfg.Sinks) == 0 {
+		sink := newInfluxdbSink(m.InfluxdbHost, m.InfluxdbPort, m.InfluxdbName, m.InfluxdbUser, dbKey, m.InfluxdbVerbose, spool == nil)
+		if spool == nil {
+			return []Sink{sink}
+		}
+		return []Sink{spool.wrap(sink)}
+	}
+
+	var sinks []Sink
+	for _, sc := range cfg.Sinks {
+		var sink Sink
+		switch strings.ToLower(sc.Type) {
+		case SinkInfluxdb:
+			sink = newInfluxdbSink(m.InfluxdbHost, m.InfluxdbPort, m.InfluxdbName, m.InfluxdbUser, dbKey, m.InfluxdbVerbose || sc.Verbose, spool == nil)
+		case SinkInfluxdb2:
+			sink = newInfluxdb2Sink(sc)
+		case SinkPushgateway:
+			sink = newPushgatewaySink(sc)
+		case SinkOpenMetrics:
+			sink = newOpenMetricsSink(sc)
+		case SinkStdout:
+			sink = stdoutSink{}
+		default:
+			log.Printf("Unknown metrics sink type '%s' in metrics.toml - ignoring\n", sc.Type)
+			continue
+		}
+		if spool != nil {
+			sink = spool.wrap(sink)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+// permanentError is returned by a sink when it has rejected a batch, so that writing the batch again would fail too
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// isPermanent returns true if the sink rejected the batch
+func isPermanent(err error) bool {
+	_, ok := err.(permanentError)
+	return ok
+}
+
+// statusError returns an error for a failed HTTP request. Only rejections of the payload itself are permanent - other
+// failures, including authentication (e.g. an expired token), may succeed later so the batch is kept.
+func statusError(status int, msg string) error {
+	err := fmt.Errorf("request failed with status %d: %s", status, msg)
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return permanentError{err}
+	}
+	return err
+}
+
+// fanOut writes the batch to every sink. A failure of one sink doesn't prevent the others being written.
+func fanOut(sinks []Sink, batch MetricBatch) error {
+	var errs []string
+	for _, s := range sinks {
+		if err := s.Write(batch); err != nil {
+			log.Printf("Failed to write metrics to %s: %s\n", s.Name(), err.Error())
+			errs = append(errs, s.Name()+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// sinkName returns the name of a sink of the type, writing to the destination
+func sinkName(sinkType string, destination string) string {
+	return sinkType + " " + destination
+}
+
+// stdoutSink writes metrics to stdout in InfluxDB line protocol
+type stdoutSink struct{}
+
+func (stdoutSink) Name() string {
+	return SinkStdout
+}
+
+func (stdoutSink) Write(batch MetricBatch) error {
+	for _, p := range batch.Points {
+		pt, err := client.NewPoint(p.DBTableName, p.Tags, p.Fields, p.TimeStamp)
+		if err != nil {
+			return err
+		}
+		fmt.Println(pt.PrecisionString("ms"))
+	}
+	return nil
+}
+
+// nextMetricsFileName returns the first unused incremental filename in the directory e.g. sysbench1.json, sysbench2.json ...
+func nextMetricsFileName(filePath string, testName string, extension string) (string, error) {
+	if _, dirErr := os.Stat(filePath); os.IsNotExist(dirErr) {
+		// #nosec G301
+		if err := os.MkdirAll(filePath, 0775); err != nil {
+			return "", fmt.Errorf("error creating metrics file directory: %s - %s", filePath, err.Error())
+		}
+	}
+
+	fileNum := 0
+	for {
+		fileNum++
+		fullFileName := strings.TrimSuffix(filePath, "/") + "/" + testName + strconv.Itoa(fileNum) + extension
+		_, fileErr := os.Stat(fullFileName)
+		if fileErr == nil {
+			continue
+		}
+		// We are looking for an unused filename, so IsNotExist should be true. For any other error, stop processing and report the cause.
+		if !os.IsNotExist(fileErr) {
+			return "", fmt.Errorf("unexpected error when looking for metrics filename %s: %s", fullFileName, fileErr.Error())
+		}
+		return fullFileName, nil
+	}
+}
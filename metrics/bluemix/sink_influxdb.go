@@ -0,0 +1,260 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2019, 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package metricsservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/influxdata/influxdb1-client" // this is important because of the bug in go mod
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+const defaultInfluxdbTokenEnvVar = "INFLUXDB_TOKEN"
+
+// influxdbSink writes metrics to an InfluxDB v1 database. If the database can't be reached, or no password is available,
+// the metrics are written to a local json file instead, which can be sent later by send-file-to-Influx.
+// When the sink is spooled there is no file fallback, and these failures are returned as errors.
+type influxdbSink struct {
+	host         string
+	port         string
+	dbName       string
+	user         string
+	pw           string
+	verbose      bool
+	fileFallback bool
+
+	mutex       sync.Mutex // Guards checked and writeToFile
+	checked     bool       // Connectivity to the database has been checked
+	writeToFile bool       // The database can't be reached, so metrics are written to files
+}
+
+// influxdbSinkKey identifies an InfluxDB v1 sink by its configuration
+type influxdbSinkKey struct {
+	host, port, dbName, user, pw string
+	verbose, fileFallback        bool
+}
+
+var influxdbSinks = make(map[influxdbSinkKey]*influxdbSink)
+var influxdbSinksMutex sync.Mutex
+
+// newInfluxdbSink returns the sink for the database. Sinks are created for each batch of metrics, so a sink with the same
+// configuration is reused, and connectivity to the database is only checked the first time it is written to.
+func newInfluxdbSink(host string, port string, dbName string, user string, pw string, verbose bool, fileFallback bool) *influxdbSink {
+	key := influxdbSinkKey{host: host, port: port, dbName: dbName, user: user, pw: pw, verbose: verbose, fileFallback: fileFallback}
+
+	influxdbSinksMutex.Lock()
+	defer influxdbSinksMutex.Unlock()
+	if sink, ok := influxdbSinks[key]; ok {
+		return sink
+	}
+	sink := &influxdbSink{host: host, port: port, dbName: dbName, user: user, pw: pw, verbose: verbose, fileFallback: fileFallback}
+	influxdbSinks[key] = sink
+	return sink
+}
+
+func (s *influxdbSink) Name() string {
+	return sinkName(SinkInfluxdb, fmt.Sprintf("%s:%s/%s", s.host, s.port, s.dbName))
+}
+
+func (s *influxdbSink) Write(batch MetricBatch) error {
+	testName := batch.TestName
+
+	if !s.fileFallback {
+		if len(s.pw) == 0 {
+			return errors.New("no DB password specified")
+		}
+		return s.writeToInfluxdb(batch)
+	}
+
+	s.mutex.Lock()
+	if !s.checked || strings.HasPrefix(testName, "dummycruisermaster") || strings.HasPrefix(testName, "cruiserchurn") {
+		// Check Influxdb connectivity - if we can't connect we will write the metrics to a file
+		// For long running tests (cruiser_mon & cruiserchurn check everytime, otherwise intermiitent issues can cause results
+		// to be writtento file forever)
+		s.checked = true
+		conn, err := net.DialTimeout("tcp", s.host+":"+s.port, time.Duration(5)*time.Second)
+		if conn != nil {
+			defer conn.Close()
+			log.Printf("Connection to Influxdb succeeded\n")
+			s.writeToFile = false
+		} else {
+			fmt.Printf("No connection available to Influxdb (expected if metrics are sent from a Cruiser), so writing metrics to a local file. Reason for failure: %s\n", err)
+			s.writeToFile = true
+		}
+	}
+
+	if len(s.pw) == 0 {
+		log.Println("No DB password specified, so writing metrics to a local file. ")
+		s.writeToFile = true
+	}
+	writeToFile := s.writeToFile
+	s.mutex.Unlock()
+
+	if writeToFile {
+		// Don't write cruiser or carrier metrics to files or we could end up writing large amounts of data. They should have direct access to the Influxdb anyway.
+		if batch.Bulk {
+			return nil
+		}
+		return writeInfluxdbFile(batch)
+	}
+	return s.writeToInfluxdb(batch)
+}
+
+// writeToInfluxdb writes the batch directly to Influxdb
+func (s *influxdbSink) writeToInfluxdb(batch MetricBatch) error {
+	httpClient, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr:     "http://" + s.host + ":" + s.port,
+		Username: s.user,
+		Password: s.pw, // pragma: allowlist secret
+		Timeout:  300 * time.Second,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create http client to Influxdb: %s", err.Error())
+	}
+	defer httpClient.Close()
+
+	bp, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:  s.dbName,
+		Precision: "ms",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Influxdb batch point: %s", err.Error())
+	}
+
+	for _, p := range batch.Points {
+		pt, err := client.NewPoint(p.DBTableName, p.Tags, p.Fields, p.TimeStamp)
+		if err != nil {
+			fmt.Println("Error creating Influxdb data point: ", err.Error())
+		} else {
+			bp.AddPoint(pt)
+		}
+	}
+
+	if err := httpClient.Write(bp); err != nil {
+		// Influxdb rejects points which don't match the schema of the existing data
+		if strings.Contains(err.Error(), "partial write") || strings.Contains(err.Error(), "unable to parse") {
+			return permanentError{err}
+		}
+		return err
+	}
+	if s.verbose && !batch.Bulk {
+		log.Println("Metrics successfully sent to influxdb")
+	}
+	return nil
+}
+
+// writeInfluxdbFile writes the metrics to the next unused json file for the test e.g. sysbench1.json, sysbench2.json ...
+func writeInfluxdbFile(batch MetricBatch) error {
+	fullFileName, err := nextMetricsFileName(defaultMetricsFilePath, batch.TestName, ".json")
+	if err != nil {
+		return err
+	}
+	log.Printf("Writing metrics to file: %s\n", fullFileName)
+
+	metricsFile, err := json.MarshalIndent(InfluxMetricArray{InfluxMetricArray: batch.Points}, "", " ")
+	if err != nil {
+		return fmt.Errorf("error marshalling metrics data: %s", err.Error())
+	}
+	f, err := os.OpenFile(fullFileName, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating or opening file: %s - %s", fullFileName, err.Error())
+	}
+	defer f.Close()
+	if _, err = f.Write(metricsFile); err != nil {
+		return fmt.Errorf("error writing metrics data to file: %s", err.Error())
+	}
+	return nil
+}
+
+// influxdb2Sink writes metrics to an InfluxDB v2 bucket, using the line protocol write API
+type influxdb2Sink struct {
+	url        string
+	org        string
+	bucket     string
+	token      string
+	verbose    bool
+	httpClient *http.Client
+}
+
+func newInfluxdb2Sink(sc SinkConfig) *influxdb2Sink {
+	tokenEnv := sc.TokenEnv
+	if len(tokenEnv) == 0 {
+		tokenEnv = defaultInfluxdbTokenEnvVar
+	}
+	token := os.Getenv(tokenEnv)
+	if len(token) == 0 {
+		log.Printf("WARNING: InfluxDB v2 token not provided. Check '%s' environment variable\n", tokenEnv)
+	}
+
+	return &influxdb2Sink{
+		url:        strings.TrimSuffix(sc.URL, "/"),
+		org:        sc.Org,
+		bucket:     sc.Bucket,
+		token:      token,
+		verbose:    sc.Verbose,
+		httpClient: &http.Client{Timeout: 300 * time.Second},
+	}
+}
+
+func (s *influxdb2Sink) Name() string {
+	return sinkName(SinkInfluxdb2, fmt.Sprintf("%s %s/%s", s.url, s.org, s.bucket))
+}
+
+func (s *influxdb2Sink) Write(batch MetricBatch) error {
+	var body bytes.Buffer
+	for _, p := range batch.Points {
+		pt, err := client.NewPoint(p.DBTableName, p.Tags, p.Fields, p.TimeStamp)
+		if err != nil {
+			fmt.Println("Error creating Influxdb data point: ", err.Error())
+			continue
+		}
+		body.WriteString(pt.PrecisionString("ms"))
+		body.WriteByte('\n')
+	}
+
+	query := url.Values{}
+	query.Set("org", s.org)
+	query.Set("bucket", s.bucket)
+	query.Set("precision", "ms")
+	req, err := http.NewRequest(http.MethodPost, s.url+"/api/v2/write?"+query.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+s.token)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return statusError(resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if s.verbose && !batch.Bulk {
+		log.Println("Metrics successfully sent to InfluxDB v2")
+	}
+	return nil
+}
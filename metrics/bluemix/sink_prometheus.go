@@ -0,0 +1,215 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package metricsservice
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPushgatewayJob = "armada_performance"
+
+var invalidPromNameChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+var invalidPromLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+var promLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promSample is a single sample in the Prometheus text formats. The metric name is the measurement and field name,
+// and the labels are the non-empty tags.
+type promSample struct {
+	name      string
+	labels    string
+	value     float64
+	timestamp time.Time
+}
+
+// promName converts a name to a valid Prometheus metric or label name
+func promName(name string, invalid *regexp.Regexp) string {
+	name = invalid.ReplaceAllString(name, "_")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// promLabels formats the tags as Prometheus labels, in a consistent order
+func promLabels(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if len(v) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		labels[i] = promName(k, invalidPromLabelChars) + `="` + promLabelValueEscaper.Replace(tags[k]) + `"`
+	}
+	return strings.Join(labels, ",")
+}
+
+// promSamples converts the points to samples, sorted by metric name so that each metric family is contiguous.
+// If there are several samples for the same metric and labels, only the latest is kept.
+func promSamples(points []InfluxDataStruc) []promSample {
+	latest := make(map[string]int)
+	var samples []promSample
+	for _, p := range points {
+		labels := promLabels(p.Tags)
+		for field, v := range p.Fields {
+			value, err := interfaceToFloat64(v)
+			if err != nil {
+				log.Printf("Skipping metric %s_%s: %s\n", p.DBTableName, field, err.Error())
+				continue
+			}
+			s := promSample{
+				name:      promName(p.DBTableName+"_"+field, invalidPromNameChars),
+				labels:    labels,
+				value:     value,
+				timestamp: p.TimeStamp,
+			}
+			key := s.name + "{" + s.labels + "}"
+			if i, ok := latest[key]; ok {
+				if !s.timestamp.Before(samples[i].timestamp) {
+					samples[i] = s
+				}
+			} else {
+				latest[key] = len(samples)
+				samples = append(samples, s)
+			}
+		}
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].name < samples[j].name })
+	return samples
+}
+
+// writePromText writes the samples in the Prometheus text exposition format. OpenMetrics also requires timestamps
+// (in seconds) and an EOF marker; the Pushgateway rejects timestamps.
+func writePromText(buf *bytes.Buffer, samples []promSample, openMetrics bool) {
+	lastName := ""
+	for _, s := range samples {
+		if s.name != lastName {
+			fmt.Fprintf(buf, "# TYPE %s gauge\n", s.name)
+			lastName = s.name
+		}
+		buf.WriteString(s.name)
+		if len(s.labels) > 0 {
+			buf.WriteString("{" + s.labels + "}")
+		}
+		buf.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64))
+		if openMetrics {
+			buf.WriteString(" " + strconv.FormatFloat(float64(s.timestamp.UnixNano()/int64(time.Millisecond))/1000, 'f', 3, 64))
+		}
+		buf.WriteByte('\n')
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+}
+
+// pushgatewaySink pushes metrics to a Prometheus Pushgateway, grouped by job and test name
+type pushgatewaySink struct {
+	url        string
+	job        string
+	verbose    bool
+	httpClient *http.Client
+}
+
+func newPushgatewaySink(sc SinkConfig) *pushgatewaySink {
+	job := sc.Job
+	if len(job) == 0 {
+		job = defaultPushgatewayJob
+	}
+	return &pushgatewaySink{
+		url:        strings.TrimSuffix(sc.URL, "/"),
+		job:        job,
+		verbose:    sc.Verbose,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *pushgatewaySink) Name() string {
+	return sinkName(SinkPushgateway, fmt.Sprintf("%s/metrics/job/%s", s.url, s.job))
+}
+
+func (s *pushgatewaySink) Write(batch MetricBatch) error {
+	var body bytes.Buffer
+	writePromText(&body, promSamples(batch.Points), false)
+
+	// POST only replaces metrics with the same names in the group, so a test can push its metrics in several batches
+	pushURL := fmt.Sprintf("%s/metrics/job/%s/test/%s", s.url, url.PathEscape(s.job), url.PathEscape(batch.TestName))
+	req, err := http.NewRequest(http.MethodPost, pushURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return statusError(resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if s.verbose && !batch.Bulk {
+		log.Println("Metrics successfully pushed to Pushgateway")
+	}
+	return nil
+}
+
+// openMetricsSink writes each batch of metrics to the next unused OpenMetrics text file for the test
+// e.g. sysbench1.om, sysbench2.om ...
+type openMetricsSink struct {
+	path    string
+	verbose bool
+}
+
+func newOpenMetricsSink(sc SinkConfig) *openMetricsSink {
+	path := sc.Path
+	if len(path) == 0 {
+		path = defaultMetricsFilePath
+	}
+	return &openMetricsSink{path: path, verbose: sc.Verbose}
+}
+
+func (s *openMetricsSink) Name() string {
+	return sinkName(SinkOpenMetrics, s.path)
+}
+
+func (s *openMetricsSink) Write(batch MetricBatch) error {
+	fullFileName, err := nextMetricsFileName(s.path, batch.TestName, ".om")
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	writePromText(&body, promSamples(batch.Points), true)
+	if err := ioutil.WriteFile(fullFileName, body.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing metrics data to file: %s", err.Error())
+	}
+
+	if s.verbose && !batch.Bulk {
+		log.Printf("Metrics written to file: %s\n", fullFileName)
+	}
+	return nil
+}
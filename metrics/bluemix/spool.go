@@ -0,0 +1,883 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package metricsservice
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Spool defaults
+const (
+	defaultSpoolSegmentBytes  = 4 * 1024 * 1024
+	defaultSpoolMaxBytes      = 256 * 1024 * 1024
+	defaultSpoolMaxAge        = 7 * 24 * time.Hour
+	defaultSpoolFlushInterval = 30 * time.Second
+	maxSpoolBackoff           = 10 * time.Minute
+)
+
+const (
+	spoolSegmentExt       = ".seg"
+	spoolCheckpointFile   = "checkpoint"
+	spoolReplayLockFile   = "replay.lock"
+	spoolRecordHeaderSize = 8
+	maxSpoolRecordBytes   = 64 * 1024 * 1024
+)
+
+var spoolCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errSpoolTruncated = errors.New("truncated record")
+var errSpoolCorrupt = errors.New("corrupt record")
+var errSpoolBusy = errors.New("spool is being replayed by another process")
+
+// SpoolConfig defines the optional [spool] section in metrics.toml. If it is configured, batches which can't be written
+// to a sink are appended to a spool on disk, rather than written to json files, and replayed once the sink is reachable.
+//
+//	[spool]
+//	dir = "/performance/metrics/spool"
+type SpoolConfig struct {
+	Dir             string `toml:"dir"`
+	MaxSegmentBytes int64  `toml:"maxSegmentBytes"` // Size at which a new segment is started (default 4MB)
+	MaxBytes        int64  `toml:"maxBytes"`        // Oldest segments are dropped when the spool for a sink exceeds this (default 256MB)
+	MaxAge          string `toml:"maxAge"`          // Segments not written to for this long are dropped (default 168h)
+	FlushInterval   string `toml:"flushInterval"`   // Initial delay between replay attempts, doubled after each failure (default 30s)
+}
+
+// Spool is a write-ahead spool of metric batches which couldn't be written to their sinks.
+//
+// Each sink has its own directory of append-only segment files. Each record in a segment is a length, a CRC-32C
+// checksum and a json encoded batch. The checkpoint file holds the position of the next record to be replayed, and is
+// updated after each batch is written to the sink, so a batch is only replayed twice if the process stops between the
+// write and the update. The points keep their original timestamps, so InfluxDB overwrites rather than duplicates them.
+//
+// The spool is shared by every collector on the machine. Each sink directory is locked with flock whenever its files
+// are read or changed, and a separate replay lock is held for the whole of a replay, so only one process replays a
+// sink at a time while the others carry on appending.
+type Spool struct {
+	dir             string
+	maxSegmentBytes int64
+	maxBytes        int64
+	maxAge          time.Duration
+	flushInterval   time.Duration
+
+	mutex      sync.Mutex // Guards the files, within the process. The sink directory lock guards them between processes.
+	flushMutex sync.Mutex // Serializes replays within the process. The replay lock serializes them between processes.
+
+	active      map[string]uint64 // Segment being appended to, for each sink
+	sinks       map[string]Sink
+	nextAttempt map[string]time.Time
+	backoff     map[string]time.Duration
+	flusherOnce sync.Once
+}
+
+// spoolRecord is a batch in the spool
+type spoolRecord struct {
+	Spooled time.Time   `json:"spooled"`
+	Batch   MetricBatch `json:"batch"`
+}
+
+// spoolPosition is the position of a record in the spool for a sink
+type spoolPosition struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// SpoolSegment summarizes a segment file, for inspection
+type SpoolSegment struct {
+	Sink     string
+	File     string
+	Bytes    int64
+	Batches  int
+	Replayed int
+	Points   int
+	Corrupt  int
+	Oldest   time.Time
+	Newest   time.Time
+}
+
+// OpenSpool opens, or creates, the spool
+func OpenSpool(cfg SpoolConfig) (*Spool, error) {
+	if len(cfg.Dir) == 0 {
+		return nil, errors.New("no spool directory specified")
+	}
+	s := &Spool{
+		dir:             cfg.Dir,
+		maxSegmentBytes: cfg.MaxSegmentBytes,
+		maxBytes:        cfg.MaxBytes,
+		maxAge:          defaultSpoolMaxAge,
+		flushInterval:   defaultSpoolFlushInterval,
+		active:          make(map[string]uint64),
+		sinks:           make(map[string]Sink),
+		nextAttempt:     make(map[string]time.Time),
+		backoff:         make(map[string]time.Duration),
+	}
+	if s.maxSegmentBytes <= 0 {
+		s.maxSegmentBytes = defaultSpoolSegmentBytes
+	}
+	if s.maxBytes <= 0 {
+		s.maxBytes = defaultSpoolMaxBytes
+	}
+	if len(cfg.MaxAge) > 0 {
+		d, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spool maxAge '%s': %s", cfg.MaxAge, err.Error())
+		}
+		s.maxAge = d
+	}
+	if len(cfg.FlushInterval) > 0 {
+		d, err := time.ParseDuration(cfg.FlushInterval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid spool flushInterval '%s'", cfg.FlushInterval)
+		}
+		s.flushInterval = d
+	}
+
+	// #nosec G301
+	if err := os.MkdirAll(s.dir, 0775); err != nil {
+		return nil, fmt.Errorf("error creating spool directory: %s - %s", s.dir, err.Error())
+	}
+	return s, nil
+}
+
+// Append adds a batch to the end of the spool for the sink
+func (s *Spool) Append(sinkName string, batch MetricBatch) error {
+	payload, err := json.Marshal(spoolRecord{Spooled: time.Now(), Batch: batch})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxSpoolRecordBytes {
+		return fmt.Errorf("batch of %d bytes is too large to spool", len(payload))
+	}
+	record := make([]byte, spoolRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, spoolCRCTable))
+	copy(record[spoolRecordHeaderSize:], payload)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir := s.sinkDir(sinkName)
+	// #nosec G301
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	lock, err := lockSinkDir(dir)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	s.expire(sinkName)
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+	// Each process starts a new segment, so a record truncated by a crash is always at the end of a segment.
+	// A new segment also follows the checkpoint, so it isn't mistaken for one which has been replayed.
+	var last uint64
+	if len(segments) > 0 {
+		last = segments[len(segments)-1]
+	}
+	seq := s.active[sinkName]
+	if seq == 0 || seq != last {
+		seq = newSegment(last, readCheckpoint(dir))
+	} else if info, err := os.Stat(segmentPath(dir, seq)); err == nil && info.Size()+int64(len(record)) > s.maxSegmentBytes {
+		seq = newSegment(last, readCheckpoint(dir))
+	}
+	s.active[sinkName] = seq
+
+	f, err := os.OpenFile(segmentPath(dir, seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(record); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	s.enforceMaxBytes(sinkName)
+	return nil
+}
+
+// Pending returns true if there are batches waiting to be replayed to the sink
+func (s *Spool) Pending(sinkName string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir := s.sinkDir(sinkName)
+	lock, err := lockSinkDir(dir)
+	if err != nil {
+		return false
+	}
+	defer lock.Close()
+
+	segments, err := listSegments(dir)
+	if err != nil || len(segments) == 0 {
+		return false
+	}
+	cp := readCheckpoint(dir)
+	last := segments[len(segments)-1]
+	if last > cp.Segment {
+		return true
+	}
+	info, err := os.Stat(segmentPath(dir, last))
+	return err == nil && info.Size() > cp.Offset
+}
+
+// Flush replays the pending batches to the sink, oldest first, until the spool is empty or a write fails.
+// Batches which the sink rejects are dropped. It returns the number of batches replayed.
+// If another process is replaying the spool for the sink, nothing is replayed and errSpoolBusy is returned.
+func (s *Spool) Flush(sink Sink) (int, error) {
+	s.flushMutex.Lock()
+	defer s.flushMutex.Unlock()
+
+	lock, err := lockReplay(s.sinkDir(sink.Name()))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Close()
+
+	sent := 0
+	for {
+		rec, next, err := s.next(sink.Name())
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+		werr := sink.Write(rec.Batch)
+		if werr != nil && !isPermanent(werr) {
+			return sent, werr
+		}
+		if err := s.commit(sink.Name(), next); err != nil {
+			return sent, err
+		}
+		if werr != nil {
+			log.Printf("Dropping spooled metrics batch for %s, spooled at %v, which was rejected by %s: %s\n", rec.Batch.TestName, rec.Spooled, sink.Name(), werr.Error())
+		} else {
+			sent++
+		}
+	}
+}
+
+// Purge removes the spool for the sinks matching sinkName (see MatchSink), or for all sinks if sinkName is empty.
+// It returns the number of segments removed.
+func (s *Spool) Purge(sinkName string) (int, error) {
+	s.flushMutex.Lock()
+	defer s.flushMutex.Unlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sinkNames, err := s.sinkNames()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, name := range sinkNames {
+		if !MatchSink(sinkName, name) {
+			continue
+		}
+		n, err := s.purge(name)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// purge removes the spool in the sink directory. The spool mustn't be being replayed, as the replay would restore the
+// checkpoint. The caller must hold the mutex.
+func (s *Spool) purge(name string) (int, error) {
+	dir := filepath.Join(s.dir, name)
+	replayLock, err := lockReplay(dir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err.Error())
+	}
+	defer replayLock.Close()
+	lock, err := lockSinkDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Close()
+
+	removed := 0
+	segments, err := listSegments(dir)
+	if err != nil {
+		return removed, err
+	}
+	for _, seq := range segments {
+		if err := os.Remove(segmentPath(dir, seq)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	if err := os.Remove(filepath.Join(dir, spoolCheckpointFile)); err != nil && !os.IsNotExist(err) {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// Inspect summarizes the segments in the spool
+func (s *Spool) Inspect() ([]SpoolSegment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sinkNames, err := s.sinkNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var summary []SpoolSegment
+	for _, name := range sinkNames {
+		segments, err := inspectSinkDir(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, ss := range segments {
+			ss.Sink = name
+			summary = append(summary, ss)
+		}
+	}
+	return summary, nil
+}
+
+// inspectSinkDir summarizes the segments in the spool directory for a sink
+func inspectSinkDir(dir string) ([]SpoolSegment, error) {
+	lock, err := lockSinkDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Close()
+
+	cp := readCheckpoint(dir)
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	var summary []SpoolSegment
+	for _, seq := range segments {
+		ss, err := inspectSegment(segmentPath(dir, seq), seq, cp)
+		if err != nil {
+			return nil, err
+		}
+		summary = append(summary, ss)
+	}
+	return summary, nil
+}
+
+// Replay builds the sinks configured in metrics.toml and replays the pending batches to them.
+// If sinkName is set, only the sinks matching it (see MatchSink) are replayed. It returns the number of batches replayed.
+func (s *Spool) Replay(cfg ServiceConfig, dbKey string, sinkName string) (int, error) {
+	cfg.Spool = nil
+	sent := 0
+	var errs []string
+	for _, sink := range newSinks(cfg, dbKey) {
+		if !MatchSink(sinkName, spoolDirName(sink.Name())) {
+			continue
+		}
+		// Falling back to a file would lose track of the batch
+		if influxdb, ok := sink.(*influxdbSink); ok {
+			sink = newInfluxdbSink(influxdb.host, influxdb.port, influxdb.dbName, influxdb.user, influxdb.pw, influxdb.verbose, false)
+		}
+		n, err := s.Flush(sink)
+		sent += n
+		if err != nil {
+			errs = append(errs, sink.Name()+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return sent, errors.New(strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// next returns the next record to replay to the sink, and the position after it. Segments which have been replayed
+// are removed. Corrupt records are skipped, as is the remainder of a segment after a truncated record.
+func (s *Spool) next(sinkName string) (spoolRecord, spoolPosition, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir := s.sinkDir(sinkName)
+	lock, err := lockSinkDir(dir)
+	if os.IsNotExist(err) {
+		return spoolRecord{}, spoolPosition{}, io.EOF
+	}
+	if err != nil {
+		return spoolRecord{}, spoolPosition{}, err
+	}
+	defer lock.Close()
+
+	s.expire(sinkName)
+	cp := readCheckpoint(dir)
+	segments, err := listSegments(dir)
+	if err != nil {
+		return spoolRecord{}, cp, err
+	}
+
+	for i, seq := range segments {
+		path := segmentPath(dir, seq)
+		last := i == len(segments)-1
+		if seq < cp.Segment {
+			os.Remove(path)
+			continue
+		}
+		if seq > cp.Segment {
+			cp = spoolPosition{Segment: seq}
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return spoolRecord{}, cp, err
+		}
+		for {
+			rec, n, err := readSpoolRecord(f, cp.Offset)
+			if err == nil {
+				f.Close()
+				return rec, spoolPosition{Segment: seq, Offset: cp.Offset + n}, nil
+			}
+			if err == errSpoolCorrupt {
+				log.Printf("Skipping corrupt record at offset %d in metrics spool segment %s\n", cp.Offset, path)
+				cp.Offset += n
+				continue
+			}
+			if err == errSpoolTruncated {
+				log.Printf("Skipping truncated record at offset %d in metrics spool segment %s\n", cp.Offset, path)
+			} else if err != io.EOF {
+				f.Close()
+				return spoolRecord{}, cp, err
+			}
+			break
+		}
+		f.Close()
+
+		// The segment has been replayed
+		os.Remove(path)
+		if last {
+			os.Remove(filepath.Join(dir, spoolCheckpointFile))
+		}
+	}
+	return spoolRecord{}, cp, io.EOF
+}
+
+// commit records that the batches before the position have been replayed
+func (s *Spool) commit(sinkName string, pos spoolPosition) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	dir := s.sinkDir(sinkName)
+	lock, err := lockSinkDir(dir)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	tmp := filepath.Join(dir, spoolCheckpointFile+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, spoolCheckpointFile))
+}
+
+// expire drops segments which haven't been written to within the maximum age. The caller must hold the mutex and the
+// sink directory lock.
+func (s *Spool) expire(sinkName string) {
+	dir := s.sinkDir(sinkName)
+	segments, _ := listSegments(dir)
+	for _, seq := range segments {
+		path := segmentPath(dir, seq)
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) <= s.maxAge {
+			continue
+		}
+		log.Printf("Dropping metrics spool segment %s, which is older than %v\n", path, s.maxAge)
+		os.Remove(path)
+	}
+}
+
+// enforceMaxBytes drops the oldest segments until the spool for the sink is within the size limit.
+// The segment being written to is always kept. The caller must hold the mutex and the sink directory lock.
+func (s *Spool) enforceMaxBytes(sinkName string) {
+	dir := s.sinkDir(sinkName)
+	segments, _ := listSegments(dir)
+	var total int64
+	sizes := make([]int64, len(segments))
+	for i, seq := range segments {
+		if info, err := os.Stat(segmentPath(dir, seq)); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	for i := 0; i < len(segments)-1 && total > s.maxBytes; i++ {
+		path := segmentPath(dir, segments[i])
+		log.Printf("Dropping metrics spool segment %s, as the spool for %s exceeds %d bytes\n", path, sinkName, s.maxBytes)
+		os.Remove(path)
+		total -= sizes[i]
+	}
+}
+
+func (s *Spool) sinkDir(sinkName string) string {
+	return filepath.Join(s.dir, spoolDirName(sinkName))
+}
+
+// spoolDirName returns the name of the spool directory for a sink. Characters other than letters, digits, '.' and '-'
+// are replaced by '_', so the directory name starts with the sink type and an '_' e.g. influxdb2_http___influx_8086_...
+func spoolDirName(sinkName string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, sinkName)
+}
+
+// MatchSink returns true if the spool directory for a sink matches the filter, which is either the directory name,
+// as listed by Inspect, or a sink type e.g. influxdb. An empty filter matches every sink.
+func MatchSink(filter string, dirName string) bool {
+	return len(filter) == 0 || dirName == filter || strings.HasPrefix(dirName, filter+"_")
+}
+
+// lockSinkDir takes an exclusive lock on the spool directory for a sink, waiting for any other process holding it.
+// The lock is released by closing the returned file.
+func lockSinkDir(dir string) (*os.File, error) {
+	// #nosec G304
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %s", dir, err.Error())
+	}
+	return f, nil
+}
+
+// lockReplay takes the replay lock for the spool directory of a sink, returning errSpoolBusy if another process holds
+// it. The lock is released by closing the returned file.
+func lockReplay(dir string) (*os.File, error) {
+	// #nosec G304
+	f, err := os.OpenFile(filepath.Join(dir, spoolReplayLockFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, errSpoolBusy
+		}
+		return nil, fmt.Errorf("failed to lock %s: %s", f.Name(), err.Error())
+	}
+	return f, nil
+}
+
+// sinkNames returns the names of the sinks with a spool directory
+func (s *Spool) sinkNames() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// listSegments returns the sequence numbers of the segments in the directory, oldest first
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var segments []uint64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), spoolSegmentExt), 10, 64)
+		if err == nil {
+			segments = append(segments, seq)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+// newSegment returns the sequence number for a new segment, after the last segment and the checkpoint
+func newSegment(last uint64, cp spoolPosition) uint64 {
+	if cp.Segment > last {
+		return cp.Segment + 1
+	}
+	return last + 1
+}
+
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", seq, spoolSegmentExt))
+}
+
+// readCheckpoint returns the position of the next record to replay. If there's no checkpoint, replay starts from the
+// oldest segment.
+func readCheckpoint(dir string) spoolPosition {
+	var cp spoolPosition
+	// #nosec G304
+	data, err := ioutil.ReadFile(filepath.Join(dir, spoolCheckpointFile))
+	if err != nil {
+		return cp
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		log.Printf("Ignoring invalid metrics spool checkpoint in %s: %s\n", dir, err.Error())
+		return spoolPosition{}
+	}
+	return cp
+}
+
+// readSpoolRecord reads the record at the offset, and returns it with its size in the file. The size is also returned
+// for a corrupt record, so that it can be skipped.
+func readSpoolRecord(f *os.File, offset int64) (spoolRecord, int64, error) {
+	var rec spoolRecord
+	header := make([]byte, spoolRecordHeaderSize)
+	n, err := f.ReadAt(header, offset)
+	if n == 0 && err == io.EOF {
+		return rec, 0, io.EOF
+	}
+	if n < spoolRecordHeaderSize {
+		return rec, 0, errSpoolTruncated
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxSpoolRecordBytes {
+		return rec, 0, errSpoolTruncated
+	}
+	payload := make([]byte, length)
+	if n, _ := f.ReadAt(payload, offset+spoolRecordHeaderSize); n < int(length) {
+		return rec, 0, errSpoolTruncated
+	}
+
+	size := int64(spoolRecordHeaderSize + length)
+	if crc32.Checksum(payload, spoolCRCTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return rec, size, errSpoolCorrupt
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, size, errSpoolCorrupt
+	}
+	return rec, size, nil
+}
+
+// inspectSegment reads every record in a segment
+func inspectSegment(path string, seq uint64, cp spoolPosition) (SpoolSegment, error) {
+	ss := SpoolSegment{File: path}
+	// #nosec G304
+	f, err := os.Open(path)
+	if err != nil {
+		return ss, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		ss.Bytes = info.Size()
+	}
+
+	var offset int64
+	for {
+		rec, n, err := readSpoolRecord(f, offset)
+		if err == io.EOF {
+			break
+		}
+		if err == errSpoolTruncated {
+			ss.Corrupt++
+			break
+		}
+		if err != nil {
+			ss.Corrupt++
+			offset += n
+			continue
+		}
+
+		ss.Batches++
+		if seq < cp.Segment || (seq == cp.Segment && offset < cp.Offset) {
+			ss.Replayed++
+		}
+		ss.Points += len(rec.Batch.Points)
+		if ss.Oldest.IsZero() || rec.Spooled.Before(ss.Oldest) {
+			ss.Oldest = rec.Spooled
+		}
+		if rec.Spooled.After(ss.Newest) {
+			ss.Newest = rec.Spooled
+		}
+		offset += n
+	}
+	return ss, nil
+}
+
+// spoolingSink writes batches to a sink, spooling them if the sink can't be written to.
+// While there are batches in the spool, new batches are spooled behind them, so the sink receives them in order.
+type spoolingSink struct {
+	sink  Sink
+	spool *Spool
+}
+
+func (s *spoolingSink) Name() string {
+	return s.sink.Name()
+}
+
+func (s *spoolingSink) Write(batch MetricBatch) error {
+	name := s.sink.Name()
+	if s.spool.Pending(name) && !s.spool.tryFlush(s.sink) {
+		return s.spool.Append(name, batch)
+	}
+
+	if err := s.sink.Write(batch); err != nil {
+		if isPermanent(err) {
+			return err
+		}
+		log.Printf("Failed to write metrics to %s, so adding them to the spool. Reason for failure: %s\n", name, err.Error())
+		s.spool.failed(name)
+		return s.spool.Append(name, batch)
+	}
+	return nil
+}
+
+// tryFlush replays the spool for the sink, unless the sink is backing off after a failure.
+// It returns true if the spool was emptied.
+func (s *Spool) tryFlush(sink Sink) bool {
+	name := sink.Name()
+	s.mutex.Lock()
+	wait := time.Until(s.nextAttempt[name])
+	s.mutex.Unlock()
+	if wait > 0 {
+		return false
+	}
+
+	n, err := s.Flush(sink)
+	if n > 0 {
+		log.Printf("Replayed %d spooled metrics batches to %s\n", n, name)
+	}
+	if err == errSpoolBusy {
+		// The other process replays the batches, including any spooled behind them
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to replay spooled metrics to %s: %s\n", name, err.Error())
+		s.failed(name)
+		return false
+	}
+
+	s.mutex.Lock()
+	delete(s.backoff, name)
+	delete(s.nextAttempt, name)
+	s.mutex.Unlock()
+	return true
+}
+
+// failed backs off replays to the sink, doubling the delay after each failure
+func (s *Spool) failed(sinkName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b := s.backoff[sinkName] * 2
+	if b < s.flushInterval {
+		b = s.flushInterval
+	}
+	if b > maxSpoolBackoff {
+		b = maxSpoolBackoff
+	}
+	s.backoff[sinkName] = b
+	s.nextAttempt[sinkName] = time.Now().Add(b)
+}
+
+// wrap returns a spooling version of the sink, and starts the background flusher.
+// The flusher uses the most recently wrapped sink with each name, which identifies its destination.
+func (s *Spool) wrap(sink Sink) Sink {
+	s.mutex.Lock()
+	s.sinks[sink.Name()] = sink
+	s.mutex.Unlock()
+
+	s.flusherOnce.Do(func() { go s.flusher() })
+	return &spoolingSink{sink: sink, spool: s}
+}
+
+// flusher periodically replays the spool to any sinks with pending batches, so that long running collectors catch up
+// without waiting for their next batch of metrics
+func (s *Spool) flusher() {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mutex.Lock()
+		sinks := make([]Sink, 0, len(s.sinks))
+		for _, sink := range s.sinks {
+			sinks = append(sinks, sink)
+		}
+		s.mutex.Unlock()
+
+		for _, sink := range sinks {
+			if s.Pending(sink.Name()) {
+				s.tryFlush(sink)
+			}
+		}
+	}
+}
+
+var metricsSpool *Spool
+var metricsSpoolMutex sync.Mutex
+
+// getSpool returns the spool configured in metrics.toml, opening it the first time it's used
+func getSpool(cfg *SpoolConfig) *Spool {
+	if cfg == nil || len(cfg.Dir) == 0 {
+		return nil
+	}
+
+	metricsSpoolMutex.Lock()
+	defer metricsSpoolMutex.Unlock()
+	if metricsSpool != nil && metricsSpool.dir == cfg.Dir {
+		return metricsSpool
+	}
+	spool, err := OpenSpool(*cfg)
+	if err != nil {
+		log.Printf("Unable to open the metrics spool, failed metrics will not be spooled: %s\n", err.Error())
+		return nil
+	}
+	metricsSpool = spool
+	return metricsSpool
+}
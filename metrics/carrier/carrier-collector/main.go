@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -24,6 +24,7 @@ import (
 
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/carrier/config"
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/carrier/prometheus"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/carrier/resolver"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -196,7 +197,19 @@ func main() {
 	go initialize(stopChannel, readyChannel)
 	<-readyChannel
 
-	pc := prometheus.NewClient()
+	// Node addresses and hostnames in the metrics are resolved to node names and metadata, e.g. from the carrier nodes
+	nodeResolver, err := resolver.New(
+		resolver.Config{
+			Type:       config.Prometheus.Resolver,
+			Kubeconfig: config.CarrierKubeconfig,
+			NodesFile:  config.Prometheus.Nodes,
+		},
+		config.GetConfigPath())
+	if err != nil {
+		log.Fatalf("Failed to create node resolver : %s\n", err.Error())
+	}
+
+	pc := prometheus.NewClient(nodeResolver)
 	for {
 		// Wait for start time if necessary
 		if tts := time.Until(start); tts > 0 {
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -42,7 +42,8 @@ type PromConfig struct {
 	Environment string                         `toml:"environment"`
 	Carrier     string                         `toml:"carrier"`
 	Port        int                            `toml:"port"`
-	Devices     string                         `toml:"devices"`
+	Resolver    string                         `toml:"resolver"` // How nodes are identified in metrics - kube (default), static or none
+	Nodes       string                         `toml:"nodes"`    // Nodes file for the static resolver, relative to the config directory
 	Metrics     map[string][]promMetricsConfig `toml:"metrics"`
 }
 
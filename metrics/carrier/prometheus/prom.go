@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2023 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -12,13 +12,9 @@ package prometheus
 
 import (
 	"context"
-	"encoding/csv"
-	"fmt"
 	"log"
 	"net"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -27,6 +23,7 @@ import (
 
 	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
 	"github.ibm.com/alchemy-containers/armada-performance/metrics/carrier/config"
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/carrier/resolver"
 
 	papi "github.com/prometheus/client_golang/api"
 	prom "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -35,77 +32,55 @@ import (
 
 // PromAPI is a wrapper for prometheus API
 type PromAPI struct {
-	api prom.API
+	api      prom.API
+	resolver resolver.Resolver
+
+	// Unresolved IP addresses, which have been warned about
+	unresolved map[string]bool
 }
 
-type deviceDetails struct {
-	hostname  string
-	privateIP string
-	publicIP  string
+// nodeLabels are the Prometheus labels which identify a node by hostname. Label values which are IP addresses
+// are also resolved, whatever the label. Resolved nodes tag the metrics with their metadata.
+var nodeLabels = map[model.LabelName]bool{
+	"hostname":        true,
+	"instance":        true,
+	"node":            true,
+	"nodename":        true,
+	"kubernetes_node": true,
 }
 
+// isPrivateIP returns true for the private node addresses which are replaced by node names in the metric names
 func isPrivateIP(v string) bool {
 	match, err := regexp.MatchString("^10\\.\\d{1,3}\\.\\d{1,3}\\.\\d{1,3}$", v)
 	return err == nil && match
 }
-func isPublicIP(v string) bool {
-	match, err := regexp.MatchString("^169\\.\\d{1,3}\\.\\d{1,3}\\.\\d{1,3}$", v)
-	return err == nil && match
-}
-
-func getDeviceDetails(deviceID string) *deviceDetails {
-	const (
-		hostnameIdx = 0
-		privateIdx  = 4
-		publicIdx   = 3
-	)
-	deviceFile := filepath.Join(os.Getenv("GOPATH"), config.Prometheus.Devices)
-	if _, err := os.Stat(deviceFile); !os.IsNotExist(err) {
-		// #nosec G304
-		f, err := os.Open(deviceFile)
-		if err != nil {
-			log.Fatalf("Unable to open devices.csv file. Error : %s\n", err.Error())
-		}
-		defer f.Close()
 
-		devices, err := csv.NewReader(f).ReadAll()
-		if err != nil {
-			panic(err)
-		}
-
-		var matchOn int
+// resolve returns the node identified by a label, if it identifies one
+func (p PromAPI) resolve(label model.LabelName, value string) (*resolver.NodeInfo, bool) {
+	host := value
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		host = h
+	}
+	isIP := net.ParseIP(host) != nil
+	if !isIP && !nodeLabels[label] {
+		return nil, false
+	}
 
-		if isPrivateIP(deviceID) {
-			// Private IP
-			matchOn = privateIdx
+	n, ok := p.resolver.Resolve(value)
+	if !ok && isIP && !p.unresolved[value] {
+		p.unresolved[value] = true
+		if isPrivateIP(value) {
+			log.Printf("WARNING: Unable to find the node with address %s - it will be left in the metric names\n", value)
 		} else {
-			if isPublicIP(deviceID) {
-				// Public IP
-				matchOn = publicIdx
-			} else {
-				// Hostname
-				matchOn = hostnameIdx
-			}
-		}
-
-		for _, d := range devices {
-			// First column is hostname, 4th column is Public IP
-			matchEndIdx := len(d[matchOn])
-			if matchOn == hostnameIdx {
-				matchEndIdx = strings.Index(d[matchOn], ".")
-			}
-
-			if matchEndIdx >= 0 && d[matchOn][0:matchEndIdx] == deviceID {
-				return &deviceDetails{hostname: d[hostnameIdx], privateIP: d[privateIdx], publicIP: d[publicIdx]}
-			}
+			log.Printf("WARNING: Unable to find the node with address %s - its metrics won't be tagged\n", value)
 		}
 	}
-	fmt.Printf("WARNING: Unable to find device %s in %s - ensure the file is up to date\n", deviceID, deviceFile)
-	return nil
+	return n, ok
 }
 
-// NewClient returns a client for accessing carrier/tugboat Prometheus metrics
-func NewClient() *PromAPI {
+// NewClient returns a client for accessing carrier/tugboat Prometheus metrics. Label values identifying nodes are
+// looked up with the resolver.
+func NewClient(r resolver.Resolver) *PromAPI {
 	roundTripper := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
@@ -131,7 +106,7 @@ func NewClient() *PromAPI {
 	}
 
 	// Prometheus query api client
-	return &PromAPI{api: prom.NewAPI(pclient)}
+	return &PromAPI{api: prom.NewAPI(pclient), resolver: r, unresolved: make(map[string]bool)}
 }
 
 // GatherMetrics ...
@@ -156,17 +131,19 @@ func (p PromAPI) GatherMetrics(startTime, endTime time.Time, stepInterval time.D
 
 			// For each prometheus metric
 			for _, s := range v.(model.Matrix) {
+				// Replace private ip addresses with the cut-down node name. Other label values, including hostnames
+				// and public addresses, are left unchanged, so the metric names don't change - the node metadata is
+				// only added as tags.
+				nodes := make(map[string]*resolver.NodeInfo)
 				midArr := make([]string, 0, len(s.Metric))
-				for _, id := range s.Metric {
+				for ln, id := range s.Metric {
 					var mf = string(id)
 
-					// Replace ip address with cut-down hostname
-					if isPrivateIP(mf) {
-						mf = strings.Split(getDeviceDetails(mf).hostname, ".")[0]
-
-						mf = strings.Replace(mf, "stage-", "", 1)
-						mf = strings.Replace(mf, "carrier", "c", 1)
-						mf = strings.Replace(mf, "worker-", "w", 1)
+					if n, ok := p.resolve(ln, mf); ok {
+						nodes[n.Name] = n
+						if isPrivateIP(mf) {
+							mf = n.ShortName()
+						}
 					}
 
 					midArr = append(midArr, mf)
@@ -174,6 +151,17 @@ func (p PromAPI) GatherMetrics(startTime, endTime time.Time, stepInterval time.D
 				sort.Strings(midArr)
 				mid := strings.Join(midArr, ".")
 
+				// Tag with the metadata of the (first) node
+				var tags map[string]string
+				if len(nodes) > 0 {
+					nodeNames := make([]string, 0, len(nodes))
+					for name := range nodes {
+						nodeNames = append(nodeNames, name)
+					}
+					sort.Strings(nodeNames)
+					tags = nodes[nodeNames[0]].Tags()
+				}
+
 				// Grab all the values (should be one per sample interval)
 				for _, sp := range s.Values {
 					mn := strings.Join(
@@ -198,6 +186,7 @@ func (p PromAPI) GatherMetrics(startTime, endTime time.Time, stepInterval time.D
 							Name:      mn,
 							Timestamp: sp.Timestamp.Unix(),
 							Value:     float64(sp.Value),
+							Tags:      tags,
 						},
 					)
 				}
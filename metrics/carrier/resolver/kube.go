@@ -0,0 +1,138 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// cacheTTL is how long the node list is used before it is refreshed
+	cacheTTL = 10 * time.Minute
+
+	// missRefreshInterval limits how often an unknown value causes the node list to be refreshed early,
+	// e.g. when nodes are added to the carrier
+	missRefreshInterval = time.Minute
+)
+
+// Node labels holding the metadata, in order of preference. Classic, VPC and Satellite nodes don't all set the
+// same labels.
+var (
+	zoneLabels         = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone", "ibm-cloud.kubernetes.io/zone"}
+	instanceTypeLabels = []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type", "ibm-cloud.kubernetes.io/machine-type"}
+	poolLabels         = []string{"ibm-cloud.kubernetes.io/worker-pool-name", "ibm-cloud.kubernetes.io/worker-pool-id"}
+)
+
+// KubeResolver resolves nodes from the Kubernetes nodes of the carrier. Nodes are matched on their name, hostname
+// label, or any of their addresses. The node list is cached.
+type KubeResolver struct {
+	client kubernetes.Interface
+
+	mu          sync.Mutex
+	nodes       map[string]*NodeInfo
+	refreshed   time.Time
+	lastFailure error
+}
+
+// NewKubeResolver returns a resolver for the nodes of the cluster in the kubeconfig
+func NewKubeResolver(kubeconfig string) (*KubeResolver, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build carrier kubeconfig : %s", err.Error())
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create clientset for carrier kubeconfig : %s", err.Error())
+	}
+	return NewKubeResolverForClient(client), nil
+}
+
+// NewKubeResolverForClient returns a resolver for the nodes visible to the client
+func NewKubeResolverForClient(client kubernetes.Interface) *KubeResolver {
+	return &KubeResolver{client: client}
+}
+
+// Resolve returns the node with the name or address
+func (r *KubeResolver) Resolve(value string) (*NodeInfo, bool) {
+	key := lookupKey(value)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.refreshed) > cacheTTL {
+		r.refresh()
+	}
+	if n, ok := r.nodes[key]; ok {
+		return n, true
+	}
+
+	// Unknown value - the node may be new, so refresh if we haven't recently
+	if time.Since(r.refreshed) > missRefreshInterval {
+		r.refresh()
+		if n, ok := r.nodes[key]; ok {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// refresh reloads the node list. If it can't be loaded, the previous list is kept. The caller must hold r.mu.
+func (r *KubeResolver) refresh() {
+	// Don't retry immediately if the list can't be loaded
+	r.refreshed = time.Now()
+
+	nodes, err := r.client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if r.lastFailure == nil || r.lastFailure.Error() != err.Error() {
+			log.Printf("WARNING: Failed to list carrier nodes : %s\n", err.Error())
+		}
+		r.lastFailure = err
+		return
+	}
+	r.lastFailure = nil
+
+	r.nodes = make(map[string]*NodeInfo, 3*len(nodes.Items))
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		info := &NodeInfo{
+			Name:         node.Name,
+			Zone:         firstLabel(node, zoneLabels),
+			InstanceType: firstLabel(node, instanceTypeLabels),
+			Pool:         firstLabel(node, poolLabels),
+		}
+		r.nodes[lookupKey(node.Name)] = info
+		if hostname, ok := node.Labels[apiv1.LabelHostname]; ok {
+			r.nodes[lookupKey(hostname)] = info
+		}
+		for _, a := range node.Status.Addresses {
+			r.nodes[lookupKey(a.Address)] = info
+		}
+	}
+}
+
+// firstLabel returns the value of the first of the labels set on the node
+func firstLabel(node *apiv1.Node, labels []string) string {
+	for _, l := range labels {
+		if v, ok := node.Labels[l]; ok && len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
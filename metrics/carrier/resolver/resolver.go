@@ -0,0 +1,115 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+// Package resolver looks up the carrier node identified by a Prometheus label value (an IP address or a hostname),
+// so carrier metrics can be named after, and tagged with, the node.
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
+)
+
+// Resolver types, as set by the resolver option in prom.toml
+const (
+	TypeKube   = "kube"   // Kubernetes node metadata from the carrier (the default)
+	TypeStatic = "static" // A static file of nodes
+	TypeNone   = "none"   // Label values are left unchanged
+)
+
+// NodeInfo is the metadata of a node
+type NodeInfo struct {
+	Name         string `toml:"name"`
+	Zone         string `toml:"zone"`
+	InstanceType string `toml:"instanceType"`
+	Pool         string `toml:"pool"`
+}
+
+// Resolver looks up the node identified by an IP address or hostname
+type Resolver interface {
+	// Resolve returns the node, or false if the value doesn't identify a known node
+	Resolve(value string) (*NodeInfo, bool)
+}
+
+// Config selects and configures a resolver
+type Config struct {
+	Type       string // kube, static or none. Defaults to kube.
+	Kubeconfig string // Carrier kubeconfig, for the kube resolver
+	NodesFile  string // Nodes file, for the static resolver. Relative paths are relative to configDir.
+}
+
+// New returns the resolver selected by the config
+func New(cfg Config, configDir string) (Resolver, error) {
+	switch cfg.Type {
+	case "", TypeKube:
+		return NewKubeResolver(cfg.Kubeconfig)
+	case TypeStatic:
+		nodesFile := cfg.NodesFile
+		if len(nodesFile) == 0 {
+			return nil, fmt.Errorf("A nodes file must be specified for the %s resolver", TypeStatic)
+		}
+		if !filepath.IsAbs(nodesFile) {
+			nodesFile = filepath.Join(configDir, nodesFile)
+		}
+		return NewStaticResolver(nodesFile)
+	case TypeNone:
+		return noneResolver{}, nil
+	}
+	return nil, fmt.Errorf("Unrecognized resolver '%s'. Expected %s, %s or %s", cfg.Type, TypeKube, TypeStatic, TypeNone)
+}
+
+// ShortName returns a short form of the node name for use in metric names. Hostnames are cut down to their first
+// label, with the classic carrier naming shortened (e.g. stage-dal10-carrier5-worker-1001 to dal10-c5-w1001).
+// IP addresses are returned unchanged.
+func (n *NodeInfo) ShortName() string {
+	if net.ParseIP(n.Name) != nil {
+		return n.Name
+	}
+	name := strings.Split(n.Name, ".")[0]
+	name = strings.Replace(name, "stage-", "", 1)
+	name = strings.Replace(name, "carrier", "c", 1)
+	name = strings.Replace(name, "worker-", "w", 1)
+	return name
+}
+
+// Tags returns the node metadata as metric tags. Unknown metadata is omitted.
+func (n *NodeInfo) Tags() map[string]string {
+	tags := map[string]string{metricsservice.TagNode: n.Name}
+	if len(n.Zone) > 0 {
+		tags[metricsservice.TagZone] = n.Zone
+	}
+	if len(n.InstanceType) > 0 {
+		tags[metricsservice.TagInstanceType] = n.InstanceType
+	}
+	if len(n.Pool) > 0 {
+		tags[metricsservice.TagWorkerPool] = n.Pool
+	}
+	return tags
+}
+
+// lookupKey normalises a label value for lookup - ports are removed (e.g. from an instance label) and hostnames
+// are lower cased
+func lookupKey(value string) string {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return strings.ToLower(value)
+}
+
+// noneResolver leaves label values unchanged
+type noneResolver struct{}
+
+func (noneResolver) Resolve(string) (*NodeInfo, bool) {
+	return nil, false
+}
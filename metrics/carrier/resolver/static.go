@@ -0,0 +1,66 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// staticNode is a node in a static nodes file
+type staticNode struct {
+	NodeInfo
+	Addresses []string `toml:"addresses"`
+}
+
+// StaticResolver resolves nodes from a TOML file, e.g.
+//
+//	[[node]]
+//	name = "stage-dal10-carrier5-worker-1001"
+//	addresses = ["10.130.0.4", "169.60.0.4"]
+//	zone = "dal10"
+//	instanceType = "b3c.16x64"
+//	pool = "default"
+//
+// Nodes are matched on their name, or any of their addresses.
+type StaticResolver struct {
+	nodes map[string]*NodeInfo
+}
+
+// NewStaticResolver loads the nodes in the file
+func NewStaticResolver(path string) (*StaticResolver, error) {
+	var file struct {
+		Nodes []staticNode `toml:"node"`
+	}
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return nil, fmt.Errorf("Failed to parse nodes file %s : %s", path, err.Error())
+	}
+
+	r := &StaticResolver{nodes: make(map[string]*NodeInfo)}
+	for i := range file.Nodes {
+		n := &file.Nodes[i]
+		if len(n.Name) == 0 {
+			return nil, fmt.Errorf("Node %d in %s has no name", i, path)
+		}
+		r.nodes[lookupKey(n.Name)] = &n.NodeInfo
+		for _, a := range n.Addresses {
+			r.nodes[lookupKey(a)] = &n.NodeInfo
+		}
+	}
+	return r, nil
+}
+
+// Resolve returns the node with the name or address
+func (r *StaticResolver) Resolve(value string) (*NodeInfo, bool) {
+	n, ok := r.nodes[lookupKey(value)]
+	return n, ok
+}
@@ -0,0 +1,202 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/cruiser/endpoints"
+)
+
+// Status reports the state of metrics collection
+type Status struct {
+	Collecting    bool       `json:"collecting"`
+	Filter        string     `json:"filter"`
+	Level         string     `json:"level"`
+	Interval      string     `json:"interval"`
+	Collections   int        `json:"collections"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+	LastSample    *time.Time `json:"lastSample,omitempty"`
+}
+
+// Snapshot holds the resources gathered by the last successful collection, keyed by resource name (e.g. "nodes")
+type Snapshot struct {
+	Time      time.Time                     `json:"time"`
+	Resources map[string]endpoints.Resource `json:"resources"`
+}
+
+// Monitor is implemented by the collector to report its state to the HTTP control API
+type Monitor interface {
+	Status() Status
+	Snapshot() *Snapshot
+}
+
+// commandTimeout limits how long the HTTP control API waits for the collector to act on a command.
+// A one-shot collection retries failed requests to the metrics server, so allow for that.
+const commandTimeout = 2 * time.Minute
+
+type api struct {
+	control chan<- Command
+	monitor Monitor
+}
+
+// StartHTTP is the entrypoint for the HTTP control API. It serves:
+//
+//	POST /start      start collection, optionally overriding the filter, level, interval and delay query parameters
+//	POST /stop       stop collection
+//	POST /collect    collect metrics once, whether or not collection is started
+//	POST /terminate  end the collection utility
+//	GET  /status     report the collection status
+//	GET  /snapshot   return the resources gathered by the last successful collection
+//
+// Each command returns the collection status as JSON once the collector has acted on it.
+func StartHTTP(addr string, control chan<- Command, monitor Monitor) {
+	a := &api{control: control, monitor: monitor}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", a.command(START))
+	mux.HandleFunc("/stop", a.command(STOP))
+	mux.HandleFunc("/collect", a.command(COLLECT))
+	mux.HandleFunc("/terminate", a.command(TERMINATE))
+	mux.HandleFunc("/status", a.status)
+	mux.HandleFunc("/snapshot", a.snapshot)
+
+	log.Printf("Cruiser Metrics : Listening for HTTP control requests on %s\n", addr)
+	// #nosec G114
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Println(err)
+	}
+}
+
+// command returns a handler which sends a command to the collector, and waits for the result
+func (a *api) command(cmdType CommandType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires a POST", r.URL.Path))
+			return
+		}
+
+		cmd := Command{Type: cmdType}
+		if cmdType == START {
+			opts, err := parseOptions(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			cmd.Options = opts
+		}
+
+		log.Printf("Cruiser Metrics : %s (HTTP)\n", cmdType)
+
+		// The collector exits as soon as it receives TERMINATE, so respond before sending it
+		if cmdType == TERMINATE {
+			writeJSON(w, http.StatusOK, a.monitor.Status())
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			go func() { a.control <- cmd }()
+			return
+		}
+
+		reply := make(chan error, 1)
+		cmd.Reply = reply
+
+		timeout := time.NewTimer(commandTimeout)
+		defer timeout.Stop()
+		select {
+		case a.control <- cmd:
+		case <-timeout.C:
+			writeError(w, http.StatusServiceUnavailable, fmt.Errorf("Timed out sending %s to the collector", cmdType))
+			return
+		}
+		select {
+		case err := <-reply:
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		case <-timeout.C:
+			writeError(w, http.StatusGatewayTimeout, fmt.Errorf("Timed out waiting for the collector to %s", cmdType))
+			return
+		}
+		writeJSON(w, http.StatusOK, a.monitor.Status())
+	}
+}
+
+func (a *api) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires a GET", r.URL.Path))
+		return
+	}
+	writeJSON(w, http.StatusOK, a.monitor.Status())
+}
+
+func (a *api) snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires a GET", r.URL.Path))
+		return
+	}
+	snapshot := a.monitor.Snapshot()
+	if snapshot == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("No metrics have been collected"))
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// parseOptions reads the START overrides from the request's query or form parameters.
+// An empty filter parameter clears the filter.
+func parseOptions(r *http.Request) (Options, error) {
+	var opts Options
+	if err := r.ParseForm(); err != nil {
+		return opts, err
+	}
+
+	if _, ok := r.Form["filter"]; ok {
+		filter := r.Form.Get("filter")
+		opts.Filter = &filter
+	}
+	opts.Level = r.Form.Get("level")
+	if interval := r.Form.Get("interval"); len(interval) > 0 {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("Invalid interval '%s'", interval)
+		}
+		opts.Interval = d
+	}
+	if delay := r.Form.Get("delay"); len(delay) > 0 {
+		d, err := time.ParseDuration(delay)
+		if err != nil || d < 0 {
+			return opts, fmt.Errorf("Invalid delay '%s'", delay)
+		}
+		opts.Delay = &d
+	}
+	return opts, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
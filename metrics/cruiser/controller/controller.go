@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -17,16 +17,19 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // CommandType defines the comand control bytes
 type CommandType byte
 
-// START, STOP and TERMINATE metrics gathering control bytes
+// START, STOP and TERMINATE metrics gathering control bytes.
+// COLLECT is only available through the HTTP control API.
 const (
 	START     CommandType = 0x1
-	STOP                  = 0x2
-	TERMINATE             = 0x3
+	STOP      CommandType = 0x2
+	TERMINATE CommandType = 0x3
+	COLLECT   CommandType = 0x4
 )
 
 func (cmd CommandType) String() string {
@@ -37,16 +40,35 @@ func (cmd CommandType) String() string {
 		return "STOP"
 	case TERMINATE:
 		return "TERMINATE"
+	case COLLECT:
+		return "COLLECT"
 	}
 	return fmt.Sprintf("INVALID: %x", byte(cmd))
 }
 
-func (cmd CommandType) valid() bool {
-	return !strings.HasPrefix(fmt.Sprintf("%s", cmd), "INVALID")
+// legacy returns true for the commands accepted by the single byte protocol
+func (cmd CommandType) legacy() bool {
+	return cmd == START || cmd == STOP || cmd == TERMINATE
+}
+
+// Command is a control command sent to the collector.
+// Options only apply to START. If Reply is set, the collector sends the result of the command on it.
+type Command struct {
+	Type    CommandType
+	Options Options
+	Reply   chan<- error
+}
+
+// Options override the collector's command line flags when collection is started
+type Options struct {
+	Filter   *string
+	Level    string
+	Interval time.Duration
+	Delay    *time.Duration
 }
 
 // Communications channel for controller
-var controlChan chan CommandType
+var controlChan chan<- Command
 
 func check(e error) {
 	if e != nil {
@@ -78,16 +100,16 @@ func handleCommand(c net.Conn) bool {
 		terminate = (controlVal == TERMINATE)
 
 		// If valid, send the received control command back
-		if controlVal.valid() {
-			controlChan <- controlVal
+		if controlVal.legacy() {
+			controlChan <- Command{Type: controlVal}
 		}
 	}
 	return terminate
 }
 
-// Start is the entrypoint for the cruiser metrics controller
-// It listens for user control commands and invokes the processing logic
-func Start(controlPort int, control chan CommandType) {
+// Start is the entrypoint for the legacy cruiser metrics controller
+// It listens for single byte control commands and invokes the processing logic
+func Start(controlPort int, control chan<- Command) {
 	controlChan = control
 
 	log.Printf("Cruiser Metrics : Listening for control commands on port %d\n", controlPort)
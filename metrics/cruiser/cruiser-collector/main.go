@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2020 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -15,6 +15,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"sync"
 	"time"
 
 	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
@@ -28,27 +30,117 @@ import (
 
 var verbose, publish bool
 
-func collect(resources []endpoints.Resource) {
+// collector holds the state of metrics collection, which is reported by the HTTP control API
+type collector struct {
+	mu       sync.Mutex
+	status   controller.Status
+	snapshot *controller.Snapshot
+}
+
+// Status returns the current collection status
+func (c *collector) Status() controller.Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Snapshot returns the resources gathered by the last successful collection, or nil if there are none
+func (c *collector) Snapshot() *controller.Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snapshot
+}
+
+// configure records the collection settings in the status
+func (c *collector) configure(collecting bool, interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Collecting = collecting
+	c.status.Filter = endpoints.Filter
+	c.status.Level = endpoints.Level
+	c.status.Interval = interval.String()
+}
+
+// collect gathers and outputs metrics for the resources at the current level, recording the result in the status
+func (c *collector) collect() error {
+	resources, err := endpoints.NewResources(endpoints.Level)
+	if err == nil {
+		err = collect(resources)
+	}
+
+	now := time.Now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Collections++
+	if err != nil {
+		log.Printf("WARNING: %s\n", err.Error())
+		c.status.LastError = err.Error()
+		c.status.LastErrorTime = &now
+		return err
+	}
+	c.status.LastSample = &now
+	c.snapshot = &controller.Snapshot{Time: now, Resources: make(map[string]endpoints.Resource, len(resources))}
+	for _, r := range resources {
+		c.snapshot.Resources[r.Name()] = r
+	}
+	return nil
+}
+
+// collect gathers and outputs metrics for each resource. Metrics are output for the resources which were
+// gathered successfully, even if others failed.
+func collect(resources []endpoints.Resource) error {
+	var failed error
 	for _, r := range resources {
-		res := r.Metrics()
+		res, err := r.Metrics()
+		if err != nil {
+			failed = err
+			continue
+		}
 
 		// Generate metrics for use with IBM Cloud monitoring service
-		if res != nil {
-			if verbose {
-				fmt.Println(res)
-			}
+		if verbose {
+			fmt.Println(res)
+		}
 
-			bm := res.BMMetrics()
+		bm := res.BMMetrics()
 
-			// Send to metrics serivce if requested - razee alerts not required
-			if publish {
-				metricsservice.WriteBluemixMetrics(bm, true, "", "")
-			} else {
-				fmt.Println(bm)
-				fmt.Println()
-			}
+		// Send to metrics serivce if requested - razee alerts not required
+		if publish {
+			metricsservice.WriteBluemixMetrics(bm, true, "", "")
+		} else {
+			fmt.Println(bm)
+			fmt.Println()
+		}
+	}
+	return failed
+}
+
+// applyOptions validates the START overrides, and applies them to the collection settings
+func applyOptions(opts controller.Options, interval *time.Duration, delay *time.Duration) error {
+	if opts.Filter != nil {
+		if _, err := regexp.Compile(*opts.Filter); err != nil {
+			return fmt.Errorf("Invalid filter %s - %s", *opts.Filter, err.Error())
+		}
+	}
+	if len(opts.Level) > 0 {
+		if _, err := endpoints.NewResources(opts.Level); err != nil {
+			return err
 		}
 	}
+
+	if opts.Filter != nil {
+		endpoints.Filter = *opts.Filter
+	}
+	if len(opts.Level) > 0 {
+		endpoints.Level = opts.Level
+	}
+	if opts.Interval > 0 {
+		*interval = opts.Interval
+	}
+	if opts.Delay != nil {
+		*delay = *opts.Delay
+	}
+	return nil
 }
 
 func main() {
@@ -59,6 +151,7 @@ func main() {
 	delay := flag.Duration("delay", 0, "metrics collection delay period")
 	interval := flag.Duration("interval", 60*time.Second, "metrics collection step interval")
 	controlPort := flag.Int("controlPort", 20569, "local controller control port")
+	httpAddr := flag.String("httpAddr", "localhost:20570", "address for the HTTP control API. Set to \"\" to disable it")
 
 	flag.BoolVar(&verbose, "verbose", false, "Verbose output required")
 	flag.BoolVar(&publish, "publish", false, "Publish carrier metrics to IBM Cloud monitoring service")
@@ -81,52 +174,71 @@ func main() {
 		fatalLog.Fatalln(err.Error())
 	}
 
-	var resources []endpoints.Resource
-
-	// Levels hierarchy is: Node -> Namespace -> Pod -> Container
-	// We'll always collect metrics at the node level, and then calculate aggregated values at the requested level
-	switch endpoints.Level {
-	case "namespace", "pod", "container":
-		resources = append(resources, &endpoints.PodMetrics{})
-		fallthrough
-	case "node":
-		resources = append(resources, &endpoints.NodeMetrics{})
-	default:
-		fatalLog.Fatalf("Unrecognized level '%s'\n", endpoints.Level)
+	// Check the level and filter before waiting for a START
+	if _, err := endpoints.NewResources(endpoints.Level); err != nil {
+		fatalLog.Fatalln(err.Error())
 	}
+	if _, err := regexp.Compile(endpoints.Filter); err != nil {
+		fatalLog.Fatalf("Invalid filter %s - %s\n", endpoints.Filter, err.Error())
+	}
+
+	c := &collector{}
+	c.configure(false, *interval)
 
-	// Create communication channel and start the controller
-	controlChan := make(chan controller.CommandType)
+	// Create communication channel and start the controllers
+	controlChan := make(chan controller.Command)
 	go controller.Start(*controlPort, controlChan)
+	if len(*httpAddr) > 0 {
+		go controller.StartHTTP(*httpAddr, controlChan, c)
+	}
 
 	var afterChan, tickerChan <-chan time.Time
 	var ticker *time.Ticker
 
+	stopTicker := func() {
+		afterChan, tickerChan = nil, nil
+		if ticker != nil {
+			ticker.Stop()
+			ticker = nil
+		}
+	}
+
 	for terminate := false; !terminate; {
 		select {
 		case <-afterChan:
 			// Initial '--delay' period complete. Fire off future collections every '--interval'
+			afterChan = nil
 			ticker = time.NewTicker(*interval)
 			tickerChan = ticker.C
 
 			// and collect initial set of metrics
-			collect(resources)
+			_ = c.collect()
 
 		case <-tickerChan:
 			// Interval ticker fired, collect metrics
-			collect(resources)
+			_ = c.collect()
 
 		case control := <-controlChan:
-			switch control {
+			var err error
+			switch control.Type {
 			case controller.START:
-				// Request to start metric collection received. Start collection after specified delay period.
-				afterChan = time.After(*delay)
+				// Request to start metric collection received. Start collection after specified delay period,
+				// with any settings overridden by the request.
+				err = applyOptions(control.Options, interval, delay)
+				if err == nil {
+					stopTicker()
+					afterChan = time.After(*delay)
+					c.configure(true, *interval)
+				}
 
 			case controller.STOP:
 				// Request to stop metric collection recieved. Stop ticker but continue to listen for further commands.
-				if ticker != nil {
-					ticker.Stop()
-				}
+				stopTicker()
+				c.configure(false, *interval)
+
+			case controller.COLLECT:
+				// One-shot collection requested. Any started collection continues as before.
+				err = c.collect()
 
 			case controller.TERMINATE:
 				// Terminate request received. Metrics collection tool will exit immediately.
@@ -134,7 +246,11 @@ func main() {
 
 			default:
 				// Shrug - should never receive invalid commands from controller.
-				fatalLog.Fatalf("Unrecognized control byte %x\n", byte(control))
+				fatalLog.Fatalf("Unrecognized control byte %x\n", byte(control.Type))
+			}
+
+			if control.Reply != nil {
+				control.Reply <- err
 			}
 		}
 	}
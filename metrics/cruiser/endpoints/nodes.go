@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2020 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -36,7 +36,7 @@ func (nm *NodeMetrics) Name() string {
 }
 
 // Metrics gathers resource metrics data via the Kuberrnetes metrics api
-func (nm *NodeMetrics) Metrics() Resource {
+func (nm *NodeMetrics) Metrics() (Resource, error) {
 	return Metrics(nm)
 }
 
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2020 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -45,7 +45,7 @@ func (pm *PodMetrics) Name() string {
 }
 
 // Metrics returns resource data via Kuberrnetes metrics api
-func (pm *PodMetrics) Metrics() Resource {
+func (pm *PodMetrics) Metrics() (Resource, error) {
 	return Metrics(pm)
 }
 
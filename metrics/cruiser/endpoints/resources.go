@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2018, 2021 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2018, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -48,6 +48,7 @@ package endpoints
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"regexp"
@@ -72,7 +73,7 @@ type usage struct {
 // Resource defines operations on a kubernetes resurce tpye, e.g. node, pod
 type Resource interface {
 	Name() string
-	Metrics() Resource
+	Metrics() (Resource, error)
 	BMMetrics() []metricsservice.BluemixMetric
 	Unmarshal(data []byte) error
 }
@@ -94,6 +95,24 @@ var (
 	fatallog = log.New(os.Stderr, log.Prefix(), log.LstdFlags|log.Lshortfile)
 )
 
+// NewResources returns the resources to collect for a level.
+// Levels hierarchy is: Node -> Namespace -> Pod -> Container
+// We'll always collect metrics at the node level, and then calculate aggregated values at the requested level
+func NewResources(level string) ([]Resource, error) {
+	var resources []Resource
+
+	switch level {
+	case "namespace", "pod", "container":
+		resources = append(resources, &PodMetrics{})
+		fallthrough
+	case "node":
+		resources = append(resources, &NodeMetrics{})
+	default:
+		return nil, fmt.Errorf("Unrecognized level '%s'", level)
+	}
+	return resources, nil
+}
+
 // Returns cpu in nano cores, and memory in bytes
 func parseMetric(m, s string) int64 {
 	var idx, mult = 1, 1
@@ -156,15 +175,16 @@ func parseMetric(m, s string) int64 {
 }
 
 // Metrics returns resource data via Kuberrnetes metrics api
-func Metrics(r Resource) Resource {
+func Metrics(r Resource) (Resource, error) {
 	const retries = 2
 	var err error
 
-	// Process --filter regexp if supplied
+	// Process --filter regexp if supplied. The filter may have changed since the last collection.
+	re = nil
 	if len(Filter) > 0 {
 		re, err = regexp.Compile(Filter)
 		if err != nil {
-			fatallog.Fatalf("Invalid filter %s - %s\n", Filter, err.Error())
+			return nil, fmt.Errorf("Invalid filter %s - %s", Filter, err.Error())
 		}
 	}
 
@@ -186,16 +206,15 @@ func Metrics(r Resource) Resource {
 			// Store JSON data in our struct
 			err = r.Unmarshal(data)
 			if err != nil {
-				fatallog.Fatalf("Failure parsing %s data metrics response - %s\n", r.Name(), err)
+				return nil, fmt.Errorf("Failure parsing %s data metrics response - %s", r.Name(), err.Error())
 			}
 
-			return r
+			return r, nil
 		}
 
 		// Maybe transitory problem, retry in 5s
 		time.Sleep(5 * time.Second)
 	}
 
-	log.Printf("WARNING: Failure getting cruiser %s metrics - %s\n", r.Name(), err)
-	return nil
+	return nil, fmt.Errorf("Failure getting cruiser %s metrics - %s", r.Name(), err.Error())
 }
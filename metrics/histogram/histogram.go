@@ -0,0 +1,436 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+// Package histogram provides a High Dynamic Range (HDR) histogram for recording latencies.
+// Values are recorded into logarithmic buckets, each split into linear sub-buckets, so that any
+// percentile can be reported to a fixed number of significant figures using a fixed amount of memory.
+// Histograms with the same configuration can be merged, both within a run and offline across runs.
+package histogram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default latency histogram configuration: 1 microsecond to 24 hours, to 3 significant figures
+const (
+	DefaultLowest  = int64(time.Microsecond)
+	DefaultHighest = int64(24 * time.Hour)
+	DefaultSigFigs = 3
+)
+
+// Percentiles are the percentiles reported for each histogram
+var Percentiles = []float64{50, 90, 99, 99.9}
+
+// Histogram records durations. It is safe for concurrent use.
+type Histogram struct {
+	mu sync.Mutex
+
+	lowest  int64
+	highest int64
+	sigFigs int
+
+	unitMagnitude               uint
+	subBucketHalfCountMagnitude uint
+	subBucketCount              int64
+	subBucketHalfCount          int64
+	subBucketMask               int64
+
+	counts     []int64
+	totalCount int64
+	min        int64
+	max        int64
+	sum        float64
+}
+
+// New creates a latency histogram with the default configuration
+func New() *Histogram {
+	h, _ := NewWithRange(DefaultLowest, DefaultHighest, DefaultSigFigs)
+	return h
+}
+
+// NewWithRange creates a histogram which tracks values between lowest and highest (in nanoseconds) to sigFigs significant figures
+func NewWithRange(lowest, highest int64, sigFigs int) (*Histogram, error) {
+	if lowest < 1 {
+		return nil, errors.New("lowest trackable value must be >= 1")
+	}
+	if highest < 2*lowest {
+		return nil, errors.New("highest trackable value must be >= 2 * lowest trackable value")
+	}
+	if sigFigs < 1 || sigFigs > 5 {
+		return nil, errors.New("significant figures must be between 1 and 5")
+	}
+
+	h := &Histogram{
+		lowest:  lowest,
+		highest: highest,
+		sigFigs: sigFigs,
+	}
+
+	// The sub-buckets must be able to resolve the largest value with a single unit at the requested precision
+	largestSingleUnit := 2 * int64(math.Pow10(sigFigs))
+	subBucketCountMagnitude := uint(math.Ceil(math.Log2(float64(largestSingleUnit))))
+	h.subBucketHalfCountMagnitude = subBucketCountMagnitude - 1
+	h.unitMagnitude = uint(math.Floor(math.Log2(float64(lowest))))
+	h.subBucketCount = int64(1) << subBucketCountMagnitude
+	h.subBucketHalfCount = h.subBucketCount / 2
+	h.subBucketMask = (h.subBucketCount - 1) << h.unitMagnitude
+
+	// Each bucket covers twice the range of the previous one
+	smallestUntrackable := h.subBucketCount << h.unitMagnitude
+	bucketCount := int64(1)
+	for smallestUntrackable <= highest {
+		if smallestUntrackable > math.MaxInt64/2 {
+			bucketCount++
+			break
+		}
+		smallestUntrackable <<= 1
+		bucketCount++
+	}
+	h.counts = make([]int64, (bucketCount+1)*h.subBucketHalfCount)
+	return h, nil
+}
+
+// Record adds a duration to the histogram. Durations outside the trackable range are clamped to it.
+func (h *Histogram) Record(d time.Duration) {
+	h.RecordValue(int64(d))
+}
+
+// RecordValue adds a value (in nanoseconds) to the histogram. Values outside the trackable range are clamped to it.
+func (h *Histogram) RecordValue(v int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recordValues(v, 1)
+}
+
+func (h *Histogram) recordValues(v int64, n int64) {
+	if h.totalCount == 0 || v < h.min {
+		h.min = v
+	}
+	if h.totalCount == 0 || v > h.max {
+		h.max = v
+	}
+	h.totalCount += n
+	h.sum += float64(v) * float64(n)
+
+	if v < 0 {
+		v = 0
+	} else if v > h.highest {
+		v = h.highest
+	}
+	h.counts[h.countsIndex(v)] += n
+}
+
+// Merge adds all the values recorded by other to this histogram.
+// Both histograms must have the same configuration.
+func (h *Histogram) Merge(other *Histogram) error {
+	s := other.Snapshot()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lowest != s.lowest || h.highest != s.highest || h.sigFigs != s.sigFigs {
+		return errors.New("can't merge histograms with different configurations")
+	}
+	if s.totalCount == 0 {
+		return nil
+	}
+
+	if h.totalCount == 0 || s.min < h.min {
+		h.min = s.min
+	}
+	if h.totalCount == 0 || s.max > h.max {
+		h.max = s.max
+	}
+	h.totalCount += s.totalCount
+	h.sum += s.sum
+	for i, c := range s.counts {
+		h.counts[i] += c
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the histogram, which won't change as further values are recorded
+func (h *Histogram) Snapshot() *Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &Histogram{
+		lowest:                      h.lowest,
+		highest:                     h.highest,
+		sigFigs:                     h.sigFigs,
+		unitMagnitude:               h.unitMagnitude,
+		subBucketHalfCountMagnitude: h.subBucketHalfCountMagnitude,
+		subBucketCount:              h.subBucketCount,
+		subBucketHalfCount:          h.subBucketHalfCount,
+		subBucketMask:               h.subBucketMask,
+		counts:                      make([]int64, len(h.counts)),
+		totalCount:                  h.totalCount,
+		min:                         h.min,
+		max:                         h.max,
+		sum:                         h.sum,
+	}
+	copy(s.counts, h.counts)
+	return s
+}
+
+// TotalCount returns the number of values recorded
+func (h *Histogram) TotalCount() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.totalCount
+}
+
+// Min returns the exact minimum value recorded
+func (h *Histogram) Min() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.min)
+}
+
+// Max returns the exact maximum value recorded
+func (h *Histogram) Max() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.max)
+}
+
+// Total returns the exact sum of all values recorded
+func (h *Histogram) Total() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.sum)
+}
+
+// Mean returns the exact mean of all values recorded
+func (h *Histogram) Mean() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.totalCount == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.totalCount))
+}
+
+// Percentile returns the value below which the specified percentage of recorded values fall.
+// The value is accurate to the histogram's significant figures, and is never larger than the maximum recorded value.
+func (h *Histogram) Percentile(pcnt float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.totalCount == 0 {
+		return 0
+	}
+
+	pcnt = math.Min(math.Max(pcnt, 0), 100)
+	countAtPercentile := int64(pcnt/100*float64(h.totalCount) + 0.5)
+	if countAtPercentile < 1 {
+		countAtPercentile = 1
+	}
+
+	var total int64
+	for i, c := range h.counts {
+		total += c
+		if total >= countAtPercentile {
+			v := h.highestEquivalentValue(h.valueFromIndex(i))
+			if v > h.max {
+				v = h.max
+			}
+			return time.Duration(v)
+		}
+	}
+	return time.Duration(h.max)
+}
+
+// PercentileName returns the metric name for a percentile, e.g. "P99" or "P99_9"
+func PercentileName(pcnt float64) string {
+	return "P" + strings.Replace(strconv.FormatFloat(pcnt, 'f', -1, 64), ".", "_", 1)
+}
+
+// countsIndex returns the index of the counts bucket which a value is recorded in
+func (h *Histogram) countsIndex(v int64) int {
+	bucketIdx := h.bucketIndex(v)
+	subBucketIdx := h.subBucketIndex(v, bucketIdx)
+	return int((int64(bucketIdx+1) << h.subBucketHalfCountMagnitude) + (subBucketIdx - h.subBucketHalfCount))
+}
+
+func (h *Histogram) bucketIndex(v int64) int {
+	pow2Ceiling := 64 - bits.LeadingZeros64(uint64(v|h.subBucketMask))
+	return pow2Ceiling - int(h.unitMagnitude) - int(h.subBucketHalfCountMagnitude+1)
+}
+
+func (h *Histogram) subBucketIndex(v int64, bucketIdx int) int64 {
+	return v >> uint(bucketIdx+int(h.unitMagnitude))
+}
+
+// valueFromIndex returns the lowest value which is recorded in a counts bucket
+func (h *Histogram) valueFromIndex(i int) int64 {
+	bucketIdx := (i >> h.subBucketHalfCountMagnitude) - 1
+	subBucketIdx := int64(i)&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucketIdx < 0 {
+		subBucketIdx -= h.subBucketHalfCount
+		bucketIdx = 0
+	}
+	return subBucketIdx << uint(bucketIdx+int(h.unitMagnitude))
+}
+
+// highestEquivalentValue returns the highest value which is recorded in the same counts bucket as v
+func (h *Histogram) highestEquivalentValue(v int64) int64 {
+	bucketIdx := h.bucketIndex(v)
+	if h.subBucketIndex(v, bucketIdx) >= h.subBucketCount {
+		bucketIdx++
+	}
+	size := int64(1) << uint(int(h.unitMagnitude)+bucketIdx)
+	return v&^(size-1) + size - 1
+}
+
+// dump is the file format for a histogram. Only non-zero counts are written, keyed by counts index.
+type dump struct {
+	Name       string            `json:"name,omitempty"`
+	Lowest     int64             `json:"lowest"`
+	Highest    int64             `json:"highest"`
+	SigFigs    int               `json:"sigFigs"`
+	TotalCount int64             `json:"totalCount"`
+	Min        int64             `json:"min"`
+	Max        int64             `json:"max"`
+	Sum        float64           `json:"sum"`
+	Counts     map[int]int64     `json:"counts"`
+	Summary    map[string]string `json:"summary,omitempty"`
+}
+
+// toDump converts a snapshot of the histogram to its file format
+func (h *Histogram) toDump(name string) dump {
+	s := h.Snapshot()
+
+	d := dump{
+		Name:       name,
+		Lowest:     s.lowest,
+		Highest:    s.highest,
+		SigFigs:    s.sigFigs,
+		TotalCount: s.totalCount,
+		Min:        s.min,
+		Max:        s.max,
+		Sum:        s.sum,
+		Counts:     make(map[int]int64),
+		Summary:    make(map[string]string),
+	}
+	for i, c := range s.counts {
+		if c > 0 {
+			d.Counts[i] = c
+		}
+	}
+	// Human readable percentiles - ignored when the file is read
+	for _, p := range Percentiles {
+		d.Summary[PercentileName(p)] = s.Percentile(p).String()
+	}
+	return d
+}
+
+// fromDump creates a histogram from its file format
+func fromDump(d dump) (*Histogram, error) {
+	h, err := NewWithRange(d.Lowest, d.Highest, d.SigFigs)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range d.Counts {
+		if i < 0 || i >= len(h.counts) {
+			return nil, fmt.Errorf("invalid histogram counts index %d", i)
+		}
+		h.counts[i] = c
+	}
+	h.totalCount = d.TotalCount
+	h.min = d.Min
+	h.max = d.Max
+	h.sum = d.Sum
+	return h, nil
+}
+
+// MarshalJSON encodes the histogram in the same format as WriteFile, so that it can be sent to another process
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.toDump(""))
+}
+
+// UnmarshalJSON decodes a histogram encoded by MarshalJSON
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var d dump
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	decoded, err := fromDump(d)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lowest = decoded.lowest
+	h.highest = decoded.highest
+	h.sigFigs = decoded.sigFigs
+	h.unitMagnitude = decoded.unitMagnitude
+	h.subBucketHalfCountMagnitude = decoded.subBucketHalfCountMagnitude
+	h.subBucketCount = decoded.subBucketCount
+	h.subBucketHalfCount = decoded.subBucketHalfCount
+	h.subBucketMask = decoded.subBucketMask
+	h.counts = decoded.counts
+	h.totalCount = decoded.totalCount
+	h.min = decoded.min
+	h.max = decoded.max
+	h.sum = decoded.sum
+	return nil
+}
+
+// WriteFile dumps the raw histogram to a file, so that histograms can be merged offline across runs
+func (h *Histogram) WriteFile(path string, name string) error {
+	data, err := json.MarshalIndent(h.toDump(name), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// runID identifies the process in dump file names, and dumpSeq the dump within it, so that dumps from each
+// batch and each run don't overwrite each other
+var (
+	runID   = fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405"), os.Getpid())
+	dumpSeq uint64
+)
+
+// DumpFileName returns a unique file name for a dump of the named histogram, e.g.
+// <name>.20260101T120000-1234.1.hgrm.json. The name is also stored in the file, for merging.
+func DumpFileName(name string) string {
+	seq := atomic.AddUint64(&dumpSeq, 1)
+	return fmt.Sprintf("%s.%s.%d.hgrm.json", strings.ReplaceAll(name, "/", "_"), runID, seq)
+}
+
+// ReadFile loads a histogram previously dumped by WriteFile, returning it and its name
+func ReadFile(path string) (*Histogram, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var d dump
+	if err = json.Unmarshal(data, &d); err != nil {
+		return nil, "", err
+	}
+
+	h, err := fromDump(d)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return h, d.Name, nil
+}
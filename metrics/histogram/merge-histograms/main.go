@@ -0,0 +1,95 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.ibm.com/alchemy-containers/armada-performance/metrics/histogram"
+)
+
+// Merge histograms dumped by the performance clients (e.g. armada-perf-client -histogramDir) across runs.
+// Histograms with the same metric name are merged, and the merged percentiles are printed.
+func main() {
+	var outDir string
+	flag.StringVar(&outDir, "o", "", "directory to write the merged histograms to")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-o outdir] file|dir ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	merged := make(map[string]*histogram.Histogram)
+	for _, arg := range flag.Args() {
+		files := []string{arg}
+		if fi, err := os.Stat(arg); err == nil && fi.IsDir() {
+			files, _ = filepath.Glob(filepath.Join(arg, "*.hgrm.json"))
+		}
+
+		for _, f := range files {
+			h, name, err := histogram.ReadFile(f)
+			if err != nil {
+				log.Fatalf("Failed to read histogram %s: %s", f, err)
+			}
+			if len(name) == 0 {
+				name = strings.TrimSuffix(filepath.Base(f), ".hgrm.json")
+			}
+			if m, ok := merged[name]; ok {
+				if err = m.Merge(h); err != nil {
+					log.Fatalf("Failed to merge histogram %s: %s", f, err)
+				}
+			} else {
+				merged[name] = h
+			}
+		}
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := []string{"Metric", "Count", "Min", "Mean"}
+	for _, p := range histogram.Percentiles {
+		header = append(header, histogram.PercentileName(p))
+	}
+	fmt.Fprintln(tw, strings.Join(append(header, "Max"), "\t"))
+
+	for _, name := range names {
+		h := merged[name]
+		row := []string{name, fmt.Sprint(h.TotalCount()), h.Min().String(), h.Mean().String()}
+		for _, p := range histogram.Percentiles {
+			row = append(row, h.Percentile(p).String())
+		}
+		fmt.Fprintln(tw, strings.Join(append(row, h.Max().String()), "\t"))
+
+		if len(outDir) > 0 {
+			if err := h.WriteFile(filepath.Join(outDir, name+".hgrm.json"), name); err != nil {
+				log.Fatalf("Failed to write merged histogram %s: %s", name, err)
+			}
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,184 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Aggregations applied to each series over the time window
+const (
+	AggregateNone = "none" // Output every sample
+	AggregateAvg  = "avg"
+	AggregateMin  = "min"
+	AggregateMax  = "max"
+	AggregateSum  = "sum"
+	AggregateLast = "last"
+	AggregateP95  = "p95"
+)
+
+// conversions are the named unit conversions a query can apply, with the unit they convert to
+var conversions = map[string]struct {
+	scale float64
+	unit  string
+}{
+	"bytes-to-kib":     {1.0 / 1024, "KiB"},
+	"bytes-to-mib":     {1.0 / (1024 * 1024), "MiB"},
+	"bytes-to-gib":     {1.0 / (1024 * 1024 * 1024), "GiB"},
+	"seconds-to-ms":    {1000, "ms"},
+	"ms-to-seconds":    {1.0 / 1000, "s"},
+	"ratio-to-percent": {100, "%"},
+	// Rate of a milliseconds counter (e.g. node_disk_io_time_ms) as a percentage of each second
+	"ms-per-second-to-percent": {0.1, "%"},
+}
+
+// Column maps a Prometheus label to an output column
+type Column struct {
+	Label  string `yaml:"label"`
+	Column string `yaml:"column"`
+}
+
+// Query is a PromQL query in the catalog, and how to present its results
+type Query struct {
+	Name      string        `yaml:"name"`
+	Help      string        `yaml:"help"`
+	Query     string        `yaml:"query"`
+	Step      time.Duration `yaml:"step"`      // Defaults to the catalog step
+	Columns   []Column      `yaml:"columns"`   // Labels to output, in order. Other labels are dropped.
+	Convert   string        `yaml:"convert"`   // Optional named unit conversion, e.g. bytes-to-mib
+	Scale     float64       `yaml:"scale"`     // Optional multiplier, applied after Convert
+	Unit      string        `yaml:"unit"`      // Defaults to the unit Convert converts to
+	Aggregate string        `yaml:"aggregate"` // none, avg, min, max, sum, last or p95. Defaults to none.
+	Min       *float64      `yaml:"min"`       // Optional - samples below this (after conversion) are dropped
+}
+
+// Catalog is a set of queries, and named groups of them
+type Catalog struct {
+	Step    time.Duration       `yaml:"step"` // Must line up with the scrape interval to ensure all data is collected
+	Groups  map[string][]string `yaml:"groups"`
+	Queries []*Query            `yaml:"queries"`
+}
+
+// loadCatalog reads and validates a YAML query catalog
+func loadCatalog(path string) (*Catalog, error) {
+	// #nosec G304
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Catalog
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing catalog %s: %w", path, err)
+	}
+	if c.Step <= 0 {
+		c.Step = 10 * time.Second
+	}
+
+	names := make(map[string]bool, len(c.Queries))
+	for _, q := range c.Queries {
+		if err := q.validate(c.Step); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", path, err)
+		}
+		if names[q.Name] {
+			return nil, fmt.Errorf("catalog %s: duplicate query %s", path, q.Name)
+		}
+		names[q.Name] = true
+	}
+	for group, members := range c.Groups {
+		for _, m := range members {
+			if !names[m] {
+				return nil, fmt.Errorf("catalog %s: group %s refers to unknown query %s", path, group, m)
+			}
+		}
+	}
+	return &c, nil
+}
+
+// validate checks the query and fills in defaults
+func (q *Query) validate(defaultStep time.Duration) error {
+	if len(q.Name) == 0 || len(q.Query) == 0 {
+		return fmt.Errorf("every query needs a name and a query")
+	}
+	if q.Step <= 0 {
+		q.Step = defaultStep
+	}
+	if len(q.Aggregate) == 0 {
+		q.Aggregate = AggregateNone
+	}
+	switch q.Aggregate {
+	case AggregateNone, AggregateAvg, AggregateMin, AggregateMax, AggregateSum, AggregateLast, AggregateP95:
+	default:
+		return fmt.Errorf("query %s: unknown aggregate %s", q.Name, q.Aggregate)
+	}
+	if q.Scale == 0 {
+		q.Scale = 1
+	}
+	if len(q.Convert) > 0 {
+		c, ok := conversions[q.Convert]
+		if !ok {
+			return fmt.Errorf("query %s: unknown conversion %s", q.Name, q.Convert)
+		}
+		q.Scale *= c.scale
+		if len(q.Unit) == 0 {
+			q.Unit = c.unit
+		}
+	}
+	for i, c := range q.Columns {
+		if len(c.Label) == 0 {
+			return fmt.Errorf("query %s: column %d has no label", q.Name, i)
+		}
+		if len(c.Column) == 0 {
+			q.Columns[i].Column = c.Label
+		}
+	}
+	return nil
+}
+
+// selectQueries returns the queries named in the comma separated list, which may include group names.
+// An empty list selects every query in the catalog.
+func (c *Catalog) selectQueries(list string) ([]*Query, error) {
+	if len(strings.TrimSpace(list)) == 0 {
+		return c.Queries, nil
+	}
+
+	byName := make(map[string]*Query, len(c.Queries))
+	for _, q := range c.Queries {
+		byName[q.Name] = q
+	}
+
+	var selected []*Query
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			selected = append(selected, byName[name])
+		}
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if members, ok := c.Groups[name]; ok {
+			for _, m := range members {
+				add(m)
+			}
+		} else if _, ok := byName[name]; ok {
+			add(name)
+		} else {
+			return nil, fmt.Errorf("no query or group named %s in the catalog", name)
+		}
+	}
+	return selected, nil
+}
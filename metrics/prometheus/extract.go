@@ -0,0 +1,146 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// Point is a single, converted, value
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Series is the result for one set of column values
+type Series struct {
+	Labels map[string]string `json:"labels"` // Keyed by column name
+	Points []Point           `json:"points"`
+}
+
+// Result holds the series returned by a catalog query
+type Result struct {
+	Query     *Query    `json:"-"`
+	Name      string    `json:"query"`
+	Help      string    `json:"help,omitempty"`
+	Unit      string    `json:"unit,omitempty"`
+	Aggregate string    `json:"aggregate"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Series    []Series  `json:"series"`
+}
+
+// extract runs a catalog query over the window, converting, filtering and aggregating the samples
+func extract(ctx context.Context, api prom.API, q *Query, start time.Time, end time.Time, stepOverride time.Duration) (Result, error) {
+	step := q.Step
+	if stepOverride > 0 {
+		step = stepOverride
+	}
+
+	v, warnings, err := api.QueryRange(ctx, q.Query, prom.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return Result{}, fmt.Errorf("could not execute query %s (%s): %w", q.Name, q.Query, err)
+	}
+	for _, w := range warnings {
+		logger.Println("warning from query", q.Name, ":", w)
+	}
+
+	// queries return model.Value which needs to be cast to String, Scalar, Vector or Matrix based on v.Type()
+	matrix, ok := v.(model.Matrix)
+	if !ok {
+		return Result{}, fmt.Errorf("query %s returned a %s, a range vector was expected", q.Name, v.Type())
+	}
+
+	result := Result{Query: q, Name: q.Name, Help: q.Help, Unit: q.Unit, Aggregate: q.Aggregate, Start: start, End: end}
+
+	// Several streams can map to the same column values once unmapped labels are dropped, so merge them by key
+	byKey := make(map[string]*Series)
+	var keys []string
+	for _, stream := range matrix {
+		labels := make(map[string]string, len(q.Columns))
+		keyParts := make([]string, len(q.Columns))
+		for i, c := range q.Columns {
+			labels[c.Column] = string(stream.Metric[model.LabelName(c.Label)])
+			keyParts[i] = labels[c.Column]
+		}
+		key := strings.Join(keyParts, "\x00")
+
+		s, ok := byKey[key]
+		if !ok {
+			s = &Series{Labels: labels}
+			byKey[key] = s
+			keys = append(keys, key)
+		}
+		for _, pair := range stream.Values {
+			value := float64(pair.Value) * q.Scale
+			if math.IsNaN(value) || (q.Min != nil && value < *q.Min) {
+				continue
+			}
+			s.Points = append(s.Points, Point{Time: pair.Timestamp.Time().UTC(), Value: value})
+		}
+	}
+
+	// For consistent output, sort the series by their column values
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := byKey[key]
+		sort.SliceStable(s.Points, func(i, j int) bool { return s.Points[i].Time.Before(s.Points[j].Time) })
+		if q.Aggregate != AggregateNone {
+			if len(s.Points) == 0 {
+				continue
+			}
+			s.Points = []Point{{Time: end.UTC(), Value: aggregate(q.Aggregate, s.Points)}}
+		}
+		result.Series = append(result.Series, *s)
+	}
+	return result, nil
+}
+
+// aggregate reduces the points of a series to a single value
+func aggregate(how string, points []Point) float64 {
+	switch how {
+	case AggregateLast:
+		return points[len(points)-1].Value
+	case AggregateP95:
+		values := make([]float64, len(points))
+		for i, p := range points {
+			values[i] = p.Value
+		}
+		sort.Float64s(values)
+		return values[int(math.Ceil(0.95*float64(len(values))))-1]
+	}
+
+	total := 0.0
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		total += p.Value
+		min = math.Min(min, p.Value)
+		max = math.Max(max, p.Value)
+	}
+	switch how {
+	case AggregateMin:
+		return min
+	case AggregateMax:
+		return max
+	case AggregateSum:
+		return total
+	default:
+		return total / float64(len(points))
+	}
+}
@@ -2,430 +2,201 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2017, 2019 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
  ******************************************************************************/
 
+// Extract data from Prometheus using the queries in a YAML catalog, and output it as CSV, JSON or metrics
+
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"sort"
-	"strconv"
+	"strings"
 	"time"
 
 	papi "github.com/prometheus/client_golang/api"
 	prom "github.com/prometheus/client_golang/api/prometheus/v1"
-	"github.com/prometheus/common/model"
-	"golang.org/x/net/context"
+	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
 )
 
 var logger *log.Logger
 
-const debug bool = false
-
-// typedefs for data holders; values indexed by only instance (hostname) or by instance & device
-
-// SingleMap ....
-type SingleMap map[string]float64
-
-// DoubleMap ...
-type DoubleMap map[string]map[string]float64
-
 func init() {
-	logger = log.New(os.Stdout, "metrics ", log.Lshortfile|log.Ltime)
+	logger = log.New(os.Stderr, "metrics ", log.Lshortfile|log.Ltime)
 }
 
-func main() {
-	var startTime, endTime time.Time
-	var err error
-
-	// default to retreiving an hour of data from the current time
-	d, err := time.ParseDuration("-60m")
-
-	if err != nil {
-		logger.Fatalln("invalid duration", err)
+// defaultCatalogPath is the catalog in the performance repo, found in the same way as metrics.toml
+func defaultCatalogPath() string {
+	repo := os.Getenv("ARMADA_PERF_REPO_PATH")
+	if len(repo) == 0 {
+		repo = filepath.Join(os.Getenv("GOPATH"), "src", "github.ibm.com", "alchemy-containers", "armada-performance")
 	}
+	return filepath.Join(repo, "metrics", "prometheus", "catalog.yaml")
+}
 
-	endpoint := "http://localhost:9090"
-	now := time.Now()
-	startTime = now.Add(d)
-	endTime = now
-
-	if len(os.Args) > 1 {
-		endpoint = os.Args[1]
+func main() {
+	var endpoint, catalogPath, queries, format, output string
+	var startFlag, endFlag string
+	var window, step, timeout time.Duration
+	var testName, dbKey, measurement string
+	var list bool
+
+	flag.StringVar(&endpoint, "endpoint", "http://localhost:9090", "Prometheus endpoint")
+	flag.StringVar(&catalogPath, "catalog", defaultCatalogPath(), "YAML query catalog")
+	flag.StringVar(&queries, "queries", "", "Comma separated queries and/or groups from the catalog to run. Defaults to all queries")
+	flag.BoolVar(&list, "list", false, "List the queries and groups in the catalog, and exit")
+	flag.StringVar(&format, "format", "csv", "Output format - csv, json or metrics")
+	flag.StringVar(&output, "output", "", "File to write csv or json output to. Defaults to stdout")
+	flag.StringVar(&startFlag, "start", "", "Start of the window - RFC3339, HH:MM (UTC today), \"now\" or relative to now e.g. -2h. Defaults to end - window")
+	flag.StringVar(&endFlag, "end", "", "End of the window, in the same forms as -start. Defaults to start + window, or now if -start isn't set")
+	flag.DurationVar(&window, "window", time.Hour, "Length of the window, used when only one of -start and -end is set")
+	flag.DurationVar(&step, "step", 0, "Override the step of every query. Must line up with the scrape interval to ensure all data is collected")
+	flag.DurationVar(&timeout, "timeout", 2*time.Minute, "Timeout for each query")
+	flag.StringVar(&testName, "testname", "prometheus", "Test name, for -format metrics")
+	flag.StringVar(&dbKey, "dbkey", "", "Metrics database key, for -format metrics")
+	flag.StringVar(&measurement, "measurement", "prometheus", "Measurement to write the metrics to, for -format metrics")
+	flag.Parse()
+
+	catalog, err := loadCatalog(catalogPath)
+	if err != nil {
+		logger.Fatalln(err)
 	}
-	// second argument must be a time
-	if len(os.Args) > 2 {
-		startTime, err = parseTime(now, os.Args[2])
-
-		if err != nil {
-			logger.Fatalln("invalid start time", os.Args[2])
-		}
+	if list {
+		listCatalog(catalog)
+		return
 	}
-	// third argument can be a time or a duration
-	// duration can be negative which makes the first argument the end time
-	if len(os.Args) > 3 {
-		endTime, err = parseTime(now, os.Args[3])
-
-		if err != nil {
-			if debug {
-				logger.Println("parsing", os.Args[3], "as a duration")
-			}
-			d, err = time.ParseDuration(os.Args[3])
-
-			if err != nil {
-				logger.Fatalln("invalid duration ", err)
-			}
-
-			endTime = startTime.Add(d)
-		}
+	selected, err := catalog.selectQueries(queries)
+	if err != nil {
+		logger.Fatalln(err)
 	}
 
-	// swap times if necessary
-	if endTime.Before(startTime) {
-		startTime, endTime = endTime, startTime
+	start, end, err := timeWindow(time.Now(), startFlag, endFlag, window)
+	if err != nil {
+		logger.Fatalln(err)
 	}
-
-	fmt.Println("getting Prometheus data for", endpoint, "from", startTime.Format(time.RFC822), "to", endTime.Format(time.RFC822))
+	logger.Println("getting Prometheus data for", endpoint, "from", start.Format(time.RFC822), "to", end.Format(time.RFC822))
 
 	// prometheus.Client
 	client, err := papi.NewClient(papi.Config{Address: endpoint})
-
 	if err != nil {
-		logger.Println("error", err)
-		return
+		logger.Fatalln("error", err)
 	}
-
 	// prometheus.QueryAPI
-	q := prom.NewAPI(client)
-
-	// Range used for all queries
-	// note Step of 10 must line up with scrape interval to ensure all data is collected
-	r := prom.Range{Start: startTime, End: endTime, Step: 10 * time.Second}
-
-	// set to track all instances (hostnames)
-	instances := make(map[string]struct{})
-
-	// queries return model.Value which needs to be cast to String, Scalar, Vector or Matrix based on v.Type()
-	fmt.Println("current CPU")
-	outputSingleValues(&q, "100 * (1 - avg by(instance)(irate(node_cpu{mode='idle'}[30s])))", now)
-	fmt.Println()
+	api := prom.NewAPI(client)
 
-	cpuData := collectForSingleValue(&q, "100 * (1 - avg by(instance)(irate(node_cpu{mode='idle'}[30s])))", &r, instances)
-	memoryData := collectForSingleValue(&q, "node_memory_Active / 1024 / 1024 / 1024", &r, instances)
-	diskReadData := collectForTwoValues(&q, "(sum(irate(node_disk_bytes_read[30s])) by (instance,device)) / 1024 / 1024", &r, instances)
-	diskWriteData := collectForTwoValues(&q, "(sum(irate(node_disk_bytes_written[30s])) by (instance,device)) / 1024 / 1024", &r, instances)
-	networkReadData := collectForTwoValues(&q, "(irate(node_network_receive_bytes{device=~'^bond.*'}[30s])) / 1024 / 1024", &r, instances)
-	networkWriteData := collectForTwoValues(&q, "(irate(node_network_transmit_bytes{device=~'^bond.*'}[30s])) / 1024 / 1024", &r, instances)
-
-	// for consistent output, sort the instances, disks and network interfaces
-	sortedInstances := make([]string, len(instances))
-	n := 0
-
-	for instance := range instances {
-		sortedInstances[n] = instance
-		n++
-	}
-
-	sort.Strings(sortedInstances)
-
-	disks := make(map[string]struct{})
-	interfaces := make(map[string]struct{})
-
-	for _, instance := range sortedInstances {
-		m := diskReadData[instance]
-
-		for disk := range m {
-			disks[disk] = struct{}{}
-		}
-
-		m = diskWriteData[instance]
-
-		for disk := range m {
-			disks[disk] = struct{}{}
-		}
-
-		m = networkReadData[instance]
-
-		for iface := range m {
-			interfaces[iface] = struct{}{}
-		}
-
-		m = networkWriteData[instance]
-
-		for iface := range m {
-			interfaces[iface] = struct{}{}
+	results := make([]Result, 0, len(selected))
+	for _, q := range selected {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		r, err := extract(ctx, api, q, start, end, step)
+		cancel()
+		if err != nil {
+			logger.Fatalln(err)
 		}
+		results = append(results, r)
 	}
 
-	sortedDisks := make([]string, len(disks))
-	sortedInterfaces := make([]string, len(interfaces))
-
-	n = 0
-
-	for disk := range disks {
-		sortedDisks[n] = disk
-		n++
-	}
-
-	n = 0
-
-	for iface := range interfaces {
-		sortedInterfaces[n] = iface
-		n++
+	if format == "metrics" {
+		if len(dbKey) == 0 {
+			dbKey = os.Getenv("METRICS_DB_KEY")
+		}
+		metricsservice.WriteMetrics(toMetrics(measurement, results), testName, dbKey)
+		return
 	}
 
-	// output all the data
-	// 1st row - headers
-	// Weird spec for '%' is to make go vet happy
-	fmt.Printf("Instance,CPU Utilization (%s),Active Memory (GB),Disk Read (MB/s)\n", "%")
-	for range sortedDisks {
-		fmt.Print(",")
-	}
-	fmt.Print("Disk Write (MB/s)")
-	for range sortedDisks {
-		fmt.Print(",")
+	out := os.Stdout
+	if len(output) > 0 {
+		// #nosec G304
+		out, err = os.Create(output)
+		if err != nil {
+			logger.Fatalln(err)
+		}
+		defer out.Close()
 	}
-	fmt.Print("Network Rx (MB/s)")
-	for range sortedInterfaces {
-		fmt.Print(",")
+	switch format {
+	case "csv":
+		err = writeCSV(out, results)
+	case "json":
+		err = writeJSON(out, results)
+	default:
+		err = fmt.Errorf("unknown format %s - must be csv, json or metrics", format)
 	}
-	fmt.Print("Network Tx (MB/s)")
-	for range sortedInterfaces {
-		fmt.Print(",")
+	if err != nil {
+		logger.Fatalln(err)
 	}
-	fmt.Println()
+}
 
-	// 2nd row - disks and interface names
-	// printed twice for read + write
-	fmt.Print(",,,")
-	for _, disk := range sortedDisks {
-		fmt.Print(disk)
-		fmt.Print(",")
+// listCatalog prints the groups and queries in the catalog
+func listCatalog(c *Catalog) {
+	groups := make([]string, 0, len(c.Groups))
+	for group := range c.Groups {
+		groups = append(groups, group)
 	}
-	for _, disk := range sortedDisks {
-		fmt.Print(disk)
-		fmt.Print(",")
+	sort.Strings(groups)
+	for _, group := range groups {
+		fmt.Printf("group %s: %s\n", group, strings.Join(c.Groups[group], ", "))
 	}
-	for _, iface := range sortedInterfaces {
-		fmt.Print(iface)
-		fmt.Print(",")
-	}
-	for _, iface := range sortedInterfaces {
-		fmt.Print(iface)
-		fmt.Print(",")
-	}
-	fmt.Println()
-
-	// one row for each instance (hostname)
-	for _, instance := range sortedInstances {
-		fmt.Print(instance)
-		fmt.Print(",")
-		fmt.Print(cpuData.fmt(instance))
-		fmt.Print(",")
-		fmt.Print(memoryData.fmt(instance))
-		fmt.Print(",")
-
-		for _, disk := range sortedDisks {
-			fmt.Print(diskReadData.fmt(instance, disk))
-			fmt.Print(",")
-		}
-		for _, disk := range sortedDisks {
-			fmt.Print(diskWriteData.fmt(instance, disk))
-			fmt.Print(",")
-		}
-
-		for _, iface := range sortedInterfaces {
-			fmt.Print(networkReadData.fmt(instance, iface))
-			fmt.Print(",")
-		}
-		for _, iface := range sortedInterfaces {
-			fmt.Print(networkWriteData.fmt(instance, iface))
-			fmt.Print(",")
-		}
-
-		fmt.Println()
+	for _, q := range c.Queries {
+		fmt.Printf("%s (%s, step %s): %s\n", q.Name, q.Aggregate, q.Step, q.Help)
 	}
 }
 
-func outputSingleValues(q *prom.API, query string, time time.Time) {
-	// Background context => no timeout
-	// queries return model.Value which needs to be cast to String, Scalar, Vector or Matrix based on v.Type()
-	v, _, err := (*q).Query(context.Background(), query, time)
+// timeWindow works out the start and end of the window from the flags
+func timeWindow(now time.Time, startFlag string, endFlag string, window time.Duration) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
 
-	if err != nil {
-		logger.Println("could not execute query", query, err)
-	} else {
-		// Vector is an array of Samples
-		for _, sample := range (v).(model.Vector) {
-			// Sample.Metric is a map[string]string; Sample.Value is a float64
-			if device, exists := sample.Metric["device"]; exists {
-				fmt.Println(sample.Metric["instance"], device, strconv.FormatFloat(float64(sample.Value), 'f', 3, 64))
-			} else {
-				fmt.Println(sample.Metric["instance"], strconv.FormatFloat(float64(sample.Value), 'f', 3, 64))
-			}
+	if len(startFlag) > 0 {
+		if start, err = parseTime(now, startFlag); err != nil {
+			return start, end, fmt.Errorf("invalid start time %s: %w", startFlag, err)
 		}
 	}
-}
-
-func collectForSingleValue(q *prom.API, query string, r *prom.Range, instances map[string]struct{}) SingleMap {
-	// Background context => no timeout
-	// queries return model.Value which needs to be cast to String, Scalar, Vector or Matrix based on v.Type()
-	v, _, err := (*q).QueryRange(context.Background(), query, *r)
-
-	if err != nil {
-		logger.Fatalln("could not execute query", query, err)
-	}
-
-	if debug {
-		logger.Println("retrieved", len(v.(model.Matrix)), "metrics for '", query, "'")
-	}
-
-	// map the instance to the average value
-	values := make(map[string]float64)
-
-	// Matrix is an array of SampleStreams
-	for _, sampleStream := range v.(model.Matrix) {
-		// SampleStream.Metric is a map[string]string
-		// get the instance and add it to the master list, if necessary
-		instance := string(sampleStream.Metric["instance"])
-
-		if _, exists := instances[instance]; !exists {
-			instances[instance] = struct{}{}
-
-			if debug {
-				logger.Println("added instance", instance)
-			}
-		}
-
-		// SampleStream.Values is an array of SamplePairs
-		// SamplePair contains a Time and a float64 Value
-		// calculate the average
-		if debug {
-			logger.Println("retrieved", len(sampleStream.Values), "values for", instance)
-		}
-
-		sum := 0.0
-
-		for _, samplePair := range sampleStream.Values {
-			sum += float64(samplePair.Value)
-		}
-
-		values[instance] = sum / float64(len(sampleStream.Values))
-
-		if debug {
-			logger.Println("added", values[instance], "for", instance)
+	if len(endFlag) > 0 {
+		if end, err = parseTime(now, endFlag); err != nil {
+			return start, end, fmt.Errorf("invalid end time %s: %w", endFlag, err)
 		}
 	}
 
-	return values
-}
-
-func collectForTwoValues(q *prom.API, query string, r *prom.Range, instances map[string]struct{}) DoubleMap {
-	// Background context => no timeout
-	// queries return model.Value which needs to be cast to String, Scalar, Vector or Matrix based on v.Type()
-	v, _, err := (*q).QueryRange(context.Background(), query, *r)
-
-	if err != nil {
-		logger.Fatalln("could not execute query", query, err)
-	}
-
-	if debug {
-		logger.Println("retrieved", len(v.(model.Matrix)), "metrics for '", query, "'")
+	switch {
+	case start.IsZero() && end.IsZero():
+		end = now
+		start = end.Add(-window)
+	case start.IsZero():
+		start = end.Add(-window)
+	case end.IsZero():
+		end = start.Add(window)
 	}
 
-	// map the instance and device to the average value
-	values := make(map[string]map[string]float64)
-
-	// Matrix is an array of SampleStreams
-	for _, sampleStream := range v.(model.Matrix) {
-		// SampleStream.Metric is a map[string]string
-		// get the instance and add it to the master list, if necessary
-		instance := string(sampleStream.Metric["instance"])
-
-		if _, exists := instances[instance]; !exists {
-			instances[instance] = struct{}{}
-
-			if debug {
-				logger.Println("added instance", instance)
-			}
-		}
-
-		if _, exists := values[instance]; !exists {
-			values[instance] = make(map[string]float64)
-		}
-
-		device := string(sampleStream.Metric["device"])
-
-		// SampleStream.Values is an array of SamplePairs
-		// SamplePair contains a Time and a float64 Value
-		// calculate the average
-		if debug {
-			logger.Println("retrieved", len(sampleStream.Values), "values for", instance, ":", device)
-		}
-
-		sum := 0.0
-
-		for _, samplePair := range sampleStream.Values {
-			sum += float64(samplePair.Value)
-		}
-
-		values[instance][device] = sum / float64(len(sampleStream.Values))
-
-		if debug {
-			logger.Println("added", values[instance][device], "for", instance, ":", device)
-		}
+	// swap times if necessary
+	if end.Before(start) {
+		start, end = end, start
 	}
-
-	return values
+	return start, end, nil
 }
 
-func (values SingleMap) fmt(key string) string {
-	if value, exists := values[key]; exists {
-		return strconv.FormatFloat(value, 'f', 3, 64)
+// parseTime parses an RFC3339 time, an HH:MM time today (UTC), "now", or a duration relative to now e.g. -90m
+func parseTime(now time.Time, toParse string) (time.Time, error) {
+	if toParse == "now" {
+		return now, nil
 	}
-	return ""
-}
-
-func (values DoubleMap) fmt(key1 string, key2 string) string {
-	if value, exists := values[key1]; exists {
-		if value2, exists := value[key2]; exists {
-			return strconv.FormatFloat(value2, 'f', 3, 64)
-		}
-		return ""
+	if t, err := time.Parse(time.RFC3339, toParse); err == nil {
+		return t, nil
 	}
-	return ""
-}
-
-func parseTime(base time.Time, toParse string) (time.Time, error) {
-	// try as full datetime
-	toReturn, err := time.Parse(time.RFC3339, toParse)
-
-	if err != nil {
-		// try as HH:MM
-		toReturn, err = time.Parse("15:04", toParse)
-
-		if err != nil {
-			// just return the base time; assume errors are checked
-			return base, err
-		}
-
-		// go parsing is braindead; the HH:MM time parsed is based off the _zero_ time
-		// get the value for that and the start time for today and fix it up
-		zero, _ := time.Parse("15:04", "00:00")
-		today := base.Truncate(time.Second * 86400)
-
-		toReturn = time.Unix(today.Unix()+toReturn.Unix()-zero.Unix(), 0)
+	if t, err := time.Parse("15:04", toParse); err == nil {
+		today := now.UTC().Truncate(24 * time.Hour)
+		return today.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
 	}
-
-	if debug {
-		logger.Println("parsed", toParse, "to", toReturn)
+	d, err := time.ParseDuration(toParse)
+	if err != nil {
+		return now, fmt.Errorf("expected RFC3339, HH:MM, now or a relative duration")
 	}
-
-	return toReturn, nil
+	return now.Add(d), nil
 }
@@ -0,0 +1,93 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
+)
+
+// writeCSV writes one row per point: query, the union of the columns of all the queries (in catalog order),
+// time, value and unit. Columns a query doesn't map are left empty.
+func writeCSV(w io.Writer, results []Result) error {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		for _, c := range r.Query.Columns {
+			if !seen[c.Column] {
+				seen[c.Column] = true
+				columns = append(columns, c.Column)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := append(append([]string{"query"}, columns...), "time", "value", "unit")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		for _, s := range r.Series {
+			for _, p := range s.Points {
+				row := make([]string, 0, len(header))
+				row = append(row, r.Name)
+				for _, c := range columns {
+					row = append(row, s.Labels[c])
+				}
+				row = append(row, p.Time.Format(time.RFC3339), strconv.FormatFloat(p.Value, 'f', 3, 64), r.Unit)
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON writes the results as an indented JSON array
+func writeJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// toMetrics converts each point to a Metric in the measurement, with the query name as the field and the
+// Prometheus labels as tags
+func toMetrics(measurement string, results []Result) []metricsservice.Metric {
+	fieldReplacer := strings.NewReplacer("-", "_", ".", "_", " ", "_")
+
+	var metrics []metricsservice.Metric
+	for _, r := range results {
+		field := fieldReplacer.Replace(r.Name)
+		for _, s := range r.Series {
+			for _, p := range s.Points {
+				m := metricsservice.NewMetric(measurement, field, p.Value).
+					WithTag(metricsservice.TagMetricName, field).
+					WithTimestamp(p.Time)
+				for _, c := range r.Query.Columns {
+					m = m.WithTag(c.Label, s.Labels[c.Column])
+				}
+				if len(r.Unit) > 0 {
+					m = m.WithUnit(r.Unit)
+				}
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics
+}
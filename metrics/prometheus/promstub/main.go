@@ -0,0 +1,202 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+// A Prometheus-compatible stub serving synthetic data from a YAML fixture, for testing the extraction tool
+// without a real Prometheus. It implements just enough of /api/v1/query_range and /api/v1/query.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Series is a synthetic series. It is returned for every query that contains Match.
+// Its value at time t is Base + Amplitude * sin(2π t / Period).
+type Series struct {
+	Match     string            `yaml:"match"`
+	Labels    map[string]string `yaml:"labels"`
+	Base      float64           `yaml:"base"`
+	Amplitude float64           `yaml:"amplitude"`
+	Period    time.Duration     `yaml:"period"`
+}
+
+// Fixture is the set of synthetic series served by the stub
+type Fixture struct {
+	Series []Series `yaml:"series"`
+}
+
+func (s Series) valueAt(t time.Time) float64 {
+	if s.Period <= 0 {
+		return s.Base
+	}
+	phase := 2 * math.Pi * float64(t.UnixNano()%int64(s.Period)) / float64(s.Period)
+	return s.Base + s.Amplitude*math.Sin(phase)
+}
+
+// apiResponse is the Prometheus HTTP API response envelope
+type apiResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type queryData struct {
+	ResultType string        `json:"resultType"`
+	Result     []interface{} `json:"result"`
+}
+
+type matrixSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+type vectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]interface{}    `json:"value"`
+}
+
+type stub struct {
+	fixture Fixture
+}
+
+// matching returns the series whose match string appears in the query
+func (s *stub) matching(query string) []Series {
+	var matched []Series
+	for _, series := range s.fixture.Series {
+		if strings.Contains(query, series.Match) {
+			matched = append(matched, series)
+		}
+	}
+	return matched
+}
+
+func samplePair(t time.Time, v float64) [2]interface{} {
+	return [2]interface{}{float64(t.Unix()), strconv.FormatFloat(v, 'f', -1, 64)}
+}
+
+func (s *stub) queryRange(w http.ResponseWriter, r *http.Request) {
+	start, err := parseTimeParam(r.FormValue("start"))
+	if err != nil {
+		writeError(w, "bad_data", fmt.Sprintf("invalid start: %v", err))
+		return
+	}
+	end, err := parseTimeParam(r.FormValue("end"))
+	if err != nil {
+		writeError(w, "bad_data", fmt.Sprintf("invalid end: %v", err))
+		return
+	}
+	step, err := parseDurationParam(r.FormValue("step"))
+	if err != nil || step <= 0 {
+		writeError(w, "bad_data", "invalid step")
+		return
+	}
+	if end.Sub(start)/step > 11000 {
+		writeError(w, "bad_data", "exceeded maximum resolution of 11,000 points per timeseries. Try decreasing the query resolution (?step=XX)")
+		return
+	}
+
+	query := r.FormValue("query")
+	data := queryData{ResultType: "matrix", Result: []interface{}{}}
+	for _, series := range s.matching(query) {
+		ms := matrixSeries{Metric: series.Labels}
+		for t := start; !t.After(end); t = t.Add(step) {
+			ms.Values = append(ms.Values, samplePair(t, series.valueAt(t)))
+		}
+		data.Result = append(data.Result, ms)
+	}
+	log.Printf("query_range %q returned %d series\n", query, len(data.Result))
+	writeJSON(w, apiResponse{Status: "success", Data: data})
+}
+
+func (s *stub) query(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+	if ts := r.FormValue("time"); len(ts) > 0 {
+		var err error
+		if t, err = parseTimeParam(ts); err != nil {
+			writeError(w, "bad_data", fmt.Sprintf("invalid time: %v", err))
+			return
+		}
+	}
+
+	query := r.FormValue("query")
+	data := queryData{ResultType: "vector", Result: []interface{}{}}
+	for _, series := range s.matching(query) {
+		data.Result = append(data.Result, vectorSample{Metric: series.Labels, Value: samplePair(t, series.valueAt(t))})
+	}
+	log.Printf("query %q returned %d series\n", query, len(data.Result))
+	writeJSON(w, apiResponse{Status: "success", Data: data})
+}
+
+// parseTimeParam parses a Prometheus API time - unix seconds (possibly fractional) or RFC3339
+func parseTimeParam(s string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parseDurationParam parses a Prometheus API duration - seconds (possibly fractional) or a Go duration
+func parseDurationParam(s string) (time.Duration, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func writeJSON(w http.ResponseWriter, resp apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != "success" {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Println("error writing response:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, errorType string, msg string) {
+	writeJSON(w, apiResponse{Status: "error", ErrorType: errorType, Error: msg})
+}
+
+func main() {
+	var listen, fixturePath string
+	flag.StringVar(&listen, "listen", "localhost:9090", "Address to listen on")
+	flag.StringVar(&fixturePath, "fixture", "fixture.yaml", "YAML file of synthetic series to serve")
+	flag.Parse()
+
+	// #nosec G304
+	data, err := ioutil.ReadFile(fixturePath)
+	if err != nil {
+		log.Fatalf("Failed to read fixture %s: %v\n", fixturePath, err)
+	}
+	s := &stub{}
+	if err := yaml.UnmarshalStrict(data, &s.fixture); err != nil {
+		log.Fatalf("Failed to parse fixture %s: %v\n", fixturePath, err)
+	}
+
+	http.HandleFunc("/api/v1/query_range", s.queryRange)
+	http.HandleFunc("/api/v1/query", s.query)
+
+	log.Printf("Serving %d synthetic series on %s\n", len(s.fixture.Series), listen)
+	// #nosec G114
+	log.Fatal(http.ListenAndServe(listen, nil))
+}
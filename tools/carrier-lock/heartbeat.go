@@ -0,0 +1,90 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2023 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// startHeartbeat - Start a detached carrier-lock process that keeps the lock renewed. It is owned by the
+// process that called acquire, which is the same process that will later call release.
+func startHeartbeat(token int64) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(heartbeatLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe,
+		"--kubeconfig", kubeconfig,
+		"--action", "heartbeat",
+		"--identity", identity,
+		"--fencing-token", strconv.FormatInt(token, 10),
+		"--lease-duration", leaseDuration.String(),
+		"--owner-pid", strconv.Itoa(os.Getppid()),
+	)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// Run in its own session so it isn't killed along with acquire's process group
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("%s Started heartbeat process %d, logging to %s\n", time.Now().Format(time.Stamp), cmd.Process.Pid, heartbeatLog)
+	return cmd.Process.Release()
+}
+
+// runHeartbeat - Renew the lock every third of the lease duration. Stops when the lock has been released or
+// taken over (the fencing token no longer matches), or when the owning process has exited, in which case the
+// lock is released straight away rather than left to expire. The owner is always on this host, so the PID check is valid.
+func runHeartbeat() {
+	ctx := context.TODO()
+	interval := leaseDuration / 3
+	lastRenew := time.Now()
+	for {
+		time.Sleep(interval)
+
+		if ownerPid > 0 {
+			running, err := pidExists(ownerPid)
+			if err != nil {
+				fmt.Printf("%s Error occurred when checking if owner PID %d is running: %v\n", time.Now().Format(time.Stamp), ownerPid, err)
+			} else if !running {
+				fmt.Printf("%s Owner PID %d of lock %s is no longer running, releasing the lock\n", time.Now().Format(time.Stamp), ownerPid, LockName)
+				if _, err := clearHolder(ctx, true, fencingToken); err != nil {
+					log.Fatalf("Failed to release lock %s: %v . It will expire after %s\n", LockName, err, leaseDuration)
+				}
+				return
+			}
+		}
+
+		err := renewLock(ctx, fencingToken)
+		if _, ok := err.(errNotHolder); ok {
+			fmt.Printf("%s Lock %s no longer held by %s with fencing token %d (%v), stopping heartbeat\n", time.Now().Format(time.Stamp), LockName, identity, fencingToken, err)
+			return
+		} else if err != nil {
+			// Keep trying - the lock survives until the lease duration has passed since the last successful renewal
+			fmt.Printf("%s Failed to renew lock %s, %s since last renewal: %v\n", time.Now().Format(time.Stamp), LockName, time.Since(lastRenew).Round(time.Second), err)
+			continue
+		}
+		lastRenew = time.Now()
+	}
+}
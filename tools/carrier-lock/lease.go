@@ -0,0 +1,361 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2023 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// FencingTokenAnnotation holds the fencing token issued to the most recent holder of the lock
+	FencingTokenAnnotation string = "armada-perf.ibm.com/fencing-token"
+	// NextTicketAnnotation holds the next queue ticket to hand out to a waiter
+	NextTicketAnnotation string = "armada-perf.ibm.com/next-ticket"
+)
+
+// errNotHolder is returned when renewing or releasing a lock that is held by someone else (or nobody)
+type errNotHolder struct {
+	holder string
+	token  int64
+}
+
+func (e errNotHolder) Error() string {
+	if len(e.holder) == 0 {
+		return fmt.Sprintf("lock is not held (last fencing token %d)", e.token)
+	}
+	return fmt.Sprintf("lock is held by %s with fencing token %d", e.holder, e.token)
+}
+
+// lockStatus - The json representation of the lock output by query
+type lockStatus struct {
+	Holder               string         `json:"holder,omitempty"`
+	FencingToken         int64          `json:"fencing-token"`
+	AcquireTime          string         `json:"acquire-time,omitempty"`
+	RenewTime            string         `json:"renew-time,omitempty"`
+	LeaseDurationSeconds int32          `json:"lease-duration-seconds,omitempty"`
+	Expired              bool           `json:"expired"`
+	Waiters              []waiterStatus `json:"waiters"`
+}
+
+// annotationInt - Read an integer annotation from a Lease, treating a missing or malformed value as 0
+func annotationInt(lease *coordinationv1.Lease, key string) int64 {
+	v, err := strconv.ParseInt(lease.Annotations[key], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// setAnnotationInt - Set an integer annotation on a Lease
+func setAnnotationInt(lease *coordinationv1.Lease, key string, value int64) {
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Annotations[key] = strconv.FormatInt(value, 10)
+}
+
+// holderOf - The holder identity of a Lease, or "" if there isn't one
+func holderOf(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// leaseExpired - Check whether a Lease has no holder, or hasn't been renewed within its lease duration
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if len(holderOf(lease)) == 0 || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return now.After(expiry)
+}
+
+// getLockLease - Get the lock Lease, creating an unheld one if it doesn't exist yet.
+// The Lease is never deleted once created, so the fencing token and ticket counter only ever increase.
+func getLockLease(ctx context.Context) (*coordinationv1.Lease, error) {
+	leases := kubeClient.CoordinationV1().Leases(Namespace)
+	lease, err := leases.Get(ctx, LockName, metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		return lease, err
+	}
+
+	fmt.Printf("%s Lease %s in namespace %s not found, it will be created\n", time.Now().Format(time.Stamp), LockName, Namespace)
+	lease = &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LockName,
+			Namespace: Namespace,
+		},
+	}
+	setAnnotationInt(lease, FencingTokenAnnotation, 0)
+	setAnnotationInt(lease, NextTicketAnnotation, 1)
+	lease, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// Someone else created it first
+		return leases.Get(ctx, LockName, metav1.GetOptions{})
+	}
+	return lease, err
+}
+
+// tryAcquire - Take the lock if it is free (or expired) and this waiter is at the head of the queue.
+// Returns the fencing token issued, or 0 if the lock could not be taken yet.
+func tryAcquire(ctx context.Context, ticket int64) (int64, error) {
+	var token int64
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		token = 0
+		lease, err := getLockLease(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		holder := holderOf(lease)
+		if holder == identity {
+			// Already ours (e.g. acquire re-run by the same caller) - keep the existing token
+			token = annotationInt(lease, FencingTokenAnnotation)
+		} else if !leaseExpired(lease, now) {
+			fmt.Printf("%s Lock %s is held by %s (fencing token %d), waiting...\n", now.Format(time.Stamp), LockName, holder, annotationInt(lease, FencingTokenAnnotation))
+			return nil
+		} else {
+			head, err := queueHead(ctx)
+			if err != nil {
+				return err
+			}
+			if head != 0 && head != ticket {
+				fmt.Printf("%s Lock %s is free but ticket %d is ahead of ticket %d in the queue, waiting...\n", now.Format(time.Stamp), LockName, head, ticket)
+				return nil
+			}
+			if len(holder) > 0 {
+				fmt.Printf("%s Lock %s held by %s has expired, taking it over\n", now.Format(time.Stamp), LockName, holder)
+			}
+			token = annotationInt(lease, FencingTokenAnnotation) + 1
+			lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+			transitions := int32(1)
+			if lease.Spec.LeaseTransitions != nil {
+				transitions += *lease.Spec.LeaseTransitions
+			}
+			lease.Spec.LeaseTransitions = &transitions
+		}
+
+		setAnnotationInt(lease, FencingTokenAnnotation, token)
+		lease.Spec.HolderIdentity = &identity
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseDurationSeconds = leaseDurationSeconds()
+		_, err = kubeClient.CoordinationV1().Leases(Namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return token, nil
+}
+
+// renewLock - Extend the lock, provided it is still held by this identity (and fencing token, if non-zero)
+func renewLock(ctx context.Context, token int64) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease, err := getLockLease(ctx)
+		if err != nil {
+			return err
+		}
+		currentToken := annotationInt(lease, FencingTokenAnnotation)
+		if holderOf(lease) != identity || (token != 0 && token != currentToken) {
+			return errNotHolder{holder: holderOf(lease), token: currentToken}
+		}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+		lease.Spec.LeaseDurationSeconds = leaseDurationSeconds()
+		_, err = kubeClient.CoordinationV1().Leases(Namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// clearHolder - Release the lock by clearing its holder. The Lease itself is kept so the fencing token survives.
+// If check is set, the lock is only released if it is still held by this identity (and fencing token, if non-zero).
+func clearHolder(ctx context.Context, check bool, token int64) (string, error) {
+	var previous string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease, err := getLockLease(ctx)
+		if err != nil {
+			return err
+		}
+		previous = holderOf(lease)
+		if len(previous) == 0 {
+			return nil
+		}
+		currentToken := annotationInt(lease, FencingTokenAnnotation)
+		if check && (previous != identity || (token != 0 && token != currentToken)) {
+			return errNotHolder{holder: previous, token: currentToken}
+		}
+		lease.Spec.HolderIdentity = nil
+		lease.Spec.RenewTime = nil
+		_, err = kubeClient.CoordinationV1().Leases(Namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+	return previous, err
+}
+
+// leaseDurationSeconds - The --lease-duration flag as the Lease spec expects it
+func leaseDurationSeconds() *int32 {
+	s := int32(leaseDuration / time.Second)
+	return &s
+}
+
+// acquireLock - Join the queue for the lock and wait until it can be taken, then start the heartbeat
+// and export the fencing token
+func acquireLock() {
+	ctx := context.TODO()
+	acquireExpiryTime := time.Now().Add(maxWaitTime)
+
+	ticket, err := enqueue(ctx)
+	if err != nil {
+		log.Fatalf("Unexpected Error occurred joining the queue for lock %s: %v . Will exit without taking lock \n", LockName, err)
+	}
+	fmt.Printf("%s Joined the queue for lock %s as %s with ticket %d\n", time.Now().Format(time.Stamp), LockName, identity, ticket)
+
+	var token int64
+	for token == 0 {
+		token, err = tryAcquire(ctx, ticket)
+		if err != nil {
+			dequeue(ctx, ticket)
+			log.Fatalf("Unexpected Error occurred attempting to take lock %s: %v . Lock status is unknown \n", LockName, err)
+		}
+		if token != 0 {
+			break
+		}
+		if time.Now().After(acquireExpiryTime) {
+			dequeue(ctx, ticket)
+			log.Fatalf("Unable to acquire the lock after maxWaitTime: %s . Will exit without taking lock \n", maxWaitTime)
+		}
+		time.Sleep(pollInterval)
+		if err := renewWaiter(ctx, ticket); err != nil {
+			fmt.Printf("%s Failed to renew queue ticket %d, will retry: %v\n", time.Now().Format(time.Stamp), ticket, err)
+		}
+	}
+	dequeue(ctx, ticket)
+	fmt.Printf("%s Lock %s acquired by %s with fencing token %d\n", time.Now().Format(time.Stamp), LockName, identity, token)
+
+	if len(tokenFile) > 0 {
+		if err := os.WriteFile(tokenFile, []byte(strconv.FormatInt(token, 10)+"\n"), 0644); err != nil {
+			log.Fatalf("Failed to write fencing token to %s: %v . Lock is held and will expire after %s\n", tokenFile, err, leaseDuration)
+		}
+	}
+	if heartbeat {
+		if err := startHeartbeat(token); err != nil {
+			log.Fatalf("Failed to start heartbeat: %v . Lock is held and will expire after %s\n", err, leaseDuration)
+		}
+	}
+
+	// Last line of output so callers can eval or parse it
+	fmt.Printf("CARRIER_LOCK_FENCING_TOKEN=%d\n", token)
+}
+
+// releaseLock - Release the lock
+func releaseLock(force bool) {
+	previous, err := clearHolder(context.TODO(), !force, fencingToken)
+	if _, ok := err.(errNotHolder); ok {
+		log.Fatalf("Lock is not held by %s: %v . Will not release the lock\n", identity, err)
+	} else if err != nil {
+		log.Fatalf("Unexpected Error occurred attempting to release the lock: %v .\n", err)
+	}
+	if len(previous) == 0 {
+		fmt.Printf("%s Lock %s in namespace %s is not held, nothing to do\n", time.Now().Format(time.Stamp), LockName, Namespace)
+		return
+	}
+	fmt.Printf("%s Lock held by %s Released\n", time.Now().Format(time.Stamp), previous)
+}
+
+// renewOnce - Renew the lock a single time. Exits non-zero if the lock has been lost, so callers can use it as a fencing check.
+func renewOnce() {
+	if err := renewLock(context.TODO(), fencingToken); err != nil {
+		log.Fatalf("Failed to renew lock %s for %s: %v\n", LockName, identity, err)
+	}
+	fmt.Printf("%s Lock %s renewed by %s for %s\n", time.Now().Format(time.Stamp), LockName, identity, leaseDuration)
+}
+
+// queryLock - Query the current lock status
+func queryLock() {
+	ctx := context.TODO()
+	lease, err := kubeClient.CoordinationV1().Leases(Namespace).Get(ctx, LockName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		log.Fatalf("Unexpected Error occurred attempting to get the Lease: %v \n", err)
+	}
+
+	status := lockStatus{Expired: true, Waiters: []waiterStatus{}}
+	if err == nil {
+		status.Holder = holderOf(lease)
+		status.FencingToken = annotationInt(lease, FencingTokenAnnotation)
+		status.Expired = leaseExpired(lease, time.Now())
+		if lease.Spec.AcquireTime != nil {
+			status.AcquireTime = lease.Spec.AcquireTime.String()
+		}
+		if lease.Spec.RenewTime != nil {
+			status.RenewTime = lease.Spec.RenewTime.String()
+		}
+		if lease.Spec.LeaseDurationSeconds != nil {
+			status.LeaseDurationSeconds = *lease.Spec.LeaseDurationSeconds
+		}
+	}
+	status.Waiters, err = listWaiters(ctx)
+	if err != nil {
+		log.Fatalf("Unexpected Error occurred attempting to list the waiters: %v \n", err)
+	}
+
+	// Print the result as json
+	b, err := json.Marshal(status)
+	if err != nil {
+		fmt.Printf("Error occurred marshalling results to json : %v", err)
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// cleanupLock - Release the lock if its holder has stopped renewing it, and remove any waiters that have gone away.
+// Expiry is judged from the Lease renew time, so this works regardless of which host took the lock.
+func cleanupLock() {
+	ctx := context.TODO()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease, err := getLockLease(ctx)
+		if err != nil {
+			return err
+		}
+		holder := holderOf(lease)
+		if len(holder) == 0 {
+			fmt.Printf("%s Lock %s in namespace %s is not held, nothing to do\n", time.Now().Format(time.Stamp), LockName, Namespace)
+			return nil
+		}
+		if !leaseExpired(lease, time.Now()) {
+			fmt.Printf("%s Lock held by %s was renewed at %s so will not clean up the lock\n", time.Now().Format(time.Stamp), holder, lease.Spec.RenewTime.Format(time.Stamp))
+			return nil
+		}
+		fmt.Printf("%s Lock held by %s has expired so will clean up the lock\n", time.Now().Format(time.Stamp), holder)
+		lease.Spec.HolderIdentity = nil
+		lease.Spec.RenewTime = nil
+		_, err = kubeClient.CoordinationV1().Leases(Namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.Fatalf("Unexpected Error occurred attempting to clean up the lock: %v .\n", err)
+	}
+
+	if _, err := queueHead(ctx); err != nil {
+		log.Fatalf("Unexpected Error occurred attempting to clean up expired waiters: %v .\n", err)
+	}
+}
@@ -0,0 +1,175 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2023 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Waiters are queued as one Lease each, named after a ticket taken from the lock Lease's counter.
+// The lock is only handed to the waiter holding the lowest live ticket, so waiters are served in FIFO order.
+// A waiter renews its Lease every poll, so one that has gone away (e.g. a killed Jenkins job) expires and
+// drops out of the queue rather than blocking everyone behind it.
+
+const (
+	// WaiterLabel marks Leases that represent queued waiters for the lock. The value is the lock name.
+	WaiterLabel string = "armada-perf.ibm.com/lock-waiter"
+	// TicketAnnotation holds the queue ticket of a waiter Lease
+	TicketAnnotation string = "armada-perf.ibm.com/ticket"
+)
+
+// waiterStatus - The json representation of a queued waiter output by query
+type waiterStatus struct {
+	Ticket    int64  `json:"ticket"`
+	Identity  string `json:"identity"`
+	QueuedAt  string `json:"queued-at,omitempty"`
+	RenewTime string `json:"renew-time,omitempty"`
+	Expired   bool   `json:"expired"`
+}
+
+// waiterName - The name of the Lease representing the waiter with the given ticket
+func waiterName(ticket int64) string {
+	return fmt.Sprintf("%s-waiter-%d", LockName, ticket)
+}
+
+// waiterDurationSeconds - How long a waiter stays in the queue without renewing. Waiters renew once per poll,
+// so allow a few missed polls before dropping them.
+func waiterDurationSeconds() *int32 {
+	d := 3 * pollInterval
+	if d < time.Minute {
+		d = time.Minute
+	}
+	s := int32(d / time.Second)
+	return &s
+}
+
+// buildWaiterLease - Build the Lease that holds this waiter's place in the queue
+func buildWaiterLease(ticket int64) *coordinationv1.Lease {
+	now := metav1.MicroTime{Time: time.Now()}
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      waiterName(ticket),
+			Namespace: Namespace,
+			Labels:    map[string]string{WaiterLabel: LockName},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &identity,
+			LeaseDurationSeconds: waiterDurationSeconds(),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+	setAnnotationInt(lease, TicketAnnotation, ticket)
+	return lease
+}
+
+// enqueue - Take the next ticket from the lock Lease and create a waiter Lease for it
+func enqueue(ctx context.Context) (int64, error) {
+	var ticket int64
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease, err := getLockLease(ctx)
+		if err != nil {
+			return err
+		}
+		ticket = annotationInt(lease, NextTicketAnnotation)
+		if ticket < 1 {
+			ticket = 1
+		}
+		setAnnotationInt(lease, NextTicketAnnotation, ticket+1)
+		_, err = kubeClient.CoordinationV1().Leases(Namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = kubeClient.CoordinationV1().Leases(Namespace).Create(ctx, buildWaiterLease(ticket), metav1.CreateOptions{})
+	return ticket, err
+}
+
+// renewWaiter - Keep this waiter's place in the queue. If the waiter Lease has been removed (e.g. by cleanup
+// after a long pause) it is recreated with the same ticket, so the place in the queue is kept.
+func renewWaiter(ctx context.Context, ticket int64) error {
+	leases := kubeClient.CoordinationV1().Leases(Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease, err := leases.Get(ctx, waiterName(ticket), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = leases.Create(ctx, buildWaiterLease(ticket), metav1.CreateOptions{})
+			return err
+		} else if err != nil {
+			return err
+		}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// dequeue - Remove this waiter from the queue. Failures are only logged - the waiter Lease expires on its own.
+func dequeue(ctx context.Context, ticket int64) {
+	err := kubeClient.CoordinationV1().Leases(Namespace).Delete(ctx, waiterName(ticket), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		fmt.Printf("%s Failed to remove queue ticket %d, it will expire: %v\n", time.Now().Format(time.Stamp), ticket, err)
+	}
+}
+
+// listWaiters - List the queued waiters in ticket order
+func listWaiters(ctx context.Context) ([]waiterStatus, error) {
+	list, err := kubeClient.CoordinationV1().Leases(Namespace).List(ctx, metav1.ListOptions{LabelSelector: WaiterLabel + "=" + LockName})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	waiters := make([]waiterStatus, 0, len(list.Items))
+	for i := range list.Items {
+		lease := &list.Items[i]
+		w := waiterStatus{
+			Ticket:   annotationInt(lease, TicketAnnotation),
+			Identity: holderOf(lease),
+			Expired:  leaseExpired(lease, now),
+		}
+		if lease.Spec.AcquireTime != nil {
+			w.QueuedAt = lease.Spec.AcquireTime.String()
+		}
+		if lease.Spec.RenewTime != nil {
+			w.RenewTime = lease.Spec.RenewTime.String()
+		}
+		waiters = append(waiters, w)
+	}
+	sort.Slice(waiters, func(i, j int) bool { return waiters[i].Ticket < waiters[j].Ticket })
+	return waiters, nil
+}
+
+// queueHead - The lowest live ticket in the queue, or 0 if the queue is empty. Expired waiters are removed.
+func queueHead(ctx context.Context) (int64, error) {
+	waiters, err := listWaiters(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, w := range waiters {
+		if w.Expired {
+			fmt.Printf("%s Removing expired queue ticket %d for %s\n", time.Now().Format(time.Stamp), w.Ticket, w.Identity)
+			dequeue(ctx, w.Ticket)
+			continue
+		}
+		return w.Ticket, nil
+	}
+	return 0, nil
+}
@@ -2,7 +2,7 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2020 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2020, 2023 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
@@ -19,15 +19,20 @@ import (
 )
 
 func main() {
-	var generate, encrypt, decrypt bool
+	var generate, encrypt, decrypt, rotate, envelope, dryRun bool
 
 	var key string
+	var kmsKeyFile string
 	var data string
 
 	flag.BoolVar(&generate, "generate", false, "Generate and use a new encryption key")
 	flag.BoolVar(&encrypt, "encrypt", false, "Encrypt input data")
 	flag.BoolVar(&decrypt, "decrypt", false, "Decrypt input data")
-	flag.StringVar(&key, "key", "", "Existing encryption key to be used")
+	flag.BoolVar(&rotate, "rotate", false, "Re-encrypt the encrypted values in the given TOML files in place with the current key")
+	flag.BoolVar(&envelope, "envelope", false, "Use envelope encryption with a master key from the KMS key file when encrypting or rotating")
+	flag.BoolVar(&dryRun, "dry-run", false, "With -rotate, report the values that would be re-encrypted without changing any files")
+	flag.StringVar(&key, "key", "", "Existing encryption key(s) to be used. Comma separated - the first key encrypts, all keys decrypt")
+	flag.StringVar(&kmsKeyFile, "kms-keyfile", "", fmt.Sprintf("File of master keys for envelope encryption, one per line, current key first. Overrides %s", utils.KMSKeyFileEnvVar))
 
 	flag.Parse()
 
@@ -39,6 +44,9 @@ func main() {
 	if len(key) != 0 {
 		os.Setenv(utils.KeyEnvVar, key)
 	}
+	if len(kmsKeyFile) != 0 {
+		os.Setenv(utils.KMSKeyFileEnvVar, kmsKeyFile)
+	}
 
 	if generate {
 		key, err := utils.GenerateKey()
@@ -46,11 +54,23 @@ func main() {
 			log.Fatalf("%s\n", err.Error())
 		}
 
+		kr, err := utils.NewKeyring(key)
+		if err != nil {
+			log.Fatalf("%s\n", err.Error())
+		}
+
 		fmt.Printf("Cipher Key: %s\n", key)
+		fmt.Printf("Key ID: %s\n", kr.PrimaryID())
 	}
 
 	if encrypt {
-		ciphertext, err := utils.Encrypt(data)
+		var ciphertext string
+		var err error
+		if envelope {
+			ciphertext, err = envelopeEncrypt(data)
+		} else {
+			ciphertext, err = utils.Encrypt(data)
+		}
 		if err != nil {
 			log.Fatalf("%s\n", err.Error())
 		}
@@ -68,4 +88,66 @@ func main() {
 
 		fmt.Printf("%s\n", plaintext)
 	}
+
+	if rotate {
+		rotateFiles(flag.Args(), envelope, dryRun)
+	}
+}
+
+// envelopeEncrypt encrypts data with a master key from the KMS key file
+func envelopeEncrypt(data string) (string, error) {
+	kms, err := utils.KMSFromEnv()
+	if err != nil {
+		return "", err
+	}
+	if kms == nil {
+		return "", fmt.Errorf("No master key file specified. Please set \"%s\" environment variable or use -kms-keyfile", utils.KMSKeyFileEnvVar)
+	}
+	return utils.EnvelopeEncrypt(kms, data)
+}
+
+// rotateFiles re-encrypts the values in each file with the current key (or master key in envelope mode)
+func rotateFiles(files []string, envelope, dryRun bool) {
+	if len(files) == 0 {
+		log.Fatalf("No TOML files specified to rotate\n")
+	}
+
+	var r rotator
+	var err error
+	r.envelope = envelope
+	if len(os.Getenv(utils.KeyEnvVar)) > 0 {
+		r.keyring, err = utils.KeyringFromEnv()
+		if err != nil {
+			log.Fatalf("%s\n", err.Error())
+		}
+	} else if !envelope {
+		log.Fatalf("No cipher key specified. Please set \"%s\" environment variable or use -key\n", utils.KeyEnvVar)
+	}
+	if kmsPath := os.Getenv(utils.KMSKeyFileEnvVar); len(kmsPath) > 0 {
+		r.kms, err = utils.LoadKMSKeyFile(kmsPath)
+		if err != nil {
+			log.Fatalf("%s\n", err.Error())
+		}
+	} else if envelope {
+		log.Fatalf("No master key file specified. Please set \"%s\" environment variable or use -kms-keyfile\n", utils.KMSKeyFileEnvVar)
+	}
+
+	var target string
+	if envelope {
+		target = "master key " + r.kms.PrimaryID()
+	} else {
+		target = "key " + r.keyring.PrimaryID()
+	}
+
+	for _, file := range files {
+		changed, err := r.rotateFile(file, dryRun)
+		if err != nil {
+			log.Fatalf("Failed to rotate %s : %s\n", file, err.Error())
+		}
+		if dryRun {
+			fmt.Printf("%s: %d value(s) would be re-encrypted with %s\n", file, changed, target)
+		} else {
+			fmt.Printf("%s: %d value(s) re-encrypted with %s\n", file, changed, target)
+		}
+	}
 }
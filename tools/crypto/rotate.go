@@ -0,0 +1,156 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2023 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.ibm.com/alchemy-containers/armada-performance/tools/crypto/utils"
+)
+
+// tomlStringValue matches a `key = "value"` line, keeping the text either side of the value so that
+// comments and formatting are left untouched when the value is replaced
+var tomlStringValue = regexp.MustCompile(`^(\s*[^#=\s][^=]*=\s*")([^"]*)(".*)$`)
+
+// rotator re-encrypts values with the current key. Values are decrypted with whichever key (or master key)
+// they were encrypted with, so every key still in use must be in the keyring or master key file.
+type rotator struct {
+	keyring  *utils.Keyring // Direct mode keys, from STAGE_GLOBAL_ARMPERF_CRYPTOKEY
+	kms      *utils.Keyring // Envelope mode master keys, from the local KMS key file
+	envelope bool           // Re-encrypt in envelope mode rather than direct mode
+}
+
+// rotateValue returns the value re-encrypted with the current key. The bool result is false if the value
+// is already encrypted with the current key, or isn't encrypted at all.
+func (r rotator) rotateValue(value string) (string, bool, error) {
+	var plaintext string
+	var err error
+
+	format, keyID := utils.CiphertextKeyID(value)
+	switch format {
+	case utils.FormatDirect:
+		if r.keyring == nil {
+			return "", false, fmt.Errorf("value encrypted with key %s but no cipher key specified", keyID)
+		}
+		if !r.envelope && keyID == r.keyring.PrimaryID() {
+			return value, false, nil
+		}
+		plaintext, err = r.keyring.Decrypt(value)
+	case utils.FormatEnvelope:
+		if r.kms == nil {
+			return "", false, fmt.Errorf("value envelope encrypted with master key %s but no master key file specified", keyID)
+		}
+		if r.envelope && keyID == r.kms.PrimaryID() {
+			return value, false, nil
+		}
+		plaintext, err = utils.EnvelopeDecrypt(r.kms, value)
+	case "":
+		// Legacy values are bare hex. Anything that doesn't decrypt with one of our keys isn't ours to rotate.
+		if r.keyring == nil || !isLegacyCiphertext(value) {
+			return value, false, nil
+		}
+		plaintext, err = r.keyring.Decrypt(value)
+		if err != nil {
+			return value, false, nil
+		}
+	default:
+		return value, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	var rotated string
+	if r.envelope {
+		rotated, err = utils.EnvelopeEncrypt(r.kms, plaintext)
+	} else {
+		rotated, err = r.keyring.Encrypt(plaintext)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return rotated, true, nil
+}
+
+// isLegacyCiphertext checks if a value could be unversioned ciphertext - hex encoded nonce, data and GCM tag
+func isLegacyCiphertext(value string) bool {
+	const minLength = 2 * (12 + 16)
+	if len(value) < minLength {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// rotateFile re-encrypts the encrypted values in a TOML file in place, returning the number of values changed.
+// The file is only rewritten if something changed and dryRun isn't set.
+func (r rotator) rotateFile(path string, dryRun bool) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var out bytes.Buffer
+	changed := 0
+	lineNum := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+		if m := tomlStringValue.FindStringSubmatch(line); m != nil {
+			rotated, ok, err := r.rotateValue(m[2])
+			if err != nil {
+				return 0, fmt.Errorf("%s:%d (%s): %s", path, lineNum, strings.TrimSpace(strings.SplitN(m[1], "=", 2)[0]), err.Error())
+			}
+			if ok {
+				line = m[1] + rotated + m[3]
+				changed++
+			}
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if changed == 0 || dryRun {
+		return changed, nil
+	}
+
+	// Write to a temporary file and rename it over the original, so the file is never left half written
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".rotate-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return changed, os.Rename(tmp.Name(), path)
+}
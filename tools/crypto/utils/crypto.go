@@ -14,105 +14,266 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 // KeyEnvVar is the name of encryption key environment variable.
+// It holds one or more comma separated hex encoded keys. The first key is used for encryption, all of them
+// can be used for decryption, which allows a new key to be introduced before the old one is retired.
 const KeyEnvVar = "STAGE_GLOBAL_ARMPERF_CRYPTOKEY"
 
-// GenerateKey will generate a random 32 byte key
-func GenerateKey() (string, error) {
-	keyBytes := make([]byte, 32)
-	_, err := rand.Read(keyBytes)
-	if err != nil {
-		return "", fmt.Errorf("Error generating ciper key : %s", err.Error())
-	}
+// Versioned ciphertext formats. Unversioned (legacy) ciphertext is plain hex with no header.
+//
+//	v2:<key id>:<hex nonce+ciphertext>
+//	v2e:<master key id>:<hex wrapped data key>:<hex nonce+ciphertext>
+//
+// The header is passed to AES-GCM as additional data, so it can't be altered without detection.
+const (
+	// FormatDirect identifies data encrypted directly with a key from the keyring
+	FormatDirect = "v2"
+	// FormatEnvelope identifies data encrypted with a data key wrapped by a master key
+	FormatEnvelope = "v2e"
+	headerSep      = ":"
+)
 
-	key := fmt.Sprintf("%x", keyBytes)
+// Keyring holds a set of AES keys indexed by key ID. The primary key is used for encryption.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+	order   []string
+}
 
-	os.Setenv(KeyEnvVar, key)
+// KeyID returns the identifier for a key - the first 8 hex characters of its SHA-256 digest.
+// The ID is derived from the key itself, so no separate key naming needs to be kept in step.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
 
-	return key, nil
+// NewKeyring creates a keyring from hex encoded keys. The first key is the primary key.
+func NewKeyring(hexKeys ...string) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string][]byte)}
+	for _, hk := range hexKeys {
+		hk = strings.TrimSpace(hk)
+		if len(hk) == 0 {
+			continue
+		}
+		key, err := hex.DecodeString(hk)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed cipher key. Hexadecimal string expected : %s", err.Error())
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("Invalid cipher key : %s", err.Error())
+		}
+		id := KeyID(key)
+		if _, ok := kr.keys[id]; ok {
+			continue
+		}
+		if len(kr.primary) == 0 {
+			kr.primary = id
+		}
+		kr.keys[id] = key
+		kr.order = append(kr.order, id)
+	}
+	if len(kr.primary) == 0 {
+		return nil, fmt.Errorf("No cipher key specified")
+	}
+	return kr, nil
 }
 
-// Encrypt will encrypt the given input data with the armada_performance_crytpo_key
-// The crypto key is stored in vault and must be set in the STAGE_GLOBAL_ARMPERF_CRYPTOKEY env var before calling this method
-// INPUT: Text to encrypt
-// OUTPUT: Hex encoded encrypted input data
-func Encrypt(plaintext string) (string, error) {
+// KeyringFromEnv creates a keyring from the keys in the STAGE_GLOBAL_ARMPERF_CRYPTOKEY env var
+func KeyringFromEnv() (*Keyring, error) {
 	ck := os.Getenv(KeyEnvVar)
 	if len(ck) == 0 {
-		return "", fmt.Errorf("No cipher key specified. Please set \"%s\" environment variable", KeyEnvVar)
+		return nil, fmt.Errorf("No cipher key specified. Please set \"%s\" environment variable", KeyEnvVar)
 	}
+	return NewKeyring(strings.Split(ck, ",")...)
+}
+
+// PrimaryID returns the ID of the key used for encryption
+func (kr *Keyring) PrimaryID() string {
+	return kr.primary
+}
 
-	key, err := hex.DecodeString(ck)
+// IDs returns the IDs of all keys in the keyring, primary first
+func (kr *Keyring) IDs() []string {
+	return append([]string(nil), kr.order...)
+}
+
+// Encrypt encrypts the plaintext with the primary key
+// OUTPUT: v2:<key id>:<hex encoded encrypted data>
+func (kr *Keyring) Encrypt(plaintext string) (string, error) {
+	header := FormatDirect + headerSep + kr.primary
+	sealed, err := seal(kr.keys[kr.primary], []byte(plaintext), []byte(header))
 	if err != nil {
-		return "", fmt.Errorf("Malformed cipher key. Hexadecimal string expected : %s", err.Error())
+		return "", err
+	}
+	return header + headerSep + hex.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts direct mode (v2) or legacy ciphertext.
+// Legacy ciphertext has no key ID so each key is tried in turn.
+func (kr *Keyring) Decrypt(data string) (string, error) {
+	if !strings.Contains(data, headerSep) {
+		ciphertext, err := hex.DecodeString(data)
+		if err != nil {
+			return "", fmt.Errorf("Invalid cipher text supplied : %s", err.Error())
+		}
+		for _, id := range kr.order {
+			if plaintext, err := open(kr.keys[id], ciphertext, nil); err == nil {
+				return string(plaintext), nil
+			}
+		}
+		return "", fmt.Errorf("Failed to decrypt cipher text : no key in the keyring matches")
 	}
 
-	cb, err := aes.NewCipher(key)
+	parts := strings.Split(data, headerSep)
+	if parts[0] != FormatDirect {
+		return "", fmt.Errorf("Unsupported cipher text format \"%s\"", parts[0])
+	}
+	if len(parts) != 3 {
+		return "", fmt.Errorf("Invalid cipher text supplied : expected %s:<key id>:<data>", FormatDirect)
+	}
+	key, ok := kr.keys[parts[1]]
+	if !ok {
+		return "", fmt.Errorf("Failed to decrypt cipher text : key %s is not in the keyring", parts[1])
+	}
+	ciphertext, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("Invalid cipher text supplied : %s", err.Error())
+	}
+	plaintext, err := open(key, ciphertext, []byte(parts[0]+headerSep+parts[1]))
 	if err != nil {
-		return "", fmt.Errorf("Failed to create cipher block : %s", err.Error())
+		return "", err
 	}
+	return string(plaintext), nil
+}
 
-	aesgcm, err := cipher.NewGCM(cb)
+// WrapKey encrypts a data key with the primary key, so a keyring can act as a local KMS for envelope encryption
+func (kr *Keyring) WrapKey(dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(kr.keys[kr.primary], dataKey, []byte(FormatEnvelope+headerSep+kr.primary))
+	return kr.primary, wrapped, err
+}
+
+// UnwrapKey decrypts a data key that was wrapped with the given key
+func (kr *Keyring) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("Failed to unwrap data key : master key %s is not available", keyID)
+	}
+	return open(key, wrapped, []byte(FormatEnvelope+headerSep+keyID))
+}
+
+// CiphertextKeyID returns the format and key ID of a ciphertext. Legacy ciphertext has an empty format and key ID.
+func CiphertextKeyID(data string) (format string, keyID string) {
+	parts := strings.SplitN(data, headerSep, 3)
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// seal encrypts with AES-GCM, prefixing the random nonce to the result
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aesgcm, err := newGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("Failed to generate GCM block cipher : %s", err.Error())
+		return nil, err
 	}
 
 	iv := make([]byte, aesgcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", fmt.Errorf("Failed to generate initialization vector : %s", err.Error())
+		return nil, fmt.Errorf("Failed to generate initialization vector : %s", err.Error())
 	}
 
-	ciphertext := aesgcm.Seal(iv, iv, []byte(plaintext), nil)
-
-	return hex.EncodeToString(ciphertext), nil
+	return aesgcm.Seal(iv, iv, plaintext, additionalData), nil
 }
 
-// Decrypt will decrypt the given input data with the STAGE_GLOBAL_ARMPERF_CRYPTOKEY
-// The crypto key is stored in vault and must be set in the STAGE_GLOBAL_ARMPERF_CRYPTOKEY env var before calling this method
-// INPUT: Hex encoded encrypted data
-// OUTPUT: Decrypted text
-func Decrypt(data string) (string, error) {
-	ciphertext, err := hex.DecodeString(data)
+// open decrypts the output of seal
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aesgcm, err := newGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("Invalid cipher text supplied : %s", err.Error())
+		return nil, err
 	}
 
-	ck := os.Getenv(KeyEnvVar)
-	if len(ck) == 0 {
-		return "", fmt.Errorf("No cipher key specified. Please set \"%s\" environment variable", KeyEnvVar)
+	ivSize := aesgcm.NonceSize()
+	if len(ciphertext) < ivSize {
+		return nil, fmt.Errorf("Missing initialisation vector in cipher text")
 	}
 
-	key, err := hex.DecodeString(ck)
+	iv, ciphertext := ciphertext[:ivSize], ciphertext[ivSize:]
+	plaintext, err := aesgcm.Open(nil, iv, ciphertext, additionalData)
 	if err != nil {
-		return "", fmt.Errorf("Failed to process cipher key : %s", err.Error())
+		return nil, fmt.Errorf("Failed to decrypt cipher text : %s", err.Error())
 	}
+	return plaintext, nil
+}
 
+func newGCM(key []byte) (cipher.AEAD, error) {
 	cb, err := aes.NewCipher(key)
 	if err != nil {
-		return "", fmt.Errorf("Failed to create cipher block : %s", err.Error())
+		return nil, fmt.Errorf("Failed to create cipher block : %s", err.Error())
 	}
 
 	aesgcm, err := cipher.NewGCM(cb)
 	if err != nil {
-		return "", fmt.Errorf("Failed to generate GCM block cipher : %s", err.Error())
+		return nil, fmt.Errorf("Failed to generate GCM block cipher : %s", err.Error())
 	}
+	return aesgcm, nil
+}
 
-	ivSize := aesgcm.NonceSize()
-	if len(ciphertext) < ivSize {
-		return "", fmt.Errorf("Missing initialisation vector in cipher text")
+// GenerateKey will generate a random 32 byte key
+func GenerateKey() (string, error) {
+	keyBytes := make([]byte, 32)
+	_, err := rand.Read(keyBytes)
+	if err != nil {
+		return "", fmt.Errorf("Error generating ciper key : %s", err.Error())
 	}
 
-	iv, ciphertext := ciphertext[:ivSize], ciphertext[ivSize:]
-	plaintext, err := aesgcm.Open(nil, iv, ciphertext, nil)
+	key := fmt.Sprintf("%x", keyBytes)
+
+	os.Setenv(KeyEnvVar, key)
+
+	return key, nil
+}
+
+// Encrypt will encrypt the given input data with the armada_performance_crytpo_key
+// The crypto key is stored in vault and must be set in the STAGE_GLOBAL_ARMPERF_CRYPTOKEY env var before calling this method
+// If the env var holds several keys, the first one is used
+// INPUT: Text to encrypt
+// OUTPUT: Versioned, hex encoded encrypted input data
+func Encrypt(plaintext string) (string, error) {
+	kr, err := KeyringFromEnv()
 	if err != nil {
-		return "", fmt.Errorf("Failed to decrypt cipher text : %s", err.Error())
+		return "", err
 	}
+	return kr.Encrypt(plaintext)
+}
 
-	return string(plaintext), nil
+// Decrypt will decrypt the given input data with the STAGE_GLOBAL_ARMPERF_CRYPTOKEY
+// The crypto key is stored in vault and must be set in the STAGE_GLOBAL_ARMPERF_CRYPTOKEY env var before calling this method
+// Envelope encrypted data needs the master key file in ARMPERF_CRYPTO_KMS_KEYFILE instead.
+// INPUT: Versioned or legacy hex encoded encrypted data
+// OUTPUT: Decrypted text
+func Decrypt(data string) (string, error) {
+	if format, _ := CiphertextKeyID(data); format == FormatEnvelope {
+		kms, err := KMSFromEnv()
+		if err != nil {
+			return "", err
+		}
+		if kms == nil {
+			return "", fmt.Errorf("Envelope encrypted data supplied but no master key file specified. Please set \"%s\" environment variable", KMSKeyFileEnvVar)
+		}
+		return EnvelopeDecrypt(kms, data)
+	}
+
+	kr, err := KeyringFromEnv()
+	if err != nil {
+		return "", err
+	}
+	return kr.Decrypt(data)
 }
@@ -0,0 +1,124 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2023 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package utils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KMSKeyFileEnvVar is the name of the environment variable holding the path of the local master key file.
+// In envelope mode each value gets its own random data key, which is wrapped by a master key from this file.
+const KMSKeyFileEnvVar = "ARMPERF_CRYPTO_KMS_KEYFILE"
+
+// KeyProvider wraps and unwraps data keys with a master key. It is the extension point for a real KMS -
+// the local implementation is a Keyring loaded from a file.
+type KeyProvider interface {
+	// WrapKey encrypts a data key with the current master key, returning the master key's ID
+	WrapKey(dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key with the identified master key
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// LoadKMSKeyFile creates a local KMS stand-in from a file of hex encoded master keys, one per line.
+// The first key is the current master key. Blank lines and lines starting with # are ignored.
+func LoadKMSKeyFile(path string) (*Keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open master key file : %s", err.Error())
+	}
+	defer f.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read master key file : %s", err.Error())
+	}
+
+	kr, err := NewKeyring(keys...)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid master key file %s : %s", path, err.Error())
+	}
+	return kr, nil
+}
+
+// KMSFromEnv loads the local master key file named by ARMPERF_CRYPTO_KMS_KEYFILE.
+// Returns nil if the env var isn't set.
+func KMSFromEnv() (KeyProvider, error) {
+	path := os.Getenv(KMSKeyFileEnvVar)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	kms, err := LoadKMSKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return kms, nil
+}
+
+// EnvelopeEncrypt encrypts the plaintext with a new random data key, and wraps the data key with the master key
+// OUTPUT: v2e:<master key id>:<hex encoded wrapped data key>:<hex encoded encrypted data>
+func EnvelopeEncrypt(kms KeyProvider, plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("Error generating data key : %s", err.Error())
+	}
+
+	keyID, wrapped, err := kms.WrapKey(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := strings.Join([]string{FormatEnvelope, keyID, hex.EncodeToString(wrapped)}, headerSep)
+	sealed, err := seal(dataKey, []byte(plaintext), []byte(header))
+	if err != nil {
+		return "", err
+	}
+	return header + headerSep + hex.EncodeToString(sealed), nil
+}
+
+// EnvelopeDecrypt unwraps the data key with the master key and decrypts the data
+func EnvelopeDecrypt(kms KeyProvider, data string) (string, error) {
+	parts := strings.Split(data, headerSep)
+	if len(parts) != 4 || parts[0] != FormatEnvelope {
+		return "", fmt.Errorf("Invalid cipher text supplied : expected %s:<key id>:<data key>:<data>", FormatEnvelope)
+	}
+
+	wrapped, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("Invalid wrapped data key supplied : %s", err.Error())
+	}
+	dataKey, err := kms.UnwrapKey(parts[1], wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return "", fmt.Errorf("Invalid cipher text supplied : %s", err.Error())
+	}
+	plaintext, err := open(dataKey, ciphertext, []byte(strings.Join(parts[:3], headerSep)))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -2,30 +2,49 @@
  *
  * OCO Source Materials
  * , 5737-D43
- * (C) Copyright IBM Corp. 2017, 2018 All Rights Reserved.
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
  * The source code for this program is not  published or otherwise divested of
  * its trade secrets,  * irrespective of what has been deposited with
  * the U.S. Copyright Office.
  ******************************************************************************/
 
-// Scan a nmon file and report in unexpected gaps in collecting data
+// Analyse a nmon file - report unexpected gaps in collecting data, CPU saturation and disk busy,
+// or convert the data to CSV or metrics
 
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.ibm.com/alchemy-containers/armada-performance/tools/nmonGaps/nmon"
+)
+
+var (
+	report       string
+	section      string
+	slack        time.Duration
+	cpuThreshold float64
+	minSamples   int
+	diskLimit    float64
 )
 
 func main() {
-	nmonFile := os.Stdin
+	flag.StringVar(&report, "report", "gaps", "Report to produce - gaps, cpu, disk, csv or metrics")
+	flag.StringVar(&section, "section", nmon.SectionCPUAll, "Section to write with -report csv, e.g. CPU_ALL, MEM, DISKBUSY, NET or TOP")
+	flag.DurationVar(&slack, "slack", 2*time.Second, "Gaps shorter than the interval plus this aren't reported")
+	flag.Float64Var(&cpuThreshold, "cpu-threshold", 90, "CPU busy percentage (User% + Sys%) considered saturated")
+	flag.IntVar(&minSamples, "min-samples", 2, "Minimum number of consecutive saturated snapshots to report")
+	flag.Float64Var(&diskLimit, "disk-limit", 80, "Disk busy percentage counted as over the limit")
+	metricsFlags()
+	flag.Parse()
 
-	if len(os.Args) > 1 && len(os.Args[1]) > 0 {
-		nmonFilePath := os.Args[1]
+	nmonFile := os.Stdin
+	if len(flag.Args()) > 0 && len(flag.Args()[0]) > 0 {
+		nmonFilePath := flag.Args()[0]
 
 		// Open files
 		var err error
@@ -35,53 +54,70 @@ func main() {
 			fmt.Println("ERROR: Opening file: ", err)
 			os.Exit(1)
 		}
+		defer nmonFile.Close()
 	}
 
-	var standardDelta time.Duration
-	var lastLine string
-	var lastTime time.Time
-
-	scanner := bufio.NewScanner(nmonFile)
-
-	for scanner.Scan() {
-		if strings.HasPrefix(scanner.Text(), "AAA,interval") {
-			items := strings.Split(scanner.Text(), ",")
-			seconds, err := strconv.ParseInt(items[2], 10, 64)
-			if err != nil {
-				fmt.Printf("Bad conversion of interval: %s - %v\n", items[2], err)
-			}
-			standardDelta = time.Duration(seconds) * time.Second
-			//fmt.Printf("Expected interval is %d seconds\n", seconds)
-			break
-		}
+	f, err := nmon.Parse(nmonFile)
+	if err != nil {
+		fmt.Println("ERROR: Parsing file: ", err)
+		os.Exit(1)
 	}
 
-	minReportableDelta := standardDelta + 2*time.Second
-
-	for scanner.Scan() {
-		// Find each of these lines: ZZZZ,T0001,16:20:52,05-DEC-2017
-		if strings.HasPrefix(scanner.Text(), "ZZZZ,T") {
-			items := strings.Split(scanner.Text(), ",")
+	switch report {
+	case "gaps":
+		reportGaps(f)
+	case "cpu":
+		reportCPUSaturation(f)
+	case "disk":
+		reportDiskBusy(f)
+	case "csv":
+		err = writeCSV(f)
+	case "metrics":
+		err = writeMetrics(f)
+	default:
+		err = fmt.Errorf("unknown report %q - must be gaps, cpu, disk, csv or metrics", report)
+	}
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+		os.Exit(1)
+	}
+}
 
-			// Extract the time from the line
-			currentTime, err := time.Parse("15:04:05,02-Jan-2006", items[2]+","+items[3])
-			if err != nil {
-				fmt.Printf("ERROR: Bad conversion of time %s - %v\n", items[2]+","+items[3], err)
-			}
-			//fmt.Println(currentTime)
+// reportGaps prints the snapshot, date, time and excess delay of each gap
+func reportGaps(f *nmon.File) {
+	for _, g := range nmon.Gaps(f, slack) {
+		fmt.Printf("%s %s %s %v\n", g.Snapshot.Tag, strings.ToUpper(g.Snapshot.Time.Format("02-Jan-2006")), g.Snapshot.Time.Format("15:04:05"), g.Excess)
+	}
+}
 
-			// If no previous delta then just save time and continue
-			if len(lastLine) != 0 {
-				// Calculate delta and compare to expected delta, if changed print this and previos T#, Time, Delta
-				currentDelta := currentTime.Sub(lastTime)
+// reportCPUSaturation prints the windows in which the CPU was saturated
+func reportCPUSaturation(f *nmon.File) {
+	windows := nmon.CPUSaturation(f, cpuThreshold, minSamples)
+	if len(windows) == 0 {
+		fmt.Printf("No CPU saturation (>= %.1f%% for %d snapshots) found\n", cpuThreshold, minSamples)
+		return
+	}
+	for _, w := range windows {
+		fmt.Printf("%s - %s %v %d snapshots peak %.1f%% mean %.1f%%\n", w.Start.Format("15:04:05"), w.End.Format("15:04:05"), w.Duration(), w.Samples, w.Peak, w.Mean)
+	}
+}
 
-				if currentDelta != standardDelta && currentDelta >= minReportableDelta && items[1] != "T0003" {
-					fmt.Printf("%s %s %s %v\n", items[1], items[3], items[2], currentDelta-standardDelta)
-				}
-			}
+// reportDiskBusy prints a summary per disk, busiest first
+func reportDiskBusy(f *nmon.File) {
+	fmt.Printf("%-20s %8s %8s %8s %8s\n", "Disk", "Mean%", "P95%", "Max%", fmt.Sprintf(">=%.0f%%", diskLimit))
+	for _, d := range nmon.DiskBusy(f, diskLimit) {
+		fmt.Printf("%-20s %8.1f %8.1f %8.1f %8d\n", d.Disk, d.Stats.Mean, d.Stats.P95, d.Stats.Max, d.OverLimit)
+	}
+}
 
-			lastTime = currentTime
-			lastLine = scanner.Text()
-		}
+// writeCSV writes the selected section to stdout
+func writeCSV(f *nmon.File) error {
+	if section == nmon.SectionTop {
+		return f.WriteTopCSV(os.Stdout)
+	}
+	t := f.Table(section)
+	if t == nil {
+		return fmt.Errorf("section %s not found", section)
 	}
+	return f.WriteCSV(os.Stdout, t)
 }
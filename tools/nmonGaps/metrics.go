@@ -0,0 +1,87 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	metricsservice "github.ibm.com/alchemy-containers/armada-performance/metrics/bluemix"
+	"github.ibm.com/alchemy-containers/armada-performance/tools/nmonGaps/nmon"
+)
+
+var (
+	verbose         bool
+	testName        string
+	dbKey           string
+	metricsTestName string
+	metricSections  string
+)
+
+// metricNameReplacer makes nmon host and column names safe to use as parts of a dotted metric name
+var metricNameReplacer = strings.NewReplacer("%", "_pct", "/", "_per_", ".", "_", ",", "_", " ", "_")
+
+func metricsFlags() {
+	flag.StringVar(&testName, "testname", "", "Test name in Jenkins - only needed if sending alerts to RazeeDash")
+	flag.StringVar(&dbKey, "dbkey", "", "Metrics database key - only needed if sending metrics to database")
+	flag.StringVar(&metricsTestName, "metricsTestName", "nmon", "Name to be used at the start of the metrics path")
+	flag.StringVar(&metricSections, "metricSections", strings.Join([]string{nmon.SectionCPUAll, nmon.SectionMem, nmon.SectionDiskBusy, nmon.SectionNet}, ","), "Comma separated sections to send with -report metrics")
+	flag.BoolVar(&verbose, "verbose", false, "verbose output")
+}
+
+// toBluemixMetrics converts every sample of the selected sections to a metric named
+// <metricsTestName>.<host>.<section>.<column>, timestamped with its snapshot time.
+// TOP isn't converted - there is a series per process, which is too many for the metrics service.
+func toBluemixMetrics(f *nmon.File, sections []string) []metricsservice.BluemixMetric {
+	host := metricNameReplacer.Replace(f.Header.Host)
+	var tables []*nmon.Table
+	for _, s := range sections {
+		if s == nmon.SectionDiskBusy {
+			tables = append(tables, f.DiskBusy()...)
+		} else if t := f.Table(s); t != nil {
+			tables = append(tables, t)
+		}
+	}
+
+	var bm []metricsservice.BluemixMetric
+	for _, t := range tables {
+		for _, s := range t.AllSeries() {
+			column := s.Name[len(t.Name)+1:]
+			name := strings.Join([]string{metricsTestName, host, t.Name, metricNameReplacer.Replace(column)}, ".")
+			for _, p := range s.Points {
+				bm = append(bm, metricsservice.BluemixMetric{Name: name, Timestamp: p.Time.Unix(), Value: p.Value})
+			}
+		}
+	}
+	return bm
+}
+
+// writeMetrics sends the selected sections to the metrics service
+func writeMetrics(f *nmon.File) error {
+	bm := toBluemixMetrics(f, strings.Split(metricSections, ","))
+	if len(bm) == 0 {
+		return fmt.Errorf("no data found for sections %s", metricSections)
+	}
+
+	if verbose {
+		for _, m := range bm {
+			fmt.Println(m)
+		}
+	}
+
+	if len(dbKey) == 0 {
+		dbKey = os.Getenv("METRICS_DB_KEY")
+	}
+	metricsservice.WriteBluemixMetrics(bm, true, testName, dbKey)
+	return nil
+}
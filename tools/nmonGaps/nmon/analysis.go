@@ -0,0 +1,137 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2017, 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package nmon
+
+import (
+	"sort"
+	"time"
+)
+
+// Gap is a snapshot that was taken later than the capture interval after the previous one
+type Gap struct {
+	Snapshot Snapshot
+	Delta    time.Duration // Time since the previous snapshot
+	Excess   time.Duration // Delta beyond the expected interval
+}
+
+// Gaps reports unexpected gaps in collecting data - snapshots taken at least slack later than the capture interval.
+// T0003 is not reported as it is always late.
+func Gaps(f *File, slack time.Duration) []Gap {
+	var gaps []Gap
+	expected := f.Header.Interval
+	for i := 1; i < len(f.Snapshots); i++ {
+		s := f.Snapshots[i]
+		delta := s.Time.Sub(f.Snapshots[i-1].Time)
+		if delta != expected && delta >= expected+slack && s.Tag != "T0003" {
+			gaps = append(gaps, Gap{Snapshot: s, Delta: delta, Excess: delta - expected})
+		}
+	}
+	return gaps
+}
+
+// CPUBusy returns the CPU_ALL User% + Sys% series. Wait% is excluded as the CPU is idle waiting for I/O.
+func CPUBusy(f *File) (Series, bool) {
+	t := f.CPUAll()
+	if t == nil {
+		return Series{}, false
+	}
+	usr, ok := t.Series("User%")
+	if !ok {
+		return Series{}, false
+	}
+	sys, ok := t.Series("Sys%")
+	if !ok {
+		return Series{}, false
+	}
+	return Combine("CPU_ALL.Busy%", usr, sys), true
+}
+
+// Window is a period in which a series stayed at or above a threshold
+type Window struct {
+	Start   time.Time
+	End     time.Time
+	Samples int
+	Peak    float64
+	Mean    float64
+}
+
+// Duration is the length of the window
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// Windows returns the periods in which the series was at or above threshold for at least minSamples consecutive points
+func Windows(s Series, threshold float64, minSamples int) []Window {
+	var windows []Window
+	var current *Window
+	sum := 0.0
+
+	closeWindow := func() {
+		if current != nil && current.Samples >= minSamples {
+			current.Mean = sum / float64(current.Samples)
+			windows = append(windows, *current)
+		}
+		current = nil
+		sum = 0
+	}
+
+	for _, p := range s.Points {
+		if p.Value < threshold {
+			closeWindow()
+			continue
+		}
+		if current == nil {
+			current = &Window{Start: p.Time, Peak: p.Value}
+		}
+		current.End = p.Time
+		current.Samples++
+		sum += p.Value
+		if p.Value > current.Peak {
+			current.Peak = p.Value
+		}
+	}
+	closeWindow()
+	return windows
+}
+
+// CPUSaturation returns the windows in which CPU busy was at or above threshold percent for at least minSamples snapshots
+func CPUSaturation(f *File, threshold float64, minSamples int) []Window {
+	busy, ok := CPUBusy(f)
+	if !ok {
+		return nil
+	}
+	return Windows(busy, threshold, minSamples)
+}
+
+// DiskBusySummary summarises the %busy of one disk
+type DiskBusySummary struct {
+	Disk      string
+	Stats     Stats
+	OverLimit int // Number of snapshots at or above the busy limit
+}
+
+// DiskBusy summarises DISKBUSY per disk, busiest (by mean) first
+func DiskBusy(f *File, limit float64) []DiskBusySummary {
+	var summaries []DiskBusySummary
+	for _, t := range f.DiskBusy() {
+		for _, s := range t.AllSeries() {
+			summary := DiskBusySummary{Disk: s.Name[len(t.Name)+1:], Stats: s.Stats()}
+			for _, p := range s.Points {
+				if p.Value >= limit {
+					summary.OverLimit++
+				}
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Stats.Mean > summaries[j].Stats.Mean })
+	return summaries
+}
@@ -0,0 +1,72 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package nmon
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+func formatValue(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// WriteCSV writes a table as CSV with a header row. Each row starts with the snapshot time (RFC 3339) and tag.
+func (f *File) WriteCSV(w io.Writer, t *Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"time", "snapshot"}, t.Columns...)); err != nil {
+		return err
+	}
+	for _, s := range t.Samples {
+		row := make([]string, 0, len(t.Columns)+2)
+		row = append(row, s.Time.Format(time.RFC3339), f.Snapshots[s.Snapshot].Tag)
+		for _, v := range s.Values {
+			row = append(row, formatValue(v))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTopCSV writes the TOP samples as CSV with a header row
+func (f *File) WriteTopCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "snapshot", "pid", "%CPU", "%Usr", "%Sys", "ResSet", "MajorFault", "Command"}); err != nil {
+		return err
+	}
+	for _, s := range f.Top {
+		row := []string{
+			s.Time.Format(time.RFC3339),
+			f.Snapshots[s.Snapshot].Tag,
+			strconv.Itoa(s.PID),
+			formatValue(s.CPU),
+			formatValue(s.Usr),
+			formatValue(s.Sys),
+			formatValue(s.ResSetKB),
+			formatValue(s.MajorFault),
+			s.Command,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,366 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+// Package nmon parses nmon capture files into typed time series.
+//
+// An nmon file is a CSV-like file of sections, each line starting with the section name:
+//
+//	AAA,interval,30                          - capture header
+//	BBBP,000,/etc/release,"..."              - system configuration
+//	ZZZZ,T0001,16:20:52,05-DEC-2017          - snapshot timestamp
+//	CPU_ALL,CPU Total host,User%,Sys%,...    - section header, naming the columns
+//	CPU_ALL,T0001,1.2,0.5,...                - one sample per snapshot
+//	TOP,0001234,T0002,12.3,...               - per-process samples
+package nmon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Section names with typed accessors
+const (
+	SectionCPUAll   = "CPU_ALL"
+	SectionMem      = "MEM"
+	SectionDiskBusy = "DISKBUSY"
+	SectionNet      = "NET"
+	SectionTop      = "TOP"
+)
+
+// snapshotTimeLayout is the layout of the date and time fields of ZZZZ lines, e.g. 16:20:52,05-DEC-2017, once
+// rearranged as date then time. Joining them as time,date would make Go read the comma as a fractional second separator.
+const snapshotTimeLayout = "02-Jan-2006 15:04:05"
+
+// snapshotTag matches the T0001 style tags that link samples to snapshots
+var snapshotTag = regexp.MustCompile(`^T\d+$`)
+
+// Header holds the AAA capture header lines
+type Header struct {
+	Program   string
+	Version   string
+	Host      string
+	OS        string
+	Interval  time.Duration // Expected time between snapshots
+	Snapshots int           // Number of snapshots requested
+	Start     time.Time     // Zero if the date or time lines are missing or unparseable
+	Raw       map[string]string
+}
+
+// Snapshot is a ZZZZ line - the time at which a set of samples was taken.
+// nmon records local time without a zone, so times are parsed as UTC.
+type Snapshot struct {
+	Tag  string // e.g. T0001
+	Time time.Time
+}
+
+// Sample is one row of a Table, taken at a snapshot
+type Sample struct {
+	Snapshot int // Index into File.Snapshots
+	Time     time.Time
+	Values   []float64 // One per Table column, NaN if blank or not a number
+}
+
+// Table is a section sampled once per snapshot, such as CPU_ALL or MEM
+type Table struct {
+	Name        string
+	Description string // e.g. "CPU Total myhost"
+	Columns     []string
+	Samples     []Sample
+}
+
+// TopSample is one process from a TOP section
+type TopSample struct {
+	Snapshot   int // Index into File.Snapshots
+	Time       time.Time
+	PID        int
+	CPU        float64 // %CPU
+	Usr        float64 // %Usr
+	Sys        float64 // %Sys
+	ResSetKB   float64 // Resident set size
+	MajorFault float64
+	Command    string
+}
+
+// File is a parsed nmon capture
+type File struct {
+	Header    Header
+	System    map[string][]string // BBB configuration lines by section e.g. BBBP, with the section name removed
+	Snapshots []Snapshot
+	Tables    map[string]*Table // Every sampled section, by name
+	Top       []TopSample
+
+	snapshotIndex map[string]int
+	topColumns    map[string]int
+}
+
+// ParseFile opens and parses an nmon file
+func ParseFile(path string) (*File, error) {
+	// #nosec G304
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads an nmon capture. Lines that can't be understood are skipped, as nmon files from
+// interrupted captures are often truncated.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{
+		Header:        Header{Raw: make(map[string]string)},
+		System:        make(map[string][]string),
+		Tables:        make(map[string]*Table),
+		snapshotIndex: make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		section := fields[0]
+
+		switch {
+		case section == "AAA":
+			f.parseHeader(fields)
+		case strings.HasPrefix(section, "BBB"):
+			f.System[section] = append(f.System[section], strings.TrimPrefix(line, section+","))
+		case section == "ZZZZ":
+			f.parseSnapshot(fields)
+		case section == SectionTop:
+			f.parseTop(fields)
+		case section == "UARG":
+			// Full process arguments - free text that doesn't fit the table model
+		default:
+			f.parseTable(fields)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading nmon data: %w", err)
+	}
+	f.finishHeader()
+	return f, nil
+}
+
+func (f *File) parseHeader(fields []string) {
+	if len(fields) < 3 {
+		return
+	}
+	key, value := fields[1], strings.Join(fields[2:], ",")
+	f.Header.Raw[key] = value
+
+	switch key {
+	case "progname":
+		f.Header.Program = value
+	case "version":
+		f.Header.Version = value
+	case "host":
+		f.Header.Host = value
+	case "OS":
+		f.Header.OS = value
+	case "interval":
+		if seconds, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			f.Header.Interval = time.Duration(seconds) * time.Second
+		}
+	case "snapshots":
+		if n, err := strconv.Atoi(fields[2]); err == nil {
+			f.Header.Snapshots = n
+		}
+	}
+}
+
+// finishHeader works out the start time once both the date and time lines have been seen
+func (f *File) finishHeader() {
+	date, tm := f.Header.Raw["date"], f.Header.Raw["time"]
+	if len(date) == 0 || len(tm) == 0 {
+		return
+	}
+	// AIX uses dots rather than colons in the time
+	tm = strings.ReplaceAll(tm, ".", ":")
+	if start, err := time.Parse(snapshotTimeLayout, date+" "+tm); err == nil {
+		f.Header.Start = start
+	}
+}
+
+func (f *File) parseSnapshot(fields []string) {
+	// ZZZZ,T0001,16:20:52,05-DEC-2017
+	if len(fields) < 4 {
+		return
+	}
+	t, err := time.Parse(snapshotTimeLayout, fields[3]+" "+fields[2])
+	if err != nil {
+		return
+	}
+	f.snapshotIndex[fields[1]] = len(f.Snapshots)
+	f.Snapshots = append(f.Snapshots, Snapshot{Tag: fields[1], Time: t})
+}
+
+func (f *File) parseTable(fields []string) {
+	if len(fields) < 2 {
+		return
+	}
+	name := fields[0]
+	t, ok := f.Tables[name]
+
+	if !snapshotTag.MatchString(fields[1]) {
+		// Section header - the first one wins if a section is repeated
+		if !ok {
+			f.Tables[name] = &Table{Name: name, Description: fields[1], Columns: fields[2:]}
+		}
+		return
+	}
+	if !ok {
+		// Data without a header can't be labelled
+		return
+	}
+	idx, ok := f.snapshotIndex[fields[1]]
+	if !ok {
+		return
+	}
+
+	values := make([]float64, len(t.Columns))
+	for i := range values {
+		values[i] = math.NaN()
+		if i+2 < len(fields) {
+			values[i] = parseValue(fields[i+2])
+		}
+	}
+	t.Samples = append(t.Samples, Sample{Snapshot: idx, Time: f.Snapshots[idx].Time, Values: values})
+}
+
+func (f *File) parseTop(fields []string) {
+	if len(fields) < 3 {
+		return
+	}
+	if !snapshotTag.MatchString(fields[2]) {
+		// Header e.g. TOP,+PID,Time,%CPU,%Usr,%Sys,Size,ResSet,ResText,ResData,ShdLib,MinorFault,MajorFault,Command
+		if strings.HasPrefix(fields[1], "+PID") {
+			f.topColumns = make(map[string]int, len(fields))
+			for i, c := range fields {
+				f.topColumns[c] = i
+			}
+		}
+		return
+	}
+	idx, ok := f.snapshotIndex[fields[2]]
+	if !ok || f.topColumns == nil {
+		return
+	}
+
+	column := func(name string) string {
+		if i, ok := f.topColumns[name]; ok && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	pid, _ := strconv.Atoi(strings.TrimLeft(fields[1], "0"))
+	f.Top = append(f.Top, TopSample{
+		Snapshot:   idx,
+		Time:       f.Snapshots[idx].Time,
+		PID:        pid,
+		CPU:        parseValue(column("%CPU")),
+		Usr:        parseValue(column("%Usr")),
+		Sys:        parseValue(column("%Sys")),
+		ResSetKB:   parseValue(column("ResSet")),
+		MajorFault: parseValue(column("MajorFault")),
+		Command:    column("Command"),
+	})
+}
+
+func parseValue(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+// Table returns the named section, or nil if the capture doesn't include it
+func (f *File) Table(name string) *Table {
+	return f.Tables[name]
+}
+
+// CPUAll returns the CPU_ALL section - User%, Sys%, Wait%, Idle% etc. across all CPUs
+func (f *File) CPUAll() *Table {
+	return f.Tables[SectionCPUAll]
+}
+
+// Mem returns the MEM section - memtotal, memfree, cached etc. in MB
+func (f *File) Mem() *Table {
+	return f.Tables[SectionMem]
+}
+
+// Net returns the NET section - read and write KB/s per interface
+func (f *File) Net() *Table {
+	return f.Tables[SectionNet]
+}
+
+// DiskBusy returns the DISKBUSY sections - %busy per disk. nmon splits the disks across DISKBUSY,
+// DISKBUSY1, DISKBUSY2 etc. when there are more than disks_per_line, so all of them are returned.
+func (f *File) DiskBusy() []*Table {
+	var tables []*Table
+	if t, ok := f.Tables[SectionDiskBusy]; ok {
+		tables = append(tables, t)
+	}
+	for i := 1; ; i++ {
+		t, ok := f.Tables[SectionDiskBusy+strconv.Itoa(i)]
+		if !ok {
+			return tables
+		}
+		tables = append(tables, t)
+	}
+}
+
+// Column returns the index of the named column, or -1
+func (t *Table) Column(name string) int {
+	for i, c := range t.Columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Series returns the named column as a time series
+func (t *Table) Series(column string) (Series, bool) {
+	i := t.Column(column)
+	if i < 0 {
+		return Series{}, false
+	}
+	s := Series{Name: t.Name + "." + column, Points: make([]Point, 0, len(t.Samples))}
+	for _, sample := range t.Samples {
+		if !math.IsNaN(sample.Values[i]) {
+			s.Points = append(s.Points, Point{Time: sample.Time, Value: sample.Values[i]})
+		}
+	}
+	return s, true
+}
+
+// AllSeries returns every column of the table as a time series
+func (t *Table) AllSeries() []Series {
+	series := make([]Series, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		if s, ok := t.Series(c); ok && len(c) > 0 {
+			series = append(series, s)
+		}
+	}
+	return series
+}
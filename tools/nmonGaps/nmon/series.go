@@ -0,0 +1,85 @@
+/*******************************************************************************
+ *
+ * OCO Source Materials
+ * , 5737-D43
+ * (C) Copyright IBM Corp. 2026 All Rights Reserved.
+ * The source code for this program is not  published or otherwise divested of
+ * its trade secrets,  * irrespective of what has been deposited with
+ * the U.S. Copyright Office.
+ ******************************************************************************/
+
+package nmon
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Point is a single value in a time series
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a named time series, e.g. CPU_ALL.User%
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Stats summarises a series
+type Stats struct {
+	Count int
+	Min   float64
+	Max   float64
+	Mean  float64
+	P95   float64
+}
+
+// Stats returns summary statistics for the series. All values are zero for an empty series.
+func (s Series) Stats() Stats {
+	if len(s.Points) == 0 {
+		return Stats{}
+	}
+
+	values := make([]float64, len(s.Points))
+	sum := 0.0
+	for i, p := range s.Points {
+		values[i] = p.Value
+		sum += p.Value
+	}
+	sort.Float64s(values)
+
+	return Stats{
+		Count: len(values),
+		Min:   values[0],
+		Max:   values[len(values)-1],
+		Mean:  sum / float64(len(values)),
+		P95:   values[int(math.Ceil(0.95*float64(len(values))))-1],
+	}
+}
+
+// Combine returns a series with the points of each series added together, matched by time.
+// Times missing from any of the series are left out.
+func Combine(name string, series ...Series) Series {
+	combined := Series{Name: name}
+	if len(series) == 0 {
+		return combined
+	}
+
+	sums := make(map[time.Time]float64)
+	counts := make(map[time.Time]int)
+	for _, s := range series {
+		for _, p := range s.Points {
+			sums[p.Time] += p.Value
+			counts[p.Time]++
+		}
+	}
+	for _, p := range series[0].Points {
+		if counts[p.Time] == len(series) {
+			combined.Points = append(combined.Points, Point{Time: p.Time, Value: sums[p.Time]})
+		}
+	}
+	return combined
+}